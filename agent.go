@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/pkg/build"
+)
+
+// runAgent registers with the DENNIS server given by the `-server` flag in
+// args as a remote agent, resolving the Queries it sends against the
+// resolvers in configFile until interrupted.
+func runAgent(ctx context.Context, configFile string, args []string) int {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer cancel()
+
+	flags := flag.NewFlagSet("agent", flag.ContinueOnError)
+	server := flags.String("server", "", "URL of the DENNIS server to register with, such as https://dennis.example.com")
+	location := flags.String("location", "", "where this agent resolves from, such as EU, shown with it's lookups")
+	token := flags.String(
+		"token", os.Getenv("DENNIS_AGENT_TOKEN"), "token to register with, defaults to $DENNIS_AGENT_TOKEN",
+	)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	switch {
+	case *server == "":
+		return exitError(2, "agent: -server is required")
+	case *location == "":
+		return exitError(2, "agent: -location is required")
+	case *token == "":
+		return exitError(2, "agent: -token or $DENNIS_AGENT_TOKEN is required")
+	}
+
+	cfg, err := config.Read(configFile)
+	if err != nil {
+		return exitError(2, "config: %s", err)
+	}
+
+	log := cfg.Logging.GetLogger()
+
+	// the agent stores nothing itself, it's Lookups are returned to the
+	// server which stores them.
+	api := app.NewServer(nil, cfg.Resolvers, cfg.Resolution, apiv1.NewFeatures(cfg.Features), log)
+	api.SetInstanceID(cfg.GetInstanceID())
+	api.SetLocation(*location)
+	defer api.Close()
+
+	// requests for work are held open by the server for up to 25 seconds.
+	agent := app.NewAgent(api, *server, *token, *location, &http.Client{Timeout: time.Minute}, log)
+
+	log.Info(
+		"starting DENNIS agent...",
+		slog.String("server", *server), slog.String("location", *location),
+		slog.String("version", build.GetVersion()), slog.String("commit", build.GetCommit(7)),
+	)
+
+	err = agent.Run(ctx)
+	if err != nil {
+		return exitError(1, "agent: %s", err)
+	}
+
+	return 0
+}
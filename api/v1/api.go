@@ -15,4 +15,41 @@ type API interface {
 	// does not exist, either because it never did or because it's been
 	// removed, the `NotFound` error code will be returned.
 	GetQuery(ctx context.Context, req *GetQueryRequest) (*GetQueryResponse, error)
+
+	// ListResolvers retrieves the upstream DNS resolvers that each Query is
+	// resolved against, in the order they should be displayed.
+	ListResolvers(ctx context.Context, req *ListResolversRequest) (*ListResolversResponse, error)
+
+	// CreateRecipe defines a new Recipe, a named set of Queries that can be
+	// run against a domain name at once.
+	CreateRecipe(ctx context.Context, req *CreateRecipeRequest) (*CreateRecipeResponse, error)
+
+	// ListRecipes retrieves the Recipes built in to DENNIS, followed by those
+	// defined by users.
+	ListRecipes(ctx context.Context, req *ListRecipesRequest) (*ListRecipesResponse, error)
+
+	// DeleteRecipe removes a user defined Recipe by it's unique ID. Built in
+	// Recipes cannot be deleted.
+	DeleteRecipe(ctx context.Context, req *DeleteRecipeRequest) (*DeleteRecipeResponse, error)
+
+	// RunRecipe creates a Query for each step of a Recipe against a domain
+	// name, grouped together as a RecipeRun.
+	RunRecipe(ctx context.Context, req *RunRecipeRequest) (*RunRecipeResponse, error)
+
+	// GetRecipeRun retrieves a previously run Recipe, and it's Queries, by
+	// it's unique ID.
+	GetRecipeRun(ctx context.Context, req *GetRecipeRunRequest) (*GetRecipeRunResponse, error)
+
+	// GetStatus retrieves the current operational status of DENNIS, such as
+	// whether it is in maintenance mode.
+	GetStatus(ctx context.Context, req *GetStatusRequest) (*GetStatusResponse, error)
+
+	// GetVersion retrieves the build information of DENNIS and the features
+	// compiled into it.
+	GetVersion(ctx context.Context, req *GetVersionRequest) (*GetVersionResponse, error)
+
+	// GetTime retrieves the current time according to the clock of DENNIS,
+	// allowing clients to account for any skew between their own clock and
+	// the timestamps returned by DENNIS.
+	GetTime(ctx context.Context, req *GetTimeRequest) (*GetTimeResponse, error)
 }
@@ -0,0 +1,184 @@
+package apiv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is an implementation of API that makes requests to a DENNIS server
+// over HTTP using it's JSON API.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+var _ API = (*Client)(nil)
+
+// NewClient initializes a Client for the DENNIS server at baseURL, such as
+// `http://localhost:8080`. If hc is nil, net/http.DefaultClient is used.
+func NewClient(baseURL string, hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1",
+		http:    hc,
+	}
+}
+
+// do makes a request with the JSON encoding of body, if not nil, to path and
+// decodes the JSON response into dst. An error response from the server is
+// returned as an *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, dst any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not encode request: %w", err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		var wrapper ErrorWrapper
+		if err := json.NewDecoder(res.Body).Decode(&wrapper); err != nil || wrapper.Error == nil {
+			return &Error{Code: ErrorCodeInternal, Message: "Unexpected response from server: " + res.Status}
+		}
+
+		return wrapper.Error
+	}
+
+	// some requests, such as DeleteRecipe, have no response body.
+	if dst == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(dst); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) CreateQuery(ctx context.Context, req *CreateQueryRequest) (*CreateQueryResponse, error) {
+	res := new(CreateQueryResponse)
+	if err := c.do(ctx, http.MethodPost, "/queries", req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetQuery(ctx context.Context, req *GetQueryRequest) (*GetQueryResponse, error) {
+	res := new(GetQueryResponse)
+	if err := c.do(ctx, http.MethodGet, "/queries/"+url.PathEscape(req.ID), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) ListResolvers(ctx context.Context, req *ListResolversRequest) (*ListResolversResponse, error) {
+	res := new(ListResolversResponse)
+	if err := c.do(ctx, http.MethodGet, "/resolvers", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) CreateRecipe(ctx context.Context, req *CreateRecipeRequest) (*CreateRecipeResponse, error) {
+	res := new(CreateRecipeResponse)
+	if err := c.do(ctx, http.MethodPost, "/recipes", req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) ListRecipes(ctx context.Context, req *ListRecipesRequest) (*ListRecipesResponse, error) {
+	res := new(ListRecipesResponse)
+	if err := c.do(ctx, http.MethodGet, "/recipes", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) DeleteRecipe(ctx context.Context, req *DeleteRecipeRequest) (*DeleteRecipeResponse, error) {
+	res := new(DeleteRecipeResponse)
+	if err := c.do(ctx, http.MethodDelete, "/recipes/"+url.PathEscape(req.ID), nil, nil); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) RunRecipe(ctx context.Context, req *RunRecipeRequest) (*RunRecipeResponse, error) {
+	res := new(RunRecipeResponse)
+	if err := c.do(ctx, http.MethodPost, "/recipes/"+url.PathEscape(req.ID)+"/runs", req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetRecipeRun(ctx context.Context, req *GetRecipeRunRequest) (*GetRecipeRunResponse, error) {
+	res := new(GetRecipeRunResponse)
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(req.ID), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetStatus(ctx context.Context, req *GetStatusRequest) (*GetStatusResponse, error) {
+	res := new(GetStatusResponse)
+	if err := c.do(ctx, http.MethodGet, "/status", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetVersion(ctx context.Context, req *GetVersionRequest) (*GetVersionResponse, error) {
+	res := new(GetVersionResponse)
+	if err := c.do(ctx, http.MethodGet, "/version", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetTime(ctx context.Context, req *GetTimeRequest) (*GetTimeResponse, error) {
+	res := new(GetTimeResponse)
+	if err := c.do(ctx, http.MethodGet, "/time", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package apiv1
+
+// the features are optional capabilities of DENNIS that can be enabled or
+// disabled per deployment in it's configuration, allowing risky features to
+// be dark-launched.
+const (
+	// FeatureAPI is the JSON API served under `/api/v1`.
+	FeatureAPI = "api"
+
+	// FeatureZoneTransfer allows Queries of types AXFR and IXFR, which connect to
+	// a server given by the user rather than a configured resolver.
+	FeatureZoneTransfer = "zoneTransfer"
+
+	// FeatureAdHocResolver allows a Query to be resolved against a server
+	// given by the user, like the `@server` of dig, rather than the
+	// configured resolvers.
+	FeatureAdHocResolver = "adHocResolver"
+
+	// FeatureStatusPage is a page served at `/status` showing the current
+	// health and latency of each resolver, which cannot be used to query
+	// them, so it can be shared more widely than DENNIS itself.
+	FeatureStatusPage = "statusPage"
+)
+
+// DefaultFeatures are all of the features known to DENNIS, and whether or not
+// they are enabled if not configured.
+var DefaultFeatures = map[string]bool{
+	FeatureAPI:           true,
+	FeatureZoneTransfer:  false,
+	FeatureAdHocResolver: false,
+	FeatureStatusPage:    false,
+}
+
+// Features is the set of features known to DENNIS and whether or not they are
+// enabled in a deployment.
+type Features map[string]bool
+
+// NewFeatures returns the Features of a deployment, where overrides enables or
+// disables features from their default. Unknown features are ignored.
+func NewFeatures(overrides map[string]bool) Features {
+	f := make(Features, len(DefaultFeatures))
+
+	for name, enabled := range DefaultFeatures {
+		if override, ok := overrides[name]; ok {
+			enabled = override
+		}
+
+		f[name] = enabled
+	}
+
+	return f
+}
+
+// Enabled returns true if the feature name is enabled.
+func (f Features) Enabled(name string) bool {
+	return f[name]
+}
@@ -2,6 +2,7 @@ package apiv1
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/jamescun/dennis/app/models"
 )
@@ -11,17 +12,152 @@ import (
 type CreateQueryRequest struct {
 	// Type is the DNS record type to query for.
 	//
-	// Required.
-	// Supported type: A, AAAA, CAA, CNAME, DNSKEY, MX, NS, PTR, SOA, SRV,
-	// SVCB and TXT.
+	// Required, unless Types is set.
+	// Supported type: A, AAAA, CAA, CNAME, DNSKEY, DS, HTTPS, LOC, MX, NAPTR,
+	// NS, PTR, SOA, SRV, SSHFP, SVCB, TLSA and TXT. AXFR transfers the zone
+	// Name from Server, and IXFR only it's changes since Serial, see
+	// FeatureZoneTransfer.
 	Type string `json:"type"`
 
-	// Name is the domain name to query for.
+	// Types queries for more than one DNS record type at once, such as A,
+	// AAAA and MX, resolving each type against every resolver. It may be set
+	// instead of Type, with up to 8 of the types supported by Type other than
+	// AXFR and IXFR. See models.Lookup.Type.
+	Types []string `json:"types,omitempty"`
+
+	// Name is the domain name to query for. Internationalized names may be
+	// given in their Unicode form, and will be resolved in their ASCII form.
+	// For type PTR, or Types including PTR, an IPv4 or IPv6 address may be
+	// given, and it's reverse name in `in-addr.arpa` or `ip6.arpa` will be
+	// resolved.
 	//
 	// Required.
 	Name string `json:"name"`
+
+	// Class is the DNS class to query in. If not set, IN is assumed. CH may
+	// be used with type TXT to identify the server that answered, such as
+	// which anycast node, by names like `version.bind`, `id.server` and
+	// `hostname.bind`.
+	//
+	// Supported classes: IN and CH.
+	Class string `json:"class,omitempty"`
+
+	// Server is the address of a DNS server, an IP address or host name
+	// optionally followed by a port, and optionally preceded by `@` as with
+	// dig. It is required for types AXFR and IXFR, to transfer the zone from.
+	// For any other type, it resolves the Query against only that server rather than
+	// the configured resolvers, see FeatureAdHocResolver, and cannot be
+	// combined with Groups.
+	Server string `json:"server,omitempty"`
+
+	// Serial is the serial of the zone Name as it was last transferred from
+	// Server, such as by a previous AXFR, so an IXFR transfers only the
+	// changes made since. It is required for type IXFR, and may not be set
+	// otherwise.
+	Serial *uint32 `json:"serial,omitempty"`
+
+	// Groups optionally resolves the Query against only the resolvers tagged
+	// with at least one of these groups, such as `public` or `eu`, rather
+	// than every resolver. Each group must have at least one resolver. See
+	// models.Resolver.Groups.
+	Groups []string `json:"groups,omitempty"`
+
+	// Priority is the scheduling class of the Query when DENNIS is busy.
+	// Interactive Queries, such as those from the web interface, are always
+	// resolved ahead of batch Queries. If not set, interactive is assumed.
+	//
+	// Supported priorities: interactive and batch.
+	Priority string `json:"priority,omitempty"`
+
+	// Samples is the number of times each resolver is queried, reporting the
+	// distinct answers observed, such as to verify round-robin or weighted
+	// DNS. If not set, each resolver is queried once. It may not exceed the
+	// maximum configured by the server.
+	Samples int `json:"samples,omitempty"`
+
+	// DNSSEC sets the DNSSEC OK (DO) bit on every lookup, reporting whether
+	// each resolver validated the answer. See models.Lookup.DNSSEC.
+	DNSSEC bool `json:"dnssec,omitempty"`
+
+	// DNS64 resolves the A records of the name alongside each lookup that
+	// returned AAAA records synthesized by DNS64, showing the IPv4 addresses
+	// they were synthesized from. Only permitted for type AAAA. See
+	// models.Lookup.DNS64.
+	DNS64 bool `json:"dns64,omitempty"`
+
+	// NoRecursion clears the Recursion Desired (RD) bit on every lookup, as
+	// expected by authoritative servers, which answer only from the zones they
+	// serve. By default recursion is desired.
+	NoRecursion bool `json:"noRecursion,omitempty"`
+
+	// CheckingDisabled sets the Checking Disabled (CD) bit on every lookup,
+	// asking validating resolvers to return answers even if they fail DNSSEC
+	// validation, such as to inspect a broken signature.
+	CheckingDisabled bool `json:"checkingDisabled,omitempty"`
+
+	// Capture stores the raw request and response messages of every lookup,
+	// to debug a resolver returning malformed responses. Responses that
+	// cannot be parsed are stored rather than discarded. See
+	// models.Lookup.Wire.
+	Capture bool `json:"capture,omitempty"`
+
+	// EDNS0 optionally configures the EDNS0 OPT record sent with every
+	// lookup. UDPSize replaces the size configured by the server, Options are
+	// added to those configured by the server. The DNSSEC OK bit is set by
+	// DNSSEC above.
+	EDNS0 *models.EDNS0 `json:"edns0,omitempty"`
+
+	// RunAt optionally schedules the Query to be resolved at a specific time
+	// in the future, such as when a TTL expires or a maintenance window opens,
+	// rather than immediately. Scheduled Queries are resolved as interactive
+	// when they are due. If it is in the past, the Query is resolved
+	// immediately. It may be no more than 7 days in the future.
+	RunAt *time.Time `json:"runAt,omitempty"`
 }
 
+// MaxRunAtDelay is the furthest in the future a Query may be scheduled with
+// CreateQueryRequest.RunAt.
+const MaxRunAtDelay = 7 * 24 * time.Hour
+
+// the priorities are the values to be contained within
+// CreateQueryRequest.Priority to control how a Query is scheduled.
+const (
+	// PriorityInteractive is used for Queries that a user is waiting on.
+	PriorityInteractive = "interactive"
+
+	// PriorityBatch is used for bulk or automated Queries that can tolerate
+	// waiting behind interactive Queries.
+	PriorityBatch = "batch"
+)
+
+// the transfer types are the values of CreateQueryRequest.Type to transfer a
+// zone from an authoritative server, rather than resolve a name against each
+// resolver.
+const (
+	// TypeAXFR transfers every record of the zone.
+	TypeAXFR = "AXFR"
+
+	// TypeIXFR transfers only the changes to the zone since
+	// CreateQueryRequest.Serial, as described by RFC 1995.
+	TypeIXFR = "IXFR"
+)
+
+// IsTransfer returns true if typ is one of the transfer types, TypeAXFR or
+// TypeIXFR.
+func IsTransfer(typ string) bool {
+	return typ == TypeAXFR || typ == TypeIXFR
+}
+
+// the classes are the values to be contained within CreateQueryRequest.Class
+// to control the DNS class queried.
+const (
+	// ClassIN is the Internet class, used by almost every Query.
+	ClassIN = "IN"
+
+	// ClassCH is the CHAOS class, used to identify a DNS server.
+	ClassCH = "CH"
+)
+
 // CreateQueryResponse contains the Query that was created in response to
 // CreateQueryRequest.
 type CreateQueryResponse struct {
@@ -39,6 +175,192 @@ type GetQueryRequest struct {
 // GetQueryRequest.
 type GetQueryResponse struct {
 	Query *models.Query `json:"query"`
+
+	// Age is the time elapsed since the Query was created, in milliseconds,
+	// as measured by the server. Clients should prefer this over comparing
+	// CreatedAt against their own clock, which may be skewed. It is not set
+	// once the Query has finished, as the response may then be cached.
+	Age int `json:"age,omitempty"`
+
+	// UpdatedAt is the UTC timestamp indicating when the Query last changed,
+	// such as when a Lookup was resolved.
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// ETag is an opaque identifier for the current content of the Query,
+	// which changes whenever the Query does. Clients may compare it against a
+	// previous value to detect changes. It is also sent as the HTTP `ETag`
+	// header.
+	ETag string `json:"etag"`
+
+	// ExpiresIn is the time remaining until the Query expires and is
+	// deleted, in milliseconds, as measured by the server. It is not set if
+	// Queries do not expire, or once the Query has finished.
+	ExpiresIn *int `json:"expiresIn,omitempty"`
+}
+
+// ListResolversRequest is the arguments given to API when requesting the
+// configured DNS resolvers.
+type ListResolversRequest struct{}
+
+// ListResolversResponse contains the configured DNS resolvers in response to
+// ListResolversRequest.
+type ListResolversResponse struct {
+	Resolvers []*models.Resolver `json:"resolvers"`
+}
+
+// CreateRecipeRequest is the arguments given to API when defining a new
+// Recipe.
+type CreateRecipeRequest struct {
+	// Name is a short human readable name for the Recipe.
+	//
+	// Required.
+	Name string `json:"name"`
+
+	// Description is an optional longer description of what the Recipe
+	// checks.
+	Description string `json:"description,omitempty"`
+
+	// Steps are the Queries made when the Recipe is run, in order.
+	//
+	// Required.
+	Steps []*models.RecipeStep `json:"steps"`
+}
+
+// CreateRecipeResponse contains the Recipe that was created in response to
+// CreateRecipeRequest.
+type CreateRecipeResponse struct {
+	Recipe *models.Recipe `json:"recipe"`
+}
+
+// ListRecipesRequest is the arguments given to API when requesting all
+// Recipes.
+type ListRecipesRequest struct{}
+
+// ListRecipesResponse contains all Recipes in response to ListRecipesRequest.
+type ListRecipesResponse struct {
+	Recipes []*models.Recipe `json:"recipes"`
+}
+
+// DeleteRecipeRequest is the arguments given to API when removing a user
+// defined Recipe.
+type DeleteRecipeRequest struct {
+	// ID is the unique UUID of the Recipe.
+	ID string `json:"id"`
+}
+
+// DeleteRecipeResponse is returned once a Recipe has been removed in response
+// to DeleteRecipeRequest.
+type DeleteRecipeResponse struct{}
+
+// RunRecipeRequest is the arguments given to API when running a Recipe
+// against a domain name.
+type RunRecipeRequest struct {
+	// ID is the unique UUID of the Recipe to run.
+	//
+	// Required.
+	ID string `json:"id"`
+
+	// Name is the domain name to run the Recipe against.
+	//
+	// Required.
+	Name string `json:"name"`
+
+	// Priority is the scheduling class of the Queries created, see
+	// CreateQueryRequest.Priority.
+	Priority string `json:"priority,omitempty"`
+}
+
+// RunRecipeResponse contains the RecipeRun that was created in response to
+// RunRecipeRequest.
+type RunRecipeResponse struct {
+	Run *models.RecipeRun `json:"run"`
+}
+
+// GetRecipeRunRequest is the arguments given to API when requesting a
+// previously run Recipe.
+type GetRecipeRunRequest struct {
+	// ID is the unique UUID of a previously run Recipe.
+	ID string `json:"id"`
+}
+
+// GetRecipeRunResponse contains the RecipeRun that was requested by ID, and
+// it's Queries, in response to GetRecipeRunRequest.
+type GetRecipeRunResponse struct {
+	Run *models.RecipeRun `json:"run"`
+
+	// Queries are the Queries of the RecipeRun, in the order of it's steps.
+	// Queries that have since expired are omitted.
+	Queries []*models.Query `json:"queries"`
+
+	// Report is the outcome of evaluating the assertions of the RecipeRun's
+	// steps. A RecipeRun without assertions passes once it has finished.
+	Report *models.RecipeReport `json:"report"`
+}
+
+// GetStatusRequest is the arguments given to API when requesting the current
+// operational status of the server.
+type GetStatusRequest struct{}
+
+// GetStatusResponse contains the current operational status of the server in
+// response to GetStatusRequest.
+type GetStatusResponse struct {
+	// Maintenance is true if the server is in maintenance mode. Existing
+	// Queries may be retrieved, but new Queries cannot be created.
+	Maintenance bool `json:"maintenance"`
+
+	// ReadOnly is true if the server cannot reach it's database. Recently
+	// viewed Queries may be retrieved, but new Queries cannot be created.
+	ReadOnly bool `json:"readOnly"`
+
+	// Queued is the number of Queries waiting to be resolved, of any
+	// priority.
+	Queued int `json:"queued"`
+
+	// Busy is true if the backlog of interactive Queries is deep enough that
+	// new Queries may take longer than usual to resolve.
+	Busy bool `json:"busy"`
+}
+
+// GetVersionRequest is the arguments given to API when requesting the build
+// information of the server.
+type GetVersionRequest struct{}
+
+// GetVersionResponse contains the build information of the server in
+// response to GetVersionRequest.
+type GetVersionResponse struct {
+	// Version is the semantic release of the server.
+	Version string `json:"version"`
+
+	// Commit is the git commit the server was built from.
+	Commit string `json:"commit"`
+
+	// Date is the RFC 3339 timestamp of when the server was built, if known.
+	Date string `json:"date,omitempty"`
+
+	// GoVersion is the version of Go the server was compiled with.
+	GoVersion string `json:"goVersion"`
+
+	// Backends are the database backends compiled into the server.
+	Backends []string `json:"backends"`
+
+	// Transports are the network transports the server can use to query
+	// resolvers.
+	Transports []string `json:"transports"`
+
+	// Features are the optional features known to the server, and whether
+	// they are enabled.
+	Features Features `json:"features"`
+}
+
+// GetTimeRequest is the arguments given to API when requesting the current
+// time of the server.
+type GetTimeRequest struct{}
+
+// GetTimeResponse contains the current time of the server in response to
+// GetTimeRequest.
+type GetTimeResponse struct {
+	// Time is the current UTC timestamp according to the server's clock.
+	Time time.Time `json:"time"`
 }
 
 // the error codes are the values to be contained within Error.Code to
@@ -53,6 +375,19 @@ const (
 	// by ID, that does not exist (possibly anymore).
 	ErrorCodeNotFound = "NotFound"
 
+	// ErrorCodeUnauthorized is used when a request requires credentials,
+	// such as the token of a remote agent, which were missing or incorrect.
+	ErrorCodeUnauthorized = "Unauthorized"
+
+	// ErrorCodeFeatureDisabled is used when a request requires a feature
+	// that has not been enabled on the server. See GetVersionResponse.Features.
+	ErrorCodeFeatureDisabled = "FeatureDisabled"
+
+	// ErrorCodeUnavailable is used when a request cannot be completed right
+	// now, such as creating a Query while the server is in maintenance mode,
+	// but may be retried later.
+	ErrorCodeUnavailable = "Unavailable"
+
 	// ErrorCodeInternal is used when an unexpected error occurs on the server
 	// and the request could not be completed.
 	ErrorCodeInternal = "Internal"
@@ -81,6 +416,12 @@ func (e *Error) StatusCode() int {
 		return http.StatusBadRequest
 	case ErrorCodeNotFound:
 		return http.StatusNotFound
+	case ErrorCodeUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorCodeFeatureDisabled:
+		return http.StatusForbidden
+	case ErrorCodeUnavailable:
+		return http.StatusServiceUnavailable
 
 	default:
 		return http.StatusInternalServerError
@@ -1,38 +1,294 @@
 package apiv1
 
 import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/idn"
+
+	"codeberg.org/miekg/dns/dnsutil"
 )
 
 // Validate asserts that all required fields are set, and all set fields are
 // valid.
 func (c *CreateQueryRequest) Validate() error {
+	return c.ValidateAt(time.Now())
+}
+
+// ValidateAt is Validate as of now, which RunAt must not be more than
+// MaxRunAtDelay after.
+func (c *CreateQueryRequest) ValidateAt(now time.Time) error {
 	if c == nil {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".", Message: "Request body is required"}
 	}
 
-	if c.Type == "" {
+	if len(c.Types) > 0 {
+		if err := c.validateTypes(); err != nil {
+			return err
+		}
+	} else if c.Type == "" {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".type", Message: "Type of record is required"}
-	} else if c.Name == "" {
+	}
+
+	if c.Name == "" {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is required"}
 	}
 
-	if !validRecordType(c.Type) {
+	if c.Priority != "" && c.Priority != PriorityInteractive && c.Priority != PriorityBatch {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".priority", Message: "Priority must be interactive or batch"}
+	}
+
+	if c.Class != "" && c.Class != ClassIN && c.Class != ClassCH {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".class", Message: "Class must be IN or CH"}
+	} else if c.Class == ClassCH && !slices.Equal(c.QueryTypes(), []string{"TXT"}) {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".type", Message: "Only TXT records may be queried in class CH"}
+	}
+
+	if c.Samples < 0 {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".samples", Message: "Samples must be zero or greater"}
+	}
+
+	if c.Server != "" {
+		if _, err := c.ServerAddr(); err != nil {
+			return &Error{
+				Code:    ErrorCodeBadRequest,
+				Field:   ".server",
+				Message: "Server must be an IP address or host name, optionally with a port",
+			}
+		}
+	}
+
+	if c.Type == TypeIXFR && c.Serial == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".serial", Message: "Serial to transfer changes since is required"}
+	} else if c.Type != TypeIXFR && c.Serial != nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".serial", Message: "Serial can only be set for type IXFR"}
+	}
+
+	if IsTransfer(c.Type) {
+		if c.Server == "" {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".server", Message: "Server to transfer the zone from is required"}
+		} else if c.Samples > 1 {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".samples", Message: "Zone transfers cannot be sampled"}
+		} else if c.NoRecursion {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".noRecursion", Message: "Zone transfers are never recursive"}
+		} else if c.CheckingDisabled {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".checkingDisabled", Message: "Zone transfers are not validated"}
+		} else if c.Capture {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".capture", Message: "Zone transfers cannot be captured"}
+		} else if len(c.Groups) > 0 {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".groups", Message: "Zone transfers do not use resolvers"}
+		}
+	} else if c.Server != "" && len(c.Groups) > 0 {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".groups", Message: "Groups cannot be set with a server"}
+	}
+
+	for i, group := range c.Groups {
+		if group == "" {
+			return &Error{
+				Code:    ErrorCodeBadRequest,
+				Field:   ".groups[" + strconv.Itoa(i) + "]",
+				Message: "Name of group is required",
+			}
+		}
+	}
+
+	if c.DNS64 && !slices.Contains(c.QueryTypes(), "AAAA") {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".dns64", Message: "DNS64 can only be requested for type AAAA"}
+	}
+
+	if err := validateEDNS0(c.EDNS0); err != nil {
+		return err
+	} else if c.DNSSEC && c.EDNS0 != nil && c.EDNS0.Disabled {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".dnssec", Message: "DNSSEC cannot be requested with EDNS0 disabled"}
+	}
+
+	if c.RunAt != nil && c.RunAt.Sub(now) > MaxRunAtDelay {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".runAt",
+			Message: "Queries cannot be scheduled more than 7 days in the future",
+		}
+	}
+
+	if len(c.Types) == 0 && !validRecordType(c.Type) && !IsTransfer(c.Type) {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".type", Message: "Record type is not supported"}
 	}
 
-	if len(c.Name) < 4 {
+	name, err := c.QueryName()
+	if err != nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is invalid"}
+	}
+
+	if SingleLabel(name) {
+		// single-label names, such as `intranet`, are only permitted by
+		// servers configured to allow internal names.
+		if len(name) > 63 || !label.MatchString(name) {
+			return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is invalid"}
+		}
+	} else if len(name) < 4 {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain must be at least 4 characters"}
-	} else if len(c.Name) > 253 {
+	} else if len(name) > 253 {
 		// 253 is the upper limit of a DNS packet.
 		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain cannot be longest than 253 characters"}
-	} else if !validRecordName(c.Name) {
+	} else if !validRecordName(name) {
 		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is invalid"}
 	}
 
 	return nil
 }
 
+// maxQueryTypes is the maximum number of types a Query may resolve at once.
+const maxQueryTypes = 8
+
+// validateTypes asserts that the Types of a Query can be resolved together.
+func (c *CreateQueryRequest) validateTypes() error {
+	if c.Type != "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".types", Message: "Only one of type and types can be set"}
+	} else if len(c.Types) > maxQueryTypes {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".types",
+			Message: "Cannot query more than " + strconv.Itoa(maxQueryTypes) + " types at once",
+		}
+	}
+
+	for i, t := range c.Types {
+		field := ".types[" + strconv.Itoa(i) + "]"
+
+		if !validRecordType(t) {
+			return &Error{Code: ErrorCodeBadRequest, Field: field, Message: "Record type is not supported"}
+		} else if slices.Index(c.Types, t) != i {
+			return &Error{Code: ErrorCodeBadRequest, Field: field, Message: "Record type is repeated"}
+		}
+	}
+
+	return nil
+}
+
+// QueryTypes returns each type of record resolved for the Query, Types if it
+// is set, otherwise Type.
+func (c *CreateQueryRequest) QueryTypes() []string {
+	if len(c.Types) > 0 {
+		return c.Types
+	}
+
+	return []string{c.Type}
+}
+
+// QueryName returns the name that is resolved for the Query, in it's canonical
+// form given by NormalizeName. Internationalized names are resolved in their
+// ASCII form, and an IP address given with type PTR, including among Types, is
+// resolved as it's `in-addr.arpa` or `ip6.arpa` reverse name.
+func (c *CreateQueryRequest) QueryName() (string, error) {
+	name := NormalizeName(c.Name)
+
+	if slices.Contains(c.QueryTypes(), "PTR") {
+		if addr, err := netip.ParseAddr(name); err == nil {
+			return strings.TrimSuffix(dnsutil.ReverseAddr(addr.Unmap()), "."), nil
+		}
+	}
+
+	return idn.ToASCII(name)
+}
+
+// NormalizeName returns name in the canonical form it is stored and resolved
+// in, lowercase and without surrounding whitespace or a trailing dot, so names
+// pasted from a zone file, such as `Example.COM.`, are accepted.
+func NormalizeName(name string) string {
+	name = strings.TrimSpace(name)
+	name = strings.TrimSuffix(name, ".")
+
+	return strings.ToLower(name)
+}
+
+// ServerAddr returns the address of the server a zone is transferred from, or
+// a Query is resolved against, including it's port, which is 53 if not given.
+// Host names are normalized, but not resolved.
+func (c *CreateQueryRequest) ServerAddr() (string, error) {
+	server := strings.TrimPrefix(strings.TrimSpace(c.Server), "@")
+
+	if addr, err := netip.ParseAddr(server); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), 53).String(), nil
+	}
+
+	if addrPort, err := netip.ParseAddrPort(server); err == nil {
+		if addrPort.Port() == 0 {
+			return "", errors.New("port must not be zero")
+		}
+
+		return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()).String(), nil
+	}
+
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, "53"
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", errors.New("port must be between 1 and 65535")
+	}
+
+	// single-label host names, such as `localhost`, are resolved by the
+	// system as dig would.
+	host = NormalizeName(host)
+	if !validRecordName(host) && !(SingleLabel(host) && len(host) <= 63 && label.MatchString(host)) {
+		return "", errors.New("host name is invalid")
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// maxEDNS0Options is the maximum number of EDNS0 options a Query may set.
+const maxEDNS0Options = 8
+
+// validateEDNS0 asserts that the UDP buffer size and options of an EDNS0 OPT
+// record can be sent to a resolver.
+func validateEDNS0(e *models.EDNS0) error {
+	if e == nil {
+		return nil
+	}
+
+	if e.Disabled && (e.UDPSize != 0 || len(e.Options) > 0) {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".edns0.disabled",
+			Message: "UDP size and options cannot be set with EDNS0 disabled",
+		}
+	} else if e.UDPSize != 0 && (e.UDPSize < 512 || e.UDPSize > 4096) {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".edns0.udpSize", Message: "UDP size must be between 512 and 4096"}
+	} else if len(e.Options) > maxEDNS0Options {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".edns0.options",
+			Message: "Cannot set more than " + strconv.Itoa(maxEDNS0Options) + " options",
+		}
+	}
+
+	for i, option := range e.Options {
+		field := ".edns0.options[" + strconv.Itoa(i) + "]"
+
+		if option == nil {
+			return &Error{Code: ErrorCodeBadRequest, Field: field, Message: "Option is required"}
+		} else if option.Code < 0 || option.Code > 65535 {
+			return &Error{Code: ErrorCodeBadRequest, Field: field + ".code", Message: "Code must be between 0 and 65535"}
+		} else if len(option.Data) > 512 {
+			return &Error{Code: ErrorCodeBadRequest, Field: field + ".data", Message: "Data cannot be longer than 256 bytes"}
+		} else if _, err := hex.DecodeString(option.Data); err != nil {
+			return &Error{Code: ErrorCodeBadRequest, Field: field + ".data", Message: "Data must be hex encoded"}
+		}
+	}
+
+	return nil
+}
+
 // Validate asserts that all required fields are set, and all set fields are
 // valid.
 func (g *GetQueryRequest) Validate() error {
@@ -47,11 +303,165 @@ func (g *GetQueryRequest) Validate() error {
 	return nil
 }
 
+const (
+	// maxRecipeSteps is the maximum number of steps in a Recipe.
+	maxRecipeSteps = 20
+
+	// maxStepAssertions is the maximum number of assertions in a step of a
+	// Recipe.
+	maxStepAssertions = 10
+)
+
+// Validate asserts that all required fields are set, and all set fields are
+// valid.
+func (c *CreateRecipeRequest) Validate() error {
+	if c == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".", Message: "Request body is required"}
+	}
+
+	if c.Name == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of recipe is required"}
+	} else if len(c.Name) > 64 {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".name",
+			Message: "Name of recipe cannot be longer than 64 characters",
+		}
+	} else if len(c.Description) > 256 {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".description",
+			Message: "Description of recipe cannot be longer than 256 characters",
+		}
+	}
+
+	if len(c.Steps) < 1 {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".steps", Message: "At least one step is required"}
+	} else if len(c.Steps) > maxRecipeSteps {
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   ".steps",
+			Message: "Recipe cannot have more than " + strconv.Itoa(maxRecipeSteps) + " steps",
+		}
+	}
+
+	for i, step := range c.Steps {
+		field := ".steps[" + strconv.Itoa(i) + "]"
+
+		if step == nil {
+			return &Error{Code: ErrorCodeBadRequest, Field: field, Message: "Step is required"}
+		} else if !validRecordType(step.Type) {
+			return &Error{Code: ErrorCodeBadRequest, Field: field + ".type", Message: "Record type is not supported"}
+		} else if step.Prefix != "" && !recipePrefix.MatchString(step.Prefix) {
+			return &Error{
+				Code:    ErrorCodeBadRequest,
+				Field:   field + ".prefix",
+				Message: "Prefix must be one or more valid labels",
+			}
+		} else if len(step.Assertions) > maxStepAssertions {
+			return &Error{
+				Code:    ErrorCodeBadRequest,
+				Field:   field + ".assertions",
+				Message: "Step cannot have more than " + strconv.Itoa(maxStepAssertions) + " assertions",
+			}
+		}
+
+		for j, assertion := range step.Assertions {
+			if err := validateAssertion(field+".assertions["+strconv.Itoa(j)+"]", assertion); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAssertion asserts that an Assertion of a Recipe step is of a known
+// kind, and it's value can be interpreted as that kind.
+func validateAssertion(field string, a *models.Assertion) error {
+	if a == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: field, Message: "Assertion is required"}
+	} else if a.Value == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: field + ".value", Message: "Value of assertion is required"}
+	}
+
+	_, err := a.Compile()
+	if err == nil {
+		return nil
+	}
+
+	switch a.Kind {
+	case models.AssertionRegex:
+		return &Error{Code: ErrorCodeBadRequest, Field: field + ".value", Message: "Value must be a valid regular expression"}
+	case models.AssertionCIDR:
+		return &Error{Code: ErrorCodeBadRequest, Field: field + ".value", Message: "Value must be a valid CIDR range"}
+	default:
+		return &Error{
+			Code:    ErrorCodeBadRequest,
+			Field:   field + ".kind",
+			Message: "Kind of assertion must be one of exact, regex or cidr",
+		}
+	}
+}
+
+// recipePrefix is a regex that matches one or more labels of a domain name,
+// such as `www` or `_dmarc`, that can be prepended to a domain name.
+var recipePrefix = regexp.MustCompile(`^[a-z0-9_\-]+(\.[a-z0-9_\-]+)*$`)
+
+// Validate asserts that all required fields are set, and all set fields are
+// valid.
+func (d *DeleteRecipeRequest) Validate() error {
+	if d == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".", Message: "Request body is required"}
+	}
+
+	if d.ID == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".id", Message: "ID of Recipe is required"}
+	}
+
+	return nil
+}
+
+// Validate asserts that all required fields are set, and all set fields are
+// valid.
+func (r *RunRecipeRequest) Validate() error {
+	if r == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".", Message: "Request body is required"}
+	}
+
+	if r.ID == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".id", Message: "ID of Recipe is required"}
+	} else if r.Name == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is required"}
+	}
+
+	if r.Priority != "" && r.Priority != PriorityInteractive && r.Priority != PriorityBatch {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".priority", Message: "Priority must be interactive or batch"}
+	}
+
+	return nil
+}
+
+// Validate asserts that all required fields are set, and all set fields are
+// valid.
+func (g *GetRecipeRunRequest) Validate() error {
+	if g == nil {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".", Message: "Request body is required"}
+	}
+
+	if g.ID == "" {
+		return &Error{Code: ErrorCodeBadRequest, Field: ".id", Message: "ID of Recipe run is required"}
+	}
+
+	return nil
+}
+
 // validRecordType returns true if DNS record type t is a type supported by
 // DENNIS.
 func validRecordType(t string) bool {
 	switch t {
-	case "A", "AAAA", "CAA", "CNAME", "DNSKEY", "MX", "NS", "PTR", "SOA", "SRV", "SVCB", "TXT":
+	case "A", "AAAA", "CAA", "CNAME", "DNSKEY", "DS", "HTTPS", "LOC", "MX", "NAPTR", "NS", "PTR", "SOA", "SRV", "SSHFP",
+		"SVCB", "TLSA", "TXT":
 		return true
 
 	default:
@@ -59,15 +469,37 @@ func validRecordType(t string) bool {
 	}
 }
 
-// hostname is a regex that matches a hostname. The TLD must be between 2 and
-// 18 characters in length (not including `xn--` for i18n).
+// label is a regex that matches a label of a hostname. Underscores are
+// permitted, as they are used by names such as `_dmarc` and `_sip._tcp`.
+var label = regexp.MustCompile(`^[a-z0-9_\-]+$`)
+
+// tld is a regex that matches the TLD of a hostname. The TLD must be between 1
+// and 18 characters in length (not including `xn--` for i18n).
 //
 // fun fact: longest is 18 characters, `.northwesternmutual`.
-var hostname = regexp.MustCompile(`^([a-z0-9\-\.]+)\.((xn\-\-)?[a-z0-9]{1,18})$`)
+var tld = regexp.MustCompile(`^(xn\-\-)?[a-z0-9]{1,18}$`)
+
+// SingleLabel returns true if name is a single label, such as `intranet`,
+// rather than a name beneath a TLD.
+func SingleLabel(name string) bool {
+	return !strings.Contains(name, ".")
+}
 
 // validRecordName returns true if DNS record name t is a (roughly) valid
 // hostname. It doesn't actually resolve the name itself, just checks if it
 // is likely to be accepted by a DNS resolver.
 func validRecordName(n string) bool {
-	return hostname.MatchString(n)
+	labels := strings.Split(n, ".")
+	if len(labels) < 2 {
+		return false
+	}
+
+	for _, l := range labels[:len(labels)-1] {
+		// 63 is the upper limit of a single label.
+		if len(l) > 63 || !label.MatchString(l) {
+			return false
+		}
+	}
+
+	return tld.MatchString(labels[len(labels)-1])
 }
@@ -0,0 +1,136 @@
+package apiv1
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"Example.COM.", "example.com"},
+		{"  www.example.com\t", "www.example.com"},
+		{"intranet.", "intranet"},
+		{".", ""},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeName(test.name); got != test.want {
+			t.Errorf("NormalizeName(%q): expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestCreateQueryRequestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   *CreateQueryRequest
+		field string
+	}{
+		{"Valid", &CreateQueryRequest{Type: "A", Name: "example.com"}, ""},
+		{"TrailingDot", &CreateQueryRequest{Type: "A", Name: "Example.COM."}, ""},
+		{"Underscore", &CreateQueryRequest{Type: "TXT", Name: "_dmarc.example.com"}, ""},
+		{"UnderscoreService", &CreateQueryRequest{Type: "SRV", Name: "_sip._tcp.example.com"}, ""},
+		{"UnderscoreTLD", &CreateQueryRequest{Type: "A", Name: "example._com"}, ".name"},
+		{"SingleLabel", &CreateQueryRequest{Type: "A", Name: "intranet"}, ""},
+		{"SingleLabelInvalid", &CreateQueryRequest{Type: "A", Name: "intra net"}, ".name"},
+		{"TooShort", &CreateQueryRequest{Type: "A", Name: "a.b"}, ".name"},
+		{"PTRAddr", &CreateQueryRequest{Type: "PTR", Name: "192.0.2.1"}, ""},
+		{"PTRAddr6", &CreateQueryRequest{Type: "PTR", Name: "2001:db8::1"}, ""},
+		{"PTRTypes", &CreateQueryRequest{Types: []string{"PTR"}, Name: "192.0.2.1"}, ""},
+		{"TypeAndTypes", &CreateQueryRequest{Type: "A", Types: []string{"AAAA"}, Name: "example.com"}, ".types"},
+		{"RepeatedType", &CreateQueryRequest{Types: []string{"A", "A"}, Name: "example.com"}, ".types[1]"},
+		{"Server", &CreateQueryRequest{Type: "A", Name: "example.com", Server: "@ns1.example.com"}, ""},
+		{"ServerInvalid", &CreateQueryRequest{Type: "A", Name: "example.com", Server: "192.0.2.1:0"}, ".server"},
+		{"Transfer", &CreateQueryRequest{Type: TypeAXFR, Name: "example.com", Server: "192.0.2.53"}, ""},
+		{"TransferNoServer", &CreateQueryRequest{Type: TypeAXFR, Name: "example.com"}, ".server"},
+		{"IncrementalNoSerial", &CreateQueryRequest{Type: TypeIXFR, Name: "example.com", Server: "192.0.2.53"}, ".serial"},
+		{"SerialNotIncremental", &CreateQueryRequest{Type: "A", Name: "example.com", Serial: new(uint32(1))}, ".serial"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.req.Validate()
+
+			var apiErr *Error
+			switch {
+			case test.field == "" && err != nil:
+				t.Errorf("expected no error, got %v", err)
+			case test.field != "" && (!errors.As(err, &apiErr) || apiErr.Field != test.field):
+				t.Errorf("expected error of field %q, got %v", test.field, err)
+			}
+		})
+	}
+}
+
+func TestCreateQueryRequestQueryName(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *CreateQueryRequest
+		want string
+	}{
+		{"Normalized", &CreateQueryRequest{Type: "A", Name: "Example.COM."}, "example.com"},
+		{"Unicode", &CreateQueryRequest{Type: "A", Name: "bücher.example"}, "xn--bcher-kva.example"},
+		{"PTR", &CreateQueryRequest{Type: "PTR", Name: "192.0.2.1"}, "1.2.0.192.in-addr.arpa"},
+		{"PTRMapped", &CreateQueryRequest{Type: "PTR", Name: "::ffff:192.0.2.1"}, "1.2.0.192.in-addr.arpa"},
+		{
+			"PTR6", &CreateQueryRequest{Type: "PTR", Name: "2001:db8::1"},
+			"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+		},
+		{"PTRTypes", &CreateQueryRequest{Types: []string{"TXT", "PTR"}, Name: "192.0.2.1"}, "1.2.0.192.in-addr.arpa"},
+		{"PTRName", &CreateQueryRequest{Type: "PTR", Name: "1.2.0.192.in-addr.arpa."}, "1.2.0.192.in-addr.arpa"},
+		{"NotPTR", &CreateQueryRequest{Type: "A", Name: "192.0.2.1"}, "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.req.QueryName()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateQueryRequestServerAddr(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+		valid  bool
+	}{
+		{"192.0.2.1", "192.0.2.1:53", true},
+		{"@192.0.2.1", "192.0.2.1:53", true},
+		{"192.0.2.1:5353", "192.0.2.1:5353", true},
+		{"::ffff:192.0.2.1", "192.0.2.1:53", true},
+		{"2001:db8::1", "[2001:db8::1]:53", true},
+		{"[2001:db8::1]:5353", "[2001:db8::1]:5353", true},
+		{"NS1.Example.COM.", "ns1.example.com:53", true},
+		{"@ns1.example.com:5353", "ns1.example.com:5353", true},
+		{"localhost", "localhost:53", true},
+		{"192.0.2.1:0", "", false},
+		{"ns1.example.com:0", "", false},
+		{"ns1.example.com:65536", "", false},
+		{"ns1.example.com:dns", "", false},
+		{"ns1 example.com", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.server, func(t *testing.T) {
+			got, err := (&CreateQueryRequest{Server: test.server}).ServerAddr()
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("expected valid %t, got error %v", test.valid, err)
+			}
+
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+	"github.com/jamescun/dennis/app/views/text"
+
+	"github.com/gofrs/uuid"
+)
+
+// Admin implements the JSON-based HTTP interface for operators of DENNIS to
+// inspect and manage a running instance. It has no authentication of it's
+// own, so must only be exposed on a trusted network.
+type Admin struct {
+	server    *Server
+	telemetry *Telemetry
+	reports   *Reports
+
+	// compressor is nil if compression is not configured.
+	compressor *db.Compressor
+
+	// errors are the unexpected errors retained by the UI and API.
+	errors *Errors
+
+	// support produces support bundles.
+	support *Support
+
+	// pprof exposes the Go runtime profiler if set.
+	pprof bool
+	log   *slog.Logger
+}
+
+// NewAdmin initializes a new HTTP admin interface for a Server, and a logger
+// for error messages. If pprof is set, the Go runtime profiler is exposed at
+// `/debug/pprof/`.
+func NewAdmin(
+	server *Server, telemetry *Telemetry, reports *Reports, compressor *db.Compressor, errs *Errors, support *Support,
+	pprof bool, log *slog.Logger,
+) *Admin {
+	return &Admin{
+		server:     server,
+		telemetry:  telemetry,
+		reports:    reports,
+		compressor: compressor,
+		errors:     errs,
+		support:    support,
+		pprof:      pprof,
+		log:        log,
+	}
+}
+
+// Routes applies the path-based routes of Admin to an HTTP router.
+func (a *Admin) Routes(r *web.Router) {
+	r.NotFound(a.NotFound)
+	r.ErrorHandler(a.ErrorHandler)
+
+	r.Get("/telemetry", a.GetTelemetry)
+	r.Get("/maintenance", a.GetMaintenance)
+	r.Put("/maintenance", a.SetMaintenance)
+	r.Get("/features", a.ListFeatures)
+	r.Get("/reports/duplicates", a.GetDuplicateReport)
+	r.Get("/db/compression", a.GetCompressionStats)
+	r.Get("/schedule", a.ListScheduled)
+	r.Get("/schedule.ics", a.GetScheduleCalendar)
+	r.Get("/diagnostics", a.GetDiagnostics)
+	r.Get("/errors/{id}", a.GetError)
+	r.Get("/resolvers", a.ListResolvers)
+	r.Post("/resolvers", a.AddResolver)
+	r.Get("/resolvers/changes", a.ListResolverChanges)
+	r.Post("/resolvers/import", a.ImportResolvers)
+	r.Get("/resolvers/fingerprints", a.ListResolverFingerprints)
+	r.Put("/resolvers/*", a.SetResolverDisabled)
+	r.Delete("/resolvers/*", a.RemoveResolver)
+	r.Post("/queries/capture", a.CaptureQuery)
+	r.Get("/support/bundle", a.GetSupportBundle)
+	r.Get("/agents", a.ListAgents)
+
+	if a.pprof {
+		r.Handle("/debug/pprof/*", http.HandlerFunc(pprof.Index))
+		r.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+		r.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+		r.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+		r.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+	}
+}
+
+// AgentList is the response body of the agents endpoint.
+type AgentList struct {
+	// Agents are the remote agents currently registered, in the order they
+	// registered.
+	Agents []*AgentInfo `json:"agents"`
+}
+
+// ListAgents returns the remote agents currently registered, so operators
+// can see where Queries are resolved from.
+func (a *Admin) ListAgents(ctx context.Context, r *web.Request) (web.Template, error) {
+	if a.server.agents == nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeFeatureDisabled, Message: "Remote agents are not configured"}
+	}
+
+	return web.JSON(&AgentList{Agents: a.server.agents.List()}), nil
+}
+
+// ResolverChanges is the response body of the resolver changes endpoint.
+type ResolverChanges struct {
+	// Changes are every recorded change to the configured resolvers, most
+	// recent first.
+	Changes []*models.ResolverChange `json:"changes"`
+}
+
+// ListResolverChanges returns every recorded change to the configured
+// resolvers, so unexpected results can be correlated with them.
+func (a *Admin) ListResolverChanges(ctx context.Context, r *web.Request) (web.Template, error) {
+	changes, err := a.server.ListResolverChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(&ResolverChanges{Changes: changes}), nil
+}
+
+// ResolverFingerprints is the response body of the resolver fingerprints
+// endpoint.
+type ResolverFingerprints struct {
+	// Fingerprints are every recorded fingerprint of the resolver, most
+	// recent first.
+	Fingerprints []*models.ResolverFingerprint `json:"fingerprints"`
+}
+
+// ListResolverFingerprints returns the history of the fingerprint of the
+// resolver named by the `resolver` query parameter, showing when the anycast
+// site answering it or the transports it supports changed.
+func (a *Admin) ListResolverFingerprints(ctx context.Context, r *web.Request) (web.Template, error) {
+	fps, err := a.server.ListResolverFingerprints(ctx, r.URL.Query().Get("resolver"))
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(&ResolverFingerprints{Fingerprints: fps}), nil
+}
+
+// ResolverImport is the request body of the resolver import endpoint.
+type ResolverImport struct {
+	// ResolvConf is the content of a resolv.conf file, a Resolver is
+	// imported for each of it's nameservers.
+	ResolvConf string `json:"resolvConf"`
+}
+
+// ImportedResolvers is the response body of the resolver import endpoint.
+type ImportedResolvers struct {
+	// Added are the names of the Resolvers that were imported.
+	Added []string `json:"added"`
+
+	// Duplicates are the addresses of nameservers that were not imported, as
+	// they are already queried by a Resolver.
+	Duplicates []string `json:"duplicates"`
+}
+
+// ImportResolvers adds a Resolver for each nameserver of a resolv.conf file,
+// such as to compare every resolver used by a fleet of hosts. They are kept
+// until DENNIS restarts.
+func (a *Admin) ImportResolvers(ctx context.Context, r *web.Request) (web.Template, error) {
+	var req ResolverImport
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+
+	added, duplicates, err := a.server.ImportResolvers(ctx, req.ResolvConf, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("resolvers imported", slog.Int("added", len(added)), slog.Int("duplicates", len(duplicates)))
+
+	return web.JSON(&ImportedResolvers{Added: added, Duplicates: duplicates}), nil
+}
+
+// ManagedResolvers is the response body of the resolver list endpoint.
+type ManagedResolvers struct {
+	// Resolvers are every Resolver, including those disabled.
+	Resolvers []*ManagedResolver `json:"resolvers"`
+}
+
+// ListResolvers returns every Resolver, where it came from and whether it
+// has been disabled.
+func (a *Admin) ListResolvers(ctx context.Context, r *web.Request) (web.Template, error) {
+	return web.JSON(&ManagedResolvers{Resolvers: a.server.ManagedResolvers()}), nil
+}
+
+// AddResolver adds a Resolver, configured as it would be in the
+// configuration file, without restarting DENNIS. It is kept in the database
+// so is restored when DENNIS restarts.
+func (a *Admin) AddResolver(ctx context.Context, r *web.Request) (web.Template, error) {
+	var req config.Resolver
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+
+	err := a.server.AddResolver(ctx, &req, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("resolver added", slog.String("resolver", req.Name))
+
+	return web.JSON(&req), nil
+}
+
+// ResolverState is the request and response body of the resolver update
+// endpoint.
+type ResolverState struct {
+	// Disabled is true if Queries are not resolved against the Resolver.
+	Disabled bool `json:"disabled"`
+}
+
+// SetResolverDisabled disables or enables the Resolver named by the path,
+// such as one misbehaving that cannot be removed from the configuration file
+// without a restart.
+func (a *Admin) SetResolverDisabled(ctx context.Context, r *web.Request) (web.Template, error) {
+	name, err := resolverNameParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req ResolverState
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+
+	err = a.server.SetResolverDisabled(ctx, name, req.Disabled, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("resolver state changed", slog.String("resolver", name), slog.Bool("disabled", req.Disabled))
+
+	return web.JSON(&req), nil
+}
+
+// RemoveResolver removes the Resolver named by the path, which must have
+// been added or imported through the admin interface.
+func (a *Admin) RemoveResolver(ctx context.Context, r *web.Request) (web.Template, error) {
+	name, err := resolverNameParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.server.RemoveResolver(ctx, name, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("resolver removed", slog.String("resolver", name))
+
+	return nil, nil
+}
+
+// resolverNameParam returns the name of the Resolver in the path, which may
+// itself contain slashes, such as those of imported Resolvers.
+func resolverNameParam(ctx context.Context) (string, error) {
+	name, err := url.PathUnescape(web.URLParam(ctx, "*"))
+	if err != nil || name == "" {
+		return "", &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: "name", Message: "Invalid resolver name"}
+	}
+
+	return name, nil
+}
+
+// CaptureQuery creates a Query, waits for it to be resolved and returns the
+// DNS messages exchanged with each resolver as a pcap file, to hand to a
+// network team as evidence. The ID of the Query is returned in the
+// `Query-Id` header.
+func (a *Admin) CaptureQuery(ctx context.Context, r *web.Request) (web.Template, error) {
+	var req apiv1.CreateQueryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+
+	query, capture, err := a.server.CaptureQuery(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("query captured", slog.String("query_id", query.ID.String()))
+
+	filename := "dennis-" + query.ID.String() + ".pcap"
+	tpl := web.WithHeader(&pcapFile{capture: capture}, "Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	return web.WithHeader(tpl, "Query-Id", query.ID.String()), nil
+}
+
+// pcapFile is a Template that renders a PacketCapture as a pcap file.
+type pcapFile struct {
+	capture *PacketCapture
+}
+
+func (p *pcapFile) ContentType() string {
+	return "application/vnd.tcpdump.pcap"
+}
+
+func (p *pcapFile) Render(_ context.Context, w io.Writer) error {
+	return p.capture.WritePcap(w)
+}
+
+// GetSupportBundle returns a support bundle as a gzipped tarball, to attach
+// to a bug report against DENNIS. It is produced in full before it is
+// returned, so an error is not mistaken for a truncated bundle.
+func (a *Admin) GetSupportBundle(ctx context.Context, r *web.Request) (web.Template, error) {
+	name := "dennis-support-" + a.server.clock.Now().UTC().Format("20060102T150405Z")
+
+	buf := new(bytes.Buffer)
+	if err := a.support.WriteBundle(ctx, buf, name); err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("support bundle produced", slog.Int("bytes", buf.Len()))
+
+	return web.WithHeader(&supportBundle{buf: buf}, "Content-Disposition", `attachment; filename="`+name+`.tar.gz"`), nil
+}
+
+// supportBundle is a Template that renders a support bundle already written
+// to buf.
+type supportBundle struct {
+	buf *bytes.Buffer
+}
+
+func (b *supportBundle) ContentType() string {
+	return "application/gzip"
+}
+
+func (b *supportBundle) Render(_ context.Context, w io.Writer) error {
+	_, err := b.buf.WriteTo(w)
+	return err
+}
+
+// GetError returns an unexpected error by the ID of the request it occurred
+// in, as shown to the user, if it is still retained.
+func (a *Admin) GetError(ctx context.Context, r *web.Request) (web.Template, error) {
+	id, err := uuid.FromString(web.URLParam(ctx, "id"))
+	if err != nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: "id", Message: "Invalid UUID for request ID"}
+	}
+
+	logged := a.errors.get(id)
+	if logged == nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Error not found by request ID"}
+	}
+
+	return web.JSON(logged), nil
+}
+
+// GetDiagnostics returns the internal state of the Server, such as the depth
+// of the resolution queue.
+func (a *Admin) GetDiagnostics(ctx context.Context, r *web.Request) (web.Template, error) {
+	return web.JSON(a.server.Diagnostics()), nil
+}
+
+// GetTelemetry returns the anonymous usage statistics that are, or would be
+// if enabled, reported by Telemetry.
+func (a *Admin) GetTelemetry(ctx context.Context, r *web.Request) (web.Template, error) {
+	return web.JSON(a.telemetry.Report()), nil
+}
+
+// Maintenance is the request and response body of the maintenance endpoints.
+type Maintenance struct {
+	// Enabled is true if DENNIS is in maintenance mode.
+	Enabled bool `json:"enabled"`
+}
+
+// GetMaintenance returns whether DENNIS is currently in maintenance mode.
+func (a *Admin) GetMaintenance(ctx context.Context, r *web.Request) (web.Template, error) {
+	status, err := a.server.GetStatus(ctx, &apiv1.GetStatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(&Maintenance{Enabled: status.Maintenance}), nil
+}
+
+// SetMaintenance enables or disables maintenance mode, while in maintenance
+// mode existing Queries may be viewed but new Queries cannot be created.
+func (a *Admin) SetMaintenance(ctx context.Context, r *web.Request) (web.Template, error) {
+	var req Maintenance
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+
+	a.server.SetMaintenance(req.Enabled)
+
+	r.Log().Info("maintenance mode changed", slog.Bool("enabled", req.Enabled))
+
+	return web.JSON(&req), nil
+}
+
+// FeatureList is the response body of the features endpoint.
+type FeatureList struct {
+	// Features are the optional features known to DENNIS, and whether or
+	// not they are enabled.
+	Features apiv1.Features `json:"features"`
+}
+
+// ListFeatures returns whether each optional feature of DENNIS is enabled,
+// which is otherwise only reported by the API, and so cannot be seen when the
+// API itself is disabled.
+func (a *Admin) ListFeatures(ctx context.Context, r *web.Request) (web.Template, error) {
+	return web.JSON(&FeatureList{Features: a.server.features}), nil
+}
+
+// GetDuplicateReport returns the most recently generated report of the most
+// queried names, and how many Queries were duplicates.
+func (a *Admin) GetDuplicateReport(ctx context.Context, r *web.Request) (web.Template, error) {
+	report, err := a.reports.GetDuplicateReport(ctx)
+	if errors.Is(err, db.ErrReportNotFound) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Report has not been generated yet"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(report), nil
+}
+
+// GetCompressionStats returns how many Queries have been compressed in the
+// database since DENNIS started, and the space saved.
+func (a *Admin) GetCompressionStats(ctx context.Context, r *web.Request) (web.Template, error) {
+	return web.JSON(a.compressor.Stats()), nil
+}
+
+// Schedule is the response body of the schedule endpoint.
+type Schedule struct {
+	// Queries are the Queries scheduled to run, in the order they will run.
+	Queries []*models.Query `json:"queries"`
+}
+
+// ListScheduled returns the Queries scheduled to run in the future, in the
+// order they will run.
+func (a *Admin) ListScheduled(ctx context.Context, r *web.Request) (web.Template, error) {
+	queries, err := a.server.ListScheduled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(&Schedule{Queries: append([]*models.Query{}, queries...)}), nil
+}
+
+// GetScheduleCalendar returns the Queries scheduled to run in the future as
+// an iCalendar feed, which can be subscribed to from a calendar.
+func (a *Admin) GetScheduleCalendar(ctx context.Context, r *web.Request) (web.Template, error) {
+	queries, err := a.server.ListScheduled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return text.Calendar(queries, a.server.clock.Now()), nil
+}
+
+func (a *Admin) NotFound(ctx context.Context, r *web.Request) (web.Template, error) {
+	return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Endpoint not found"}
+}
+
+func (a *Admin) ErrorHandler(ctx context.Context, r *web.Request, err error) web.Template {
+	var apiErr *apiv1.Error
+	if !errors.As(err, &apiErr) {
+		r.Log().Error("an unexpected error occurred", slog.String("error", err.Error()))
+
+		apiErr = &apiv1.Error{Code: apiv1.ErrorCodeInternal, Message: "An unexpected error occurred"}
+	}
+
+	return web.JSON(&apiv1.ErrorWrapper{Error: apiErr})
+}
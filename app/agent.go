@@ -0,0 +1,254 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// agentRetryInterval is how long an agent waits before retrying after it
+// could not reach DENNIS.
+const agentRetryInterval = 5 * time.Second
+
+// Agent runs a remote agent, which registers with a central DENNIS and
+// resolves the Queries it is sent against the resolvers of a local Server,
+// from wherever the agent is running, returning their Lookups.
+type Agent struct {
+	server   *Server
+	baseURL  string
+	token    string
+	location string
+	http     *http.Client
+	log      *slog.Logger
+}
+
+// NewAgent initializes an Agent for the central DENNIS at baseURL, such as
+// `https://dennis.example.com`, registering with token as resolving from
+// location. Queries are resolved by server. If hc is nil,
+// net/http.DefaultClient is used, which must not time out requests sooner
+// than DENNIS answers a request for work.
+func NewAgent(server *Server, baseURL, token, location string, hc *http.Client, log *slog.Logger) *Agent {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	return &Agent{
+		server:   server,
+		baseURL:  strings.TrimSuffix(baseURL, "/") + "/agent/v1",
+		token:    token,
+		location: location,
+		http:     hc,
+		log:      log,
+	}
+}
+
+// Run registers the Agent and resolves the Queries it is sent until ctx is
+// canceled, when it unregisters once the Queries it is resolving have been
+// returned. It returns an error only if the Agent's token is rejected, other
+// errors are logged and retried.
+func (a *Agent) Run(ctx context.Context) error {
+	wg := new(sync.WaitGroup)
+	defer wg.Wait()
+
+	var id uuid.UUID
+
+	for ctx.Err() == nil {
+		if id.IsNil() {
+			res := new(registerAgentResponse)
+
+			err := a.do(ctx, http.MethodPost, "/agents", &registerAgentRequest{
+				Location:   a.location,
+				InstanceID: a.server.instanceID,
+			}, res)
+			if err != nil {
+				if isAPIError(err, apiv1.ErrorCodeUnauthorized) || isAPIError(err, apiv1.ErrorCodeBadRequest) {
+					return err
+				}
+
+				a.log.Error("could not register agent", slog.String("error", err.Error()))
+				sleep(ctx, agentRetryInterval)
+				continue
+			}
+
+			id = res.ID
+			a.log.Info("agent registered", slog.String("agent_id", id.String()), slog.String("location", a.location))
+		}
+
+		work := new(agentWork)
+
+		err := a.do(ctx, http.MethodGet, "/agents/"+id.String()+"/work", nil, work)
+		switch {
+		case ctx.Err() != nil:
+
+		case isAPIError(err, apiv1.ErrorCodeNotFound):
+			// DENNIS has restarted or forgotten the agent.
+			a.log.Warn("agent is no longer registered, registering again", slog.String("agent_id", id.String()))
+			id = uuid.Nil
+
+		case err != nil:
+			a.log.Error("could not get work", slog.String("error", err.Error()))
+			sleep(ctx, agentRetryInterval)
+
+		case work.Query != nil:
+			wg.Add(1)
+			go a.resolve(ctx, wg, id, work.Query)
+		}
+	}
+
+	if !id.IsNil() {
+		// the agent is unregistered even though ctx has been canceled, so
+		// Queries stop waiting for it immediately.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), agentRetryInterval)
+		defer cancel()
+
+		err := a.do(ctx, http.MethodDelete, "/agents/"+id.String(), nil, nil)
+		if err != nil {
+			a.log.Error("could not unregister agent", slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// resolve resolves query and returns it's Lookups to DENNIS.
+func (a *Agent) resolve(ctx context.Context, wg *sync.WaitGroup, id uuid.UUID, query *models.Query) {
+	defer wg.Done()
+
+	log := a.log.With(slog.String("query_id", query.ID.String()))
+
+	lookups := a.server.ResolveLookups(ctx, query)
+
+	// the Lookups are returned even if the agent is stopping, as DENNIS is
+	// waiting for them.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	err := a.do(ctx, http.MethodPost, "/agents/"+id.String()+"/lookups", &agentLookups{
+		QueryID: query.ID,
+		Lookups: lookups,
+	}, nil)
+	if err != nil {
+		log.Error("could not return lookups", slog.String("error", err.Error()))
+		return
+	}
+
+	log.Debug("returned lookups", slog.Int("lookups", len(lookups)))
+}
+
+// do makes a request to DENNIS with the JSON encoding of body, if not nil, to
+// path and decodes the JSON response into dst, if it has one. An error
+// response is returned as an *apiv1.Error.
+func (a *Agent) do(ctx context.Context, method, path string, body, dst any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not encode request: %w", err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		var wrapper apiv1.ErrorWrapper
+		if err := json.NewDecoder(res.Body).Decode(&wrapper); err != nil || wrapper.Error == nil {
+			return &apiv1.Error{Code: apiv1.ErrorCodeInternal, Message: "Unexpected response from server: " + res.Status}
+		}
+
+		return wrapper.Error
+	}
+
+	if dst == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(dst); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return nil
+}
+
+// isAPIError returns true if err is an *apiv1.Error with the given code.
+func isAPIError(err error, code string) bool {
+	var apiErr *apiv1.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// sleep waits for d, or until ctx is canceled.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// ResolveLookups resolves query against each resolver in it's groups, as
+// when the Query is created, and returns it's Lookups rather than storing
+// them, such as for an Agent to return them to DENNIS. Zone transfers and
+// Queries given a server are not resolved.
+func (s *Server) ResolveLookups(ctx context.Context, query *models.Query) []*models.Lookup {
+	if query.IsTransfer() || query.Server != "" {
+		return []*models.Lookup{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	wg := new(sync.WaitGroup)
+	log := s.log.With(
+		slog.String("query_id", query.ID.String()),
+		slog.String("query_type", query.Type),
+		slog.String("query_name", query.Name),
+	)
+
+	var mu sync.Mutex
+	lookups := []*models.Lookup{}
+
+	store := func(_ context.Context, _ uuid.UUID, l *models.Lookup) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		lookups = append(lookups, l)
+		return nil
+	}
+
+	s.resolveLookups(ctx, wg, log, startStopwatch(s.clock.Now(), s.clock.Now()), query, store)
+
+	wg.Wait()
+
+	return lookups
+}
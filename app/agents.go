@@ -0,0 +1,583 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// defaultAgentWait is how long a Query waits for the Lookups of each agent
+	// if not configured.
+	defaultAgentWait = 10 * time.Second
+
+	// agentPollTimeout is the longest a request from an agent for work is
+	// held open before it is answered with none, and must ask again.
+	agentPollTimeout = 25 * time.Second
+
+	// agentExpiry is how long since it last asked for work an agent is
+	// forgotten, such as when it has been stopped. It must re-register to
+	// receive work again.
+	agentExpiry = 90 * time.Second
+
+	// agentBacklog is the number of Queries held for an agent which has not
+	// yet asked for them, beyond which it is skipped.
+	agentBacklog = 16
+
+	// maxAgentLookupsSize is the largest request body, in bytes, of the
+	// Lookups returned by an agent.
+	maxAgentLookupsSize = 8 * 1024 * 1024
+)
+
+// Agents are the remote agents registered with DENNIS, started with `dennis
+// agent`, which resolve each Query against their own resolvers from where
+// they are running, such as in another region. Agents ask for work by long
+// polling, so need only be able to make HTTP requests to DENNIS.
+//
+// Agents are only registered with the instance of DENNIS they registered
+// with, and only resolve the Queries resolved by it, so where several
+// instances share a load balancer, every request from an agent must be routed
+// to the same instance.
+type Agents struct {
+	token string
+	wait  time.Duration
+	log   *slog.Logger
+
+	// clock tells the time agents register and ask for work, which is that of
+	// the Server once set.
+	clock clock.Clock
+
+	mu      sync.Mutex
+	agents  map[uuid.UUID]*remoteAgent
+	pending map[agentQuery]chan []*models.Lookup
+
+	// done is closed once DENNIS is shutting down, to answer requests for
+	// work immediately.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// remoteAgent is a single agent registered with Agents.
+type remoteAgent struct {
+	info *AgentInfo
+	work chan *models.Query
+}
+
+// agentQuery identifies a Query sent to an agent, which is waiting for it's
+// Lookups.
+type agentQuery struct {
+	agent uuid.UUID
+	query uuid.UUID
+}
+
+// AgentInfo describes an agent registered with DENNIS.
+type AgentInfo struct {
+	// ID is assigned to the agent when it registers.
+	ID uuid.UUID `json:"id"`
+
+	// Location is where the agent resolves from, such as `EU`, as it was
+	// registered. It is stored on every Lookup the agent resolves.
+	Location string `json:"location"`
+
+	// InstanceID identifies the instance of DENNIS running the agent.
+	InstanceID string `json:"instanceId,omitempty"`
+
+	// Addr is the remote address the agent registered from.
+	Addr string `json:"addr"`
+
+	// RegisteredAt and SeenAt are the UTC timestamps the agent registered,
+	// and last asked for work.
+	RegisteredAt time.Time `json:"registeredAt"`
+	SeenAt       time.Time `json:"seenAt"`
+}
+
+// registerAgentRequest is sent by an agent to register with DENNIS.
+type registerAgentRequest struct {
+	Location   string `json:"location"`
+	InstanceID string `json:"instanceId,omitempty"`
+}
+
+// registerAgentResponse is returned to an agent once it has registered, with
+// the ID it must use to ask for work.
+type registerAgentResponse struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// agentWork is returned to an agent asking for work, with the Query it must
+// resolve.
+type agentWork struct {
+	Query *models.Query `json:"query"`
+}
+
+// agentLookups are returned by an agent once it has resolved a Query.
+type agentLookups struct {
+	QueryID uuid.UUID        `json:"queryId"`
+	Lookups []*models.Lookup `json:"lookups"`
+}
+
+// NewAgents initializes Agents where agents register by presenting token.
+// Each Query waits at most wait for the Lookups of each agent, or
+// defaultAgentWait if zero.
+func NewAgents(token string, wait time.Duration, log *slog.Logger) *Agents {
+	if wait <= 0 {
+		wait = defaultAgentWait
+	}
+
+	return &Agents{
+		token:   token,
+		wait:    wait,
+		log:     log,
+		clock:   clock.Real,
+		agents:  make(map[uuid.UUID]*remoteAgent),
+		pending: make(map[agentQuery]chan []*models.Lookup),
+		done:    make(chan struct{}),
+	}
+}
+
+// Close answers every request from an agent waiting for work, so they do not
+// hold up a graceful shutdown.
+func (a *Agents) Close() {
+	a.closeOnce.Do(func() { close(a.done) })
+}
+
+// List returns the agents currently registered, in the order they
+// registered.
+func (a *Agents) List() []*AgentInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expire()
+
+	infos := make([]*AgentInfo, 0, len(a.agents))
+	for _, ag := range a.agents {
+		info := *ag.info
+		infos = append(infos, &info)
+	}
+
+	slices.SortFunc(infos, func(x, y *AgentInfo) int {
+		return x.RegisteredAt.Compare(y.RegisteredAt)
+	})
+
+	return infos
+}
+
+// expire forgets agents which have not asked for work within agentExpiry. It
+// must be called with mu held.
+func (a *Agents) expire() {
+	for id, ag := range a.agents {
+		if a.clock.Since(ag.info.SeenAt) > agentExpiry {
+			a.log.Info("agent expired", slog.String("agent_id", id.String()), slog.String("location", ag.info.Location))
+			delete(a.agents, id)
+		}
+	}
+}
+
+// register adds a new agent resolving from location, returning it's ID.
+func (a *Agents) register(location, instanceID, addr string) (uuid.UUID, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	now := a.clock.Now().UTC()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.agents[id] = &remoteAgent{
+		info: &AgentInfo{
+			ID:           id,
+			Location:     location,
+			InstanceID:   instanceID,
+			Addr:         addr,
+			RegisteredAt: now,
+			SeenAt:       now,
+		},
+		work: make(chan *models.Query, agentBacklog),
+	}
+
+	return id, nil
+}
+
+// unregister forgets the agent id, such as when it is stopped.
+func (a *Agents) unregister(id uuid.UUID) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, ok := a.agents[id]
+	delete(a.agents, id)
+
+	return ok
+}
+
+// next waits for a Query for the agent id to resolve, returning nil if there
+// is none within agentPollTimeout.
+func (a *Agents) next(ctx context.Context, id uuid.UUID) (*models.Query, error) {
+	ag := a.seen(id)
+	if ag == nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Agent not found by ID, it must register again"}
+	}
+	defer a.seen(id)
+
+	ctx, cancel := context.WithTimeout(ctx, agentPollTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case query := <-ag.work:
+			// a Query may have stopped waiting while it was held for the
+			// agent, such as when the agent was slow to ask for it.
+			a.mu.Lock()
+			_, ok := a.pending[agentQuery{agent: id, query: query.ID}]
+			a.mu.Unlock()
+
+			if ok {
+				return query, nil
+			}
+
+		case <-ctx.Done():
+			return nil, nil
+
+		case <-a.done:
+			return nil, nil
+		}
+	}
+}
+
+// seen records that the agent id has asked for work, returning nil if it is
+// not registered.
+func (a *Agents) seen(id uuid.UUID) *remoteAgent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ag, ok := a.agents[id]
+	if !ok {
+		return nil
+	}
+
+	ag.info.SeenAt = a.clock.Now().UTC()
+
+	return ag
+}
+
+// complete passes the Lookups returned by the agent id for the Query queryID
+// to the Query waiting for them.
+func (a *Agents) complete(id, queryID uuid.UUID, lookups []*models.Lookup) error {
+	key := agentQuery{agent: id, query: queryID}
+
+	a.mu.Lock()
+	ch, ok := a.pending[key]
+	delete(a.pending, key)
+	a.mu.Unlock()
+
+	if !ok {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeNotFound,
+			Field:   ".queryId",
+			Message: "Query is not waiting for Lookups from this agent",
+		}
+	}
+
+	ch <- lookups
+
+	return nil
+}
+
+// resolve sends query to every registered agent, and calls store with the
+// Lookups returned by each, along with it's location. It returns once every
+// agent has returned it's Lookups, or wait has passed.
+func (a *Agents) resolve(
+	ctx context.Context, query *models.Query, store func(location string, lookups []*models.Lookup),
+) {
+	type waiting struct {
+		key      agentQuery
+		location string
+		ch       chan []*models.Lookup
+	}
+
+	var queue []waiting
+
+	// the Query is sent to agents while it is still being resolved, so
+	// they are given a copy of it as it was queued.
+	queued := new(*query)
+
+	a.mu.Lock()
+	a.expire()
+
+	for id, ag := range a.agents {
+		w := waiting{
+			key:      agentQuery{agent: id, query: query.ID},
+			location: ag.info.Location,
+			ch:       make(chan []*models.Lookup, 1),
+		}
+
+		select {
+		case ag.work <- queued:
+			a.pending[w.key] = w.ch
+			queue = append(queue, w)
+
+		default:
+			a.log.Warn(
+				"agent has too much work, skipping",
+				slog.String("agent_id", id.String()), slog.String("location", ag.info.Location),
+			)
+		}
+	}
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, a.wait)
+	defer cancel()
+
+	for _, w := range queue {
+		select {
+		case lookups := <-w.ch:
+			store(w.location, lookups)
+
+		case <-ctx.Done():
+		}
+	}
+
+	// agents that did not return their Lookups in time can no longer.
+	a.mu.Lock()
+	for _, w := range queue {
+		delete(a.pending, w.key)
+	}
+	a.mu.Unlock()
+}
+
+// resolveRemote resolves query with every remote agent, storing the Lookups
+// they return with their location.
+func (s *Server) resolveRemote(
+	ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, sw *stopwatch, query *models.Query, store lookupStore,
+) {
+	defer wg.Done()
+
+	s.agents.resolve(ctx, query, func(location string, lookups []*models.Lookup) {
+		for _, l := range lookups {
+			err := checkAgentLookup(query, l)
+			if err != nil {
+				log.Warn("agent returned an invalid lookup", slog.String("location", location), slog.String("error", err.Error()))
+				continue
+			}
+
+			// the location of a Lookup is that the agent registered with,
+			// rather than any it claims itself.
+			l.ID = nil
+			l.Location = location
+
+			s.capRecords(l)
+
+			start := s.clock.Now()
+			err = store(ctx, query.ID, l)
+
+			// the clock of the agent may not agree with our own, so the
+			// Lookup is timed from when it was received.
+			sw.lookup(start, s.clock.Since(start))
+			if err != nil {
+				log.Error(
+					"could not create lookup",
+					slog.String("resolver", l.Resolver), slog.String("location", location), slog.String("error", err.Error()),
+				)
+			}
+		}
+	})
+}
+
+// checkAgentLookup returns an error if l, returned by an agent, cannot be a
+// Lookup of query.
+func checkAgentLookup(query *models.Query, l *models.Lookup) error {
+	switch {
+	case l.Resolver == "":
+		return errors.New("lookup has no resolver")
+	case len(query.Types) > 1 && !slices.Contains(query.Types, l.Type), len(query.Types) <= 1 && l.Type != "":
+		// only the Lookups of a Query of several types are labelled with their
+		// type.
+		return fmt.Errorf("lookup of type %q was not queried", l.Type)
+	case slices.Contains(l.Records, nil) || slices.Contains(l.Authority, nil) || slices.Contains(l.Additional, nil):
+		return errors.New("lookup has null records")
+	}
+
+	return nil
+}
+
+// capRecords keeps at most the configured maximum of each section of the
+// Records of l, returned by an agent, as is done for the Lookups resolved by
+// DENNIS itself. The agent may have already discarded some.
+func (s *Server) capRecords(l *models.Lookup) {
+	if l.Records == nil {
+		l.Records = []*models.Record{}
+	}
+
+	total := max(len(l.Records), l.TotalRecords)
+	l.Records = l.Records[:min(len(l.Records), s.maxRecords)]
+
+	if total > len(l.Records) {
+		l.TruncatedByDennis = true
+		l.TotalRecords = total
+	}
+
+	l.Authority = l.Authority[:min(len(l.Authority), s.maxRecords)]
+	l.Additional = l.Additional[:min(len(l.Additional), s.maxRecords)]
+}
+
+// Routes applies the path-based routes agents use to register and ask for
+// work to an HTTP router. Every request must present the token of Agents.
+func (a *Agents) Routes(r *web.Router) {
+	r.NotFound(a.NotFound)
+	r.ErrorHandler(a.ErrorHandler)
+
+	r.Post("/agents", a.Register)
+	r.Get("/agents/{id}/work", a.GetWork)
+	r.Delete("/agents/{id}", a.Unregister)
+	r.Post("/agents/{id}/lookups", a.CreateLookups)
+}
+
+// authorize returns an error unless r presents the token of Agents as a
+// bearer token.
+func (a *Agents) authorize(r *web.Request) error {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		return &apiv1.Error{Code: apiv1.ErrorCodeUnauthorized, Message: "Missing or incorrect agent token"}
+	}
+
+	return nil
+}
+
+// agentID parses the ID of the agent from the path of a request.
+func agentID(ctx context.Context) (uuid.UUID, error) {
+	id, err := uuid.FromString(web.URLParam(ctx, "id"))
+	if err != nil {
+		return uuid.Nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Invalid UUID for agent ID"}
+	}
+
+	return id, nil
+}
+
+func (a *Agents) Register(ctx context.Context, r *web.Request) (web.Template, error) {
+	if err := a.authorize(r); err != nil {
+		return nil, err
+	}
+
+	req := new(registerAgentRequest)
+
+	err := decodeJSON(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Location = strings.TrimSpace(req.Location)
+	if req.Location == "" {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".location", Message: "Location is required"}
+	} else if len(req.Location) > 64 {
+		return nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".location",
+			Message: "Location must not be longer than 64 characters",
+		}
+	}
+
+	id, err := a.register(req.Location, req.InstanceID, r.RemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log().Info("agent registered", slog.String("agent_id", id.String()), slog.String("location", req.Location))
+
+	return web.JSON(&registerAgentResponse{ID: id}), nil
+}
+
+func (a *Agents) Unregister(ctx context.Context, r *web.Request) (web.Template, error) {
+	if err := a.authorize(r); err != nil {
+		return nil, err
+	}
+
+	id, err := agentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.unregister(id) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Agent not found by ID"}
+	}
+
+	r.Log().Info("agent unregistered", slog.String("agent_id", id.String()))
+
+	return nil, nil
+}
+
+func (a *Agents) GetWork(ctx context.Context, r *web.Request) (web.Template, error) {
+	if err := a.authorize(r); err != nil {
+		return nil, err
+	}
+
+	id, err := agentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := a.next(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if query == nil {
+		return nil, nil
+	}
+
+	return web.JSON(&agentWork{Query: query}), nil
+}
+
+func (a *Agents) CreateLookups(ctx context.Context, r *web.Request) (web.Template, error) {
+	if err := a.authorize(r); err != nil {
+		return nil, err
+	}
+
+	id, err := agentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := new(agentLookups)
+
+	err = decodeJSONSize(r, req, maxAgentLookupsSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(req.Lookups, nil) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".lookups", Message: "Lookups must not be null"}
+	}
+
+	err = a.complete(id, req.QueryID, req.Lookups)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (a *Agents) NotFound(ctx context.Context, r *web.Request) (web.Template, error) {
+	return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Endpoint not found"}
+}
+
+func (a *Agents) ErrorHandler(ctx context.Context, r *web.Request, err error) web.Template {
+	var apiErr *apiv1.Error
+	if !errors.As(err, &apiErr) {
+		r.Log().Error("an unexpected error occurred", slog.String("error", err.Error()))
+
+		apiErr = &apiv1.Error{Code: apiv1.ErrorCodeInternal, Message: "An unexpected error occurred"}
+	}
+
+	return web.JSON(&apiv1.ErrorWrapper{Error: apiErr})
+}
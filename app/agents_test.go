@@ -0,0 +1,87 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/jamescun/dennis/app/models"
+)
+
+func TestCheckAgentLookup(t *testing.T) {
+	single := &models.Query{Type: "A", Name: "example.com"}
+	multiple := &models.Query{Type: "A", Types: []string{"A", "AAAA"}, Name: "example.com"}
+
+	tests := []struct {
+		name   string
+		query  *models.Query
+		lookup *models.Lookup
+		valid  bool
+	}{
+		{"Valid", single, &models.Lookup{Resolver: "a"}, true},
+		{"ValidType", multiple, &models.Lookup{Resolver: "a", Type: "AAAA"}, true},
+		{"NoResolver", single, &models.Lookup{}, false},
+		{"UnexpectedType", single, &models.Lookup{Resolver: "a", Type: "A"}, false},
+		{"NoType", multiple, &models.Lookup{Resolver: "a"}, false},
+		{"NotQueried", multiple, &models.Lookup{Resolver: "a", Type: "TXT"}, false},
+		{"NullRecord", single, &models.Lookup{Resolver: "a", Records: []*models.Record{nil}}, false},
+		{"NullAuthority", single, &models.Lookup{Resolver: "a", Authority: []*models.Record{nil}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkAgentLookup(test.query, test.lookup)
+			if valid := err == nil; valid != test.valid {
+				t.Errorf("expected valid %t, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestCapRecords(t *testing.T) {
+	s := &Server{maxRecords: 2}
+
+	records := func(n int) []*models.Record {
+		r := make([]*models.Record, n)
+		for i := range r {
+			r[i] = &models.Record{}
+		}
+
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		lookup    *models.Lookup
+		records   int
+		total     int
+		truncated bool
+	}{
+		{"Empty", &models.Lookup{}, 0, 0, false},
+		{"Under", &models.Lookup{Records: records(2)}, 2, 0, false},
+		{"Over", &models.Lookup{Records: records(5)}, 2, 5, true},
+		{"TruncatedByAgent", &models.Lookup{Records: records(2), TruncatedByDennis: true, TotalRecords: 9}, 2, 9, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := test.lookup
+			l.Authority = records(3)
+
+			s.capRecords(l)
+
+			if l.Records == nil || len(l.Records) != test.records {
+				t.Errorf("expected %d records, got %d", test.records, len(l.Records))
+			}
+
+			if l.TotalRecords != test.total || l.TruncatedByDennis != test.truncated {
+				t.Errorf(
+					"expected total %d and truncated %t, got %d and %t",
+					test.total, test.truncated, l.TotalRecords, l.TruncatedByDennis,
+				)
+			}
+
+			if len(l.Authority) != 2 {
+				t.Errorf("expected 2 authority records, got %d", len(l.Authority))
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+	"github.com/jamescun/dennis/app/views/text"
+)
+
+// API implements the JSON-based HTTP interface of DENNIS for programs and
+// scripts to interact with.
+type API struct {
+	api       apiv1.API
+	hideAddrs bool
+	errors    *Errors
+	log       *slog.Logger
+}
+
+// NewAPI initializes a new HTTP API for a given logic backend implementing
+// API, and a logger for error messages. If hideAddrs is set, the addresses of
+// resolvers are removed from responses to anonymous users. Unexpected errors
+// are retained in errs.
+func NewAPI(backend apiv1.API, hideAddrs bool, errs *Errors, log *slog.Logger) *API {
+	return &API{
+		api:       backend,
+		hideAddrs: hideAddrs,
+		errors:    errs,
+		log:       log,
+	}
+}
+
+// Routes applies the path-based routes of API to an HTTP router.
+func (a *API) Routes(r *web.Router) {
+	r.NotFound(a.NotFound)
+	r.ErrorHandler(a.ErrorHandler)
+
+	r.Post("/queries", a.CreateQuery)
+	r.Get("/queries/{id}", a.GetQuery)
+	r.Get("/resolvers", a.ListResolvers)
+	r.Post("/recipes", a.CreateRecipe)
+	r.Get("/recipes", a.ListRecipes)
+	r.Delete("/recipes/{id}", a.DeleteRecipe)
+	r.Post("/recipes/{id}/runs", a.RunRecipe)
+	r.Get("/runs/{id}", a.GetRecipeRun)
+	r.Get("/status", a.GetStatus)
+	r.Get("/version", a.GetVersion)
+	r.Get("/time", a.GetTime)
+}
+
+func (a *API) CreateQuery(ctx context.Context, r *web.Request) (web.Template, error) {
+	req := new(apiv1.CreateQueryRequest)
+
+	err := decodeJSON(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.api.CreateQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) GetQuery(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.GetQuery(ctx, &apiv1.GetQueryRequest{
+		ID: web.URLParam(ctx, "id"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Accepts("text/plain") {
+		return withQueryCaching(r, text.Query(res.Query), res, textETag(res)), nil
+	}
+
+	// the age and expiry of a finished Query, measured now, would become stale
+	// while it is cached, so are left out of the representation that is.
+	cached := *res
+	if res.Query.FinishedAt != nil {
+		cached.Age, cached.ExpiresIn = 0, nil
+	}
+
+	return withQueryCaching(r, web.JSON(&cached), res, `"`+res.ETag+`"`), nil
+}
+
+func (a *API) ListResolvers(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	// there is no authentication in DENNIS, so every user is anonymous.
+	if a.hideAddrs {
+		for _, rsv := range res.Resolvers {
+			rsv.Addr = ""
+		}
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) CreateRecipe(ctx context.Context, r *web.Request) (web.Template, error) {
+	req := new(apiv1.CreateRecipeRequest)
+
+	err := decodeJSON(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.api.CreateRecipe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) ListRecipes(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.ListRecipes(ctx, &apiv1.ListRecipesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) DeleteRecipe(ctx context.Context, r *web.Request) (web.Template, error) {
+	_, err := a.api.DeleteRecipe(ctx, &apiv1.DeleteRecipeRequest{
+		ID: web.URLParam(ctx, "id"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (a *API) RunRecipe(ctx context.Context, r *web.Request) (web.Template, error) {
+	req := new(apiv1.RunRecipeRequest)
+
+	err := decodeJSON(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Recipe to run is always taken from the path.
+	req.ID = web.URLParam(ctx, "id")
+
+	res, err := a.api.RunRecipe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) GetRecipeRun(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.GetRecipeRun(ctx, &apiv1.GetRecipeRunRequest{
+		ID: web.URLParam(ctx, "id"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) GetStatus(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.GetStatus(ctx, &apiv1.GetStatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) GetVersion(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.GetVersion(ctx, &apiv1.GetVersionRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) GetTime(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := a.api.GetTime(ctx, &apiv1.GetTimeRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.JSON(res), nil
+}
+
+func (a *API) NotFound(ctx context.Context, r *web.Request) (web.Template, error) {
+	return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Endpoint not found"}
+}
+
+func (a *API) ErrorHandler(ctx context.Context, r *web.Request, err error) web.Template {
+	var apiErr *apiv1.Error
+	if !errors.As(err, &apiErr) {
+		r.Log().Error("an unexpected error occurred", slog.String("error", err.Error()))
+		a.errors.add(ctx, r, err)
+
+		apiErr = &apiv1.Error{Code: apiv1.ErrorCodeInternal, Message: "An unexpected error occurred"}
+	}
+
+	return web.JSON(&apiv1.ErrorWrapper{Error: apiErr})
+}
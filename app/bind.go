@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"codeberg.org/miekg/dns"
+)
+
+// boundClient exchanges messages with a resolver from a specific source
+// address or through a specific network interface. A dialer only accepts a
+// local address of the network it dials, so UDP and TCP each have their own
+// client.
+type boundClient struct {
+	udp, tcp *dns.Client
+}
+
+// newBoundClient returns a client sending from src, if valid, and through
+// the network interface iface, if set.
+func newBoundClient(src netip.Addr, iface string) *boundClient {
+	c := &boundClient{
+		udp: boundDNSClient(iface),
+		tcp: boundDNSClient(iface),
+	}
+
+	if src.IsValid() {
+		c.udp.Dialer.LocalAddr = net.UDPAddrFromAddrPort(netip.AddrPortFrom(src, 0))
+		c.tcp.Dialer.LocalAddr = net.TCPAddrFromAddrPort(netip.AddrPortFrom(src, 0))
+	}
+
+	return c
+}
+
+// boundDNSClient returns a client with it's own dialer, bound to the network
+// interface iface if set.
+func boundDNSClient(iface string) *dns.Client {
+	t := dns.NewTransport()
+
+	// the dialer of a new transport is shared with every other.
+	dialer := *t.Dialer
+	t.Dialer = &dialer
+
+	if iface != "" {
+		t.Dialer.Control = bindToInterface(iface)
+	}
+
+	return &dns.Client{Transport: t}
+}
+
+func (c *boundClient) Exchange(
+	ctx context.Context, msg *dns.Msg, network, address string,
+) (*dns.Msg, time.Duration, error) {
+	if network == "udp" {
+		return c.udp.Exchange(ctx, msg, network, address)
+	}
+
+	return c.tcp.Exchange(ctx, msg, network, address)
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package app
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToInterface returns a dialer control function that binds each socket
+// to the network interface or VRF device named iface (SO_BINDTODEVICE).
+func bindToInterface(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var bindErr error
+
+		err := c.Control(func(fd uintptr) {
+			bindErr = unix.BindToDevice(int(fd), iface)
+		})
+		if err != nil {
+			return err
+		}
+
+		return bindErr
+	}
+}
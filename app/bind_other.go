@@ -0,0 +1,16 @@
+//go:build !linux
+
+package app
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToInterface returns a dialer control function that fails, as sockets
+// can only be bound to a network interface on Linux.
+func bindToInterface(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, _ syscall.RawConn) error {
+		return errors.New("binding to interface " + iface + " is only supported on Linux")
+	}
+}
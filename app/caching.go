@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+// finishedQueryMaxAge is the longest caches, such as browsers and CDNs, may
+// cache a finished Query before revalidating it.
+const finishedQueryMaxAge = time.Hour
+
+// withQueryCaching sets the HTTP caching headers of tpl, a representation of
+// the Query of res identified by etag. Once a Query has finished it's results
+// cannot change, so it may be cached publicly until it expires, for at most
+// finishedQueryMaxAge, reducing load from links that have been widely shared.
+// If r already holds the representation, it is told so rather than sent it
+// again. Representations without an etag, such as HTML with timestamps
+// relative to when it was rendered, change over time, so must be rendered
+// again each time they are viewed. Until a Query has finished, it must not be
+// cached at all.
+func withQueryCaching(r *web.Request, tpl web.Template, res *apiv1.GetQueryResponse, etag string) web.Template {
+	// the same URL renders HTML, JSON or plain text depending on Accept.
+	tpl = web.WithHeader(tpl, "Vary", "Accept")
+
+	q := res.Query
+	if q.FinishedAt == nil {
+		return web.WithHeader(tpl, "Cache-Control", "private, no-store")
+	} else if etag == "" {
+		return web.WithHeader(tpl, "Cache-Control", "private, no-cache")
+	}
+
+	maxAge := finishedQueryMaxAge
+	if res.ExpiresIn != nil {
+		maxAge = min(maxAge, time.Duration(*res.ExpiresIn)*time.Millisecond)
+	}
+
+	if notModified(r, etag, q.UpdatedAt()) {
+		tpl = web.WithHeader(web.Status(noContent{}, http.StatusNotModified), "Vary", "Accept")
+	}
+
+	tpl = web.WithHeader(tpl, "Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge/time.Second)))
+	tpl = web.WithHeader(tpl, "ETag", etag)
+
+	return web.WithHeader(tpl, "Last-Modified", q.UpdatedAt().Format(http.TimeFormat))
+}
+
+// textETag returns the ETag of the plain text representation of the Query of
+// res, which differs from that of it's JSON representation.
+func textETag(res *apiv1.GetQueryResponse) string {
+	return `"` + res.ETag + `-text"`
+}
+
+// notModified returns true if the conditional request r already holds the
+// representation identified by etag, last modified at modified. As with
+// net/http, If-Modified-Since is ignored if If-None-Match is given.
+func notModified(r *web.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for tag := range strings.SplitSeq(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+
+	return err == nil && !modified.Truncate(time.Second).After(since)
+}
+
+// noContent is a Template that renders nothing, such as the body of a
+// response with HTTP 304 Not Modified.
+type noContent struct{}
+
+func (noContent) Render(context.Context, io.Writer) error { return nil }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+func TestWithQueryCaching(t *testing.T) {
+	finished := &models.Query{FinishedAt: new(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))}
+
+	tests := []struct {
+		name string
+		res  *apiv1.GetQueryResponse
+		etag string
+		want string
+	}{
+		{"Resolving", &apiv1.GetQueryResponse{Query: &models.Query{}}, `"a"`, "private, no-store"},
+		{"Cacheable", &apiv1.GetQueryResponse{Query: finished}, `"a"`, "public, max-age=3600"},
+		{"Relative", &apiv1.GetQueryResponse{Query: finished}, "", "private, no-cache"},
+		{"Expiring", &apiv1.GetQueryResponse{Query: finished, ExpiresIn: new(90 * 1000)}, `"a"`, "public, max-age=90"},
+		{"Expired", &apiv1.GetQueryResponse{Query: finished, ExpiresIn: new(0)}, `"a"`, "public, max-age=0"},
+		{
+			"NotExpiring", &apiv1.GetQueryResponse{Query: finished, ExpiresIn: new(48 * 3600 * 1000)}, `"a"`,
+			"public, max-age=3600",
+		},
+	}
+
+	r := &web.Request{Request: httptest.NewRequest(http.MethodGet, "/", nil)}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tpl := withQueryCaching(r, web.JSON(test.res), test.res, test.etag).(web.Headerer)
+
+			if got := tpl.Header().Get("Cache-Control"); got != test.want {
+				t.Errorf("expected Cache-Control %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestWithQueryCachingNotModified(t *testing.T) {
+	finishedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	res := &apiv1.GetQueryResponse{Query: &models.Query{FinishedAt: &finishedAt}}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"Unconditional", "", "", http.StatusOK},
+		{"Match", "If-None-Match", `"b", "a"`, http.StatusNotModified},
+		{"Weak", "If-None-Match", `W/"a"`, http.StatusNotModified},
+		{"Any", "If-None-Match", "*", http.StatusNotModified},
+		{"Changed", "If-None-Match", `"b"`, http.StatusOK},
+		{"Unmodified", "If-Modified-Since", finishedAt.Format(http.TimeFormat), http.StatusNotModified},
+		{"Modified", "If-Modified-Since", finishedAt.Add(-time.Second).Format(http.TimeFormat), http.StatusOK},
+		{"Invalid", "If-Modified-Since", "yesterday", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &web.Request{Request: httptest.NewRequest(http.MethodGet, "/", nil)}
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+
+			tpl := withQueryCaching(r, web.JSON(res), res, `"a"`)
+
+			if got := tpl.(web.StatusCoder).StatusCode(); got != test.status {
+				t.Errorf("expected status %d, got %d", test.status, got)
+			}
+
+			// validators are sent with HTTP 304 Not Modified, as with 200 OK.
+			if got := tpl.(web.Headerer).Header().Get("ETag"); got != `"a"` {
+				t.Errorf(`expected ETag "a", got %q`, got)
+			}
+		})
+	}
+}
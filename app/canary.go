@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// canaryInterval is how often each resolver is checked for NXDOMAIN
+// redirection.
+const canaryInterval = time.Hour
+
+// DetectNXDOMAINRedirection checks each resolver for NXDOMAIN redirection
+// immediately, and then every canaryInterval until ctx is canceled. Resolvers
+// found to be redirecting are reported by ListResolvers.
+func (s *Server) DetectNXDOMAINRedirection(ctx context.Context) {
+	ticker := time.NewTicker(canaryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.checkNXDOMAIN(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkNXDOMAIN queries every resolver concurrently for a canary name. A
+// resolver that cannot be reached keeps the result of it's last check.
+func (s *Server) checkNXDOMAIN(ctx context.Context) {
+	wg := new(sync.WaitGroup)
+
+	for _, rsv := range s.resolvers() {
+		wg.Go(func() {
+			redirect, err := s.canary(ctx, rsv)
+			if err != nil {
+				s.log.Debug(
+					"could not check resolver for nxdomain redirection",
+					slog.String("resolver", rsv.name), slog.String("error", err.Error()),
+				)
+				return
+			}
+
+			rsv.nxdomain.Store(redirect)
+
+			if redirect != nil {
+				s.log.Warn(
+					"resolver redirects nxdomain",
+					slog.String("resolver", rsv.name), slog.String("answer", strings.Join(redirect.Answer, " ")),
+				)
+			}
+		})
+	}
+
+	wg.Wait()
+}
+
+// canary queries rsv for a random name that cannot exist, returning the
+// redirection if it was answered rather than NXDOMAIN, otherwise nil.
+func (s *Server) canary(ctx context.Context, rsv *resolver) (*models.NXDOMAINRedirect, error) {
+	name := canaryName()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	res, _, err := exchange(ctx, rsv, dns.NewMsg(name, dns.TypeA))
+	if err != nil {
+		return nil, err
+	} else if res.Rcode != dns.RcodeSuccess || len(res.Answer) == 0 {
+		return nil, nil
+	}
+
+	redirect := &models.NXDOMAINRedirect{Name: name, DetectedAt: s.clock.Now().UTC()}
+	for _, rr := range res.Answer {
+		if data := rr.Data(); data != nil {
+			redirect.Answer = append(redirect.Answer, data.String())
+		}
+	}
+
+	return redirect, nil
+}
+
+// canaryName returns a random name beneath `.com` that is vanishingly unlikely
+// to be registered. Redirection is usually only applied to names beneath
+// public TLDs, so a reserved TLD such as `.invalid` would not detect it.
+func canaryName() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return "dennis-canary-" + hex.EncodeToString(b) + ".com"
+}
@@ -29,6 +29,27 @@ func Read(path string) (*Config, error) {
 		cfg.Version = 1
 	}
 
+	// resolvers configured by preset or from the system are completed before
+	// they are validated, so their name and address are not required.
+	var resolvers []*Resolver
+
+	for i, r := range cfg.Resolvers {
+		if err := r.applyPreset(); err != nil {
+			return nil, err.prefixIdx("resolvers", i)
+		}
+
+		system, err := r.systemResolvers()
+		if err != nil {
+			return nil, err.prefixIdx("resolvers", i)
+		} else if system != nil {
+			resolvers = append(resolvers, system...)
+		} else {
+			resolvers = append(resolvers, r)
+		}
+	}
+
+	cfg.Resolvers = resolvers
+
 	err = cfg.Validate()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,73 @@
+package config
+
+// Presets are well-known public DNS resolvers that can be configured by their
+// name with `preset`, rather than by address, so a new deployment can be made
+// without looking them up. Each is the primary IPv4 address of the resolver.
+var Presets = map[string]*Resolver{
+	"google": {
+		Name:        "Google",
+		Addr:        "8.8.8.8",
+		Description: "Google Public DNS",
+	},
+	"cloudflare": {
+		Name:        "Cloudflare",
+		Addr:        "1.1.1.1",
+		Description: "Cloudflare 1.1.1.1",
+	},
+	"quad9": {
+		Name:        "Quad9",
+		Addr:        "9.9.9.9",
+		Description: "Quad9, blocks malicious domains",
+		RPZ:         true,
+	},
+	"opendns": {
+		Name:        "OpenDNS",
+		Addr:        "208.67.222.222",
+		Description: "Cisco OpenDNS",
+	},
+	"adguard": {
+		Name:        "AdGuard",
+		Addr:        "94.140.14.14",
+		Description: "AdGuard DNS, blocks ads and trackers",
+		RPZ:         true,
+	},
+	"dnssb": {
+		Name:        "DNS.SB",
+		Addr:        "185.222.222.222",
+		Description: "DNS.SB",
+	},
+	"controld": {
+		Name:        "Control D",
+		Addr:        "76.76.2.0",
+		Description: "Control D, unfiltered",
+	},
+}
+
+// applyPreset fills the fields of r that are not set from the Preset named by
+// it's Preset field, if any, so any field of a preset can be overridden.
+func (r *Resolver) applyPreset() *ValidationError {
+	if r == nil || r.Preset == "" {
+		return nil
+	}
+
+	p, ok := Presets[r.Preset]
+	if !ok {
+		return &ValidationError{Field: "preset", Message: "unknown preset"}
+	}
+
+	if r.Name == "" {
+		r.Name = p.Name
+	}
+
+	if r.Addr == "" {
+		r.Addr = p.Addr
+	}
+
+	if r.Description == "" {
+		r.Description = p.Description
+	}
+
+	r.RPZ = r.RPZ || p.RPZ
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+)
+
+// redacted replaces each secret removed by Redacted.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of c with it's secrets, such as passwords, tokens
+// and the arguments of plugins, replaced so it can be shared, such as in a
+// bug report. Connection strings and URLs keep everything but their
+// credentials.
+func (c *Config) Redacted() (*Config, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(Config)
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+
+	r.Telemetry.URL = redactURL(r.Telemetry.URL)
+	r.Resolution.Proxy = redactURL(r.Resolution.Proxy)
+
+	for _, rsv := range r.Resolvers {
+		rsv.Proxy = redactURL(rsv.Proxy)
+
+		if rsv.Plugin != nil {
+			for i := range rsv.Plugin.Args {
+				rsv.Plugin.Args[i] = redacted
+			}
+		}
+	}
+
+	for _, d := range r.Discovery {
+		if d.Consul != nil && d.Consul.Token != "" {
+			d.Consul.Token = redacted
+		}
+	}
+
+	if r.Agents != nil {
+		r.Agents.Token = redacted
+	}
+
+	if pg := r.DB.Postgres; pg != nil {
+		pg.URL = redactURL(pg.URL)
+		pg.ReplicaURL = redactURL(pg.ReplicaURL)
+	}
+
+	if rd := r.DB.Redis; rd != nil && rd.Password != "" {
+		rd.Password = redacted
+	}
+
+	return r, nil
+}
+
+// Secrets returns the values of the secrets of c that Redacted removes,
+// except the arguments of plugins, so they can also be removed from text
+// that may contain them, such as logs.
+func (c *Config) Secrets() []string {
+	var secrets []string
+
+	add := func(s string) {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+
+	addURL := func(s string) {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			pass, _ := u.User.Password()
+			add(pass)
+		}
+
+		for _, m := range connStringPassword.FindAllStringSubmatch(s, -1) {
+			add(m[2])
+		}
+	}
+
+	addURL(c.Telemetry.URL)
+	addURL(c.Resolution.Proxy)
+
+	for _, rsv := range c.Resolvers {
+		addURL(rsv.Proxy)
+	}
+
+	for _, d := range c.Discovery {
+		if d.Consul != nil {
+			add(d.Consul.Token)
+		}
+	}
+
+	if c.Agents != nil {
+		add(c.Agents.Token)
+	}
+
+	if pg := c.DB.Postgres; pg != nil {
+		addURL(pg.URL)
+		addURL(pg.ReplicaURL)
+	}
+
+	if rd := c.DB.Redis; rd != nil {
+		add(rd.Password)
+	}
+
+	return secrets
+}
+
+// connStringPassword matches the password of a libpq key/value connection
+// string, such as `host=localhost password=secret`.
+var connStringPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactURL returns the URL or connection string s with it's password and
+// the values of it's query parameters replaced.
+func redactURL(s string) string {
+	if s == "" {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return connStringPassword.ReplaceAllString(s, "${1}"+redacted)
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q.Set(k, redacted)
+		}
+
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// ParseResolvConf returns the address of each `nameserver` in the resolv.conf
+// content r, in the order they appear. Other options are ignored.
+func ParseResolvConf(r io.Reader) ([]netip.Addr, error) {
+	var nameservers []netip.Addr
+
+	sc := bufio.NewScanner(r)
+
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || fields[0] != "nameserver" {
+			continue
+		}
+
+		var (
+			addr netip.Addr
+			err  error
+		)
+
+		if len(fields) > 1 {
+			addr, err = netip.ParseAddr(fields[1])
+		}
+
+		if len(fields) < 2 || err != nil {
+			return nil, fmt.Errorf("line %d is not a valid nameserver", line)
+		}
+
+		nameservers = append(nameservers, addr)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return nameservers, nil
+}
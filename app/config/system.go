@@ -0,0 +1,23 @@
+//go:build !windows
+
+package config
+
+import (
+	"net/netip"
+	"os"
+)
+
+// resolvConfPath is where the operating system configures it's nameservers.
+const resolvConfPath = "/etc/resolv.conf"
+
+// SystemNameservers returns the nameservers the operating system resolves
+// names with, from resolvConfPath.
+func SystemNameservers() ([]netip.Addr, error) {
+	file, err := os.Open(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseResolvConf(file)
+}
@@ -0,0 +1,39 @@
+package config
+
+// systemResolvers returns a Resolver for each of the SystemNameservers if r
+// has System set, otherwise nil.
+func (r *Resolver) systemResolvers() ([]*Resolver, *ValidationError) {
+	if r == nil || !r.System {
+		return nil, nil
+	} else if r.Addr != "" || r.Preset != "" {
+		return nil, &ValidationError{Field: "system", Message: "system cannot be combined with addr or preset"}
+	}
+
+	nameservers, err := SystemNameservers()
+	if err != nil {
+		return nil, &ValidationError{Field: "system", Message: "could not read system nameservers: " + err.Error()}
+	} else if len(nameservers) == 0 {
+		return nil, &ValidationError{Field: "system", Message: "no system nameservers were found"}
+	}
+
+	resolvers := make([]*Resolver, 0, len(nameservers))
+
+	for _, ns := range nameservers {
+		sr := *r
+		sr.System = false
+		sr.Addr = ns.String()
+
+		sr.Name = ns.String()
+		if r.Name != "" {
+			sr.Name = r.Name + "/" + ns.String()
+		}
+
+		if sr.Description == "" {
+			sr.Description = "system nameserver"
+		}
+
+		resolvers = append(resolvers, &sr)
+	}
+
+	return resolvers, nil
+}
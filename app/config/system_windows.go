@@ -0,0 +1,50 @@
+//go:build windows
+
+package config
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// SystemNameservers returns the nameservers the operating system resolves
+// names with, from the DNS servers of each network adapter that is up.
+func SystemNameservers() ([]netip.Addr, error) {
+	size := uint32(15 * 1024)
+
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+
+		err := windows.GetAdaptersAddresses(
+			windows.AF_UNSPEC, windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST, 0,
+			(*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size,
+		)
+		if err == nil {
+			break
+		} else if !errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) {
+			return nil, err
+		}
+	}
+
+	var nameservers []netip.Addr
+
+	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); aa != nil; aa = aa.Next {
+		if aa.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+
+		for dns := aa.FirstDnsServerAddress; dns != nil; dns = dns.Next {
+			addr, ok := netip.AddrFromSlice(dns.Address.IP())
+			if ok && !slices.Contains(nameservers, addr.Unmap()) {
+				nameservers = append(nameservers, addr.Unmap())
+			}
+		}
+	}
+
+	return nameservers, nil
+}
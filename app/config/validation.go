@@ -1,8 +1,17 @@
 package config
 
 import (
+	"encoding/hex"
+	"net/netip"
+	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/pkg/proxy"
+
+	"codeberg.org/miekg/dns"
 )
 
 // ValidationError is an error returned by validation functions attached to
@@ -53,16 +62,60 @@ func (c *Config) Validate() error {
 		return &ValidationError{Field: "version", Message: "unsupported config version"}
 	}
 
+	if err := c.Logging.validate(); err != nil {
+		return err.prefix("logging")
+	}
+
 	if err := c.Listen.validate(); err != nil {
 		return err.prefix("listen")
 	}
 
+	if c.Admin != nil {
+		if err := c.Admin.validate(); err != nil {
+			return err.prefix("admin")
+		}
+	}
+
+	if err := c.Telemetry.validate(); err != nil {
+		return err.prefix("telemetry")
+	}
+
+	if err := c.UI.validate(); err != nil {
+		return err.prefix("ui")
+	}
+
 	for i, r := range c.Resolvers {
 		if err := r.validate(); err != nil {
 			return err.prefixIdx("resolvers", i)
 		}
 	}
 
+	for i, d := range c.Discovery {
+		if err := d.validate(); err != nil {
+			return err.prefixIdx("discovery", i)
+		}
+	}
+
+	if err := c.Resolution.validate(); err != nil {
+		return err.prefix("resolution")
+	}
+
+	if err := c.HealthCheck.validate(); err != nil {
+		return err.prefix("healthCheck")
+	}
+
+	if c.Agents != nil {
+		if err := c.Agents.validate(); err != nil {
+			return err.prefix("agents")
+		}
+	}
+
+	for name := range c.Features {
+		if _, ok := apiv1.DefaultFeatures[name]; !ok {
+			return &ValidationError{Field: "features." + name, Message: "unknown feature"}
+		}
+	}
+
 	if c.QueryMaxAge < 0 {
 		return &ValidationError{
 			Field:   "queryMaxAge",
@@ -89,6 +142,91 @@ func (l *Listener) validate() *ValidationError {
 	return nil
 }
 
+func (a *Admin) validate() *ValidationError {
+	if a.Addr == "" {
+		return &ValidationError{Field: "addr", Message: "addr to listen on is required"}
+	}
+
+	return nil
+}
+
+func (l *Logging) validate() *ValidationError {
+	if l.SampleRate < 0 || l.SampleRate > 1 {
+		return &ValidationError{Field: "sampleRate", Message: "sampleRate must be between 0 and 1"}
+	}
+
+	if a := l.AccessLog; a != nil {
+		if a.Path == "" {
+			return &ValidationError{Field: "accessLog.path", Message: "path is required"}
+		}
+
+		switch a.Format {
+		case "", "common", "combined":
+		default:
+			return &ValidationError{Field: "accessLog.format", Message: "format must be either common or combined"}
+		}
+	}
+
+	return nil
+}
+
+func (a *Agents) validate() *ValidationError {
+	if a.Token == "" {
+		return &ValidationError{Field: "token", Message: "token is required"}
+	}
+
+	if a.Wait < 0 || a.Wait > 25 {
+		return &ValidationError{Field: "wait", Message: "wait must be between 0 and 25 seconds"}
+	}
+
+	return nil
+}
+
+func (t *Telemetry) validate() *ValidationError {
+	if t.Enabled && t.URL == "" {
+		return &ValidationError{Field: "url", Message: "url is required when telemetry is enabled"}
+	}
+
+	if t.Interval < 0 {
+		return &ValidationError{Field: "interval", Message: "interval must be a positive integer in seconds"}
+	}
+
+	return nil
+}
+
+func (u *UI) validate() *ValidationError {
+	if u.Support != "" {
+		if su, err := url.Parse(u.Support); err != nil || su.Scheme == "" {
+			return &ValidationError{Field: "support", Message: "support must be an absolute URL"}
+		}
+	}
+
+	return nil
+}
+
+// Validate asserts the validity of a single Resolver, such as one added at
+// runtime rather than configured, applying it's preset first. The resolvers
+// of the system are only read when DENNIS starts, so are rejected.
+func (r *Resolver) Validate() error {
+	if r == nil {
+		return &ValidationError{Message: "resolver is required"}
+	}
+
+	if err := r.applyPreset(); err != nil {
+		return err
+	}
+
+	if r.System {
+		return &ValidationError{Field: "system", Message: "system resolvers can only be configured when DENNIS starts"}
+	}
+
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (r *Resolver) validate() *ValidationError {
 	if r == nil {
 		return &ValidationError{Message: "resolver is required"}
@@ -106,10 +244,175 @@ func (r *Resolver) validate() *ValidationError {
 		return &ValidationError{Field: "port", Message: "port must be between 1 and 65535"}
 	}
 
+	if r.Retries < 0 {
+		return &ValidationError{Field: "retries", Message: "retries must be zero or greater"}
+	} else if r.RetryInterval < 0 {
+		return &ValidationError{Field: "retryInterval", Message: "retryInterval must be zero or greater"}
+	}
+
+	if err := validateGroups(r.Groups); err != nil {
+		return err
+	}
+
+	if r.SourceAddr != "" {
+		if _, err := netip.ParseAddr(r.SourceAddr); err != nil {
+			return &ValidationError{Field: "sourceAddr", Message: "sourceAddr must be an IP address"}
+		}
+	}
+
+	if r.Plugin != nil {
+		if r.Plugin.Command == "" {
+			return &ValidationError{Field: "plugin.command", Message: "command of plugin is required"}
+		} else if r.SourceAddr != "" || r.Interface != "" || r.Proxy != "" {
+			return &ValidationError{
+				Field:   "plugin",
+				Message: "sourceAddr, interface and proxy cannot be set for a resolver implemented by a plugin",
+			}
+		}
+	}
+
+	if r.Proxy != "" {
+		if _, err := proxy.Parse(r.Proxy); err != nil {
+			return &ValidationError{Field: "proxy", Message: "invalid proxy: " + err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// validateGroups asserts that each of the groups of a Resolver is named.
+func validateGroups(groups []string) *ValidationError {
+	for i, group := range groups {
+		if strings.TrimSpace(group) == "" {
+			return &ValidationError{Field: "groups[" + strconv.Itoa(i) + "]", Message: "name of group is required"}
+		}
+	}
+
+	return nil
+}
+
+func (d *Discovery) validate() *ValidationError {
+	if d == nil {
+		return &ValidationError{Message: "discovery is required"}
+	}
+
+	if d.Name == "" {
+		return &ValidationError{Field: "name", Message: "name of discovery is required"}
+	}
+
+	if d.Interval < 0 {
+		return &ValidationError{Field: "interval", Message: "interval must be a positive integer in seconds"}
+	}
+
+	if d.Port < 0 || d.Port > 65535 {
+		return &ValidationError{Field: "port", Message: "port must be between 1 and 65535"}
+	}
+
+	if err := validateGroups(d.Groups); err != nil {
+		return err
+	}
+
+	switch {
+	case d.Consul == nil && d.Kubernetes == nil:
+		return &ValidationError{Message: "one of consul or kubernetes is required"}
+
+	case d.Consul != nil && d.Kubernetes != nil:
+		return &ValidationError{Message: "only one of consul or kubernetes may be configured"}
+
+	case d.Consul != nil && d.Consul.Service == "":
+		return &ValidationError{Field: "consul.service", Message: "service is required"}
+
+	case d.Kubernetes != nil && d.Kubernetes.Service == "":
+		return &ValidationError{Field: "kubernetes.service", Message: "service is required"}
+	}
+
+	return nil
+}
+
+func (r *Resolution) validate() *ValidationError {
+	if r.Workers < 0 {
+		return &ValidationError{Field: "workers", Message: "workers must be zero or greater"}
+	}
+
+	if r.Proxy != "" {
+		if _, err := proxy.Parse(r.Proxy); err != nil {
+			return &ValidationError{Field: "proxy", Message: "invalid proxy: " + err.Error()}
+		}
+	}
+
+	if r.MaxSamples < 0 {
+		return &ValidationError{Field: "maxSamples", Message: "maxSamples must be zero or greater"}
+	}
+
+	if r.MaxRecords < 0 {
+		return &ValidationError{Field: "maxRecords", Message: "maxRecords must be zero or greater"}
+	}
+
+	if r.MaxTransferRecords < 0 {
+		return &ValidationError{Field: "maxTransferRecords", Message: "maxTransferRecords must be zero or greater"}
+	}
+
+	if r.TransferTimeout < 0 {
+		return &ValidationError{Field: "transferTimeout", Message: "transferTimeout must be zero or greater"}
+	}
+
+	if r.EDNS0 != nil {
+		if err := r.EDNS0.validate(); err != nil {
+			return err.prefix("edns0")
+		}
+	}
+
+	return nil
+}
+
+func (h *HealthCheck) validate() *ValidationError {
+	if h.Interval < 0 {
+		return &ValidationError{Field: "interval", Message: "interval must be a positive integer in seconds"}
+	}
+
+	if h.Type != "" {
+		if _, ok := dns.StringToType[strings.ToUpper(h.Type)]; !ok {
+			return &ValidationError{Field: "type", Message: "type must be a DNS record type, such as SOA or A"}
+		}
+	}
+
+	if h.Window < 0 {
+		return &ValidationError{Field: "window", Message: "window must be zero or greater"}
+	}
+
+	return nil
+}
+
+func (e *EDNS0) validate() *ValidationError {
+	if e.UDPSize != 0 && (e.UDPSize < 512 || e.UDPSize > 65535) {
+		return &ValidationError{Field: "udpSize", Message: "udpSize must be between 512 and 65535"}
+	}
+
+	for i, option := range e.Options {
+		if option == nil {
+			return (&ValidationError{Message: "option is required"}).prefixIdx("options", i)
+		} else if option.Code < 0 || option.Code > 65535 {
+			return (&ValidationError{Field: "code", Message: "code must be between 0 and 65535"}).prefixIdx("options", i)
+		} else if _, err := hex.DecodeString(option.Data); err != nil {
+			return (&ValidationError{Field: "data", Message: "data must be hex encoded"}).prefixIdx("options", i)
+		}
+	}
+
 	return nil
 }
 
 func (d *DB) validate() *ValidationError {
+	if d.Compression != nil {
+		if d.Postgres != nil {
+			return &ValidationError{
+				Field:   "compression",
+				Message: "compression is only supported by the file and redis databases",
+			}
+		} else if d.Compression.Threshold < 0 {
+			return &ValidationError{Field: "compression.threshold", Message: "threshold must be zero or greater"}
+		}
+	}
+
 	switch {
 	case d.File != nil:
 		if d.Postgres != nil || d.Redis != nil {
@@ -166,5 +469,9 @@ func (r *RedisDB) validate() *ValidationError {
 		return &ValidationError{Field: "db", Message: "redis database must be zero or greater"}
 	}
 
+	if r.WriteBuffer < 0 {
+		return &ValidationError{Field: "writeBuffer", Message: "writeBuffer must be zero or greater"}
+	}
+
 	return nil
 }
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/jamescun/dennis/app/models"
+)
+
+// EncodingGzip is the StoredQuery.Encoding of a Query compressed using gzip.
+const EncodingGzip = "gzip"
+
+// DefaultCompressionThreshold is the size, in bytes, of the JSON of a Query
+// above which it is compressed if not otherwise configured.
+const DefaultCompressionThreshold = 16 * 1024
+
+// Compressor compresses the JSON of finished Queries for database
+// implementations that store Queries as JSON, such as those containing large
+// results, and counts the space saved. A nil Compressor never compresses.
+type Compressor struct {
+	threshold int
+
+	queries    atomic.Int64
+	bytesIn    atomic.Int64
+	bytesSaved atomic.Int64
+}
+
+// NewCompressor initializes a Compressor that compresses Queries whose JSON is
+// larger than threshold bytes.
+func NewCompressor(threshold int) *Compressor {
+	return &Compressor{threshold: threshold}
+}
+
+// CompressionStats are counters describing the activity of a Compressor since
+// it was created.
+type CompressionStats struct {
+	// Threshold is the size, in bytes, of the JSON of a Query above which it
+	// is compressed.
+	Threshold int `json:"threshold"`
+
+	// Queries is the number of Queries compressed.
+	Queries int64 `json:"queries"`
+
+	// Bytes is the size, in bytes, of the JSON of Queries before they were
+	// compressed.
+	Bytes int64 `json:"bytes"`
+
+	// BytesSaved is the number of bytes saved by compressing Queries.
+	BytesSaved int64 `json:"bytesSaved"`
+}
+
+// Stats returns counters describing the activity of the Compressor.
+func (c *Compressor) Stats() *CompressionStats {
+	if c == nil {
+		return &CompressionStats{}
+	}
+
+	return &CompressionStats{
+		Threshold:  c.threshold,
+		Queries:    c.queries.Load(),
+		Bytes:      c.bytesIn.Load(),
+		BytesSaved: c.bytesSaved.Load(),
+	}
+}
+
+// Compress returns q wrapped to be stored, compressed if it's JSON is larger
+// than the threshold of the Compressor. Only Queries that have finished should
+// be compressed, as compressed Queries can no longer be partially updated.
+func (c *Compressor) Compress(q *models.Query) (*StoredQuery, error) {
+	s := NewStoredQuery(q)
+	if c == nil {
+		return s, nil
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	} else if len(b) <= c.threshold {
+		return s, nil
+	}
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	} else if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+
+	// the compressed data is base64 encoded within the stored JSON, so
+	// compression is not worthwhile for Queries that do not compress well.
+	encoded := base64.StdEncoding.EncodedLen(buf.Len())
+	if encoded >= len(b) {
+		return s, nil
+	}
+
+	c.queries.Add(1)
+	c.bytesIn.Add(int64(len(b)))
+	c.bytesSaved.Add(int64(len(b) - encoded))
+
+	s.Encoding = EncodingGzip
+	s.Data = buf.Bytes()
+
+	return s, nil
+}
+
+// decompress decodes the compressed JSON of a StoredQuery of encoding.
+func decompress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+
+	default:
+		return nil, fmt.Errorf("unsupported query encoding %q", encoding)
+	}
+}
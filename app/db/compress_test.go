@@ -0,0 +1,55 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jamescun/dennis/app/models"
+)
+
+func TestCompressorRoundTrip(t *testing.T) {
+	q := &models.Query{Type: "TXT", Name: "example.com", Lookups: []*models.Lookup{}}
+	for range 100 {
+		q.Lookups = append(q.Lookups, &models.Lookup{
+			Resolver: "CloudFlare",
+			Records:  []*models.Record{{TTL: 300, Content: []string{strings.Repeat("v=spf1 ", 10)}}},
+		})
+	}
+
+	c := NewCompressor(1024)
+
+	s, err := c.Compress(q)
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Encoding != EncodingGzip {
+		t.Fatalf("expected query to be compressed, got encoding %q", s.Encoding)
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got StoredQuery
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("could not decode compressed query: %s", err)
+	}
+
+	if got.Name != q.Name || len(got.Lookups) != len(q.Lookups) {
+		t.Errorf("query changed after compression")
+	}
+
+	if stats := c.Stats(); stats.Queries != 1 || stats.BytesSaved <= 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCompressorBelowThreshold(t *testing.T) {
+	s, err := NewCompressor(DefaultCompressionThreshold).Compress(&models.Query{Type: "A", Name: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Encoding != "" {
+		t.Errorf("expected small query to not be compressed")
+	}
+}
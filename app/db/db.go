@@ -15,10 +15,86 @@ import (
 // point, but expired out of the database.
 var ErrQueryNotFound = errors.New("query not found")
 
+// ErrRecipeNotFound is returned by a database implementation when attempting
+// to retrieve or delete a Recipe by ID, but it does not exist.
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+// ErrRecipeRunNotFound is returned by a database implementation when
+// attempting to retrieve a RecipeRun by ID, but it does not exist. It may have
+// existed at one point, but expired out of the database.
+var ErrRecipeRunNotFound = errors.New("recipe run not found")
+
+// ErrReportNotFound is returned by a database implementation when attempting
+// to retrieve a report that has not been generated yet.
+var ErrReportNotFound = errors.New("report not found")
+
 // DB is composed of the database object interfaces in this package.
 type DB interface {
 	Queries
 	Lookups
+	Recipes
+	Reports
+	ResolverChanges
+	Resolvers
+	Fingerprints
+	Jobs
+}
+
+// Stater is optionally implemented by a database implementation to report
+// statistics about it's connections, for diagnostics.
+type Stater interface {
+	// Stats returns named counters, such as the number of open connections.
+	Stats() map[string]int64
+}
+
+// Pinger is optionally implemented by a database implementation that can
+// become unreachable, such as one across the network, so DENNIS can tell when
+// it is unavailable and when it has recovered.
+type Pinger interface {
+	// Ping returns an error if the database cannot be reached.
+	Ping(ctx context.Context) error
+}
+
+// Dispatcher is optionally implemented by a database implementation shared by
+// several instances of DENNIS, to distribute the Queries created on any of
+// them to be resolved by whichever is free. Delivery is at-least-once: a Job
+// that is not acknowledged, such as because the instance it was delivered to
+// stopped, is delivered again, so the Lookups of a dispatched Query are
+// stored with CreateLookupOnce.
+type Dispatcher interface {
+	// Dispatch adds job to be delivered to one of the instances receiving
+	// Jobs.
+	Dispatch(ctx context.Context, job *models.Job) error
+
+	// Receive blocks until Jobs are available to be delivered to consumer,
+	// which identifies this instance, returning them in order of priority.
+	// Jobs delivered to another consumer that have not been acknowledged for
+	// some time are redelivered. It may return no Jobs after a while, so the
+	// caller can stop receiving.
+	Receive(ctx context.Context, consumer string) ([]*Delivery, error)
+
+	// Ack acknowledges a Delivery once it's Query has been resolved, so it
+	// is not delivered again.
+	Ack(ctx context.Context, delivery *Delivery) error
+
+	// CreateLookupOnce inserts a Lookup like Lookups.CreateLookup, unless a
+	// Lookup of the Query has already been stored for the same type,
+	// resolver and location, such as by an instance that stopped before it
+	// could acknowledge the Query.
+	CreateLookupOnce(ctx context.Context, queryID uuid.UUID, l *models.Lookup) error
+}
+
+// Delivery is a Job received from a Dispatcher.
+type Delivery struct {
+	// ID identifies the Delivery to the Dispatcher.
+	ID string
+
+	// Job is the Job delivered.
+	Job *models.Job
+
+	// Attempts is the number of times the Job has been delivered, including
+	// this one, more than one if it was not acknowledged before.
+	Attempts int
 }
 
 // Queries is used to operate on Query objects in the database.
@@ -32,11 +108,17 @@ type Queries interface {
 	GetQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, error)
 
 	// UpdateQuery updates a Query in the database. Currently only FinishedAt
-	// is updatable. If it does not exist, ErrQueryNotFound is returned.
+	// and Timings are updatable. If it does not exist, ErrQueryNotFound is
+	// returned.
 	UpdateQuery(ctx context.Context, query *models.Query) error
 
+	// ListScheduledQueries retrieves all Queries from the database that were
+	// scheduled with RunAt, but have not finished.
+	ListScheduledQueries(ctx context.Context) ([]*models.Query, error)
+
 	// DeleteQueriesOlderThan removes all Queries from the database whose age
-	// (determined from CreatedAt) is older than maxAge.
+	// (determined from CreatedAt) is older than maxAge. Scheduled Queries that
+	// have not finished are not removed.
 	DeleteQueriesOlderThan(ctx context.Context, maxAge time.Duration) error
 }
 
@@ -48,3 +130,116 @@ type Lookups interface {
 	// returned.
 	CreateLookup(ctx context.Context, queryID uuid.UUID, l *models.Lookup) error
 }
+
+// Recipes is used to operate on user defined Recipe objects, and the
+// RecipeRun objects created when any Recipe is run, in the database.
+type Recipes interface {
+	// CreateRecipe inserts a new Recipe into the database. The ID and
+	// CreatedAt fields will be set by the database.
+	CreateRecipe(ctx context.Context, recipe *models.Recipe) error
+
+	// ListRecipes retrieves all Recipes from the database, in the order they
+	// were created.
+	ListRecipes(ctx context.Context) ([]*models.Recipe, error)
+
+	// GetRecipeByID retrieves a Recipe by it's ID from the database. If it
+	// does not exist, ErrRecipeNotFound is returned.
+	GetRecipeByID(ctx context.Context, id uuid.UUID) (*models.Recipe, error)
+
+	// DeleteRecipe removes a Recipe from the database. If it does not exist,
+	// ErrRecipeNotFound is returned. RecipeRuns of the Recipe are kept.
+	DeleteRecipe(ctx context.Context, id uuid.UUID) error
+
+	// CreateRecipeRun inserts a new RecipeRun into the database. The ID and
+	// CreatedAt fields will be set by the database.
+	CreateRecipeRun(ctx context.Context, run *models.RecipeRun) error
+
+	// GetRecipeRunByID retrieves a RecipeRun by it's ID from the database. If
+	// it does not exist, ErrRecipeRunNotFound is returned.
+	GetRecipeRunByID(ctx context.Context, id uuid.UUID) (*models.RecipeRun, error)
+}
+
+// ResolverChanges is used to record changes to the configured Resolvers in
+// the database. ResolverChanges never expire.
+type ResolverChanges interface {
+	// CreateResolverChange inserts a new ResolverChange into the database.
+	// The ID and CreatedAt fields will be set by the database.
+	CreateResolverChange(ctx context.Context, change *models.ResolverChange) error
+
+	// ListResolverChanges retrieves all ResolverChanges from the database, in
+	// the order they were created.
+	ListResolverChanges(ctx context.Context) ([]*models.ResolverChange, error)
+}
+
+// Resolvers is used to store the Resolvers managed at runtime through the
+// admin interface, by name. StoredResolvers never expire.
+type Resolvers interface {
+	// PutStoredResolver inserts a StoredResolver into the database, replacing
+	// any of the same Name. The UpdatedAt field will be set by the database.
+	PutStoredResolver(ctx context.Context, r *models.StoredResolver) error
+
+	// DeleteStoredResolver removes the StoredResolver of name from the
+	// database, if it exists.
+	DeleteStoredResolver(ctx context.Context, name string) error
+
+	// ListStoredResolvers retrieves all StoredResolvers from the database, in
+	// order of name.
+	ListStoredResolvers(ctx context.Context) ([]*models.StoredResolver, error)
+}
+
+// Fingerprints is used to record the history of the ResolverFingerprint of
+// each Resolver in the database. ResolverFingerprints never expire.
+type Fingerprints interface {
+	// CreateResolverFingerprint inserts a new ResolverFingerprint into the
+	// database. The ID and CreatedAt fields will be set by the database.
+	CreateResolverFingerprint(ctx context.Context, fp *models.ResolverFingerprint) error
+
+	// ListResolverFingerprints retrieves the ResolverFingerprints of the
+	// Resolver named resolver from the database, in the order they were
+	// created.
+	ListResolverFingerprints(ctx context.Context, resolver string) ([]*models.ResolverFingerprint, error)
+}
+
+// Reports is used to aggregate Queries in the database, and store the reports
+// generated from them.
+type Reports interface {
+	// CountQueries counts the Queries created since since, grouped by their
+	// Type and Name, in descending order of count.
+	CountQueries(ctx context.Context, since time.Time) ([]*models.QueryCount, error)
+
+	// PutDuplicateReport stores a DuplicateReport, replacing any previously
+	// stored.
+	PutDuplicateReport(ctx context.Context, report *models.DuplicateReport) error
+
+	// GetDuplicateReport retrieves the most recently stored DuplicateReport.
+	// If none has been stored, ErrReportNotFound is returned.
+	GetDuplicateReport(ctx context.Context) (*models.DuplicateReport, error)
+}
+
+// Jobs is used to record the Queries waiting to be resolved, or being
+// resolved, in the database, so they can be recovered when DENNIS restarts.
+// A Job is removed once it's Query has finished.
+type Jobs interface {
+	// PutJob inserts a Job into the database, replacing any of the same
+	// QueryID.
+	PutJob(ctx context.Context, job *models.Job) error
+
+	// ClaimJob inserts job into the database only if there is no Job of the
+	// same QueryID, returning false if there is, so that only one of several
+	// instances sharing the database resolves a Query.
+	ClaimJob(ctx context.Context, job *models.Job) (bool, error)
+
+	// TakeJob transfers the Job of job.QueryID to the instance instanceID
+	// only if it is still owned by job.InstanceID and has not been started
+	// again since job was retrieved, returning false otherwise, so that only
+	// one instance recovers a Job abandoned by another.
+	TakeJob(ctx context.Context, job *models.Job, instanceID string) (bool, error)
+
+	// DeleteJob removes the Job of the Query queryID from the database, if it
+	// exists.
+	DeleteJob(ctx context.Context, queryID uuid.UUID) error
+
+	// ListJobs retrieves all Jobs from the database, in the order they were
+	// created.
+	ListJobs(ctx context.Context) ([]*models.Job, error)
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/jamescun/dennis/app/config"
 	"github.com/jamescun/dennis/app/db"
 	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
 
 	"github.com/gofrs/uuid"
 )
@@ -21,8 +23,33 @@ type format struct {
 	// Version is the revision of this format contained within the file.
 	Version int `json:"version"`
 
-	// Queries are the queries requested by the user and their results.
-	Queries []*models.Query `json:"queries"`
+	// Queries are the queries requested by the user and their results,
+	// migrated to the current db.QuerySchemaVersion when read.
+	Queries []*db.StoredQuery `json:"queries"`
+
+	// Recipes are the user defined Recipes.
+	Recipes []*models.Recipe `json:"recipes,omitempty"`
+
+	// RecipeRuns are the Recipes that have been run, and their Queries.
+	RecipeRuns []*models.RecipeRun `json:"recipeRuns,omitempty"`
+
+	// ResolverChanges are the changes to the configured Resolvers, in the
+	// order they were recorded.
+	ResolverChanges []*models.ResolverChange `json:"resolverChanges,omitempty"`
+
+	// StoredResolvers are the Resolvers managed at runtime, in order of name.
+	StoredResolvers []*models.StoredResolver `json:"storedResolvers,omitempty"`
+
+	// ResolverFingerprints are the fingerprints of every Resolver, in the
+	// order they were observed.
+	ResolverFingerprints []*models.ResolverFingerprint `json:"resolverFingerprints,omitempty"`
+
+	// Jobs are the Queries waiting to be resolved, or being resolved, in the
+	// order they were queued.
+	Jobs []*models.Job `json:"jobs,omitempty"`
+
+	// DuplicateReport is the most recently generated DuplicateReport, if any.
+	DuplicateReport *models.DuplicateReport `json:"duplicateReport,omitempty"`
 }
 
 // getQuery iterates the Queries in format, returning the first that matches
@@ -30,7 +57,7 @@ type format struct {
 func (f *format) getQuery(id uuid.UUID) *models.Query {
 	for _, q := range f.Queries {
 		if q.ID == id {
-			return q
+			return q.Query
 		}
 	}
 
@@ -40,14 +67,17 @@ func (f *format) getQuery(id uuid.UUID) *models.Query {
 // DB is a database implementation backed by a local JSON file. Internal
 // locking is implemented between calls, so concurrent use is supported.
 type DB struct {
-	path string
-	mu   sync.Mutex
+	path       string
+	compressor *db.Compressor
+	clock      clock.Clock
+	mu         sync.Mutex
 }
 
 // New initializes a new DB implementation backed by a local JSON file. If the
-// file does not exist, it will be created.
-func New(path string) (*DB, error) {
-	d := &DB{path: path}
+// file does not exist, it will be created. If compressor is set, Queries are
+// compressed once they have finished.
+func New(path string, compressor *db.Compressor) (*DB, error) {
+	d := &DB{path: path, compressor: compressor, clock: clock.Real}
 	err := d.init()
 	if err != nil {
 		return nil, err
@@ -56,16 +86,22 @@ func New(path string) (*DB, error) {
 	return d, nil
 }
 
+// SetClock replaces the Clock used to timestamp records, and to decide which
+// Queries are old enough to be deleted.
+func (d *DB) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
 // FromConfig configures a File database implementation from a configuration
 // object supplied by the user.
-func FromConfig(_ context.Context, cfg *config.FileDB) (*DB, error) {
-	return New(cfg.Path)
+func FromConfig(_ context.Context, cfg *config.FileDB, compressor *db.Compressor) (*DB, error) {
+	return New(cfg.Path, compressor)
 }
 
 func (d *DB) init() error {
 	// if the file does not exist, write an empty one.
 	if _, err := os.Stat(d.path); err != nil {
-		err = writeJSON(d.path, &format{Version: 1, Queries: []*models.Query{}})
+		err = writeJSON(d.path, &format{Version: 1, Queries: []*db.StoredQuery{}})
 		if err != nil {
 			return fmt.Errorf("init: %w", err)
 		}
@@ -76,10 +112,10 @@ func (d *DB) init() error {
 
 func (d *DB) CreateQuery(_ context.Context, query *models.Query) error {
 	query.ID = uuid.Must(uuid.NewV7())
-	query.CreatedAt = time.Now().UTC()
+	query.CreatedAt = d.clock.Now().UTC()
 
 	err := d.write(func(f *format) error {
-		f.Queries = append(f.Queries, query)
+		f.Queries = append(f.Queries, db.NewStoredQuery(query))
 		return nil
 	})
 	if err != nil {
@@ -113,6 +149,23 @@ func (d *DB) UpdateQuery(_ context.Context, query *models.Query) error {
 		}
 
 		q.FinishedAt = query.FinishedAt
+		q.Timings = query.Timings
+		q.ClientDisconnected = query.ClientDisconnected
+		q.Partial = query.Partial
+		q.Interrupted = query.Interrupted
+
+		// a finished Query is never modified again, so can be compressed.
+		if q.FinishedAt != nil {
+			i := slices.IndexFunc(f.Queries, func(s *db.StoredQuery) bool { return s.Query == q })
+
+			s, err := d.compressor.Compress(q)
+			if err != nil {
+				return err
+			}
+
+			f.Queries[i] = s
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -122,13 +175,36 @@ func (d *DB) UpdateQuery(_ context.Context, query *models.Query) error {
 	return nil
 }
 
+func (d *DB) ListScheduledQueries(_ context.Context) (queries []*models.Query, err error) {
+	err = d.read(func(f *format) error {
+		for _, q := range f.Queries {
+			if q.RunAt != nil && q.FinishedAt == nil {
+				queries = append(queries, q.Query)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list scheduled queries: %w", err)
+	}
+
+	return
+}
+
 func (d *DB) DeleteQueriesOlderThan(ctx context.Context, maxAge time.Duration) error {
 	err := d.write(func(f *format) error {
-		for i, q := range f.Queries {
-			if time.Since(q.CreatedAt) > maxAge {
-				slices.Delete(f.Queries, i, i+1)
+		f.Queries = slices.DeleteFunc(f.Queries, func(q *db.StoredQuery) bool {
+			if q.RunAt != nil && q.FinishedAt == nil {
+				return false
 			}
-		}
+
+			return d.clock.Since(q.CreatedAt) > maxAge
+		})
+
+		f.RecipeRuns = slices.DeleteFunc(f.RecipeRuns, func(r *models.RecipeRun) bool {
+			return d.clock.Since(r.CreatedAt) > maxAge
+		})
 
 		return nil
 	})
@@ -156,6 +232,358 @@ func (d *DB) CreateLookup(_ context.Context, queryID uuid.UUID, l *models.Lookup
 	return nil
 }
 
+func (d *DB) CreateRecipe(_ context.Context, recipe *models.Recipe) error {
+	recipe.ID = uuid.Must(uuid.NewV7())
+	recipe.CreatedAt = d.clock.Now().UTC()
+
+	err := d.write(func(f *format) error {
+		f.Recipes = append(f.Recipes, recipe)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not create recipe: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListRecipes(_ context.Context) (recipes []*models.Recipe, err error) {
+	err = d.read(func(f *format) error {
+		recipes = append([]*models.Recipe{}, f.Recipes...)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list recipes: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) GetRecipeByID(_ context.Context, id uuid.UUID) (recipe *models.Recipe, err error) {
+	err = d.read(func(f *format) error {
+		i := slices.IndexFunc(f.Recipes, func(r *models.Recipe) bool { return r.ID == id })
+		if i < 0 {
+			return db.ErrRecipeNotFound
+		}
+
+		recipe = f.Recipes[i]
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not get recipe: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) DeleteRecipe(_ context.Context, id uuid.UUID) error {
+	err := d.write(func(f *format) error {
+		i := slices.IndexFunc(f.Recipes, func(r *models.Recipe) bool { return r.ID == id })
+		if i < 0 {
+			return db.ErrRecipeNotFound
+		}
+
+		f.Recipes = slices.Delete(f.Recipes, i, i+1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not delete recipe: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) CreateRecipeRun(_ context.Context, run *models.RecipeRun) error {
+	run.ID = uuid.Must(uuid.NewV7())
+	run.CreatedAt = d.clock.Now().UTC()
+
+	err := d.write(func(f *format) error {
+		f.RecipeRuns = append(f.RecipeRuns, run)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not create recipe run: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetRecipeRunByID(_ context.Context, id uuid.UUID) (run *models.RecipeRun, err error) {
+	err = d.read(func(f *format) error {
+		i := slices.IndexFunc(f.RecipeRuns, func(r *models.RecipeRun) bool { return r.ID == id })
+		if i < 0 {
+			return db.ErrRecipeRunNotFound
+		}
+
+		run = f.RecipeRuns[i]
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not get recipe run: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) CreateResolverChange(_ context.Context, change *models.ResolverChange) error {
+	change.ID = uuid.Must(uuid.NewV7())
+	change.CreatedAt = d.clock.Now().UTC()
+
+	err := d.write(func(f *format) error {
+		f.ResolverChanges = append(f.ResolverChanges, change)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not create resolver change: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverChanges(_ context.Context) (changes []*models.ResolverChange, err error) {
+	err = d.read(func(f *format) error {
+		changes = append([]*models.ResolverChange{}, f.ResolverChanges...)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list resolver changes: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) CreateResolverFingerprint(_ context.Context, fp *models.ResolverFingerprint) error {
+	fp.ID = uuid.Must(uuid.NewV7())
+	fp.CreatedAt = d.clock.Now().UTC()
+
+	err := d.write(func(f *format) error {
+		f.ResolverFingerprints = append(f.ResolverFingerprints, fp)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not create resolver fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverFingerprints(
+	_ context.Context, resolver string,
+) (fps []*models.ResolverFingerprint, err error) {
+	err = d.read(func(f *format) error {
+		fps = []*models.ResolverFingerprint{}
+
+		for _, fp := range f.ResolverFingerprints {
+			if fp.Resolver == resolver {
+				fps = append(fps, fp)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list resolver fingerprints: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) PutStoredResolver(_ context.Context, r *models.StoredResolver) error {
+	r.UpdatedAt = d.clock.Now().UTC()
+
+	err := d.write(func(f *format) error {
+		i, found := slices.BinarySearchFunc(f.StoredResolvers, r.Name, func(e *models.StoredResolver, name string) int {
+			return strings.Compare(e.Name, name)
+		})
+
+		if found {
+			f.StoredResolvers[i] = r
+		} else {
+			f.StoredResolvers = slices.Insert(f.StoredResolvers, i, r)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not put stored resolver: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) DeleteStoredResolver(_ context.Context, name string) error {
+	err := d.write(func(f *format) error {
+		f.StoredResolvers = slices.DeleteFunc(f.StoredResolvers, func(r *models.StoredResolver) bool {
+			return r.Name == name
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not delete stored resolver: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListStoredResolvers(_ context.Context) (resolvers []*models.StoredResolver, err error) {
+	err = d.read(func(f *format) error {
+		resolvers = append([]*models.StoredResolver{}, f.StoredResolvers...)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list stored resolvers: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) PutJob(_ context.Context, job *models.Job) error {
+	err := d.write(func(f *format) error {
+		i := slices.IndexFunc(f.Jobs, func(j *models.Job) bool { return j.QueryID == job.QueryID })
+		if i >= 0 {
+			f.Jobs[i] = job
+		} else {
+			f.Jobs = append(f.Jobs, job)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not put job: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ClaimJob(_ context.Context, job *models.Job) (claimed bool, err error) {
+	err = d.write(func(f *format) error {
+		if slices.ContainsFunc(f.Jobs, func(j *models.Job) bool { return j.QueryID == job.QueryID }) {
+			return nil
+		}
+
+		f.Jobs = append(f.Jobs, job)
+		claimed = true
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not claim job: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) TakeJob(_ context.Context, job *models.Job, instanceID string) (taken bool, err error) {
+	err = d.write(func(f *format) error {
+		i := slices.IndexFunc(f.Jobs, func(j *models.Job) bool { return j.QueryID == job.QueryID })
+		if i < 0 || f.Jobs[i].InstanceID != job.InstanceID || f.Jobs[i].Attempts != job.Attempts {
+			return nil
+		}
+
+		// the Job is copied, as it may still be held by the caller of ListJobs.
+		j := *f.Jobs[i]
+		j.InstanceID = instanceID
+		f.Jobs[i] = &j
+		taken = true
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not take job: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) DeleteJob(_ context.Context, queryID uuid.UUID) error {
+	err := d.write(func(f *format) error {
+		f.Jobs = slices.DeleteFunc(f.Jobs, func(j *models.Job) bool {
+			return j.QueryID == queryID
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not delete job: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListJobs(_ context.Context) (jobs []*models.Job, err error) {
+	err = d.read(func(f *format) error {
+		jobs = append([]*models.Job{}, f.Jobs...)
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	return
+}
+
+func (d *DB) CountQueries(_ context.Context, since time.Time) (counts []*models.QueryCount, err error) {
+	err = d.read(func(f *format) error {
+		index := make(map[[2]string]*models.QueryCount)
+
+		for _, q := range f.Queries {
+			if q.CreatedAt.Before(since) {
+				continue
+			}
+
+			key := [2]string{q.Type, q.Name}
+			if c, ok := index[key]; ok {
+				c.Count++
+			} else {
+				c = &models.QueryCount{Type: q.Type, Name: q.Name, Count: 1}
+				index[key] = c
+				counts = append(counts, c)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not count queries: %w", err)
+	}
+
+	slices.SortStableFunc(counts, func(a, b *models.QueryCount) int {
+		return b.Count - a.Count
+	})
+
+	return counts, nil
+}
+
+func (d *DB) PutDuplicateReport(_ context.Context, report *models.DuplicateReport) error {
+	err := d.write(func(f *format) error {
+		f.DuplicateReport = report
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not put duplicate report: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetDuplicateReport(_ context.Context) (report *models.DuplicateReport, err error) {
+	err = d.read(func(f *format) error {
+		if f.DuplicateReport == nil {
+			return db.ErrReportNotFound
+		}
+
+		report = f.DuplicateReport
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not get duplicate report: %w", err)
+	}
+
+	return
+}
+
 func (d *DB) read(fn func(*format) error) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -4,53 +4,126 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jamescun/dennis/app/config"
 	"github.com/jamescun/dennis/app/db"
 	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// querier is an interface containing just the methods we need from the
+// PostgreSQL connection pool.
+type querier interface {
+	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) pgx.Row
+}
+
 // DB is a database implementation backed by a PostgreSQL database.
 type DB struct {
-	// conn is an interface containing just the methods we need from the
-	// PostgreSQL connection pool.
-	conn interface {
-		Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
-		Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
-		QueryRow(ctx context.Context, query string, args ...any) pgx.Row
-	}
-}
+	conn querier
 
-// New initializes a new DB database implementation backed by PostgreSQL.
-func New(ctx context.Context, url string) (*DB, error) {
-	cfg, err := pgxpool.ParseConfig(url)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse postgres config: %w", err)
-	}
+	// replica is optionally set to a read-only connection pool to a replica
+	// of the primary database, used to serve Queries that have finished.
+	replica querier
+
+	// clock tells the time records are created.
+	clock clock.Clock
 
-	conn, err := pgxpool.NewWithConfig(ctx, cfg)
+	// log records errors of the replica, which are not returned as the
+	// primary is read instead.
+	log *slog.Logger
+}
+
+// New initializes a new DB database implementation backed by PostgreSQL. If
+// replicaURL is set, finished Queries will be read from that database rather
+// than the primary at url.
+func New(ctx context.Context, url, replicaURL string) (*DB, error) {
+	primary, err := connect(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("could not connect to postgres: %w", err)
+		return nil, err
 	}
 
-	d := &DB{conn: conn}
+	d := &DB{conn: primary, clock: clock.Real, log: slog.Default()}
 	if err := d.migrate(ctx); err != nil {
 		return nil, err
 	}
 
+	if replicaURL != "" {
+		d.replica, err = connect(ctx, replicaURL)
+		if err != nil {
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+	}
+
 	return d, nil
 }
 
+// SetClock replaces the Clock used to timestamp Queries and Recipe runs.
+func (d *DB) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+// SetLogger replaces the Logger errors of the replica are recorded to.
+func (d *DB) SetLogger(log *slog.Logger) {
+	d.log = log
+}
+
 // FromConfig configures a PostgreSQL database implementation from a configuration
 // object supplied by the user.
 func FromConfig(ctx context.Context, cfg *config.PostgresDB) (*DB, error) {
-	return New(ctx, cfg.URL)
+	return New(ctx, cfg.URL, cfg.ReplicaURL)
+}
+
+// Stats returns counters describing the connection pool to the primary
+// database.
+func (d *DB) Stats() map[string]int64 {
+	pool, ok := d.conn.(*pgxpool.Pool)
+	if !ok {
+		return nil
+	}
+
+	stat := pool.Stat()
+
+	return map[string]int64{
+		"total_conns":    int64(stat.TotalConns()),
+		"idle_conns":     int64(stat.IdleConns()),
+		"acquired_conns": int64(stat.AcquiredConns()),
+		"max_conns":      int64(stat.MaxConns()),
+		"empty_acquires": stat.EmptyAcquireCount(),
+	}
+}
+
+// Ping returns an error if the primary database cannot be reached.
+func (d *DB) Ping(ctx context.Context) error {
+	_, err := d.conn.Exec(ctx, "SELECT 1")
+	if err != nil {
+		return fmt.Errorf("could not ping postgres: %w", err)
+	}
+
+	return nil
+}
+
+// connect creates a new connection pool to the PostgreSQL database at url.
+func connect(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse postgres config: %w", err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to postgres: %w", err)
+	}
+
+	return pool, nil
 }
 
 // migrate creates the database schemas expected by DENNIS and applies any
@@ -76,16 +149,55 @@ func (d *DB) migrate(ctx context.Context) error {
 		return fmt.Errorf("could not create `records` table: %w", err)
 	}
 
+	if _, err := d.conn.Exec(ctx, recipeTable); err != nil {
+		return fmt.Errorf("could not create `recipes` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, recipeRunTable); err != nil {
+		return fmt.Errorf("could not create `recipe_runs` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, reportTable); err != nil {
+		return fmt.Errorf("could not create `reports` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, resolverChangeTable); err != nil {
+		return fmt.Errorf("could not create `resolver_changes` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, storedResolverTable); err != nil {
+		return fmt.Errorf("could not create `stored_resolvers` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, resolverFingerprintTable); err != nil {
+		return fmt.Errorf("could not create `resolver_fingerprints` table: %w", err)
+	}
+
+	if _, err := d.conn.Exec(ctx, jobTable); err != nil {
+		return fmt.Errorf("could not create `jobs` table: %w", err)
+	}
+
 	return nil
 }
 
 func (d *DB) CreateQuery(ctx context.Context, q *models.Query) error {
 	const query = `
-		INSERT INTO queries (type, name) VALUES ($1, $2)
-		RETURNING id, created_at
+		INSERT INTO queries (
+			type, types, name, unicode_name, class, server, serial, resolver_groups, samples, dnssec, dns64,
+			no_recursion, checking_disabled, capture, edns0, run_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id
 	`
 
-	err := d.conn.QueryRow(ctx, query, q.Type, q.Name).Scan(&q.ID, &q.CreatedAt)
+	// CreatedAt is taken from DENNIS' clock rather than PostgreSQL's, so it
+	// is consistent with the other timestamps recorded against a Query.
+	q.CreatedAt = d.clock.Now().UTC()
+
+	err := d.conn.QueryRow(
+		ctx, query, q.Type, q.Types, q.Name, q.UnicodeName, q.Class, q.Server, q.Serial, q.Groups, q.Samples, q.DNSSEC,
+		q.DNS64, q.NoRecursion, q.CheckingDisabled, q.Capture, q.EDNS0, q.RunAt, q.CreatedAt,
+	).Scan(&q.ID)
 	if err != nil {
 		return fmt.Errorf("could not create query: %w", err)
 	}
@@ -94,18 +206,42 @@ func (d *DB) CreateQuery(ctx context.Context, q *models.Query) error {
 }
 
 func (d *DB) GetQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, error) {
-	query, err := d.getQueryByID(ctx, id)
+	// a finished Query is never modified again, so it is safe to serve from a
+	// replica that may be lagging behind the primary. Lookups are written
+	// before the Query is marked finished, so if the replica has seen the
+	// latter it has also seen the former. Anything else, including a Query
+	// the replica has not seen yet or could not be read from it, is read from
+	// the primary.
+	if d.replica != nil {
+		query, err := getFullQueryByID(ctx, d.replica, id)
+		if err == nil && query.FinishedAt != nil {
+			return query, nil
+		} else if err != nil && !errors.Is(err, db.ErrQueryNotFound) {
+			d.log.Warn(
+				"could not get query from replica, reading from primary",
+				slog.String("query_id", id.String()), slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	return getFullQueryByID(ctx, d.conn, id)
+}
+
+// getFullQueryByID retrieves a Query, it's Lookups and their Records from
+// the database connection c.
+func getFullQueryByID(ctx context.Context, c querier, id uuid.UUID) (*models.Query, error) {
+	query, err := getQueryByID(ctx, c, id)
 	if err != nil {
 		return nil, err
 	}
 
-	query.Lookups, err = d.listLookupsForQueryID(ctx, query.ID)
+	query.Lookups, err = listLookupsForQueryID(ctx, c, query.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, lookup := range query.Lookups {
-		lookup.Records, err = d.listRecordsForLookupID(ctx, *lookup.ID)
+		lookup.Records, err = listRecordsForLookupID(ctx, c, *lookup.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -114,17 +250,22 @@ func (d *DB) GetQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, err
 	return query, nil
 }
 
-func (d *DB) getQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, error) {
+func getQueryByID(ctx context.Context, c querier, id uuid.UUID) (*models.Query, error) {
 	const query = `
-		SELECT id, type, name, created_at, finished_at
+		SELECT
+			id, type, types, name, unicode_name, class, server, serial, resolver_groups, samples, dnssec, dns64,
+			no_recursion, checking_disabled, capture, edns0, run_at, created_at, finished_at, timings,
+			client_disconnected, partial, interrupted
 		FROM queries
 		WHERE id = $1
 	`
 
 	q := new(models.Query)
 
-	err := d.conn.QueryRow(ctx, query, id).Scan(
-		&q.ID, &q.Type, &q.Name, &q.CreatedAt, &q.FinishedAt,
+	err := c.QueryRow(ctx, query, id).Scan(
+		&q.ID, &q.Type, &q.Types, &q.Name, &q.UnicodeName, &q.Class, &q.Server, &q.Serial, &q.Groups, &q.Samples,
+		&q.DNSSEC, &q.DNS64, &q.NoRecursion, &q.CheckingDisabled, &q.Capture, &q.EDNS0, &q.RunAt, &q.CreatedAt,
+		&q.FinishedAt, &q.Timings, &q.ClientDisconnected, &q.Partial, &q.Interrupted,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, db.ErrQueryNotFound
@@ -135,16 +276,18 @@ func (d *DB) getQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, err
 	return q, nil
 }
 
-func (d *DB) listLookupsForQueryID(ctx context.Context, queryID uuid.UUID) ([]*models.Lookup, error) {
+func listLookupsForQueryID(ctx context.Context, c querier, queryID uuid.UUID) ([]*models.Lookup, error) {
 	const query = `
-		SELECT id, resolver, rtt, error, resolved_at
+		SELECT id, resolver, type, rtt, attempts, error, policy, dnssec, truncated_by_dennis, total_records, edns0_differs,
+			dns64, header, samples, authority, additional, wire, instance_id, location, resolver_health, resolved_at
 		FROM lookups
 		WHERE query_id = $1
+		ORDER BY id
 	`
 
 	lks := []*models.Lookup{}
 
-	rows, err := d.conn.Query(ctx, query, queryID)
+	rows, err := c.Query(ctx, query, queryID)
 	if err != nil {
 		return nil, fmt.Errorf("could not query lookups: %w", err)
 	}
@@ -152,7 +295,11 @@ func (d *DB) listLookupsForQueryID(ctx context.Context, queryID uuid.UUID) ([]*m
 
 	for rows.Next() {
 		lk := new(models.Lookup)
-		err := rows.Scan(&lk.ID, &lk.Resolver, &lk.RTT, &lk.Error, &lk.ResolvedAt)
+		err := rows.Scan(
+			&lk.ID, &lk.Resolver, &lk.Type, &lk.RTT, &lk.Attempts, &lk.Error, &lk.Policy, &lk.DNSSEC, &lk.TruncatedByDennis,
+			&lk.TotalRecords, &lk.EDNS0Differs, &lk.DNS64, &lk.Header, &lk.Samples, &lk.Authority, &lk.Additional, &lk.Wire,
+			&lk.InstanceID, &lk.Location, &lk.ResolverHealth, &lk.ResolvedAt,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("could not scan lookup: %w", err)
 		}
@@ -168,16 +315,16 @@ func (d *DB) listLookupsForQueryID(ctx context.Context, queryID uuid.UUID) ([]*m
 	return lks, nil
 }
 
-func (d *DB) listRecordsForLookupID(ctx context.Context, lookupID uuid.UUID) ([]*models.Record, error) {
+func listRecordsForLookupID(ctx context.Context, c querier, lookupID uuid.UUID) ([]*models.Record, error) {
 	const query = `
-		SELECT ttl, priority, weight, port, tag, content
+		SELECT name, type, ttl, priority, weight, port, tag, content, params
 		FROM records
 		WHERE lookup_id = $1
 	`
 
 	recs := []*models.Record{}
 
-	rows, err := d.conn.Query(ctx, query, lookupID)
+	rows, err := c.Query(ctx, query, lookupID)
 	if err != nil {
 		return nil, fmt.Errorf("could not query records: %w", err)
 	}
@@ -185,7 +332,9 @@ func (d *DB) listRecordsForLookupID(ctx context.Context, lookupID uuid.UUID) ([]
 
 	for rows.Next() {
 		rec := &models.Record{}
-		err := rows.Scan(&rec.TTL, &rec.Priority, &rec.Weight, &rec.Port, &rec.Tag, &rec.Content)
+		err := rows.Scan(
+			&rec.Name, &rec.Type, &rec.TTL, &rec.Priority, &rec.Weight, &rec.Port, &rec.Tag, &rec.Content, &rec.Params,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("could not scan record: %w", err)
 		}
@@ -204,11 +353,11 @@ func (d *DB) listRecordsForLookupID(ctx context.Context, lookupID uuid.UUID) ([]
 func (d *DB) UpdateQuery(ctx context.Context, q *models.Query) error {
 	const query = `
 		UPDATE queries
-		SET finished_at = $1
-		WHERE id = $2
+		SET finished_at = $1, timings = $2, client_disconnected = $3, partial = $4, interrupted = $5
+		WHERE id = $6
 	`
 
-	result, err := d.conn.Exec(ctx, query, q.FinishedAt, q.ID)
+	result, err := d.conn.Exec(ctx, query, q.FinishedAt, q.Timings, q.ClientDisconnected, q.Partial, q.Interrupted, q.ID)
 	if err != nil {
 		return fmt.Errorf("could not update query: %w", err)
 	} else if rowsAffected := result.RowsAffected(); rowsAffected != 1 {
@@ -218,6 +367,50 @@ func (d *DB) UpdateQuery(ctx context.Context, q *models.Query) error {
 	return nil
 }
 
+func (d *DB) ListScheduledQueries(ctx context.Context) ([]*models.Query, error) {
+	const query = `
+		SELECT id
+		FROM queries
+		WHERE run_at IS NOT NULL AND finished_at IS NULL
+		ORDER BY run_at
+	`
+
+	var ids []uuid.UUID
+
+	rows, err := d.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query scheduled queries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("could not scan scheduled query: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan scheduled queries: %w", err)
+	}
+
+	queries := make([]*models.Query, 0, len(ids))
+
+	for _, id := range ids {
+		q, err := getFullQueryByID(ctx, d.conn, id)
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
+
 func (d *DB) DeleteQueriesOlderThan(ctx context.Context, maxAge time.Duration) error {
 	return nil
 }
@@ -240,14 +433,20 @@ func (d *DB) CreateLookup(ctx context.Context, queryID uuid.UUID, lk *models.Loo
 
 func (d *DB) createLookup(ctx context.Context, queryID uuid.UUID, lk *models.Lookup) error {
 	const query = `
-		INSERT INTO lookups (query_id, resolver, rtt, error, resolved_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO lookups (
+			query_id, resolver, type, rtt, attempts, error, policy, dnssec, truncated_by_dennis, total_records,
+			edns0_differs, dns64, header, samples, authority, additional, wire, instance_id, location, resolver_health,
+			resolved_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING id
 		`
 
 	err := d.conn.QueryRow(
 		ctx, query,
-		queryID, lk.Resolver, lk.RTT, lk.Error, lk.ResolvedAt,
+		queryID, lk.Resolver, lk.Type, lk.RTT, lk.Attempts, lk.Error, lk.Policy, lk.DNSSEC, lk.TruncatedByDennis,
+		lk.TotalRecords, lk.EDNS0Differs, lk.DNS64, lk.Header, lk.Samples, lk.Authority, lk.Additional, lk.Wire,
+		lk.InstanceID, lk.Location, lk.ResolverHealth, lk.ResolvedAt,
 	).Scan(&lk.ID)
 	if err != nil {
 		return fmt.Errorf("could not create lookup: %w", err)
@@ -258,13 +457,13 @@ func (d *DB) createLookup(ctx context.Context, queryID uuid.UUID, lk *models.Loo
 
 func (d *DB) createRecord(ctx context.Context, lookupID uuid.UUID, rec *models.Record) error {
 	const query = `
-		INSERT INTO records (lookup_id, ttl, priority, weight, port, tag, content)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO records (lookup_id, name, type, ttl, priority, weight, port, tag, content, params)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	_, err := d.conn.Exec(
 		ctx, query,
-		lookupID, rec.TTL, rec.Priority, rec.Weight, rec.Port, rec.Tag, rec.Content,
+		lookupID, rec.Name, rec.Type, rec.TTL, rec.Priority, rec.Weight, rec.Port, rec.Tag, rec.Content, rec.Params,
 	)
 	if err != nil {
 		return fmt.Errorf("could not create record: %w", err)
@@ -272,3 +471,464 @@ func (d *DB) createRecord(ctx context.Context, lookupID uuid.UUID, rec *models.R
 
 	return nil
 }
+
+func (d *DB) CountQueries(ctx context.Context, since time.Time) ([]*models.QueryCount, error) {
+	const query = `
+		SELECT type, name, count(*) AS count
+		FROM queries
+		WHERE created_at >= $1
+		GROUP BY type, name
+		ORDER BY count DESC
+	`
+
+	counts := []*models.QueryCount{}
+
+	rows, err := d.conn.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("could not count queries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := new(models.QueryCount)
+		err := rows.Scan(&c.Type, &c.Name, &c.Count)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan query count: %w", err)
+		}
+
+		counts = append(counts, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan query counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// duplicateReport is the name of the DuplicateReport in the `reports` table.
+const duplicateReport = "duplicates"
+
+func (d *DB) PutDuplicateReport(ctx context.Context, report *models.DuplicateReport) error {
+	const query = `
+		INSERT INTO reports (name, report) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET report = EXCLUDED.report
+	`
+
+	_, err := d.conn.Exec(ctx, query, duplicateReport, report)
+	if err != nil {
+		return fmt.Errorf("could not put duplicate report: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetDuplicateReport(ctx context.Context) (*models.DuplicateReport, error) {
+	const query = `
+		SELECT report
+		FROM reports
+		WHERE name = $1
+	`
+
+	report := new(models.DuplicateReport)
+
+	err := d.conn.QueryRow(ctx, query, duplicateReport).Scan(report)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, db.ErrReportNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get duplicate report: %w", err)
+	}
+
+	return report, nil
+}
+
+func (d *DB) CreateResolverChange(ctx context.Context, c *models.ResolverChange) error {
+	const query = `
+		INSERT INTO resolver_changes (resolver, change, before, after, source, actor)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := d.conn.QueryRow(
+		ctx, query,
+		c.Resolver, c.Change, c.Before, c.After, c.Source, c.Actor,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("could not create resolver change: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverChanges(ctx context.Context) ([]*models.ResolverChange, error) {
+	const query = `
+		SELECT id, resolver, change, before, after, source, actor, created_at
+		FROM resolver_changes
+		ORDER BY created_at, id
+	`
+
+	changes := []*models.ResolverChange{}
+
+	rows, err := d.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query resolver changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := new(models.ResolverChange)
+		err := rows.Scan(&c.ID, &c.Resolver, &c.Change, &c.Before, &c.After, &c.Source, &c.Actor, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan resolver change: %w", err)
+		}
+
+		changes = append(changes, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan resolver changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+func (d *DB) CreateResolverFingerprint(ctx context.Context, fp *models.ResolverFingerprint) error {
+	const query = `
+		INSERT INTO resolver_fingerprints (resolver, nsid, server_id, edns, udp_size, recursive, tcp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := d.conn.QueryRow(
+		ctx, query,
+		fp.Resolver, fp.NSID, fp.ServerID, fp.EDNS, fp.UDPSize, fp.Recursive, fp.TCP,
+	).Scan(&fp.ID, &fp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("could not create resolver fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverFingerprints(ctx context.Context, resolver string) ([]*models.ResolverFingerprint, error) {
+	const query = `
+		SELECT id, resolver, nsid, server_id, edns, udp_size, recursive, tcp, created_at
+		FROM resolver_fingerprints
+		WHERE resolver = $1
+		ORDER BY created_at, id
+	`
+
+	fps := []*models.ResolverFingerprint{}
+
+	rows, err := d.conn.Query(ctx, query, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("could not query resolver fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		fp := new(models.ResolverFingerprint)
+		err := rows.Scan(
+			&fp.ID, &fp.Resolver, &fp.NSID, &fp.ServerID, &fp.EDNS, &fp.UDPSize, &fp.Recursive, &fp.TCP, &fp.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan resolver fingerprint: %w", err)
+		}
+
+		fps = append(fps, fp)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan resolver fingerprints: %w", err)
+	}
+
+	return fps, nil
+}
+
+func (d *DB) PutStoredResolver(ctx context.Context, r *models.StoredResolver) error {
+	const query = `
+		INSERT INTO stored_resolvers (name, config, disabled, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+		SET config = EXCLUDED.config, disabled = EXCLUDED.disabled, updated_at = EXCLUDED.updated_at
+	`
+
+	r.UpdatedAt = d.clock.Now().UTC()
+
+	_, err := d.conn.Exec(ctx, query, r.Name, r.Config, r.Disabled, r.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("could not put stored resolver: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) DeleteStoredResolver(ctx context.Context, name string) error {
+	const query = `
+		DELETE FROM stored_resolvers
+		WHERE name = $1
+	`
+
+	_, err := d.conn.Exec(ctx, query, name)
+	if err != nil {
+		return fmt.Errorf("could not delete stored resolver: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListStoredResolvers(ctx context.Context) ([]*models.StoredResolver, error) {
+	const query = `
+		SELECT name, config, disabled, updated_at
+		FROM stored_resolvers
+		ORDER BY name
+	`
+
+	resolvers := []*models.StoredResolver{}
+
+	rows, err := d.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query stored resolvers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := new(models.StoredResolver)
+		err := rows.Scan(&r.Name, &r.Config, &r.Disabled, &r.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan stored resolver: %w", err)
+		}
+
+		resolvers = append(resolvers, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan stored resolvers: %w", err)
+	}
+
+	return resolvers, nil
+}
+
+func (d *DB) PutJob(ctx context.Context, job *models.Job) error {
+	const query = `
+		INSERT INTO jobs (query_id, instance_id, priority, attempts, started_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (query_id) DO UPDATE
+		SET instance_id = EXCLUDED.instance_id, priority = EXCLUDED.priority, attempts = EXCLUDED.attempts,
+			started_at = EXCLUDED.started_at
+	`
+
+	_, err := d.conn.Exec(
+		ctx, query, job.QueryID, job.InstanceID, job.Priority, job.Attempts, job.StartedAt, job.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("could not put job: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ClaimJob(ctx context.Context, job *models.Job) (bool, error) {
+	const query = `
+		INSERT INTO jobs (query_id, instance_id, priority, attempts, started_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (query_id) DO NOTHING
+	`
+
+	result, err := d.conn.Exec(
+		ctx, query, job.QueryID, job.InstanceID, job.Priority, job.Attempts, job.StartedAt, job.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not claim job: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (d *DB) TakeJob(ctx context.Context, job *models.Job, instanceID string) (bool, error) {
+	const query = `
+		UPDATE jobs
+		SET instance_id = $1
+		WHERE query_id = $2 AND instance_id = $3 AND attempts = $4
+	`
+
+	result, err := d.conn.Exec(ctx, query, instanceID, job.QueryID, job.InstanceID, job.Attempts)
+	if err != nil {
+		return false, fmt.Errorf("could not take job: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (d *DB) DeleteJob(ctx context.Context, queryID uuid.UUID) error {
+	const query = `
+		DELETE FROM jobs
+		WHERE query_id = $1
+	`
+
+	_, err := d.conn.Exec(ctx, query, queryID)
+	if err != nil {
+		return fmt.Errorf("could not delete job: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListJobs(ctx context.Context) ([]*models.Job, error) {
+	const query = `
+		SELECT query_id, instance_id, priority, attempts, started_at, created_at
+		FROM jobs
+		ORDER BY created_at
+	`
+
+	jobs := []*models.Job{}
+
+	rows, err := d.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		j := new(models.Job)
+		err := rows.Scan(&j.QueryID, &j.InstanceID, &j.Priority, &j.Attempts, &j.StartedAt, &j.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan job: %w", err)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (d *DB) CreateRecipe(ctx context.Context, r *models.Recipe) error {
+	const query = `
+		INSERT INTO recipes (name, description, steps) VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := d.conn.QueryRow(ctx, query, r.Name, r.Description, r.Steps).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("could not create recipe: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListRecipes(ctx context.Context) ([]*models.Recipe, error) {
+	const query = `
+		SELECT id, name, description, steps, created_at
+		FROM recipes
+		ORDER BY created_at
+	`
+
+	recipes := []*models.Recipe{}
+
+	rows, err := d.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query recipes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := new(models.Recipe)
+		err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.Steps, &r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan recipe: %w", err)
+		}
+
+		recipes = append(recipes, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not scan recipes: %w", err)
+	}
+
+	return recipes, nil
+}
+
+func (d *DB) GetRecipeByID(ctx context.Context, id uuid.UUID) (*models.Recipe, error) {
+	const query = `
+		SELECT id, name, description, steps, created_at
+		FROM recipes
+		WHERE id = $1
+	`
+
+	r := new(models.Recipe)
+
+	err := d.conn.QueryRow(ctx, query, id).Scan(&r.ID, &r.Name, &r.Description, &r.Steps, &r.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, db.ErrRecipeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get recipe: %w", err)
+	}
+
+	return r, nil
+}
+
+func (d *DB) DeleteRecipe(ctx context.Context, id uuid.UUID) error {
+	const query = `
+		DELETE FROM recipes
+		WHERE id = $1
+	`
+
+	result, err := d.conn.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("could not delete recipe: %w", err)
+	} else if result.RowsAffected() == 0 {
+		return db.ErrRecipeNotFound
+	}
+
+	return nil
+}
+
+func (d *DB) CreateRecipeRun(ctx context.Context, r *models.RecipeRun) error {
+	const query = `
+		INSERT INTO recipe_runs (recipe_id, recipe_name, name, query_ids, steps, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	// CreatedAt is taken from DENNIS' clock rather than PostgreSQL's, so it
+	// is consistent with the Queries of the run.
+	r.CreatedAt = d.clock.Now().UTC()
+
+	err := d.conn.QueryRow(ctx, query, r.RecipeID, r.RecipeName, r.Name, r.QueryIDs, r.Steps, r.CreatedAt).Scan(&r.ID)
+	if err != nil {
+		return fmt.Errorf("could not create recipe run: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetRecipeRunByID(ctx context.Context, id uuid.UUID) (*models.RecipeRun, error) {
+	const query = `
+		SELECT id, recipe_id, recipe_name, name, query_ids, steps, created_at
+		FROM recipe_runs
+		WHERE id = $1
+	`
+
+	r := new(models.RecipeRun)
+
+	err := d.conn.QueryRow(ctx, query, id).Scan(
+		&r.ID, &r.RecipeID, &r.RecipeName, &r.Name, &r.QueryIDs, &r.Steps, &r.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, db.ErrRecipeRunNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get recipe run: %w", err)
+	}
+
+	return r, nil
+}
@@ -14,50 +14,248 @@ const (
 	`
 
 	// queryTable is the `CREATE TABLE` statement to create the `queries` table
-	// within PostgreSQL.
+	// within PostgreSQL. Columns added since the table was first introduced
+	// are also added to existing tables.
 	queryTable = `
 		CREATE TABLE IF NOT EXISTS queries (
-			id    UUID  PRIMARY KEY DEFAULT uuidv7(),
-			type  TEXT  NOT NULL,
-			name  TEXT  NOT NULL,
+			id     UUID  PRIMARY KEY DEFAULT uuidv7(),
+			type   TEXT  NOT NULL,
+			types  TEXT[],
+			resolver_groups  TEXT[],
+			name   TEXT  NOT NULL,
+			class  TEXT  NOT NULL DEFAULT '',
+			server TEXT  NOT NULL DEFAULT '',
+			serial BIGINT,
+
+			unicode_name  TEXT  NOT NULL DEFAULT '',
 
 			created_at   TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC'),
-			finished_at  TIMESTAMPTZ
+			finished_at  TIMESTAMPTZ,
+
+			timings  JSONB,
+			samples  INTEGER  NOT NULL DEFAULT 0,
+			dnssec   BOOLEAN  NOT NULL DEFAULT false,
+			dns64    BOOLEAN  NOT NULL DEFAULT false,
+			no_recursion       BOOLEAN  NOT NULL DEFAULT false,
+			checking_disabled  BOOLEAN  NOT NULL DEFAULT false,
+			capture            BOOLEAN  NOT NULL DEFAULT false,
+			client_disconnected  BOOLEAN  NOT NULL DEFAULT false,
+			partial              BOOLEAN  NOT NULL DEFAULT false,
+			interrupted          BOOLEAN  NOT NULL DEFAULT false,
+			edns0    JSONB,
+			run_at   TIMESTAMPTZ
 		);
+
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS timings JSONB;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS samples INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS run_at TIMESTAMPTZ;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS dnssec BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS dns64 BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS types TEXT[];
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS edns0 JSONB;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS class TEXT NOT NULL DEFAULT '';
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS server TEXT NOT NULL DEFAULT '';
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS serial BIGINT;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS unicode_name TEXT NOT NULL DEFAULT '';
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS no_recursion BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS checking_disabled BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS capture BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS client_disconnected BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS partial BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS interrupted BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE queries ADD COLUMN IF NOT EXISTS resolver_groups TEXT[];
+
+		CREATE INDEX IF NOT EXISTS queries_scheduled_idx
+			ON queries(run_at) WHERE finished_at IS NULL;
 	`
 
 	// lookupTable is the `CREATE TABLE` statement to create the `lookups`
-	// table within PostgreSQL.
+	// table within PostgreSQL. Columns added since the table was first
+	// introduced are also added to existing tables.
 	lookupTable = `
 		CREATE TABLE IF NOT EXISTS lookups (
 			id        UUID  PRIMARY KEY DEFAULT uuidv7(),
 			query_id  UUID  NOT NULL REFERENCES queries(id),
 
 			resolver  TEXT     NOT NULL,
+			instance_id  TEXT  NOT NULL DEFAULT '',
+			location     TEXT  NOT NULL DEFAULT '',
+			type      TEXT     NOT NULL DEFAULT '',
 			rtt       INTEGER  NOT NULL,
+			attempts  INTEGER  NOT NULL DEFAULT 1,
 			error     TEXT,
+			policy    TEXT,
+			dnssec    TEXT,
+
+			truncated_by_dennis  BOOLEAN  NOT NULL DEFAULT false,
+			total_records        INTEGER  NOT NULL DEFAULT 0,
+			edns0_differs        BOOLEAN  NOT NULL DEFAULT false,
+
+			dns64       JSONB,
+			header      JSONB,
+			samples     JSONB,
+			authority   JSONB,
+			additional  JSONB,
+			wire        JSONB,
+
+			resolver_health  JSONB,
 
 			resolved_at  TIMESTAMPTZ
 		);
 
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS samples JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS policy TEXT;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS dnssec TEXT;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS truncated_by_dennis BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS total_records INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS header JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS authority JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS additional JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS edns0_differs BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS dns64 JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT '';
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS wire JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS instance_id TEXT NOT NULL DEFAULT '';
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS resolver_health JSONB;
+		ALTER TABLE lookups ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '';
+
 		CREATE INDEX IF NOT EXISTS lookups_query_id_idx
 			ON lookups(query_id);
 	`
 
+	// recipeTable is the `CREATE TABLE` statement to create the `recipes`
+	// table within PostgreSQL, containing user defined Recipes.
+	recipeTable = `
+		CREATE TABLE IF NOT EXISTS recipes (
+			id           UUID   PRIMARY KEY DEFAULT uuidv7(),
+			name         TEXT   NOT NULL,
+			description  TEXT   NOT NULL DEFAULT '',
+			steps        JSONB  NOT NULL,
+
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+	`
+
+	// recipeRunTable is the `CREATE TABLE` statement to create the
+	// `recipe_runs` table within PostgreSQL. recipe_id does not reference
+	// `recipes`, as built in Recipes are not stored and runs outlive the
+	// Recipes they were created from.
+	recipeRunTable = `
+		CREATE TABLE IF NOT EXISTS recipe_runs (
+			id           UUID    PRIMARY KEY DEFAULT uuidv7(),
+			recipe_id    UUID    NOT NULL,
+			recipe_name  TEXT    NOT NULL,
+			name         TEXT    NOT NULL,
+			query_ids    UUID[]  NOT NULL,
+			steps        JSONB,
+
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+
+		ALTER TABLE recipe_runs ADD COLUMN IF NOT EXISTS steps JSONB;
+	`
+
+	// resolverChangeTable is the `CREATE TABLE` statement to create the
+	// `resolver_changes` table within PostgreSQL, recording changes to the
+	// configured Resolvers.
+	resolverChangeTable = `
+		CREATE TABLE IF NOT EXISTS resolver_changes (
+			id        UUID  PRIMARY KEY DEFAULT uuidv7(),
+			resolver  TEXT  NOT NULL,
+			change    TEXT  NOT NULL,
+			before    JSONB,
+			after     JSONB,
+			source    TEXT  NOT NULL,
+			actor     TEXT  NOT NULL,
+
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+	`
+
+	// storedResolverTable is the `CREATE TABLE` statement to create the
+	// `stored_resolvers` table within PostgreSQL, storing the Resolvers
+	// managed at runtime.
+	storedResolverTable = `
+		CREATE TABLE IF NOT EXISTS stored_resolvers (
+			name      TEXT     PRIMARY KEY,
+			config    JSONB,
+			disabled  BOOLEAN  NOT NULL DEFAULT false,
+
+			updated_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+	`
+
+	// resolverFingerprintTable is the `CREATE TABLE` statement to create the
+	// `resolver_fingerprints` table within PostgreSQL, recording the history
+	// of the fingerprint of each Resolver.
+	resolverFingerprintTable = `
+		CREATE TABLE IF NOT EXISTS resolver_fingerprints (
+			id          UUID     PRIMARY KEY DEFAULT uuidv7(),
+			resolver    TEXT     NOT NULL,
+			nsid        TEXT     NOT NULL DEFAULT '',
+			server_id   TEXT     NOT NULL DEFAULT '',
+			edns        BOOLEAN  NOT NULL,
+			udp_size    INTEGER  NOT NULL,
+			recursive   BOOLEAN  NOT NULL,
+			tcp         BOOLEAN  NOT NULL,
+
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+
+		CREATE INDEX IF NOT EXISTS resolver_fingerprints_resolver_idx
+			ON resolver_fingerprints(resolver, created_at);
+	`
+
+	// jobTable is the `CREATE TABLE` statement to create the `jobs` table
+	// within PostgreSQL, recording the Queries waiting to be resolved, or
+	// being resolved. Columns added since the table was first introduced are
+	// also added to existing tables.
+	jobTable = `
+		CREATE TABLE IF NOT EXISTS jobs (
+			query_id     UUID     PRIMARY KEY REFERENCES queries(id) ON DELETE CASCADE,
+			instance_id  TEXT     NOT NULL DEFAULT '',
+			priority     TEXT     NOT NULL DEFAULT '',
+			attempts     INTEGER  NOT NULL DEFAULT 0,
+
+			started_at  TIMESTAMPTZ,
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT (now() at time zone 'UTC')
+		);
+
+		ALTER TABLE jobs ADD COLUMN IF NOT EXISTS instance_id TEXT NOT NULL DEFAULT '';
+	`
+
+	// reportTable is the `CREATE TABLE` statement to create the `reports`
+	// table within PostgreSQL, storing the most recent report of each name.
+	reportTable = `
+		CREATE TABLE IF NOT EXISTS reports (
+			name    TEXT   PRIMARY KEY,
+			report  JSONB  NOT NULL
+		);
+	`
+
 	// recordTable is the `CREATE TABLE statement to create the `records`
-	// table within PostgreSQL.
+	// table within PostgreSQL. Columns added since the table was first
+	// introduced are also added to existing tables.
 	recordTable = `
 		CREATE TABLE IF NOT EXISTS records (
 			lookup_id  UUID  NOT NULL REFERENCES lookups(id),
 
+			name      TEXT     NOT NULL DEFAULT '',
+			type      TEXT     NOT NULL DEFAULT '',
 			ttl       INTEGER  NOT NULL,
 			priority  INTEGER,
 			weight    INTEGER,
 			port      INTEGER,
 			tag       TEXT,
-			content   TEXT[]   NOT NULL
+			content   TEXT[]   NOT NULL,
+			params    JSONB
 		);
 
+		ALTER TABLE records ADD COLUMN IF NOT EXISTS params JSONB;
+		ALTER TABLE records ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+		ALTER TABLE records ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT '';
+
 		CREATE INDEX IF NOT EXISTS records_lookup_id_idx
 			ON records(lookup_id);
 	`
@@ -0,0 +1,247 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultWriteBuffer is the number of Lookups held while Redis is
+	// unreachable if not configured.
+	DefaultWriteBuffer = 1000
+
+	// writeTimeout is the longest a held write is attempted for before it is
+	// retried.
+	writeTimeout = 5 * time.Second
+
+	// minRetryInterval and maxRetryInterval bound how long the write buffer
+	// waits between attempts to reach Redis, doubling after each failure.
+	minRetryInterval = 250 * time.Millisecond
+	maxRetryInterval = 10 * time.Second
+)
+
+// ErrWriteBufferFull is returned when a Lookup could not be written to Redis
+// and there was no room left to hold it until it could.
+var ErrWriteBufferFull = errors.New("redis: write buffer is full")
+
+// writeBuffer holds the writes made while a Query is being resolved that
+// failed because Redis was unreachable, such as during a failover, and
+// retries them in the order they were made until they succeed. Once any
+// write is held, later writes are held behind it so they are not reordered,
+// as a Query must not be finished or compressed before it's Lookups are
+// written.
+type writeBuffer struct {
+	// mu is held for reading while a write is made directly to Redis, and
+	// for writing while writes are held or released, so no write is made
+	// directly while another is being held.
+	mu     sync.RWMutex
+	writes []heldWrite
+	closed bool
+
+	// size is the most Lookups held at once, beyond which they are dropped.
+	size    int
+	lookups int
+
+	// partial are the IDs of Queries whose held Lookups were rejected by
+	// Redis once retried, so they are marked partial when they finish.
+	partialMu sync.Mutex
+	partial   map[uuid.UUID]struct{}
+
+	// wake is signaled when a write is held, and done is closed to stop
+	// retrying held writes.
+	wake chan struct{}
+	done chan struct{}
+
+	// held counts every write that has been held, and dropped those that
+	// were dropped, either because the buffer was full or they were rejected
+	// by Redis once retried.
+	held    atomic.Int64
+	dropped atomic.Int64
+}
+
+// heldWrite is a single write held by writeBuffer, for the Query queryID.
+type heldWrite struct {
+	queryID uuid.UUID
+	lookup  bool
+	write   func(ctx context.Context) error
+}
+
+// newWriteBuffer returns a writeBuffer holding at most size Lookups, and
+// starts retrying the writes it holds in the background.
+func newWriteBuffer(size int) *writeBuffer {
+	b := &writeBuffer{
+		size:    size,
+		partial: make(map[uuid.UUID]struct{}),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b
+}
+
+// do makes write for the Query queryID, a Lookup if lookup is set, holding
+// it to be retried if Redis is unreachable or earlier writes are still held.
+// A Lookup is dropped with ErrWriteBufferFull if too many are already held,
+// other writes are always held so a Query is not left unfinished. Once the
+// writeBuffer is closed, writes are no longer held.
+func (b *writeBuffer) do(
+	ctx context.Context, queryID uuid.UUID, lookup bool, write func(ctx context.Context) error,
+) error {
+	b.mu.RLock()
+	if len(b.writes) == 0 || b.closed {
+		err := write(ctx)
+		if err == nil || !unreachable(err) || b.closed {
+			b.mu.RUnlock()
+			return err
+		}
+	}
+	b.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lookup {
+		if b.lookups >= b.size {
+			b.dropped.Add(1)
+			return ErrWriteBufferFull
+		}
+
+		b.lookups++
+	}
+
+	b.writes = append(b.writes, heldWrite{queryID: queryID, lookup: lookup, write: write})
+	b.held.Add(1)
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// run retries held writes in order, backing off while Redis is unreachable,
+// until the writeBuffer is closed. A write stays held until it has been
+// made, so later writes continue to queue behind it.
+func (b *writeBuffer) run() {
+	interval := minRetryInterval
+
+	for {
+		select {
+		case <-b.wake:
+		case <-b.done:
+			return
+		}
+
+		for {
+			b.mu.Lock()
+			if len(b.writes) == 0 {
+				b.mu.Unlock()
+				break
+			}
+			w := b.writes[0]
+			b.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+			err := w.write(ctx)
+			cancel()
+
+			if err != nil && unreachable(err) {
+				select {
+				case <-time.After(interval):
+				case <-b.done:
+					return
+				}
+
+				interval = min(interval*2, maxRetryInterval)
+				continue
+			} else if err != nil {
+				b.dropped.Add(1)
+
+				if w.lookup {
+					b.partialMu.Lock()
+					b.partial[w.queryID] = struct{}{}
+					b.partialMu.Unlock()
+				}
+			}
+
+			interval = minRetryInterval
+
+			b.mu.Lock()
+			b.writes = b.writes[1:]
+			if w.lookup {
+				b.lookups--
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// takePartial returns true if a held Lookup of the Query queryID was
+// rejected by Redis, forgetting it.
+func (b *writeBuffer) takePartial(queryID uuid.UUID) bool {
+	b.partialMu.Lock()
+	defer b.partialMu.Unlock()
+
+	_, ok := b.partial[queryID]
+	delete(b.partial, queryID)
+
+	return ok
+}
+
+// close waits until every held write has been made, or ctx is done, then
+// stops retrying them. It returns the number of writes still held, which are
+// discarded.
+func (b *writeBuffer) close(ctx context.Context) int {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for b.len() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	close(b.done)
+
+	return len(b.writes)
+}
+
+// len returns the number of writes currently held.
+func (b *writeBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.writes)
+}
+
+// unreachable returns true if err was caused by Redis being unreachable,
+// rather than Redis rejecting the write.
+func unreachable(err error) bool {
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		// a replica answers writes with READONLY until it is promoted.
+		return redis.HasErrorPrefix(err, "READONLY") || redis.HasErrorPrefix(err, "LOADING") ||
+			redis.HasErrorPrefix(err, "TRYAGAIN") || redis.HasErrorPrefix(err, "CLUSTERDOWN") ||
+			redis.HasErrorPrefix(err, "MASTERDOWN")
+	}
+
+	return !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,224 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	b := newWriteBuffer(2)
+
+	// Redis is unreachable until up is set.
+	var up atomic.Bool
+	var mu sync.Mutex
+	var written []string
+
+	write := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			if !up.Load() {
+				return errUnreachable
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			written = append(written, name)
+			return nil
+		}
+	}
+
+	ctx := context.Background()
+	id := uuid.Must(uuid.NewV7())
+
+	for _, name := range []string{"lookup1", "lookup2"} {
+		if err := b.do(ctx, id, true, write(name)); err != nil {
+			t.Fatalf("%s: expected write to be held, got %s", name, err)
+		}
+	}
+
+	if err := b.do(ctx, id, true, write("lookup3")); !errors.Is(err, ErrWriteBufferFull) {
+		t.Fatalf("expected ErrWriteBufferFull, got %v", err)
+	}
+
+	// updates are held beyond the size of the buffer, behind the lookups.
+	if err := b.do(ctx, id, false, write("update")); err != nil {
+		t.Fatalf("expected update to be held, got %s", err)
+	}
+
+	up.Store(true)
+	waitForDrain(t, b)
+
+	want := []string{"lookup1", "lookup2", "update"}
+	if !slices.Equal(written, want) {
+		t.Errorf("expected writes %v, got %v", want, written)
+	}
+
+	if held, dropped := b.held.Load(), b.dropped.Load(); held != 3 || dropped != 1 {
+		t.Errorf("expected 3 held and 1 dropped, got %d and %d", held, dropped)
+	}
+}
+
+// errUnreachable is returned by writes while Redis is unreachable.
+var errUnreachable = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestWriteBufferRejected(t *testing.T) {
+	b := newWriteBuffer(10)
+	defer b.close(context.Background())
+
+	var up atomic.Bool
+
+	ctx := context.Background()
+	rejected := uuid.Must(uuid.NewV7())
+	accepted := uuid.Must(uuid.NewV7())
+
+	err := b.do(ctx, rejected, true, func(context.Context) error {
+		if !up.Load() {
+			return errUnreachable
+		}
+
+		return redisError("ERR new objects must be created at the root")
+	})
+	must(t, err)
+
+	err = b.do(ctx, accepted, true, func(context.Context) error {
+		if !up.Load() {
+			return errUnreachable
+		}
+
+		return nil
+	})
+	must(t, err)
+
+	up.Store(true)
+	waitForDrain(t, b)
+
+	if !b.takePartial(rejected) {
+		t.Error("expected query of rejected lookup to be partial")
+	}
+
+	if b.takePartial(rejected) {
+		t.Error("expected partial query to be forgotten once taken")
+	}
+
+	if b.takePartial(accepted) {
+		t.Error("expected query of accepted lookup to not be partial")
+	}
+
+	if dropped := b.dropped.Load(); dropped != 1 {
+		t.Errorf("expected 1 dropped, got %d", dropped)
+	}
+}
+
+func TestWriteBufferClose(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.Must(uuid.NewV7())
+
+	t.Run("Drained", func(t *testing.T) {
+		b := newWriteBuffer(10)
+
+		var up atomic.Bool
+		var written atomic.Int64
+
+		for range 3 {
+			must(t, b.do(ctx, id, true, func(context.Context) error {
+				if !up.Load() {
+					return errUnreachable
+				}
+
+				written.Add(1)
+				return nil
+			}))
+		}
+
+		up.Store(true)
+
+		if n := b.close(ctx); n != 0 {
+			t.Errorf("expected no writes to be discarded, got %d", n)
+		}
+
+		if n := written.Load(); n != 3 {
+			t.Errorf("expected 3 writes to be made, got %d", n)
+		}
+	})
+
+	t.Run("Discarded", func(t *testing.T) {
+		b := newWriteBuffer(10)
+
+		for range 3 {
+			must(t, b.do(ctx, id, true, func(context.Context) error { return errUnreachable }))
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		if n := b.close(ctx); n != 3 {
+			t.Errorf("expected 3 writes to be discarded, got %d", n)
+		}
+
+		// once closed, writes are no longer held.
+		if err := b.do(ctx, id, true, func(context.Context) error { return errUnreachable }); err != errUnreachable {
+			t.Errorf("expected write to fail once closed, got %v", err)
+		}
+	})
+}
+
+// waitForDrain fails the test if b still holds writes after a few seconds.
+func waitForDrain(t *testing.T, b *writeBuffer) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected held writes to be retried, %d remain", b.len())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// must fails the test immediately if err is not nil.
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"Timeout", context.DeadlineExceeded, true},
+		{"Canceled", context.Canceled, false},
+		{"ReadOnly", redisError("READONLY You can't write against a read only replica."), true},
+		{"Rejected", redisError("ERR new objects must be created at the root"), false},
+		{"Nil", redis.Nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unreachable(test.err); got != test.want {
+				t.Errorf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+// redisError is an error returned by Redis itself.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+func (redisError) RedisError()     {}
@@ -0,0 +1,279 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// dispatchGroup is the consumer group every instance of DENNIS receives
+	// dispatched Jobs in, so each Job is delivered to only one of them.
+	dispatchGroup = "dennis"
+
+	// dispatchBlock is the longest Receive waits for a Job to be dispatched.
+	dispatchBlock = 5 * time.Second
+
+	// dispatchMinIdle is how long a Job delivered to an instance may go
+	// unacknowledged before it is redelivered to another. Resolving a Query
+	// takes little more than 30 seconds, so an instance that has not
+	// acknowledged a Job by then has most likely stopped.
+	dispatchMinIdle = 2 * time.Minute
+)
+
+// dispatchStreams are the streams Jobs are dispatched on, in the order they
+// are received from.
+var dispatchStreams = []string{interactiveStream, batchStream}
+
+// the streams Jobs are dispatched on, by their priority.
+const (
+	interactiveStream = "dennis:dispatch:interactive"
+	batchStream       = "dennis:dispatch:batch"
+)
+
+// dispatchStream returns the stream a Job of priority is dispatched on.
+func dispatchStream(priority string) string {
+	if priority == "batch" {
+		return batchStream
+	}
+
+	return interactiveStream
+}
+
+// Dispatch adds job to the stream of it's priority, to be received by one of
+// the instances of DENNIS sharing Redis.
+func (d *DB) Dispatch(ctx context.Context, job *models.Job) error {
+	bytes, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = d.conn.XAdd(ctx, &redis.XAddArgs{
+		Stream: dispatchStream(job.Priority),
+		Values: []string{"job", string(bytes)},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("could not add to stream: %w", err)
+	}
+
+	return nil
+}
+
+// Receive first claims a single Job delivered to another consumer that has
+// gone unacknowledged for dispatchMinIdle, otherwise it waits up to
+// dispatchBlock for new Jobs to be dispatched, returning at most one of each
+// priority, interactive first.
+func (d *DB) Receive(ctx context.Context, consumer string) ([]*db.Delivery, error) {
+	err := d.createGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stream := range dispatchStreams {
+		msgs, _, err := d.conn.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    dispatchGroup,
+			Consumer: consumer,
+			MinIdle:  dispatchMinIdle,
+			Start:    "0",
+			Count:    1,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("could not claim from stream: %w", err)
+		}
+
+		for _, msg := range msgs {
+			delivery, err := d.newDelivery(ctx, stream, msg, true)
+			if err != nil {
+				return nil, err
+			} else if delivery != nil {
+				return []*db.Delivery{delivery}, nil
+			}
+		}
+	}
+
+	streams, err := d.conn.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    dispatchGroup,
+		Consumer: consumer,
+		Streams:  []string{interactiveStream, batchStream, ">", ">"},
+		Count:    1,
+		Block:    dispatchBlock,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read from stream: %w", err)
+	}
+
+	var deliveries []*db.Delivery
+
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			delivery, err := d.newDelivery(ctx, stream.Stream, msg, false)
+			if err != nil {
+				return nil, err
+			} else if delivery != nil {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+	}
+
+	return deliveries, nil
+}
+
+// newDelivery returns the Delivery of the Job in msg, received from stream.
+// If the Job was claimed from another consumer, the number of times it has
+// been delivered is retrieved from Redis. A message that is not a Job is
+// acknowledged and removed, returning no Delivery, as it can never be
+// resolved.
+func (d *DB) newDelivery(ctx context.Context, stream string, msg redis.XMessage, claimed bool) (*db.Delivery, error) {
+	s, _ := msg.Values["job"].(string)
+
+	job := &models.Job{}
+	if err := json.Unmarshal([]byte(s), job); err != nil {
+		return nil, d.ack(ctx, stream, msg.ID)
+	}
+
+	delivery := &db.Delivery{ID: msg.ID, Job: job, Attempts: 1}
+
+	if claimed {
+		pending, err := d.conn.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  dispatchGroup,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("could not get pending entry: %w", err)
+		}
+
+		if len(pending) > 0 {
+			delivery.Attempts = int(pending[0].RetryCount)
+		}
+	}
+
+	return delivery, nil
+}
+
+// Ack acknowledges delivery, and removes it's Job from the stream, as every
+// instance receives Jobs in the same consumer group so it will never be read
+// again.
+func (d *DB) Ack(ctx context.Context, delivery *db.Delivery) error {
+	return d.ack(ctx, dispatchStream(delivery.Job.Priority), delivery.ID)
+}
+
+func (d *DB) ack(ctx context.Context, stream, id string) error {
+	err := d.conn.XAck(ctx, stream, dispatchGroup, id).Err()
+	if err != nil {
+		return fmt.Errorf("could not acknowledge stream entry: %w", err)
+	}
+
+	err = d.conn.XDel(ctx, stream, id).Err()
+	if err != nil {
+		return fmt.Errorf("could not delete stream entry: %w", err)
+	}
+
+	return nil
+}
+
+// createGroups creates the consumer group on each stream, and the streams
+// themselves, if they do not already exist. It is only attempted until it
+// succeeds.
+func (d *DB) createGroups(ctx context.Context) error {
+	if d.groups.Load() {
+		return nil
+	}
+
+	for _, stream := range dispatchStreams {
+		// the group is created from the start of the stream, so Jobs
+		// dispatched before any instance received them are not skipped.
+		err := d.conn.XGroupCreateMkStream(ctx, stream, dispatchGroup, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("could not create consumer group: %w", err)
+		}
+	}
+
+	d.groups.Store(true)
+
+	return nil
+}
+
+// createLookupOnce appends the Lookup ARGV[2] to the Query KEYS[1] only if
+// it's key ARGV[1] is not already in the set KEYS[2] of the keys of the
+// Lookups stored for the Query, returning -1 if the Query does not exist.
+// The set expires alongside the Query.
+var createLookupOnce = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+
+if redis.call('SADD', KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+
+redis.call('JSON.ARRAPPEND', KEYS[1], '$.lookups', ARGV[2])
+
+return 1
+`)
+
+// CreateLookupOnce is held to be retried while Redis is unreachable, like
+// CreateLookup. The Lookup is only appended if no Lookup of the same type,
+// resolver and location has been stored for the Query, which is checked and
+// appended atomically.
+func (d *DB) CreateLookupOnce(ctx context.Context, queryID uuid.UUID, lookup *models.Lookup) error {
+	bytes, err := json.Marshal(lookup)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	key, err := lookupKey(lookup)
+	if err != nil {
+		return err
+	}
+
+	return d.buffer.do(ctx, queryID, true, func(ctx context.Context) error {
+		keys := []string{queryKey(queryID), lookupKeysKey(queryID)}
+
+		n, err := createLookupOnce.Run(ctx, d.conn, keys, key, bytes).Int()
+		if err != nil {
+			return fmt.Errorf("could not append lookup: %w", err)
+		} else if n < 0 {
+			return db.ErrQueryNotFound
+		}
+
+		return nil
+	})
+}
+
+// lookupKey returns the key identifying the Lookup l among the Lookups of a
+// Query, from it's type, resolver and location.
+func lookupKey(l *models.Lookup) (string, error) {
+	bytes, err := json.Marshal([]string{l.Type, l.Resolver, l.Location})
+	if err != nil {
+		return "", fmt.Errorf("json: %w", err)
+	}
+
+	return string(bytes), nil
+}
+
+// lookupKeysKey generates a stringified key for the set of the keys of the
+// Lookups stored for a Query in Redis. It does not begin with queryKeyPrefix,
+// as only Queries are scanned by that prefix.
+func lookupKeysKey(id uuid.UUID) string {
+	return "dennis:lookup_keys:" + id.String()
+}
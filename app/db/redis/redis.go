@@ -5,12 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jamescun/dennis/app/config"
 	"github.com/jamescun/dennis/app/db"
 	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
 
 	"github.com/gofrs/uuid"
 	"github.com/redis/go-redis/v9"
@@ -21,47 +26,156 @@ type DB struct {
 	// conn is an interface containing just the methods we need from the Redis
 	// client.
 	conn interface {
+		Del(ctx context.Context, keys ...string) *redis.IntCmd
 		Expire(ctx context.Context, key string, expiry time.Duration) *redis.BoolCmd
 		JSONArrAppend(ctx context.Context, key, path string, values ...any) *redis.IntSliceCmd
 		JSONGet(ctx context.Context, key string, paths ...string) *redis.JSONCmd
 		JSONSet(ctx context.Context, key, path string, value any) *redis.StatusCmd
+		JSONSetMode(ctx context.Context, key, path string, value any, mode string) *redis.StatusCmd
+		JSONMGet(ctx context.Context, path string, keys ...string) *redis.JSONSliceCmd
+		Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+		Ping(ctx context.Context) *redis.StatusCmd
+		PoolStats() *redis.PoolStats
+
+		// the stream commands used to dispatch Jobs.
+		XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+		XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+		XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd
+		XDel(ctx context.Context, stream string, ids ...string) *redis.IntCmd
+		XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+		XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd
+		XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+
+		// scripts are used to store Lookups of dispatched Queries at most
+		// once.
+		redis.Scripter
 	}
 
 	// maxAge optionally sets an expiration on keys in Redis on create/update.
 	maxAge time.Duration
+
+	// compressor optionally compresses Queries once they have finished.
+	compressor *db.Compressor
+
+	// clock tells the time records are created.
+	clock clock.Clock
+
+	// buffer holds the writes made while resolving Queries when Redis is
+	// unreachable.
+	buffer *writeBuffer
+
+	// groups is set once the consumer groups Jobs are dispatched to have
+	// been created.
+	groups atomic.Bool
 }
 
 // New initializes a new Redis database implementation. The PING command will
 // be attempted after creating the connection to validate connectivity and
 // authentication (if configured). If maxAge is set, keys will automatically
-// expire from Redis after said age is reached.
-func New(ctx context.Context, opts *redis.Options, maxAge time.Duration) (*DB, error) {
+// expire from Redis after said age is reached. If compressor is set, Queries
+// are compressed once they have finished. While Redis is unreachable, up to
+// writeBuffer Lookups are held in memory until they can be written, or
+// DefaultWriteBuffer if zero.
+func New(
+	ctx context.Context, opts *redis.Options, maxAge time.Duration, compressor *db.Compressor, writeBuffer int,
+) (*DB, error) {
 	conn := redis.NewClient(opts)
 	err := conn.Ping(ctx).Err()
 	if err != nil {
 		return nil, fmt.Errorf("could not ping redis: %w", err)
 	}
 
-	return &DB{conn: conn, maxAge: maxAge}, nil
+	if writeBuffer <= 0 {
+		writeBuffer = DefaultWriteBuffer
+	}
+
+	return &DB{
+		conn:       conn,
+		maxAge:     maxAge,
+		compressor: compressor,
+		clock:      clock.Real,
+		buffer:     newWriteBuffer(writeBuffer),
+	}, nil
+}
+
+// drainTimeout is the longest Close waits for held writes to be made.
+const drainTimeout = 30 * time.Second
+
+// Close waits up to drainTimeout for the writes held while Redis was
+// unreachable to be made, then closes the connection to Redis. Any writes
+// still held are discarded, and returned as an error.
+func (d *DB) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	discarded := d.buffer.close(ctx)
+
+	if c, ok := d.conn.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("could not close redis: %w", err)
+		}
+	}
+
+	if discarded > 0 {
+		return fmt.Errorf("discarded %d held writes", discarded)
+	}
+
+	return nil
+}
+
+// SetClock replaces the Clock used to timestamp records, and to extend the
+// expiry of scheduled Queries until they are resolved.
+func (d *DB) SetClock(c clock.Clock) {
+	d.clock = c
 }
 
 // FromConfig configures a Redis database implementation from a configuration
 // object supplied by the user. If maxAge is set, keys will automatically
 // expire from Redis after said age is reached.
-func FromConfig(ctx context.Context, cfg *config.RedisDB, maxAge time.Duration) (*DB, error) {
+func FromConfig(
+	ctx context.Context, cfg *config.RedisDB, maxAge time.Duration, compressor *db.Compressor,
+) (*DB, error) {
 	return New(ctx, &redis.Options{
 		Addr:     cfg.Addr,
 		DB:       cfg.DB,
 		Username: cfg.Username,
 		Password: cfg.Password,
-	}, maxAge)
+	}, maxAge, compressor, cfg.WriteBuffer)
+}
+
+// Ping returns an error if Redis cannot be reached.
+func (d *DB) Ping(ctx context.Context) error {
+	err := d.conn.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("could not ping redis: %w", err)
+	}
+
+	return nil
+}
+
+// Stats returns counters describing the connection pool to Redis, and the
+// writes held while it was unreachable.
+func (d *DB) Stats() map[string]int64 {
+	stats := d.conn.PoolStats()
+
+	return map[string]int64{
+		"total_conns":     int64(stats.TotalConns),
+		"idle_conns":      int64(stats.IdleConns),
+		"stale_conns":     int64(stats.StaleConns),
+		"hits":            int64(stats.Hits),
+		"misses":          int64(stats.Misses),
+		"timeouts":        int64(stats.Timeouts),
+		"writes_held":     int64(d.buffer.len()),
+		"writes_buffered": d.buffer.held.Load(),
+		"writes_dropped":  d.buffer.dropped.Load(),
+	}
 }
 
 func (d *DB) CreateQuery(ctx context.Context, query *models.Query) error {
 	query.ID = uuid.Must(uuid.NewV7())
-	query.CreatedAt = time.Now().UTC()
+	query.CreatedAt = d.clock.Now().UTC()
 
-	bytes, err := json.Marshal(query)
+	bytes, err := json.Marshal(db.NewStoredQuery(query))
 	if err != nil {
 		return fmt.Errorf("json: %w", err)
 	}
@@ -72,7 +186,14 @@ func (d *DB) CreateQuery(ctx context.Context, query *models.Query) error {
 	}
 
 	if d.maxAge > 0 {
-		err := d.conn.Expire(ctx, queryKey(query.ID), d.maxAge).Err()
+		// a scheduled Query must not expire before it has been resolved, the
+		// expiry is reset by UpdateQuery once it has.
+		expiry := d.maxAge
+		if query.RunAt != nil {
+			expiry += d.clock.Until(*query.RunAt)
+		}
+
+		err := d.conn.Expire(ctx, queryKey(query.ID), expiry).Err()
 		if err != nil {
 			return fmt.Errorf("could not set key expire: %w", err)
 		}
@@ -89,17 +210,29 @@ func (d *DB) GetQueryByID(ctx context.Context, id uuid.UUID) (*models.Query, err
 		return nil, fmt.Errorf("could not get JSON key: %w", err)
 	}
 
-	query := &models.Query{}
+	query := &db.StoredQuery{}
 
 	err = json.Unmarshal([]byte(result), query)
 	if err != nil {
 		return nil, fmt.Errorf("json: %w", err)
 	}
 
-	return query, nil
+	return query.Query, nil
 }
 
+// UpdateQuery is held to be retried while Redis is unreachable, after any
+// Lookups of the Query that are also held, so the Query is always finished.
 func (d *DB) UpdateQuery(ctx context.Context, query *models.Query) error {
+	// the Query may be modified by the caller once it has returned, so
+	// updated from a copy if it must be retried.
+	q := new(*query)
+
+	return d.buffer.do(ctx, query.ID, false, func(ctx context.Context) error {
+		return d.updateQuery(ctx, q)
+	})
+}
+
+func (d *DB) updateQuery(ctx context.Context, query *models.Query) error {
 	if query.FinishedAt != nil {
 		err := d.conn.JSONSet(ctx, queryKey(query.ID), "$.finishedAt", strconv.Quote(query.FinishedAt.Format(time.RFC3339Nano))).Err()
 		if err != nil {
@@ -107,6 +240,49 @@ func (d *DB) UpdateQuery(ctx context.Context, query *models.Query) error {
 		}
 	}
 
+	if query.Timings != nil {
+		bytes, err := json.Marshal(query.Timings)
+		if err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+
+		err = d.conn.JSONSet(ctx, queryKey(query.ID), "$.timings", bytes).Err()
+		if err != nil {
+			return fmt.Errorf("could not update JSON key: %w", err)
+		}
+	}
+
+	if query.ClientDisconnected {
+		err := d.conn.JSONSet(ctx, queryKey(query.ID), "$.clientDisconnected", "true").Err()
+		if err != nil {
+			return fmt.Errorf("could not update JSON key: %w", err)
+		}
+	}
+
+	// a held Lookup of the Query rejected once Redis was reachable again is
+	// missing, so the Query is partial.
+	if d.buffer.takePartial(query.ID) || query.Partial {
+		err := d.conn.JSONSet(ctx, queryKey(query.ID), "$.partial", "true").Err()
+		if err != nil {
+			return fmt.Errorf("could not update JSON key: %w", err)
+		}
+	}
+
+	if query.Interrupted {
+		err := d.conn.JSONSet(ctx, queryKey(query.ID), "$.interrupted", "true").Err()
+		if err != nil {
+			return fmt.Errorf("could not update JSON key: %w", err)
+		}
+	}
+
+	// a finished Query is never modified again, so can be compressed.
+	if query.FinishedAt != nil && d.compressor != nil {
+		err := d.compress(ctx, query.ID)
+		if err != nil {
+			return err
+		}
+	}
+
 	if d.maxAge > 0 {
 		err := d.conn.Expire(ctx, queryKey(query.ID), d.maxAge).Err()
 		if err != nil {
@@ -117,19 +293,68 @@ func (d *DB) UpdateQuery(ctx context.Context, query *models.Query) error {
 	return nil
 }
 
+// compress replaces a Query in Redis with it's compressed form, if it is
+// large enough to be compressed.
+func (d *DB) compress(ctx context.Context, id uuid.UUID) error {
+	query, err := d.GetQueryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s, err := d.compressor.Compress(query)
+	if err != nil {
+		return err
+	} else if s.Encoding == "" {
+		return nil
+	}
+
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = d.conn.JSONSet(ctx, queryKey(id), "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
 func (d *DB) DeleteQueriesOlderThan(_ context.Context, _ time.Duration) error {
 	// this method is a no-op, as removing old Queries is handled by Redis' own
 	// expiration mechanism.
 	return nil
 }
 
+// CreateLookup is held to be retried while Redis is unreachable, unless too
+// many Lookups are already held, when ErrWriteBufferFull is returned.
 func (d *DB) CreateLookup(ctx context.Context, queryID uuid.UUID, lookup *models.Lookup) error {
 	bytes, err := json.Marshal(lookup)
 	if err != nil {
 		return fmt.Errorf("json: %w", err)
 	}
 
-	_, err = d.conn.JSONArrAppend(ctx, queryKey(queryID), "$.lookups", bytes).Result()
+	return d.buffer.do(ctx, queryID, true, func(ctx context.Context) error {
+		_, err := d.conn.JSONArrAppend(ctx, queryKey(queryID), "$.lookups", bytes).Result()
+		if err != nil {
+			return fmt.Errorf("could not set JSON key: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (d *DB) CreateRecipe(ctx context.Context, recipe *models.Recipe) error {
+	recipe.ID = uuid.Must(uuid.NewV7())
+	recipe.CreatedAt = d.clock.Now().UTC()
+
+	bytes, err := json.Marshal(recipe)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = d.conn.JSONSet(ctx, recipeKey(recipe.ID), "$", bytes).Err()
 	if err != nil {
 		return fmt.Errorf("could not set JSON key: %w", err)
 	}
@@ -137,7 +362,498 @@ func (d *DB) CreateLookup(ctx context.Context, queryID uuid.UUID, lookup *models
 	return nil
 }
 
+func (d *DB) ListRecipes(ctx context.Context) ([]*models.Recipe, error) {
+	recipes, err := scanJSON[models.Recipe](ctx, d, recipeKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redis scans keys in no particular order, return Recipes in the order
+	// they were created like other database implementations.
+	slices.SortFunc(recipes, func(a, b *models.Recipe) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return recipes, nil
+}
+
+// scanJSON retrieves every JSON key in Redis beginning with prefix, decoding
+// each as a T, in no particular order.
+func scanJSON[T any](ctx context.Context, d *DB, prefix string) ([]*T, error) {
+	values := []*T{}
+
+	var cursor uint64
+	for {
+		keys, next, err := d.conn.Scan(ctx, cursor, prefix+"*", 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("could not scan keys: %w", err)
+		}
+
+		if len(keys) > 0 {
+			results, err := d.conn.JSONMGet(ctx, "$", keys...).Result()
+			if err != nil {
+				return nil, fmt.Errorf("could not get JSON keys: %w", err)
+			}
+
+			for _, result := range results {
+				// keys may be deleted between being scanned and retrieved.
+				s, ok := result.(string)
+				if !ok {
+					continue
+				}
+
+				var vs []*T
+				if err := json.Unmarshal([]byte(s), &vs); err != nil {
+					return nil, fmt.Errorf("json: %w", err)
+				}
+
+				values = append(values, vs...)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return values, nil
+}
+
+func (d *DB) GetRecipeByID(ctx context.Context, id uuid.UUID) (*models.Recipe, error) {
+	result, err := d.conn.JSONGet(ctx, recipeKey(id), ".").Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, db.ErrRecipeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get JSON key: %w", err)
+	}
+
+	recipe := &models.Recipe{}
+
+	err = json.Unmarshal([]byte(result), recipe)
+	if err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	return recipe, nil
+}
+
+func (d *DB) DeleteRecipe(ctx context.Context, id uuid.UUID) error {
+	n, err := d.conn.Del(ctx, recipeKey(id)).Result()
+	if err != nil {
+		return fmt.Errorf("could not delete key: %w", err)
+	} else if n == 0 {
+		return db.ErrRecipeNotFound
+	}
+
+	return nil
+}
+
+func (d *DB) CreateRecipeRun(ctx context.Context, run *models.RecipeRun) error {
+	run.ID = uuid.Must(uuid.NewV7())
+	run.CreatedAt = d.clock.Now().UTC()
+
+	bytes, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = d.conn.JSONSet(ctx, recipeRunKey(run.ID), "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	// runs expire alongside the Queries they group.
+	if d.maxAge > 0 {
+		err := d.conn.Expire(ctx, recipeRunKey(run.ID), d.maxAge).Err()
+		if err != nil {
+			return fmt.Errorf("could not set key expire: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) GetRecipeRunByID(ctx context.Context, id uuid.UUID) (*models.RecipeRun, error) {
+	result, err := d.conn.JSONGet(ctx, recipeRunKey(id), ".").Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, db.ErrRecipeRunNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get JSON key: %w", err)
+	}
+
+	run := &models.RecipeRun{}
+
+	err = json.Unmarshal([]byte(result), run)
+	if err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	return run, nil
+}
+
+func (d *DB) ListScheduledQueries(ctx context.Context) ([]*models.Query, error) {
+	var queries []*models.Query
+
+	err := d.scanQueries(ctx, func(q *db.StoredQuery) {
+		if q.RunAt != nil && q.FinishedAt == nil {
+			queries = append(queries, q.Query)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+func (d *DB) CountQueries(ctx context.Context, since time.Time) ([]*models.QueryCount, error) {
+	var (
+		counts []*models.QueryCount
+		index  = make(map[[2]string]*models.QueryCount)
+	)
+
+	err := d.scanQueries(ctx, func(q *db.StoredQuery) {
+		if q.CreatedAt.Before(since) {
+			return
+		}
+
+		key := [2]string{q.Type, q.Name}
+		if c, ok := index[key]; ok {
+			c.Count++
+		} else {
+			c = &models.QueryCount{Type: q.Type, Name: q.Name, Count: 1}
+			index[key] = c
+			counts = append(counts, c)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(counts, func(a, b *models.QueryCount) int {
+		return b.Count - a.Count
+	})
+
+	return counts, nil
+}
+
+// scanQueries calls fn with every Query in Redis. Redis has no secondary
+// indexes on Queries, so this is expensive, and should only be done by
+// background jobs or at startup.
+func (d *DB) scanQueries(ctx context.Context, fn func(*db.StoredQuery)) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := d.conn.Scan(ctx, cursor, queryKeyPrefix+"*", 100).Result()
+		if err != nil {
+			return fmt.Errorf("could not scan keys: %w", err)
+		}
+
+		if len(keys) > 0 {
+			results, err := d.conn.JSONMGet(ctx, "$", keys...).Result()
+			if err != nil {
+				return fmt.Errorf("could not get JSON keys: %w", err)
+			}
+
+			for _, result := range results {
+				// keys may expire between being scanned and retrieved.
+				s, ok := result.(string)
+				if !ok {
+					continue
+				}
+
+				var queries []*db.StoredQuery
+				if err := json.Unmarshal([]byte(s), &queries); err != nil {
+					return fmt.Errorf("json: %w", err)
+				}
+
+				for _, q := range queries {
+					fn(q)
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func (d *DB) CreateResolverChange(ctx context.Context, change *models.ResolverChange) error {
+	change.ID = uuid.Must(uuid.NewV7())
+	change.CreatedAt = d.clock.Now().UTC()
+
+	bytes, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	// ResolverChanges never expire, unlike Queries.
+	err = d.conn.JSONSet(ctx, resolverChangeKeyPrefix+change.ID.String(), "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverChanges(ctx context.Context) ([]*models.ResolverChange, error) {
+	changes, err := scanJSON[models.ResolverChange](ctx, d, resolverChangeKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redis scans keys in no particular order, IDs are time ordered and,
+	// unlike CreatedAt, unique.
+	slices.SortFunc(changes, func(a, b *models.ResolverChange) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return changes, nil
+}
+
+func (d *DB) CreateResolverFingerprint(ctx context.Context, fp *models.ResolverFingerprint) error {
+	fp.ID = uuid.Must(uuid.NewV7())
+	fp.CreatedAt = d.clock.Now().UTC()
+
+	bytes, err := json.Marshal(fp)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	// ResolverFingerprints never expire, unlike Queries.
+	key := resolverFingerprintKeyPrefix + fp.Resolver + ":" + fp.ID.String()
+
+	err = d.conn.JSONSet(ctx, key, "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListResolverFingerprints(ctx context.Context, resolver string) ([]*models.ResolverFingerprint, error) {
+	fps, err := scanJSON[models.ResolverFingerprint](ctx, d, resolverFingerprintKeyPrefix+resolver+":")
+	if err != nil {
+		return nil, err
+	}
+
+	// the prefix of one Resolver may match the names of others, such as
+	// `a` and `a:b`.
+	fps = slices.DeleteFunc(fps, func(fp *models.ResolverFingerprint) bool {
+		return fp.Resolver != resolver
+	})
+
+	slices.SortFunc(fps, func(a, b *models.ResolverFingerprint) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return fps, nil
+}
+
+func (d *DB) PutStoredResolver(ctx context.Context, r *models.StoredResolver) error {
+	r.UpdatedAt = d.clock.Now().UTC()
+
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	// StoredResolvers never expire, unlike Queries.
+	err = d.conn.JSONSet(ctx, storedResolverKeyPrefix+r.Name, "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) DeleteStoredResolver(ctx context.Context, name string) error {
+	err := d.conn.Del(ctx, storedResolverKeyPrefix+name).Err()
+	if err != nil {
+		return fmt.Errorf("could not delete key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ListStoredResolvers(ctx context.Context) ([]*models.StoredResolver, error) {
+	resolvers, err := scanJSON[models.StoredResolver](ctx, d, storedResolverKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(resolvers, func(a, b *models.StoredResolver) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return resolvers, nil
+}
+
+func (d *DB) PutJob(ctx context.Context, job *models.Job) error {
+	bytes, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	// Jobs do not expire, they are removed once their Query has finished.
+	err = d.conn.JSONSet(ctx, jobKeyPrefix+job.QueryID.String(), "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) ClaimJob(ctx context.Context, job *models.Job) (bool, error) {
+	bytes, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("json: %w", err)
+	}
+
+	// the Job is only set if it's key does not already exist.
+	err = d.conn.JSONSetMode(ctx, jobKeyPrefix+job.QueryID.String(), "$", bytes, "NX").Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return true, nil
+}
+
+// takeJob sets the instance of the Job KEYS[1] to ARGV[3], a JSON string,
+// only if it is still owned by the instance ARGV[1] after ARGV[2] attempts,
+// returning 0 otherwise.
+var takeJob = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+
+local owner = cjson.decode(redis.call('JSON.GET', KEYS[1], '$.instanceId'))[1]
+local attempts = cjson.decode(redis.call('JSON.GET', KEYS[1], '$.attempts'))[1]
+
+if owner ~= ARGV[1] or attempts ~= tonumber(ARGV[2]) then
+	return 0
+end
+
+redis.call('JSON.SET', KEYS[1], '$.instanceId', ARGV[3])
+
+return 1
+`)
+
+func (d *DB) TakeJob(ctx context.Context, job *models.Job, instanceID string) (bool, error) {
+	bytes, err := json.Marshal(instanceID)
+	if err != nil {
+		return false, fmt.Errorf("json: %w", err)
+	}
+
+	keys := []string{jobKeyPrefix + job.QueryID.String()}
+
+	n, err := takeJob.Run(ctx, d.conn, keys, job.InstanceID, job.Attempts, bytes).Int()
+	if err != nil {
+		return false, fmt.Errorf("could not take job: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// DeleteJob is held to be retried while Redis is unreachable, so a Job is
+// never removed before the held update that finishes it's Query.
+func (d *DB) DeleteJob(ctx context.Context, queryID uuid.UUID) error {
+	return d.buffer.do(ctx, queryID, false, func(ctx context.Context) error {
+		err := d.conn.Del(ctx, jobKeyPrefix+queryID.String()).Err()
+		if err != nil {
+			return fmt.Errorf("could not delete key: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (d *DB) ListJobs(ctx context.Context) ([]*models.Job, error) {
+	jobs, err := scanJSON[models.Job](ctx, d, jobKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(jobs, func(a, b *models.Job) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+func (d *DB) PutDuplicateReport(ctx context.Context, report *models.DuplicateReport) error {
+	bytes, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = d.conn.JSONSet(ctx, duplicateReportKey, "$", bytes).Err()
+	if err != nil {
+		return fmt.Errorf("could not set JSON key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetDuplicateReport(ctx context.Context) (*models.DuplicateReport, error) {
+	result, err := d.conn.JSONGet(ctx, duplicateReportKey, ".").Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, db.ErrReportNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get JSON key: %w", err)
+	}
+
+	report := &models.DuplicateReport{}
+
+	err = json.Unmarshal([]byte(result), report)
+	if err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	return report, nil
+}
+
+// queryKeyPrefix is the prefix of all Query keys in Redis.
+const queryKeyPrefix = "dennis:query:"
+
+// recipeKeyPrefix is the prefix of all Recipe keys in Redis.
+const recipeKeyPrefix = "dennis:recipe:"
+
+// recipeKey generates a stringified key for a Recipe in Redis.
+func recipeKey(id uuid.UUID) string {
+	return recipeKeyPrefix + id.String()
+}
+
+// recipeRunKey generates a stringified key for a RecipeRun in Redis.
+func recipeRunKey(id uuid.UUID) string {
+	return "dennis:recipe_run:" + id.String()
+}
+
+// resolverChangeKeyPrefix is the prefix of all ResolverChange keys in Redis.
+const resolverChangeKeyPrefix = "dennis:resolver_change:"
+
+// storedResolverKeyPrefix is the prefix of all StoredResolver keys in Redis,
+// followed by the name of the Resolver.
+const storedResolverKeyPrefix = "dennis:stored_resolver:"
+
+// resolverFingerprintKeyPrefix is the prefix of all ResolverFingerprint keys
+// in Redis, followed by the name of the Resolver and the ID of the
+// fingerprint.
+const resolverFingerprintKeyPrefix = "dennis:resolver_fingerprint:"
+
+// jobKeyPrefix is the prefix of all Job keys in Redis, followed by the ID of
+// it's Query.
+const jobKeyPrefix = "dennis:job:"
+
+// duplicateReportKey is the key of the DuplicateReport in Redis.
+const duplicateReportKey = "dennis:report:duplicates"
+
 // queryKey generates a stringified key for Redis.
 func queryKey(id uuid.UUID) string {
-	return "dennis:query:" + id.String()
+	return queryKeyPrefix + id.String()
 }
@@ -1,8 +1,44 @@
 package redis
 
 import (
+	"testing"
+
 	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
 )
 
 // ensure DB implements the db.DB interface.
 var _ db.DB = (*DB)(nil)
+
+// ensure DB implements the db.Dispatcher interface.
+var _ db.Dispatcher = (*DB)(nil)
+
+func TestLookupKey(t *testing.T) {
+	key := func(l *models.Lookup) string {
+		k, err := lookupKey(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return k
+	}
+
+	a := key(&models.Lookup{Type: "A", Resolver: "a", Location: "EU"})
+
+	if b := key(&models.Lookup{Type: "A", Resolver: "a", Location: "EU", RTT: 10}); a != b {
+		t.Errorf("expected lookups of the same type, resolver and location to have the same key, got %q and %q", a, b)
+	}
+
+	tests := []*models.Lookup{
+		{Type: "AAAA", Resolver: "a", Location: "EU"},
+		{Type: "A", Resolver: "b", Location: "EU"},
+		{Type: "A", Resolver: "a", Location: "US"},
+		{Type: "A", Resolver: "a/EU"},
+	}
+
+	for _, test := range tests {
+		if b := key(test); a == b {
+			t.Errorf("expected %q to differ from %q", b, a)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jamescun/dennis/app/models"
+)
+
+// QuerySchemaVersion is the current version of the JSON representation of a
+// Query stored by database implementations. It must be incremented, and a
+// migration added to queryMigrations, whenever a change to the Query, Lookup
+// or Record models would prevent older stored Queries from being decoded.
+//
+// Versions:
+//
+//  1. the original representation, without a `schemaVersion` field.
+//  2. `lookups` and `records` are always arrays, never null.
+const QuerySchemaVersion = 2
+
+// queryMigrations upgrade the JSON representation of a Query, decoded as a
+// generic object, from one version to the next. The migration at index i
+// upgrades from version i+1 to version i+2.
+var queryMigrations = []func(q map[string]any) error{
+	migrateQueryV1,
+}
+
+// StoredQuery wraps a Query with the version of it's JSON representation, for
+// database implementations that store Queries as JSON. Older versions are
+// migrated to the current version when decoded.
+//
+// A StoredQuery may also be compressed by a Compressor, in which case Encoding
+// and Data are set and encoded in place of the Query. Query is still set when
+// decoded, but changes to it will not be stored.
+type StoredQuery struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Encoding is the compression algorithm of Data, if compressed.
+	Encoding string `json:"encoding,omitempty"`
+
+	// Data is the compressed JSON of the Query, if compressed.
+	Data []byte `json:"data,omitempty"`
+
+	*models.Query
+}
+
+// NewStoredQuery wraps a Query to be stored as the current QuerySchemaVersion.
+func NewStoredQuery(q *models.Query) *StoredQuery {
+	return &StoredQuery{SchemaVersion: QuerySchemaVersion, Query: q}
+}
+
+// MarshalJSON encodes a StoredQuery, encoding only it's compressed Data if it
+// has been compressed.
+func (s *StoredQuery) MarshalJSON() ([]byte, error) {
+	if s.Encoding != "" {
+		return json.Marshal(struct {
+			SchemaVersion int    `json:"schemaVersion"`
+			Encoding      string `json:"encoding"`
+			Data          []byte `json:"data"`
+		}{s.SchemaVersion, s.Encoding, s.Data})
+	}
+
+	// storedQuery does not have the methods of StoredQuery, preventing
+	// infinite recursion.
+	type storedQuery StoredQuery
+
+	return json.Marshal((*storedQuery)(s))
+}
+
+// UnmarshalJSON decodes a stored Query of any version, migrating it to the
+// current QuerySchemaVersion, and decompressing it if it was compressed.
+func (s *StoredQuery) UnmarshalJSON(b []byte) error {
+	var compressed struct {
+		Encoding string `json:"encoding"`
+		Data     []byte `json:"data"`
+	}
+	if err := json.Unmarshal(b, &compressed); err != nil {
+		return err
+	}
+
+	if compressed.Encoding != "" {
+		data, err := decompress(compressed.Encoding, compressed.Data)
+		if err != nil {
+			return err
+		}
+
+		var inner StoredQuery
+		if err := json.Unmarshal(data, &inner); err != nil {
+			return err
+		}
+
+		*s = inner
+		s.Encoding = compressed.Encoding
+		s.Data = compressed.Data
+
+		return nil
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	// Queries stored before versioning was introduced are version 1.
+	version := 1
+	if v, ok := raw["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+
+	if version < 1 || version > QuerySchemaVersion {
+		return fmt.Errorf("unsupported query schema version %d", version)
+	}
+
+	for _, migrate := range queryMigrations[version-1:] {
+		if err := migrate(raw); err != nil {
+			return fmt.Errorf("could not migrate query from schema version %d: %w", version, err)
+		}
+
+		version++
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	q := new(models.Query)
+	if err := json.Unmarshal(b, q); err != nil {
+		return err
+	}
+
+	s.SchemaVersion = QuerySchemaVersion
+	s.Query = q
+
+	return nil
+}
+
+// migrateQueryV1 replaces null `lookups` and `records` with empty arrays, as
+// some database implementations cannot append to null.
+func migrateQueryV1(q map[string]any) error {
+	lookups, _ := q["lookups"].([]any)
+	if lookups == nil {
+		lookups = []any{}
+	}
+
+	for _, l := range lookups {
+		lookup, ok := l.(map[string]any)
+		if !ok {
+			return fmt.Errorf("lookup is not an object")
+		}
+
+		if lookup["records"] == nil {
+			lookup["records"] = []any{}
+		}
+	}
+
+	q["lookups"] = lookups
+	q["schemaVersion"] = 2
+
+	return nil
+}
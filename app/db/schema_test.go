@@ -0,0 +1,93 @@
+package db
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestStoredQueryV1(t *testing.T) {
+	b, err := os.ReadFile("testdata/query_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s StoredQuery
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("could not decode v1 query: %s", err)
+	}
+
+	if s.SchemaVersion != QuerySchemaVersion {
+		t.Errorf("expected schema version %d, got %d", QuerySchemaVersion, s.SchemaVersion)
+	}
+
+	if s.Name != "example.com" || s.Type != "A" {
+		t.Errorf("unexpected query %s %s", s.Type, s.Name)
+	}
+
+	if s.FinishedAt == nil {
+		t.Error("expected finishedAt to be set")
+	}
+
+	if len(s.Lookups) != 2 {
+		t.Fatalf("expected 2 lookups, got %d", len(s.Lookups))
+	}
+
+	if len(s.Lookups[0].Records) != 1 || s.Lookups[0].Records[0].Content[0] != "93.184.215.14" {
+		t.Errorf("unexpected records for first lookup: %+v", s.Lookups[0].Records)
+	}
+
+	if s.Lookups[1].Records == nil {
+		t.Error("expected null records to be migrated to an empty array")
+	}
+}
+
+func TestStoredQueryV1Pending(t *testing.T) {
+	b, err := os.ReadFile("testdata/query_v1_pending.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s StoredQuery
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("could not decode v1 query: %s", err)
+	}
+
+	if s.Lookups == nil {
+		t.Error("expected null lookups to be migrated to an empty array")
+	}
+}
+
+func TestStoredQueryRoundTrip(t *testing.T) {
+	b, err := os.ReadFile("testdata/query_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v1 StoredQuery
+	if err := json.Unmarshal(b, &v1); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = json.Marshal(NewStoredQuery(v1.Query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var current StoredQuery
+	if err := json.Unmarshal(b, &current); err != nil {
+		t.Fatalf("could not decode current query: %s", err)
+	}
+
+	if current.ID != v1.ID || len(current.Lookups) != len(v1.Lookups) {
+		t.Errorf("query changed after round trip")
+	}
+}
+
+func TestStoredQueryUnsupportedVersion(t *testing.T) {
+	var s StoredQuery
+	err := json.Unmarshal([]byte(`{"schemaVersion": 999}`), &s)
+	if err == nil {
+		t.Fatal("expected error decoding unsupported schema version")
+	}
+}
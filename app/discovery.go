@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/pkg/discovery"
+)
+
+// ResolverChangeSourceDiscovery prefixes the Source of ResolverChanges
+// recorded when a Discovery finds its Resolvers have changed, followed by the
+// name of the Discovery.
+const ResolverChangeSourceDiscovery = "discovery:"
+
+// defaultDiscoveryInterval is how often a Discovery checks for changes if not
+// configured by config.Discovery.
+const defaultDiscoveryInterval = 30 * time.Second
+
+// defaultConsulAddr is the URL of the Consul HTTP API if not configured by
+// config.ConsulDiscovery.
+const defaultConsulAddr = "http://127.0.0.1:8500"
+
+// Discovery keeps the Resolvers of a Server in sync with the DNS servers
+// registered for a service in a service catalog, so each may be queried
+// individually.
+type Discovery struct {
+	cfg    *config.Discovery
+	source discovery.Source
+	actor  string
+	server *Server
+	log    *slog.Logger
+}
+
+// NewDiscovery initializes a Discovery of Resolvers for a Server from the
+// service catalog configured by cfg.
+func NewDiscovery(cfg *config.Discovery, server *Server, log *slog.Logger) (*Discovery, error) {
+	d := &Discovery{cfg: cfg, server: server, log: log}
+
+	switch {
+	case cfg.Consul != nil:
+		addr := defaultConsulAddr
+		if cfg.Consul.Addr != "" {
+			addr = cfg.Consul.Addr
+		}
+
+		d.actor = "consul:" + cfg.Consul.Service
+		d.source = &discovery.Consul{
+			Addr:       addr,
+			Service:    cfg.Consul.Service,
+			Datacenter: cfg.Consul.Datacenter,
+			Tag:        cfg.Consul.Tag,
+			Token:      cfg.Consul.Token,
+			Client:     &http.Client{Timeout: 10 * time.Second},
+		}
+
+	case cfg.Kubernetes != nil:
+		k, err := discovery.NewInClusterKubernetes(cfg.Kubernetes.Namespace, cfg.Kubernetes.Service)
+		if err != nil {
+			return nil, err
+		}
+
+		k.ClusterIP = cfg.Kubernetes.ClusterIP
+
+		d.actor = "kubernetes:" + k.Namespace + "/" + k.Service
+		d.source = k
+	}
+
+	return d, nil
+}
+
+// Run discovers Resolvers immediately, and then periodically until ctx is
+// canceled. If the service catalog cannot be reached, the Resolvers last
+// discovered are kept.
+func (d *Discovery) Run(ctx context.Context) {
+	interval := defaultDiscoveryInterval
+	if d.cfg.Interval > 0 {
+		interval = time.Duration(d.cfg.Interval) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := d.discover(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error("could not discover resolvers", slog.String("discovery", d.cfg.Name), slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Discovery) discover(ctx context.Context) error {
+	endpoints, err := d.source.Discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	rsv := make([]*config.Resolver, 0, len(endpoints))
+
+	for _, ep := range endpoints {
+		r := &config.Resolver{
+			Name:        d.cfg.Name + "/" + ep.Name,
+			Description: "discovered from " + d.actor,
+			Addr:        ep.Addr,
+			Port:        ep.Port,
+			Order:       d.cfg.Order,
+			Groups:      d.cfg.Groups,
+		}
+
+		if d.cfg.Port > 0 {
+			r.Port = d.cfg.Port
+		}
+
+		rsv = append(rsv, r)
+	}
+
+	d.server.setDiscovered(d.cfg.Name, rsv)
+
+	// resolvers that were added are probed for their capabilities.
+	go d.server.ProbeResolvers(ctx)
+
+	return d.server.RecordResolverChanges(ctx, rsv, ResolverChangeSourceDiscovery+d.cfg.Name, d.actor)
+}
+
+// setDiscovered replaces the resolvers found by the discovery source name
+// with rsv. Queries already being resolved continue with the resolvers they
+// started with.
+func (s *Server) setDiscovered(name string, rsv []*config.Resolver) {
+	discovered := make([]*resolver, 0, len(rsv))
+	for _, r := range rsv {
+		discovered = append(discovered, s.newResolver(r, ResolverChangeSourceDiscovery+name))
+	}
+
+	s.registry.update(func() {
+		s.registry.discovered[name] = discovered
+	})
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// dispatchRetryInterval is how long a dispatch worker waits after failing to
+// receive Jobs before trying again.
+const dispatchRetryInterval = 5 * time.Second
+
+// SetDispatcher distributes the Queries created on this Server between every
+// instance of DENNIS sharing d, and starts resolving those dispatched by any
+// of them. Zone transfers and captured Queries are still resolved by the
+// instance they were created on. It must be called before the Server is used.
+func (s *Server) SetDispatcher(d db.Dispatcher) {
+	s.dispatcher = d
+
+	// consumers must be unique, so an instance without an ID is given one
+	// until it stops.
+	s.consumer = s.instanceID
+	if s.consumer == "" {
+		s.consumer = uuid.Must(uuid.NewV4()).String()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopDispatch = cancel
+
+	for range s.workers {
+		s.wg.Add(1)
+		go s.dispatchWorker(ctx)
+	}
+}
+
+// dispatched returns true if query is resolved by whichever instance
+// receives it from the Dispatcher. The records of a zone transfer are stored
+// in batches, which cannot be stored idempotently, and a captured Query must
+// be resolved where it's capture is waiting.
+func (s *Server) dispatched(query *models.Query) bool {
+	if s.dispatcher == nil || query.IsTransfer() {
+		return false
+	}
+
+	_, captured := s.captures.Load(query.ID)
+
+	return !captured
+}
+
+// dispatch passes the Job of query to the Dispatcher. If it cannot be
+// dispatched, the Query is pushed onto the queue of this instance instead.
+func (s *Server) dispatch(ctx context.Context, job *models.Job, query *models.Query) error {
+	log := s.log.With(slog.String("query_id", query.ID.String()))
+
+	// the instance resolving the Query retrieves it from the database, so
+	// must know the client had already disconnected.
+	if query.ClientDisconnected {
+		err := s.db.UpdateQuery(ctx, query)
+		if err != nil {
+			log.Error("could not update query", slog.String("error", err.Error()))
+		}
+	}
+
+	err := s.dispatcher.Dispatch(ctx, job)
+	if err != nil {
+		// resolving the Query here is preferable to never resolving it.
+		log.Error("could not dispatch query, resolving it locally", slog.String("error", err.Error()))
+
+		s.jobs.Store(query.ID, job)
+
+		return s.queue.push(job.Priority, query)
+	}
+
+	return nil
+}
+
+// dispatchWorker resolves the Queries received from the Dispatcher until ctx
+// is canceled.
+func (s *Server) dispatchWorker(ctx context.Context) {
+	defer s.wg.Done()
+
+	for ctx.Err() == nil {
+		deliveries, err := s.dispatcher.Receive(ctx, s.consumer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.log.Error("could not receive dispatched queries", slog.String("error", err.Error()))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(dispatchRetryInterval):
+			}
+
+			continue
+		}
+
+		for _, d := range deliveries {
+			s.resolving.Add(1)
+			s.resolveDelivery(d)
+			s.resolving.Add(-1)
+		}
+	}
+}
+
+// resolveDelivery resolves the Query of a Job received from the Dispatcher,
+// then acknowledges it. A Query that is not acknowledged, because it could
+// not be retrieved or finished, is redelivered later. A Job delivered too
+// many times is finished as interrupted rather than resolved again, so a
+// Query that causes DENNIS to stop cannot do so on every instance in turn.
+// Lookups already stored by an earlier delivery are kept.
+func (s *Server) resolveDelivery(d *db.Delivery) {
+	ctx := context.Background()
+	log := s.log.With(slog.String("query_id", d.Job.QueryID.String()))
+
+	query, err := s.db.GetQueryByID(ctx, d.Job.QueryID)
+	if errors.Is(err, db.ErrQueryNotFound) || (err == nil && query.FinishedAt != nil) {
+		// the Query has expired, or was finished by an instance that stopped
+		// before it could acknowledge it.
+		s.ack(ctx, log, d)
+		return
+	} else if err != nil {
+		log.Error("could not get dispatched query", slog.String("error", err.Error()))
+		return
+	}
+
+	if d.Attempts > maxJobAttempts {
+		log.Warn("dispatched query was delivered too many times, finishing as interrupted", slog.Int("attempts", d.Attempts))
+
+		now := s.clock.Now().UTC()
+		query.FinishedAt = &now
+		query.Interrupted = true
+
+		err = s.db.UpdateQuery(ctx, query)
+		if err != nil {
+			log.Error("could not update query", slog.String("error", err.Error()))
+			return
+		}
+
+		s.finishJob(ctx, log, query.ID)
+		s.ack(ctx, log, d)
+
+		return
+	} else if d.Attempts > 1 {
+		log.Info("resuming dispatched query", slog.Int("attempts", d.Attempts))
+	}
+
+	err = s.resolveAll(query, s.dispatcher.CreateLookupOnce)
+	if err != nil {
+		return
+	}
+
+	s.ack(ctx, log, d)
+}
+
+// ack acknowledges d, logging if it could not be, when it's Query is
+// redelivered and found to have finished.
+func (s *Server) ack(ctx context.Context, log *slog.Logger, d *db.Delivery) {
+	err := s.dispatcher.Ack(ctx, d)
+	if err != nil {
+		log.Error("could not acknowledge dispatched query", slog.String("error", err.Error()))
+	}
+}
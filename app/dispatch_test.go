@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/db/file"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnstest"
+	"codeberg.org/miekg/dns/dnsutil"
+	"github.com/gofrs/uuid"
+)
+
+// fakeDispatcher is a db.Dispatcher delivering Jobs from memory, storing
+// Lookups in a file database.
+type fakeDispatcher struct {
+	db         *file.DB
+	deliveries chan *db.Delivery
+
+	mu         sync.Mutex
+	dispatched []*models.Job
+	acked      []uuid.UUID
+	keys       map[string]struct{}
+}
+
+func newFakeDispatcher(d *file.DB) *fakeDispatcher {
+	return &fakeDispatcher{db: d, deliveries: make(chan *db.Delivery, 10), keys: make(map[string]struct{})}
+}
+
+func (f *fakeDispatcher) Dispatch(_ context.Context, job *models.Job) error {
+	f.mu.Lock()
+	f.dispatched = append(f.dispatched, job)
+	f.mu.Unlock()
+
+	f.deliver(job, 1)
+
+	return nil
+}
+
+// deliver delivers job as if it had been delivered attempts times.
+func (f *fakeDispatcher) deliver(job *models.Job, attempts int) {
+	f.deliveries <- &db.Delivery{ID: job.QueryID.String(), Job: job, Attempts: attempts}
+}
+
+func (f *fakeDispatcher) Receive(ctx context.Context, _ string) ([]*db.Delivery, error) {
+	select {
+	case d := <-f.deliveries:
+		return []*db.Delivery{d}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeDispatcher) Ack(_ context.Context, d *db.Delivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.acked = append(f.acked, d.Job.QueryID)
+
+	return nil
+}
+
+// isAcked returns a function reporting whether the Job of the Query id has
+// been acknowledged.
+func (f *fakeDispatcher) isAcked(id uuid.UUID) func() bool {
+	return func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		return slices.Contains(f.acked, id)
+	}
+}
+
+func (f *fakeDispatcher) CreateLookupOnce(ctx context.Context, queryID uuid.UUID, l *models.Lookup) error {
+	f.mu.Lock()
+	key := queryID.String() + "/" + l.Type + "/" + l.Resolver + "/" + l.Location
+	_, ok := f.keys[key]
+	f.keys[key] = struct{}{}
+	f.mu.Unlock()
+
+	if ok {
+		return nil
+	}
+
+	return f.db.CreateLookup(ctx, queryID, l)
+}
+
+// newDispatchServer returns a Server resolving against a single resolver
+// named `a`, which answers every query with one A record, whose Queries are
+// dispatched by a fakeDispatcher.
+func newDispatchServer(t *testing.T) (*Server, *file.DB, *fakeDispatcher) {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+		r.Unpack()
+
+		m := new(dns.Msg)
+		dnsutil.SetReply(m, r)
+		m.Answer = []dns.RR{dnstest.New(m.Question[0].Header().Name + " 60 IN A 192.0.2.1")}
+
+		io.Copy(w, m)
+	})
+
+	cancel, addr, err := dnstest.UDPServer("127.0.0.1:0", func(s *dns.Server) { s.Handler = handler })
+	must(t, err)
+	t.Cleanup(cancel)
+
+	d, err := file.New(filepath.Join(t.TempDir(), "db.json"), nil)
+	must(t, err)
+
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d.SetClock(c)
+
+	host, port, err := net.SplitHostPort(addr)
+	must(t, err)
+
+	n, err := strconv.Atoi(port)
+	must(t, err)
+
+	rsv := []*config.Resolver{{Name: "a", Addr: host, Port: n}}
+
+	s := NewServer(d, rsv, config.Resolution{}, apiv1.NewFeatures(nil), slog.New(slog.DiscardHandler))
+	s.SetClock(c)
+
+	f := newFakeDispatcher(d)
+	s.SetDispatcher(f)
+
+	return s, d, f
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, f := newDispatchServer(t)
+	defer s.Close()
+
+	res, err := s.CreateQuery(ctx, &apiv1.CreateQueryRequest{Type: "A", Name: "example.com"})
+	must(t, err)
+
+	waitFor(t, "query to be acknowledged", f.isAcked(res.Query.ID))
+
+	q, err := d.GetQueryByID(ctx, res.Query.ID)
+	must(t, err)
+
+	if q.FinishedAt == nil || len(q.Lookups) != 1 {
+		t.Fatalf("expected query to be finished with 1 lookup, got %d", len(q.Lookups))
+	}
+
+	// the Dispatcher recovers the Query, rather than RecoverJobs.
+	jobs, err := d.ListJobs(ctx)
+	must(t, err)
+
+	if len(jobs) != 0 {
+		t.Errorf("expected no jobs to be stored, got %d", len(jobs))
+	}
+}
+
+func TestDispatchRedelivered(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, f := newDispatchServer(t)
+	defer s.Close()
+
+	createQuery := func() *models.Query {
+		q := &models.Query{Type: "A", Name: "example.com", Lookups: []*models.Lookup{}}
+		must(t, d.CreateQuery(ctx, q))
+
+		return q
+	}
+
+	// resolved by an instance that stopped after storing it's Lookup, so
+	// the Lookup is not stored again.
+	resumed := createQuery()
+	must(t, f.CreateLookupOnce(ctx, resumed.ID, &models.Lookup{Resolver: "a"}))
+
+	// finished by an instance that stopped before acknowledging it.
+	finished := createQuery()
+	finished.FinishedAt = new(s.clock.Now())
+	must(t, d.UpdateQuery(ctx, finished))
+
+	// delivered too many times already, so it is finished as interrupted.
+	exhausted := createQuery()
+
+	f.deliver(&models.Job{QueryID: resumed.ID}, 2)
+	f.deliver(&models.Job{QueryID: finished.ID}, 2)
+	f.deliver(&models.Job{QueryID: exhausted.ID}, maxJobAttempts+1)
+
+	// the Query has expired.
+	expired := uuid.Must(uuid.NewV7())
+	f.deliver(&models.Job{QueryID: expired}, 2)
+
+	tests := []struct {
+		name        string
+		query       *models.Query
+		lookups     int
+		interrupted bool
+	}{
+		{"resumed", resumed, 1, false},
+		{"finished", finished, 0, false},
+		{"exhausted", exhausted, 0, true},
+	}
+
+	for _, test := range tests {
+		waitFor(t, test.name+" to be acknowledged", f.isAcked(test.query.ID))
+
+		q, err := d.GetQueryByID(ctx, test.query.ID)
+		must(t, err)
+
+		if q.FinishedAt == nil {
+			t.Errorf("%s: expected query to be finished", test.name)
+		}
+
+		if len(q.Lookups) != test.lookups {
+			t.Errorf("%s: expected %d lookups, got %d", test.name, test.lookups, len(q.Lookups))
+		}
+
+		if q.Interrupted != test.interrupted {
+			t.Errorf("%s: expected interrupted %t, got %t", test.name, test.interrupted, q.Interrupted)
+		}
+	}
+
+	waitFor(t, "expired query to be acknowledged", f.isAcked(expired))
+}
+
+func TestDispatched(t *testing.T) {
+	s, _, _ := newDispatchServer(t)
+	defer s.Close()
+
+	captured := &models.Query{ID: uuid.Must(uuid.NewV7()), Type: "A"}
+	s.captures.Store(captured.ID, &PacketCapture{})
+
+	tests := []struct {
+		name       string
+		query      *models.Query
+		dispatched bool
+	}{
+		{"lookup", &models.Query{ID: uuid.Must(uuid.NewV7()), Type: "A"}, true},
+		{"transfer", &models.Query{ID: uuid.Must(uuid.NewV7()), Type: apiv1.TypeAXFR}, false},
+		{"captured", captured, false},
+	}
+
+	for _, test := range tests {
+		if dispatched := s.dispatched(test.query); dispatched != test.dispatched {
+			t.Errorf("%s: expected dispatched %t, got %t", test.name, test.dispatched, dispatched)
+		}
+	}
+}
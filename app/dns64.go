@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// nat64WellKnownPrefix is the NAT64 Well-Known Prefix (RFC 6052), within which
+// DNS64 resolvers synthesize AAAA records by default. Network-specific
+// prefixes cannot be told apart from a real IPv6 address, so are not detected.
+var nat64WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// detectDNS64 returns the DNS64 synthesis of the AAAA records, if every
+// address is within the Well-Known Prefix, otherwise nil.
+func detectDNS64(records []*models.Record) *models.DNS64 {
+	var embedded []string
+
+	for _, rec := range records {
+		for _, content := range rec.Content {
+			// the answer may include the CNAME records leading to the
+			// AAAA records.
+			addr, err := netip.ParseAddr(content)
+			if err != nil {
+				continue
+			} else if !nat64WellKnownPrefix.Contains(addr) {
+				return nil
+			}
+
+			// the IPv4 address is embedded in the last 32 bits of a /96.
+			b := addr.As16()
+			embedded = append(embedded, netip.AddrFrom4([4]byte(b[12:])).String())
+		}
+	}
+
+	if len(embedded) == 0 {
+		return nil
+	}
+
+	return &models.DNS64{Prefix: nat64WellKnownPrefix.String(), Embedded: embedded}
+}
+
+// resolveA returns the addresses of the A records of the name of query, as
+// answered by rsv, to show the records that DNS64 synthesized it's AAAA
+// records from. If they cannot be resolved, nil is returned.
+func (s *Server) resolveA(ctx context.Context, rsv *resolver, query *models.Query) []string {
+	q := *query
+	q.Type = "A"
+
+	res, _, _, err := s.retryExchange(ctx, rsv, s.newMsg(rsv, &q))
+	if err != nil || res.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+
+	var addrs []string
+	for _, rr := range res.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			addrs = append(addrs, a.A.Addr.String())
+		}
+	}
+
+	return addrs
+}
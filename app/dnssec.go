@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// dnssecStatus determines the DNSSEC status of a response res to req from the
+// resolver rsv. DENNIS does not validate signatures itself, it relies on the
+// resolver having done so, and setting the Authenticated Data (AD) flag. A
+// validating resolver answers SERVFAIL if validation fails, so the query is
+// repeated with Checking Disabled (CD) to tell a bogus answer apart from a
+// resolver that is failing. nil is returned if the status cannot be
+// determined.
+func dnssecStatus(ctx context.Context, rsv *resolver, req, res *dns.Msg) *string {
+	switch {
+	case res.AuthenticatedData:
+		return new(models.DNSSECSecure)
+
+	case res.Rcode == dns.RcodeServerFailure:
+		cd := req.Copy()
+		cd.CheckingDisabled = true
+
+		res, _, err := exchange(ctx, rsv, cd)
+		if err != nil || res.Rcode == dns.RcodeServerFailure {
+			return nil
+		}
+
+		return new(models.DNSSECBogus)
+
+	default:
+		return new(models.DNSSECInsecure)
+	}
+}
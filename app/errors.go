@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/pkg/clock"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+
+	"github.com/gofrs/uuid"
+)
+
+// retainedErrors is the number of recent unexpected errors kept by Errors.
+const retainedErrors = 1000
+
+// LoggedError is an unexpected error encountered while serving a request,
+// as it was logged.
+type LoggedError struct {
+	// RequestID is the unique identifier of the request, as shown to the
+	// user and sent in the `Request-Id` header.
+	RequestID uuid.UUID `json:"requestId"`
+
+	// Method and Path are those of the HTTP request.
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// Error is the message of the error.
+	Error string `json:"error"`
+
+	// OccurredAt is the UTC timestamp the error was encountered at.
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// Errors retains the most recent unexpected errors in memory, so operators
+// can find the error behind a user's report by the request ID shown to them,
+// without searching the logs.
+type Errors struct {
+	mu     sync.Mutex
+	errors []*LoggedError
+	next   int
+	clock  clock.Clock
+}
+
+// NewErrors initializes an empty set of retained errors.
+func NewErrors() *Errors {
+	return &Errors{errors: make([]*LoggedError, 0, retainedErrors), clock: clock.Real}
+}
+
+// SetClock replaces the Clock used to timestamp errors as they are retained.
+// It must be called before any error is retained.
+func (e *Errors) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// add retains err encountered while serving r, replacing the oldest retained
+// error if full.
+func (e *Errors) add(ctx context.Context, r *web.Request, err error) *LoggedError {
+	logged := &LoggedError{
+		RequestID:  web.GetRequestID(ctx),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Error:      err.Error(),
+		OccurredAt: e.clock.Now().UTC(),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.errors) < cap(e.errors) {
+		e.errors = append(e.errors, logged)
+	} else {
+		e.errors[e.next] = logged
+		e.next = (e.next + 1) % len(e.errors)
+	}
+
+	return logged
+}
+
+// get returns the retained error of a request, or nil if it has not been
+// retained.
+func (e *Errors) get(requestID uuid.UUID) *LoggedError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, logged := range e.errors {
+		if logged.RequestID == requestID {
+			return logged
+		}
+	}
+
+	return nil
+}
+
+// list returns every retained error, oldest first.
+func (e *Errors) list() []*LoggedError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append(slices.Clone(e.errors[e.next:]), e.errors[:e.next]...)
+}
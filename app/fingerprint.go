@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// fingerprintResolver fingerprints rsv, recording the fingerprint if it
+// differs from the last, so a change in the anycast site or transport
+// support of the resolver can be correlated with a change in it's latency.
+// Nothing is recorded if the resolver did not answer.
+func (s *Server) fingerprintResolver(ctx context.Context, rsv *resolver) {
+	fp := s.fingerprint(ctx, rsv)
+	if fp == nil {
+		return
+	}
+
+	prev := rsv.fingerprint.Load()
+	if prev == nil {
+		// the last fingerprint is loaded when the resolver is first
+		// fingerprinted, so restarting DENNIS is not recorded as a change.
+		fps, err := s.db.ListResolverFingerprints(ctx, rsv.name)
+		if err != nil {
+			s.log.Error(
+				"could not list resolver fingerprints",
+				slog.String("resolver", rsv.name), slog.String("error", err.Error()),
+			)
+			return
+		}
+
+		if len(fps) > 0 {
+			prev = fps[len(fps)-1]
+		}
+	}
+
+	if fp.Equal(prev) {
+		rsv.fingerprint.Store(prev)
+		return
+	}
+
+	err := s.db.CreateResolverFingerprint(ctx, fp)
+	if err != nil {
+		s.log.Error(
+			"could not create resolver fingerprint",
+			slog.String("resolver", rsv.name), slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	rsv.fingerprint.Store(fp)
+
+	if prev != nil {
+		s.log.Info("resolver fingerprint changed", slog.String("resolver", rsv.name),
+			slog.String("nsid", fp.NSID), slog.String("previous_nsid", prev.NSID),
+			slog.String("server_id", fp.ServerID), slog.String("previous_server_id", prev.ServerID),
+		)
+	}
+}
+
+// fingerprint queries rsv for the SOA of the root zone over UDP, requesting
+// it's NSID, and again over TCP, and for it's `id.server`. It returns nil if
+// rsv did not answer over UDP.
+func (s *Server) fingerprint(ctx context.Context, rsv *resolver) *models.ResolverFingerprint {
+	req := dns.NewMsg(".", dns.TypeSOA)
+	req.UDPSize = defaultUDPSize
+	req.Pseudo = append(req.Pseudo, &dns.NSID{})
+
+	udpCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	res, _, err := rsv.client.Exchange(udpCtx, req, "udp", rsv.addr)
+	if err != nil {
+		return nil
+	}
+
+	fp := &models.ResolverFingerprint{
+		Resolver:  rsv.name,
+		EDNS:      res.UDPSize > 0,
+		UDPSize:   int(res.UDPSize),
+		Recursive: res.RecursionAvailable,
+	}
+
+	for _, rr := range res.Pseudo {
+		if nsid, ok := rr.(*dns.NSID); ok {
+			fp.NSID = printable(nsid.Nsid)
+		}
+	}
+
+	tcpCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	_, _, err = rsv.client.Exchange(tcpCtx, dns.NewMsg(".", dns.TypeSOA), "tcp", rsv.addr)
+	fp.TCP = err == nil
+
+	id := dns.NewMsg("id.server.", dns.TypeTXT)
+	id.Question[0].Header().Class = dns.ClassCHAOS
+
+	idCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	res, _, err = rsv.client.Exchange(idCtx, id, "udp", rsv.addr)
+	if err == nil && res.Rcode == dns.RcodeSuccess {
+		for _, rr := range res.Answer {
+			if txt, ok := rr.(*dns.TXT); ok {
+				fp.ServerID = strings.Join(txt.Txt, " ")
+				break
+			}
+		}
+	}
+
+	return fp
+}
+
+// ListResolverFingerprints returns every recorded fingerprint of the resolver
+// named name, most recent first. Fingerprints are kept after a resolver is
+// removed, so name need not be a current resolver.
+func (s *Server) ListResolverFingerprints(ctx context.Context, name string) ([]*models.ResolverFingerprint, error) {
+	if name == "" {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: "resolver", Message: "Resolver is required"}
+	}
+
+	fps, err := s.db.ListResolverFingerprints(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(fps)
+
+	return fps, nil
+}
+
+// printable returns the hex encoded value h decoded, if it is printable,
+// otherwise h.
+func printable(h string) string {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return h
+	}
+
+	if !utf8.Valid(b) || slices.ContainsFunc([]rune(string(b)), func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return h
+	}
+
+	return string(b)
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// the defaults of config.HealthCheck.
+const (
+	defaultHealthInterval = time.Minute
+	defaultHealthWindow   = 10
+)
+
+// healthCheck is the outcome of a single health check of a resolver.
+type healthCheck struct {
+	// err describes why the check failed, empty if it succeeded.
+	err string
+
+	// rtt is the time taken by the resolver to answer, if it did.
+	rtt time.Duration
+}
+
+// CheckResolverHealth checks the health of every resolver immediately, and
+// then each interval configured by cfg until ctx is canceled. The health of
+// each resolver is reported by ListResolvers, and stored on the Lookups it
+// resolves while it is failing. Each healthy resolver is also fingerprinted.
+func (s *Server) CheckResolverHealth(ctx context.Context, cfg config.HealthCheck) {
+	interval := defaultHealthInterval
+	if cfg.Interval > 0 {
+		interval = time.Duration(cfg.Interval) * time.Second
+	}
+
+	window := defaultHealthWindow
+	if cfg.Window > 0 {
+		window = cfg.Window
+	}
+
+	name, qtype := ".", dns.TypeSOA
+	if cfg.Name != "" {
+		name = cfg.Name
+	}
+
+	if cfg.Type != "" {
+		qtype = dns.StringToType[strings.ToUpper(cfg.Type)]
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		wg := new(sync.WaitGroup)
+
+		for _, rsv := range s.resolvers() {
+			wg.Go(func() {
+				s.checkHealth(ctx, rsv, name, qtype, window)
+
+				if rsv.unhealthy() == nil {
+					s.fingerprintResolver(ctx, rsv)
+				}
+			})
+		}
+
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkHealth queries rsv for name once, without retrying, and updates it's
+// health from the last window checks. A check fails if the resolver does not
+// answer, or answers with an error rcode.
+func (s *Server) checkHealth(ctx context.Context, rsv *resolver, name string, qtype uint16, window int) {
+	req := dns.NewMsg(name, qtype)
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var check healthCheck
+
+	res, rtt, err := rsv.client.Exchange(ctx, req, "udp", rsv.addr)
+	switch {
+	case err != nil:
+		check.err = err.Error()
+	case res.Rcode != dns.RcodeSuccess:
+		check.err = dns.RcodeToString[res.Rcode]
+	default:
+		check.rtt = rtt
+	}
+
+	// checks are only modified here, and each resolver is checked by one
+	// goroutine at a time.
+	rsv.checks = append(rsv.checks, check)
+	if len(rsv.checks) > window {
+		rsv.checks = rsv.checks[len(rsv.checks)-window:]
+	}
+
+	prev := rsv.health.Load()
+	health := summarizeHealth(rsv.checks, prev, s.clock.Now().UTC())
+	rsv.health.Store(health)
+
+	switch {
+	case !health.Healthy && (prev == nil || prev.Healthy):
+		s.log.Warn("resolver failed health check", slog.String("resolver", rsv.name), slog.String("error", health.Error))
+	case health.Healthy && prev != nil && !prev.Healthy:
+		s.log.Info(
+			"resolver passed health check",
+			slog.String("resolver", rsv.name), slog.Int("failures", prev.ConsecutiveFailures),
+		)
+	}
+}
+
+// summarizeHealth returns the health of a resolver from it's recent checks,
+// most recent last, and it's previous health, if any.
+func summarizeHealth(checks []healthCheck, prev *models.ResolverHealth, checkedAt time.Time) *models.ResolverHealth {
+	last := checks[len(checks)-1]
+
+	health := &models.ResolverHealth{
+		Healthy:   last.err == "",
+		Error:     last.err,
+		Checks:    len(checks),
+		CheckedAt: checkedAt,
+	}
+
+	if !health.Healthy {
+		health.ConsecutiveFailures = 1
+		if prev != nil {
+			health.ConsecutiveFailures += prev.ConsecutiveFailures
+		}
+	}
+
+	var answered int
+	var rtt time.Duration
+
+	for _, check := range checks {
+		if check.err == "" {
+			answered++
+			rtt += check.rtt
+		}
+	}
+
+	health.SuccessRate = float64(answered) / float64(len(checks))
+	if answered > 0 {
+		health.Latency = milliseconds(rtt / time.Duration(answered))
+	}
+
+	return health
+}
+
+// unhealthy returns the health of rsv if it failed it's most recent health
+// check, otherwise nil.
+func (r *resolver) unhealthy() *models.ResolverHealth {
+	if health := r.health.Load(); health != nil && !health.Healthy {
+		return health
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+)
+
+// ResolverChangeSourceImport is the Source of ResolverChanges recorded when
+// Resolvers are imported through the admin interface.
+const ResolverChangeSourceImport = "import"
+
+// ImportResolvers adds a Resolver for each nameserver in the resolv.conf
+// content conf, named `import/` followed by it's address. Nameservers already
+// queried by a Resolver, or repeated in conf, are not added again. The names
+// of the Resolvers added, and the addresses of the duplicates skipped, are
+// returned. Imported Resolvers are not written to the configuration file, so
+// are forgotten when DENNIS restarts.
+func (s *Server) ImportResolvers(ctx context.Context, conf, actor string) (added, duplicates []string, err error) {
+	nameservers, err := parseResolvConf(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added, duplicates = []string{}, []string{}
+
+	var imports []*config.Resolver
+
+	s.registry.update(func() {
+		existing := make(map[string]bool, len(s.registry.all))
+		for _, rsv := range s.registry.all {
+			existing[rsv.addr] = true
+		}
+
+		for _, ns := range nameservers {
+			r := &config.Resolver{
+				Name:        "import/" + ns.String(),
+				Description: "imported by " + actor,
+				Addr:        ns.String(),
+			}
+
+			rsv := s.newResolver(r, ResolverChangeSourceImport)
+			if existing[rsv.addr] {
+				duplicates = append(duplicates, ns.String())
+				continue
+			}
+
+			existing[rsv.addr] = true
+
+			s.registry.imported = append(s.registry.imported, rsv)
+			s.registry.imports = append(s.registry.imports, r)
+			added = append(added, r.Name)
+		}
+
+		imports = s.registry.imports
+	})
+
+	if len(added) == 0 {
+		return added, duplicates, nil
+	}
+
+	// probing continues after the import has been returned, so must not be
+	// canceled with it.
+	go s.ProbeResolvers(context.WithoutCancel(ctx))
+
+	err = s.RecordResolverChanges(ctx, imports, ResolverChangeSourceImport, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return added, duplicates, nil
+}
+
+// parseResolvConf returns the address of each `nameserver` in the resolv.conf
+// content conf, or an *apiv1.Error describing why there are none.
+func parseResolvConf(conf string) ([]netip.Addr, error) {
+	nameservers, err := config.ParseResolvConf(strings.NewReader(conf))
+	if err != nil {
+		return nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".resolvConf",
+			Message: "Could not parse resolv.conf: " + err.Error(),
+		}
+	} else if len(nameservers) == 0 {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".resolvConf", Message: "No nameservers were found"}
+	}
+
+	return nameservers, nil
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// maxJobAttempts is the number of times resolving a Query is started before
+// it is no longer resumed after DENNIS stops, so a Query that causes DENNIS
+// to stop cannot do so repeatedly.
+const maxJobAttempts = 3
+
+const (
+	// abandonedJobAge is how long a Job of another instance may go without
+	// being queued or started before it is considered abandoned. Resolving a
+	// Query takes little more than 30 seconds, so this leaves a deep queue
+	// plenty of time to drain.
+	abandonedJobAge = time.Hour
+
+	// abandonedJobInterval is how often the Jobs abandoned by other instances
+	// are recovered while DENNIS is running.
+	abandonedJobInterval = 10 * time.Minute
+)
+
+// enqueue records job for query in the database, so the Query is recovered
+// by RecoverJobs if DENNIS stops before it has finished, then pushes it onto
+// the queue of the Job's priority. The Job is recorded first, as it is
+// updated by the worker that resolves the Query. A dispatched Query is not
+// recorded, as it is redelivered by the Dispatcher instead.
+func (s *Server) enqueue(ctx context.Context, job *models.Job, query *models.Query) error {
+	if s.dispatched(query) {
+		return s.push(ctx, job, query)
+	}
+
+	err := s.db.PutJob(ctx, job)
+	if err != nil {
+		// the Query is still resolved, but will not be recovered if DENNIS
+		// stops before it has finished.
+		s.log.Error("could not store job", slog.String("query_id", query.ID.String()), slog.String("error", err.Error()))
+	}
+
+	return s.push(ctx, job, query)
+}
+
+// push pushes query onto the queue of the priority of it's Job, which has
+// already been recorded in the database, or dispatches it to be resolved by
+// any instance.
+func (s *Server) push(ctx context.Context, job *models.Job, query *models.Query) error {
+	if s.dispatched(query) {
+		return s.dispatch(ctx, job, query)
+	}
+
+	s.jobs.Store(query.ID, job)
+
+	return s.queue.push(job.Priority, query)
+}
+
+// enqueueScheduled claims the scheduled Query query once it is due and
+// pushes it onto the queue. Every instance sharing the database schedules
+// the same Queries, so it is skipped if another instance has already claimed
+// it, or it has already finished.
+func (s *Server) enqueueScheduled(ctx context.Context, query *models.Query) error {
+	log := s.log.With(slog.String("query_id", query.ID.String()))
+	job := s.newJob(query, apiv1.PriorityInteractive)
+
+	claimed, err := s.db.ClaimJob(ctx, job)
+	if err != nil {
+		// resolving the Query twice is preferable to never resolving it.
+		log.Error("could not claim scheduled query, resolving it anyway", slog.String("error", err.Error()))
+		return s.push(ctx, job, query)
+	} else if !claimed {
+		log.Debug("scheduled query claimed by another instance")
+		return nil
+	}
+
+	// the Job of a Query is removed once it has finished, so another
+	// instance may have resolved it before it was claimed.
+	current, err := s.db.GetQueryByID(ctx, query.ID)
+	if err == nil && current.FinishedAt != nil {
+		log.Debug("scheduled query already resolved by another instance")
+		return s.db.DeleteJob(ctx, query.ID)
+	}
+
+	return s.push(ctx, job, query)
+}
+
+// newJob returns a new Job to resolve query with priority, owned by this
+// Server's instance.
+func (s *Server) newJob(query *models.Query, priority string) *models.Job {
+	return &models.Job{
+		QueryID:    query.ID,
+		InstanceID: s.instanceID,
+		Priority:   priority,
+		CreatedAt:  s.clock.Now().UTC(),
+	}
+}
+
+// startJob records that resolving the Query queryID has started, so it is
+// not resumed by RecoverJobs if some of it's Lookups have been stored.
+func (s *Server) startJob(ctx context.Context, log *slog.Logger, queryID uuid.UUID) {
+	v, ok := s.jobs.Load(queryID)
+	if !ok {
+		return
+	}
+
+	job := v.(*models.Job)
+
+	now := s.clock.Now().UTC()
+	job.StartedAt = &now
+	job.Attempts++
+
+	err := s.db.PutJob(ctx, job)
+	if err != nil {
+		log.Error("could not update job", slog.String("error", err.Error()))
+	}
+}
+
+// finishJob removes the Job of the Query queryID once it has finished.
+func (s *Server) finishJob(ctx context.Context, log *slog.Logger, queryID uuid.UUID) {
+	s.jobs.Delete(queryID)
+
+	err := s.db.DeleteJob(ctx, queryID)
+	if err != nil {
+		log.Error("could not delete job", slog.String("error", err.Error()))
+	}
+}
+
+// RecoverJobs resumes the Queries that were waiting to be resolved, or were
+// being resolved, when this instance of DENNIS last stopped. A Query whose
+// resolution had started is only resumed if none of it's Lookups were
+// stored, as they would otherwise be duplicated, and it has not been started
+// too many times already. Otherwise it is finished with the Lookups it has,
+// and marked as Interrupted, rather than being left unfinished.
+//
+// The Jobs of this instance, identified by it's instance ID, are recovered,
+// along with any abandoned by other instances sharing the database. The
+// Jobs of other instances that may still be resolving them are left alone.
+func (s *Server) RecoverJobs(ctx context.Context) error {
+	return s.recoverJobs(ctx, true)
+}
+
+// RecoverAbandonedJobs periodically recovers the Jobs abandoned by other
+// instances sharing the database, such as those of an instance that was
+// replaced while DENNIS kept running, until ctx is canceled.
+func (s *Server) RecoverAbandonedJobs(ctx context.Context) {
+	for {
+		select {
+		case <-s.clock.After(abandonedJobInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		err := s.recoverJobs(ctx, false)
+		if err != nil {
+			s.log.Error("could not recover abandoned jobs", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// abandoned returns true if job is owned by another instance, but has not
+// been started or queued for longer than any Query waits to be resolved, so
+// that instance has most likely stopped. An instance ID that changes each
+// time DENNIS starts, such as the hostname of a pod, never recovers it's own
+// Jobs once it has stopped.
+func (s *Server) abandoned(job *models.Job) bool {
+	last := job.CreatedAt
+	if job.StartedAt != nil {
+		last = *job.StartedAt
+	}
+
+	return job.InstanceID != s.instanceID && s.clock.Since(last) >= abandonedJobAge
+}
+
+// recoverJobs recovers the Jobs abandoned by other instances, and those of
+// this instance if own is set. An abandoned Job is taken from it's instance
+// before it is recovered, so that it is only recovered once.
+func (s *Server) recoverJobs(ctx context.Context, own bool) error {
+	jobs, err := s.db.ListJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if s.abandoned(job) {
+			taken, err := s.db.TakeJob(ctx, job, s.instanceID)
+			if err != nil {
+				return err
+			} else if !taken {
+				continue
+			}
+
+			s.log.Info(
+				"recovering job abandoned by another instance",
+				slog.String("query_id", job.QueryID.String()), slog.String("owner", job.InstanceID),
+			)
+
+			job.InstanceID = s.instanceID
+		} else if !own || job.InstanceID != s.instanceID {
+			continue
+		}
+
+		err = s.recoverJob(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recoverJob resumes the Query of job, or finishes it as interrupted.
+func (s *Server) recoverJob(ctx context.Context, job *models.Job) error {
+	log := s.log.With(slog.String("query_id", job.QueryID.String()))
+
+	query, err := s.db.GetQueryByID(ctx, job.QueryID)
+	if errors.Is(err, db.ErrQueryNotFound) || (err == nil && query.FinishedAt != nil) {
+		// the Query has expired, or finished before it's Job could be
+		// removed.
+		return s.db.DeleteJob(ctx, job.QueryID)
+	} else if err != nil {
+		return err
+	}
+
+	if job.StartedAt == nil || (len(query.Lookups) == 0 && job.Attempts < maxJobAttempts) {
+		log.Info("resuming query interrupted by restart", slog.Int("attempts", job.Attempts))
+
+		return s.enqueue(ctx, job, query)
+	}
+
+	log.Warn(
+		"query was interrupted while resolving, finishing as interrupted",
+		slog.Int("attempts", job.Attempts), slog.Int("lookups", len(query.Lookups)),
+	)
+
+	now := s.clock.Now().UTC()
+	query.FinishedAt = &now
+	query.Interrupted = true
+
+	err = s.db.UpdateQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return s.db.DeleteJob(ctx, job.QueryID)
+}
+
+// jobQueryIDs returns the IDs of the Queries with a Job in the database,
+// which are recovered by RecoverJobs.
+func (s *Server) jobQueryIDs(ctx context.Context) (map[uuid.UUID]struct{}, error) {
+	jobs, err := s.db.ListJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uuid.UUID]struct{}, len(jobs))
+	for _, job := range jobs {
+		ids[job.QueryID] = struct{}{}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/db/file"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
+
+	"github.com/gofrs/uuid"
+)
+
+// newTestServer returns a Server with no resolvers, backed by a file database
+// in a temporary directory, whose time is controlled by a Fake clock.
+func newTestServer(t *testing.T) (*Server, *file.DB, *clock.Fake) {
+	t.Helper()
+
+	d, err := file.New(filepath.Join(t.TempDir(), "db.json"), nil)
+	must(t, err)
+
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d.SetClock(c)
+
+	s := NewServer(d, nil, config.Resolution{}, apiv1.NewFeatures(nil), slog.New(slog.DiscardHandler))
+	s.SetClock(c)
+
+	return s, d, c
+}
+
+// must fails the test immediately if err is not nil.
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverJobs(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, c := newTestServer(t)
+	s.SetInstanceID("a")
+
+	createQuery := func() *models.Query {
+		q := &models.Query{Type: "A", Name: "example.com"}
+		must(t, d.CreateQuery(ctx, q))
+
+		return q
+	}
+
+	started := c.Now()
+
+	// queued but never started, so it is resumed.
+	pending := createQuery()
+	must(t, d.PutJob(ctx, &models.Job{QueryID: pending.ID, InstanceID: "a", CreatedAt: c.Now()}))
+
+	// started without storing any Lookups, so it is resumed.
+	empty := createQuery()
+	must(t, d.PutJob(ctx, &models.Job{
+		QueryID: empty.ID, InstanceID: "a", Attempts: 1, StartedAt: &started, CreatedAt: c.Now(),
+	}))
+
+	// started and stored a Lookup, so it is finished as interrupted.
+	partial := createQuery()
+	must(t, d.CreateLookup(ctx, partial.ID, &models.Lookup{Resolver: "a", Type: "A"}))
+	must(t, d.PutJob(ctx, &models.Job{
+		QueryID: partial.ID, InstanceID: "a", Attempts: 1, StartedAt: &started, CreatedAt: c.Now(),
+	}))
+
+	// started too many times already, so it is finished as interrupted.
+	exhausted := createQuery()
+	must(t, d.PutJob(ctx, &models.Job{
+		QueryID: exhausted.ID, InstanceID: "a", Attempts: maxJobAttempts, StartedAt: &started, CreatedAt: c.Now(),
+	}))
+
+	// owned by another instance that may still be resolving it.
+	other := createQuery()
+	must(t, d.PutJob(ctx, &models.Job{
+		QueryID: other.ID, InstanceID: "b", Attempts: 1, StartedAt: &started, CreatedAt: c.Now(),
+	}))
+
+	// abandoned by another instance that has since stopped, so it is
+	// recovered by this instance.
+	abandoned := createQuery()
+	must(t, d.PutJob(ctx, &models.Job{
+		QueryID: abandoned.ID, InstanceID: "c", CreatedAt: c.Now().Add(-abandonedJobAge),
+	}))
+
+	// the Query has expired.
+	expired := uuid.Must(uuid.NewV7())
+	must(t, d.PutJob(ctx, &models.Job{QueryID: expired, InstanceID: "a", CreatedAt: c.Now()}))
+
+	must(t, s.RecoverJobs(ctx))
+
+	// resumed Queries are resolved before Close returns.
+	must(t, s.Close())
+
+	get := func(id uuid.UUID) *models.Query {
+		q, err := d.GetQueryByID(ctx, id)
+		must(t, err)
+
+		return q
+	}
+
+	tests := []struct {
+		name        string
+		query       *models.Query
+		finished    bool
+		interrupted bool
+	}{
+		{"pending", pending, true, false},
+		{"empty", empty, true, false},
+		{"partial", partial, true, true},
+		{"exhausted", exhausted, true, true},
+		{"other", other, false, false},
+		{"abandoned", abandoned, true, false},
+	}
+
+	for _, test := range tests {
+		q := get(test.query.ID)
+
+		if finished := q.FinishedAt != nil; finished != test.finished {
+			t.Errorf("%s: expected finished %t, got %t", test.name, test.finished, finished)
+		}
+
+		if q.Interrupted != test.interrupted {
+			t.Errorf("%s: expected interrupted %t, got %t", test.name, test.interrupted, q.Interrupted)
+		}
+	}
+
+	// only the Job of the other instance remains.
+	jobs, err := d.ListJobs(ctx)
+	must(t, err)
+
+	if len(jobs) != 1 || jobs[0].QueryID != other.ID {
+		t.Errorf("expected only the job of the other instance to remain, got %d jobs", len(jobs))
+	}
+}
+
+func TestEnqueueScheduled(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, c := newTestServer(t)
+	s.SetInstanceID("a")
+
+	createQuery := func() *models.Query {
+		q := &models.Query{Type: "A", Name: "example.com", RunAt: new(c.Now())}
+		must(t, d.CreateQuery(ctx, q))
+
+		return q
+	}
+
+	// claimed by another instance, so it is not resolved.
+	claimed := createQuery()
+
+	ok, err := d.ClaimJob(ctx, &models.Job{QueryID: claimed.ID, InstanceID: "b", CreatedAt: c.Now()})
+	must(t, err)
+
+	if !ok {
+		t.Fatal("expected job to be claimed")
+	}
+
+	// resolved by another instance, which has removed it's Job.
+	finished := createQuery()
+	finished.FinishedAt = new(c.Now())
+	must(t, d.UpdateQuery(ctx, finished))
+
+	unclaimed := createQuery()
+
+	for _, q := range []*models.Query{claimed, finished, unclaimed} {
+		must(t, s.enqueueScheduled(ctx, q))
+	}
+
+	must(t, s.Close())
+
+	q, err := d.GetQueryByID(ctx, claimed.ID)
+	must(t, err)
+
+	if q.FinishedAt != nil {
+		t.Errorf("expected query claimed by another instance to not be resolved")
+	}
+
+	q, err = d.GetQueryByID(ctx, unclaimed.ID)
+	must(t, err)
+
+	if q.FinishedAt == nil {
+		t.Errorf("expected unclaimed query to be resolved")
+	}
+
+	jobs, err := d.ListJobs(ctx)
+	must(t, err)
+
+	if len(jobs) != 1 || jobs[0].InstanceID != "b" {
+		t.Errorf("expected only the job of the other instance to remain, got %d jobs", len(jobs))
+	}
+}
@@ -0,0 +1,253 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+// maxJSONSize is the largest JSON request body, in bytes, that will be
+// decoded by decodeJSON.
+const maxJSONSize = 64 * 1024
+
+// decodeJSON strictly decodes the JSON body of a request into dst. Unknown
+// fields, type mismatches, trailing data and bodies larger than maxJSONSize
+// are rejected with an *apiv1.Error, with Field set to the JSONPath of the
+// problem where known, so all endpoints report errors consistently.
+func decodeJSON(r *web.Request, dst any) error {
+	return decodeJSONSize(r, dst, maxJSONSize)
+}
+
+// decodeJSONSize is decodeJSON for a request body of at most maxSize bytes,
+// for the few requests that legitimately carry more, such as the Lookups of
+// a remote agent.
+func decodeJSONSize(r *web.Request, dst any, maxSize int64) error {
+	// the body is kept so the path of an error can be found once decoding
+	// has failed.
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxSize))
+	if err != nil {
+		return jsonError(err, nil, nil)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return jsonError(err, body, reflect.TypeOf(dst))
+	}
+
+	// a request body must contain exactly one JSON value.
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".",
+			Message: "Request body must contain a single JSON object",
+		}
+	}
+
+	return nil
+}
+
+// jsonError translates an error returned by encoding/json when decoding body
+// into a value of type t into an *apiv1.Error describing where the request
+// body was invalid.
+func jsonError(err error, body []byte, t reflect.Type) *apiv1.Error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		timeErr   *time.ParseError
+		maxErr    *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &maxErr):
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".",
+			Message: "Request body must not be larger than " + strconv.FormatInt(maxErr.Limit, 10) + " bytes",
+		}
+
+	case errors.As(err, &syntaxErr):
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".",
+			Message: "Request body is not valid JSON at offset " + strconv.FormatInt(syntaxErr.Offset, 10),
+		}
+
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".", Message: "Request body is empty or incomplete"}
+	}
+
+	// encoding/json only reports the full path of type mismatches, so the
+	// path of anything else is found by decoding body again one value at a
+	// time.
+	field, _ := jsonErrorPath(body, t, "")
+	if field == "" {
+		field = "."
+	}
+
+	switch {
+	case errors.As(err, &typeErr):
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   field,
+			Message: "Expected " + jsonType(typeErr.Type.Kind().String()) + ", got " + typeErr.Value,
+		}
+
+	case errors.As(err, &timeErr):
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   field,
+			Message: "Expected a timestamp in RFC 3339 format, such as 2006-01-02T15:04:05Z",
+		}
+
+	// encoding/json does not export a type for unknown fields, so match the
+	// error message, i.e. `json: unknown field "foo"`.
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: field, Message: "Unknown field"}
+	}
+
+	return &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: field, Message: "Request body is not valid JSON"}
+}
+
+// jsonErrorPath returns the JSONPath of the first value in raw, beneath path,
+// that cannot be strictly decoded into a value of type t, such as an unknown
+// field, a value of the wrong type or an invalid timestamp. ok is false if
+// raw can be decoded.
+func jsonErrorPath(raw []byte, t reflect.Type, path string) (field string, ok bool) {
+	if t == nil || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return "", false
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// types that decode themselves, such as time.Time, are decoded whole.
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[json.Unmarshaler]()) {
+		if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+			return path, true
+		}
+
+		return "", false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return path, true
+		}
+
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return path, true
+			}
+
+			key, _ := tok.(string)
+
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return path, true
+			}
+
+			f, found := jsonField(t, key)
+			if !found {
+				return path + "." + key, true
+			} else if field, ok := jsonErrorPath(value, f.Type, path+"."+key); ok {
+				return field, true
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		var values []json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return path, json.Unmarshal(raw, reflect.New(t).Interface()) != nil
+		}
+
+		for i, value := range values {
+			if field, ok := jsonErrorPath(value, t.Elem(), path+"["+strconv.Itoa(i)+"]"); ok {
+				return field, true
+			}
+		}
+
+	case reflect.Map:
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return path, json.Unmarshal(raw, reflect.New(t).Interface()) != nil
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			if field, ok := jsonErrorPath(values[key], t.Elem(), path+"."+key); ok {
+				return field, true
+			}
+		}
+
+	default:
+		if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// jsonField returns the field of struct type t that the JSON object key is
+// decoded into, matching it's name exactly or otherwise case-insensitively
+// as encoding/json does, including the fields of embedded structs.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold *reflect.StructField
+
+	for _, f := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		// the fields of embedded structs are visible themselves.
+		embedded := f.Anonymous && name == "" && (f.Type.Kind() == reflect.Struct || f.Type.Kind() == reflect.Pointer)
+
+		if !f.IsExported() || embedded || name == "-" {
+			continue
+		} else if name == "" {
+			name = f.Name
+		}
+
+		if name == key {
+			return f, true
+		} else if fold == nil && strings.EqualFold(name, key) {
+			fold = &f
+		}
+	}
+
+	if fold != nil {
+		return *fold, true
+	}
+
+	return reflect.StructField{}, false
+}
+
+// jsonType returns the name of the JSON type a Go kind is decoded from.
+func jsonType(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "map", "struct", "ptr":
+		return "object"
+	default:
+		return "number"
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		field   string
+		message string
+	}{
+		{"Valid", `{"type": "A", "name": "example.com", "edns0": {"udpSize": 512}}`, "", ""},
+		{"CaseInsensitive", `{"TYPE": "A", "name": "example.com"}`, "", ""},
+		{"Null", `{"type": "A", "edns0": null}`, "", ""},
+		{"UnknownField", `{"type": "A", "foo": 1}`, ".foo", "Unknown field"},
+		{"UnknownNestedField", `{"type": "A", "edns0": {"foo": 1}}`, ".edns0.foo", "Unknown field"},
+		{"UnknownArrayField", `{"edns0": {"options": [{"code": 10}, {"foo": 1}]}}`, ".edns0.options[1].foo", "Unknown field"},
+		{"WrongType", `{"type": 1}`, ".type", "Expected string, got number"},
+		{"WrongNestedType", `{"edns0": {"udpSize": "big"}}`, ".edns0.udpSize", "Expected number, got string"},
+		{"WrongArrayType", `{"groups": ["a", 1]}`, ".groups[1]", "Expected string, got number"},
+		{"WrongObjectType", `{"edns0": 1}`, ".edns0", "Expected object, got number"},
+		{
+			"InvalidTime", `{"type": "A", "runAt": "tomorrow"}`,
+			".runAt", "Expected a timestamp in RFC 3339 format, such as 2006-01-02T15:04:05Z",
+		},
+		{"Syntax", `{"type": }`, ".", "Request body is not valid JSON at offset 10"},
+		{"Empty", ``, ".", "Request body is empty or incomplete"},
+		{"Incomplete", `{"type": "A"`, ".", "Request body is empty or incomplete"},
+		{"Trailing", `{"type": "A"} {}`, ".", "Request body must contain a single JSON object"},
+		{
+			"TooLarge", `{"name": "` + strings.Repeat("a", maxJSONSize) + `"}`, ".",
+			"Request body must not be larger than 65536 bytes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &web.Request{Request: httptest.NewRequest("POST", "/", strings.NewReader(test.body))}
+
+			err := decodeJSON(r, new(apiv1.CreateQueryRequest))
+			if test.field == "" {
+				must(t, err)
+				return
+			}
+
+			apiErr, ok := err.(*apiv1.Error)
+			if !ok {
+				t.Fatalf("expected *apiv1.Error, got %T: %v", err, err)
+			}
+
+			if apiErr.Field != test.field {
+				t.Errorf("expected field %q, got %q", test.field, apiErr.Field)
+			}
+
+			if apiErr.Message != test.message {
+				t.Errorf("expected message %q, got %q", test.message, apiErr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+)
+
+// retainedLogLines is the number of recent log lines kept by RecentLogs.
+const retainedLogLines = 1000
+
+// RecentLogs retains the most recent lines written to it in memory, so they
+// can be included in a support bundle without access to wherever logs are
+// collected. Each Write must be whole lines, as written by slog handlers.
+type RecentLogs struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+}
+
+// NewRecentLogs initializes an empty set of retained log lines.
+func NewRecentLogs() *RecentLogs {
+	return &RecentLogs{lines: make([][]byte, 0, retainedLogLines)}
+}
+
+// Write retains each line of p, replacing the oldest if full.
+func (l *RecentLogs) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for line := range bytes.Lines(p) {
+		// p is reused by the writer, so must be copied.
+		line = bytes.Clone(line)
+
+		if len(l.lines) < cap(l.lines) {
+			l.lines = append(l.lines, line)
+		} else {
+			l.lines[l.next] = line
+			l.next = (l.next + 1) % len(l.lines)
+		}
+	}
+
+	return len(p), nil
+}
+
+// content returns every retained line, oldest first.
+func (l *RecentLogs) content() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return bytes.Join(slices.Concat(l.lines[l.next:], l.lines[:l.next]), nil)
+}
@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/models"
+)
+
+// ResolverChangeSourceAdmin is the Source of ResolverChanges recorded when
+// Resolvers are added, removed, disabled or enabled individually through the
+// admin interface.
+const ResolverChangeSourceAdmin = "admin"
+
+// ManagedResolver describes a Resolver as it is managed through the admin
+// interface, whether or not it is enabled.
+type ManagedResolver struct {
+	// Name is the name of the Resolver.
+	Name string `json:"name"`
+
+	// Addr is the address of the Resolver.
+	Addr string `json:"addr"`
+
+	// Groups are the groups the Resolver is tagged with.
+	Groups []string `json:"groups,omitempty"`
+
+	// Source is where the Resolver came from, one of `config`, `import`,
+	// `admin` or `discovery:` followed by the name of the discovery. Only
+	// Resolvers from `import` or `admin` can be removed.
+	Source string `json:"source"`
+
+	// Disabled is set if Queries are not resolved against the Resolver.
+	Disabled bool `json:"disabled"`
+
+	// Health is the health of the Resolver, if it has been checked.
+	Health *models.ResolverHealth `json:"health,omitempty"`
+}
+
+// ManagedResolvers returns every Resolver, including those disabled, in the
+// order they are displayed.
+func (s *Server) ManagedResolvers() []*ManagedResolver {
+	all, disabled := s.registry.list()
+
+	managed := make([]*ManagedResolver, 0, len(all))
+
+	for _, rsv := range all {
+		managed = append(managed, &ManagedResolver{
+			Name:     rsv.name,
+			Addr:     rsv.addr,
+			Groups:   rsv.groups,
+			Source:   rsv.source,
+			Disabled: disabled[rsv.name],
+			Health:   rsv.health.Load(),
+		})
+	}
+
+	return managed
+}
+
+// LoadResolvers restores the Resolvers added and disabled through the admin
+// interface from the database. It must be called before the Server is shared.
+// A stored Resolver with the same name as one configured is skipped, as the
+// configuration file takes precedence.
+func (s *Server) LoadResolvers(ctx context.Context) error {
+	stored, err := s.db.ListStoredResolvers(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.registry.update(func() {
+		for _, sr := range stored {
+			if sr.Disabled {
+				s.registry.disabled[sr.Name] = true
+			}
+
+			if sr.Config == nil {
+				continue
+			}
+
+			r := new(config.Resolver)
+
+			err := json.Unmarshal(sr.Config, r)
+			if err == nil {
+				err = r.Validate()
+			}
+
+			if err != nil {
+				s.log.Error("could not load stored resolver", slog.String("resolver", sr.Name), slog.String("error", err.Error()))
+				continue
+			}
+
+			if s.registry.nameInUse(r.Name) {
+				s.log.Warn("stored resolver is also configured, skipping", slog.String("resolver", sr.Name))
+				continue
+			}
+
+			s.registry.added = append(s.registry.added, s.newResolver(r, ResolverChangeSourceAdmin))
+			s.registry.additions = append(s.registry.additions, r)
+		}
+	})
+
+	return nil
+}
+
+// AddResolver adds the Resolver configured by r, storing it so it is restored
+// when DENNIS restarts. It's name must not be in use by another Resolver.
+// Resolvers implemented by a plugin can only be configured in the
+// configuration file, as they run a command.
+func (s *Server) AddResolver(ctx context.Context, r *config.Resolver, actor string) error {
+	if err := r.Validate(); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			return &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: "." + verr.Field, Message: verr.Message}
+		}
+
+		return err
+	}
+
+	if r.Plugin != nil {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".plugin",
+			Message: "Resolvers implemented by a plugin can only be configured in the configuration file",
+		}
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not encode resolver %q: %w", r.Name, err)
+	}
+
+	var additions []*config.Resolver
+	var conflict bool
+
+	s.registry.update(func() {
+		// a disabled resolver of the same name is still in use.
+		if conflict = s.registry.nameInUse(r.Name); conflict {
+			return
+		}
+
+		s.registry.added = append(s.registry.added, s.newResolver(r, ResolverChangeSourceAdmin))
+		s.registry.additions = append(s.registry.additions, r)
+
+		// a resolver added with the name of one that was disabled and since
+		// removed is not itself disabled.
+		delete(s.registry.disabled, r.Name)
+
+		additions = slices.Clone(s.registry.additions)
+	})
+
+	if conflict {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".name",
+			Message: "There is already a resolver named " + r.Name,
+		}
+	}
+
+	err = s.db.PutStoredResolver(ctx, &models.StoredResolver{Name: r.Name, Config: b})
+	if err != nil {
+		return err
+	}
+
+	// probing continues after the resolver has been added, so must not be
+	// canceled with it.
+	go s.ProbeResolvers(context.WithoutCancel(ctx))
+
+	return s.RecordResolverChanges(ctx, additions, ResolverChangeSourceAdmin, actor)
+}
+
+// RemoveResolver removes the Resolver named name, which must have been added
+// or imported through the admin interface. Resolvers from other sources
+// would return when DENNIS restarts or they are discovered again, so can
+// only be disabled.
+func (s *Server) RemoveResolver(ctx context.Context, name, actor string) error {
+	rsv := s.registry.get(name)
+	if rsv == nil {
+		return errResolverNotFound(name)
+	}
+
+	if rsv.source != ResolverChangeSourceAdmin && rsv.source != ResolverChangeSourceImport {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Message: "Resolver " + name + " is not managed through the admin interface, it can only be disabled",
+		}
+	}
+
+	var additions, imports []*config.Resolver
+
+	s.registry.update(func() {
+		isRsv := func(r *resolver) bool { return r == rsv }
+		isCfg := func(r *config.Resolver) bool { return r.Name == name }
+
+		if rsv.source == ResolverChangeSourceAdmin {
+			s.registry.added = slices.DeleteFunc(s.registry.added, isRsv)
+			s.registry.additions = slices.DeleteFunc(s.registry.additions, isCfg)
+		} else {
+			s.registry.imported = slices.DeleteFunc(s.registry.imported, isRsv)
+			s.registry.imports = slices.DeleteFunc(s.registry.imports, isCfg)
+		}
+
+		delete(s.registry.disabled, name)
+
+		additions, imports = slices.Clone(s.registry.additions), slices.Clone(s.registry.imports)
+	})
+
+	err := s.db.DeleteStoredResolver(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if rsv.source == ResolverChangeSourceImport {
+		return s.RecordResolverChanges(ctx, imports, ResolverChangeSourceImport, actor)
+	}
+
+	return s.RecordResolverChanges(ctx, additions, ResolverChangeSourceAdmin, actor)
+}
+
+// SetResolverDisabled disables the Resolver named name, so Queries are no
+// longer resolved against it, or enables it again. A disabled Resolver
+// remains disabled when DENNIS restarts.
+func (s *Server) SetResolverDisabled(ctx context.Context, name string, disabled bool, actor string) error {
+	rsv := s.registry.get(name)
+	if rsv == nil {
+		return errResolverNotFound(name)
+	}
+
+	var changed bool
+	var cfg *config.Resolver
+
+	s.registry.update(func() {
+		changed = s.registry.disabled[name] != disabled
+
+		if disabled {
+			s.registry.disabled[name] = true
+		} else {
+			delete(s.registry.disabled, name)
+		}
+
+		if i := slices.IndexFunc(s.registry.additions, func(r *config.Resolver) bool { return r.Name == name }); i >= 0 {
+			cfg = s.registry.additions[i]
+		}
+	})
+
+	if !changed {
+		return nil
+	}
+
+	var err error
+
+	switch {
+	case cfg != nil:
+		// the configuration of an added resolver is kept while it is
+		// disabled.
+		var b []byte
+		if b, err = json.Marshal(cfg); err == nil {
+			err = s.db.PutStoredResolver(ctx, &models.StoredResolver{Name: name, Config: b, Disabled: disabled})
+		}
+
+	case disabled:
+		err = s.db.PutStoredResolver(ctx, &models.StoredResolver{Name: name, Disabled: true})
+
+	default:
+		err = s.db.DeleteStoredResolver(ctx, name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	change := models.ResolverEnabled
+	if disabled {
+		change = models.ResolverDisabled
+	}
+
+	if !disabled {
+		go s.ProbeResolvers(context.WithoutCancel(ctx))
+	}
+
+	return s.db.CreateResolverChange(ctx, &models.ResolverChange{
+		Resolver: name,
+		Change:   change,
+		Source:   ResolverChangeSourceAdmin,
+		Actor:    actor,
+	})
+}
+
+// errResolverNotFound returns an *apiv1.Error describing that there is no
+// Resolver named name.
+func errResolverNotFound(name string) error {
+	return &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "There is no resolver named " + name}
+}
+
+// nameInUse returns true if any resolver, enabled or not, is named name. It
+// must be called with mu held.
+func (r *registry) nameInUse(name string) bool {
+	return slices.ContainsFunc(r.all, func(rsv *resolver) bool {
+		return rsv.name == name
+	}) || slices.ContainsFunc(r.added, func(rsv *resolver) bool {
+		return rsv.name == name
+	})
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// CacheEstimate is an estimate of whether a Lookup was answered from the cache
+// of it's resolver, inferred from the TTLs of it's Records.
+type CacheEstimate struct {
+	// Cached is set if the TTL of the Records was lower than the TTL they are
+	// published with, as the resolver counts down the TTL of cached records.
+	Cached bool
+
+	// PublishedTTL is the TTL the Records are thought to be published with.
+	PublishedTTL int
+
+	// ExpiresAt is when the Records expire from the resolver's cache, and so
+	// when a change to them would first be seen from the resolver.
+	ExpiresAt time.Time
+
+	// Stale is set if the Records differ from those of an authoritative
+	// answer to the Query, so the resolver is expected to answer with the
+	// changed Records once it's cached Records expire at ExpiresAt.
+	Stale bool
+}
+
+// EstimateCache estimates whether each Lookup of the Query was answered from
+// the cache of it's resolver. The published TTL is taken from an
+// authoritative answer if there is one, otherwise it is the highest TTL
+// returned by any resolver, so is underestimated if every resolver answered
+// from it's cache. Lookups are only compared with others of the same type,
+// and those without Records have no estimate.
+func (q *Query) EstimateCache() map[*Lookup]*CacheEstimate {
+	type reference struct {
+		published     int
+		authoritative *Lookup
+	}
+
+	refs := make(map[string]*reference)
+
+	for _, lookup := range q.Lookups {
+		ttl, ok := lookup.minTTL()
+		if !ok {
+			continue
+		}
+
+		ref := refs[lookup.Type]
+		if ref == nil {
+			ref = new(reference)
+			refs[lookup.Type] = ref
+		}
+
+		if lookup.Header != nil && lookup.Header.Authoritative {
+			if ref.authoritative == nil || ttl > ref.published {
+				ref.published, ref.authoritative = ttl, lookup
+			}
+		} else if ref.authoritative == nil && ttl > ref.published {
+			ref.published = ttl
+		}
+	}
+
+	estimates := make(map[*Lookup]*CacheEstimate, len(q.Lookups))
+
+	for _, lookup := range q.Lookups {
+		ttl, ok := lookup.minTTL()
+		if !ok {
+			continue
+		}
+
+		ref := refs[lookup.Type]
+
+		e := &CacheEstimate{
+			Cached:       ttl < ref.published,
+			PublishedTTL: ref.published,
+			ExpiresAt:    lookup.ResolvedAt.Add(time.Duration(ttl) * time.Second),
+		}
+
+		if ref.authoritative != nil && e.Cached {
+			e.Stale = !slices.Equal(lookup.content(), ref.authoritative.content())
+		}
+
+		estimates[lookup] = e
+	}
+
+	return estimates
+}
+
+// content returns the content of each Record of the Lookup, sorted so they
+// can be compared regardless of order.
+func (l *Lookup) content() []string {
+	content := make([]string, 0, len(l.Records))
+	for _, rec := range l.Records {
+		content = append(content, strings.Join(rec.Content, " "))
+	}
+
+	slices.Sort(content)
+	return content
+}
+
+// minTTL returns the lowest TTL of the Records of the Lookup, when the first
+// of them will expire, or false if it has no Records.
+func (l *Lookup) minTTL() (int, bool) {
+	if l.Error != nil || len(l.Records) == 0 {
+		return 0, false
+	}
+
+	ttl := l.Records[0].TTL
+	for _, rec := range l.Records[1:] {
+		ttl = min(ttl, rec.TTL)
+	}
+
+	return ttl, true
+}
@@ -0,0 +1,25 @@
+package models
+
+// EDNS0 configures the EDNS0 (RFC 6891) OPT record sent with each Lookup of a
+// Query.
+type EDNS0 struct {
+	// Disabled sends Lookups without an OPT record, as a legacy stub resolver
+	// would, so answers are limited to 512 bytes over UDP.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// UDPSize is the UDP buffer size advertised to resolvers, in bytes. Larger
+	// answers are truncated by the resolver, and retried over TCP.
+	UDPSize int `json:"udpSize,omitempty"`
+
+	// Options are arbitrary EDNS0 options added to the OPT record.
+	Options []*EDNS0Option `json:"options,omitempty"`
+}
+
+// EDNS0Option is a single option within an EDNS0 OPT record.
+type EDNS0Option struct {
+	// Code is the option code, such as `10` for COOKIE.
+	Code int `json:"code"`
+
+	// Data is the hex encoded value of the option, which may be empty.
+	Data string `json:"data"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Job records a Query that is waiting to be resolved, or is being resolved,
+// so that it can be recovered if DENNIS stops before it has finished.
+type Job struct {
+	// QueryID is the ID of the Query to be resolved.
+	QueryID uuid.UUID `json:"queryId"`
+
+	// InstanceID identifies the instance of DENNIS that queued the Query,
+	// which is the only instance that recovers it until it is abandoned.
+	InstanceID string `json:"instanceId"`
+
+	// Priority is the priority the Query was queued with.
+	Priority string `json:"priority"`
+
+	// Attempts is the number of times resolving the Query has been started.
+	Attempts int `json:"attempts"`
+
+	// StartedAt is the UTC timestamp resolving the Query was last started, or
+	// nil if it is still waiting in the queue.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+
+	// CreatedAt is the UTC timestamp the Query was queued.
+	CreatedAt time.Time `json:"createdAt"`
+}
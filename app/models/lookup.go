@@ -1,11 +1,33 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
 )
 
+// the DNSSEC statuses of a Lookup.
+const (
+	// DNSSECSecure indicates the resolver validated the answer, setting the
+	// Authenticated Data (AD) flag.
+	DNSSECSecure = "secure"
+
+	// DNSSECInsecure indicates the resolver did not validate the answer,
+	// either because the zone is not signed or the resolver does not
+	// validate.
+	DNSSECInsecure = "insecure"
+
+	// DNSSECBogus indicates the resolver failed to validate the answer,
+	// returning SERVFAIL, but would have answered with validation disabled.
+	DNSSECBogus = "bogus"
+)
+
+// LookupMalformed is the Error of a Lookup whose response could not be
+// parsed. Such Lookups are only stored if their messages were captured, see
+// Lookup.Wire.
+const LookupMalformed = "MALFORMED"
+
 // Lookup represents a single Lookup executed against a single DNS resolver as
 // part of a Query of multiple DNS resolvers.
 type Lookup struct {
@@ -17,17 +39,165 @@ type Lookup struct {
 	// `name` in Config.Resolvers.
 	Resolver string `json:"resolver"`
 
+	// Type is the type of record resolved by this Lookup, only set if the
+	// Query resolved more than one type.
+	Type string `json:"type,omitempty"`
+
+	// InstanceID identifies the instance of DENNIS that resolved this Lookup,
+	// to trace anomalies to a single replica. Lookups stored before it was
+	// recorded do not have one.
+	InstanceID string `json:"instanceId,omitempty"`
+
+	// Location is where the Lookup was resolved from, such as `EU`, if it was
+	// resolved by a remote agent or the instance of DENNIS that resolved it
+	// has been given a location.
+	Location string `json:"location,omitempty"`
+
+	// ResolverHealth is the health of the resolver when the Lookup was made,
+	// only set if it was failing it's health checks, as it's answer may be
+	// less reliable than those of other resolvers.
+	ResolverHealth *ResolverHealth `json:"resolverHealth,omitempty"`
+
 	// RTT is the round-trip time taken by DENNIS's resolver to execute the
 	// request against the upstream DNS resolver, in milliseconds.
 	RTT int `json:"rtt"`
 
+	// Attempts is the number of times the DNS resolver was queried before it
+	// responded, more than one if it was retried.
+	Attempts int `json:"attempts,omitempty"`
+
 	// Error is the error rcode returned by a DNS resolver if the name could
 	// not be resolved.
 	Error *string `json:"error,omitempty"`
 
+	// Policy describes why the answer appears to have been rewritten by a
+	// Response Policy Zone (RPZ), if the resolver is configured to apply them
+	// and it was.
+	Policy *string `json:"policy,omitempty"`
+
+	// DNSSEC is the DNSSEC status of the answer as reported by the resolver,
+	// one of `secure`, `insecure` or `bogus`. It is only set if the DNSSEC OK
+	// bit was set, either by the Query or the resolver's configuration.
+	DNSSEC *string `json:"dnssec,omitempty"`
+
+	// Header is the header of the response returned by the DNS resolver, such
+	// as it's rcode and flags, to compare the behaviour of resolvers.
+	Header *ResponseHeader `json:"header,omitempty"`
+
 	// Records are the results, if any, returned by a DNS resolver.
 	Records []*Record `json:"records"`
 
+	// Authority and Additional are the records in the authority and
+	// additional sections of the response, such as the SOA record of an
+	// NXDOMAIN response, the NS records of a delegation or their glue
+	// addresses. Unlike Records, each has it's Name and Type set.
+	Authority  []*Record `json:"authority,omitempty"`
+	Additional []*Record `json:"additional,omitempty"`
+
+	// TruncatedByDennis is set if the DNS resolver returned more Records than
+	// DENNIS is configured to store, and those beyond the limit were
+	// discarded. This is distinct from truncation by the resolver, which is
+	// retried over TCP.
+	TruncatedByDennis bool `json:"truncatedByDennis,omitempty"`
+
+	// TotalRecords is the number of Records returned by the DNS resolver
+	// before any were discarded, only set if TruncatedByDennis is.
+	TotalRecords int `json:"totalRecords,omitempty"`
+
+	// DNS64 is set if the AAAA records returned by the DNS resolver were
+	// synthesized by DNS64 for a name with only A records, as on an IPv6-only
+	// network with NAT64.
+	DNS64 *DNS64 `json:"dns64,omitempty"`
+
+	// EDNS0Differs is set if the answer differs from that returned with the
+	// default EDNS0 configuration. It is only checked if the Query disabled
+	// EDNS0 or changed it's UDP size.
+	EDNS0Differs bool `json:"edns0Differs,omitempty"`
+
+	// Wire is the raw request and response of the Lookup, only set if they
+	// were captured by the Query.
+	Wire *Wire `json:"wire,omitempty"`
+
+	// Samples are the distinct answers returned by a DNS resolver when it is
+	// queried more than once for the same Query, in the order they were first
+	// observed. Records are always from the first sample.
+	Samples []*Sample `json:"samples,omitempty"`
+
 	// ResolvedAt is the UTC timestamp indicating when this lookup completed.
 	ResolvedAt time.Time `json:"resolvedAt"`
 }
+
+// Label returns the name of the Lookup's resolver, followed by it's Location
+// in parentheses if it has one, to tell apart Lookups of resolvers with the
+// same name from different locations.
+func (l *Lookup) Label() string {
+	if l.Location == "" {
+		return l.Resolver
+	}
+
+	return l.Resolver + " (" + l.Location + ")"
+}
+
+// DNS64 describes AAAA records synthesized by a DNS64 resolver (RFC 6147),
+// which embed the IPv4 address of an A record within a NAT64 prefix.
+type DNS64 struct {
+	// Prefix is the NAT64 prefix of the synthesized addresses, such as the
+	// Well-Known Prefix `64:ff9b::/96`.
+	Prefix string `json:"prefix"`
+
+	// Embedded are the IPv4 addresses embedded within each synthesized AAAA
+	// record, in the order of the Lookup's Records.
+	Embedded []string `json:"embedded"`
+
+	// A are the addresses of the A records of the name, returned by the DNS
+	// resolver when requested by the Query, to compare with Embedded.
+	A []string `json:"a,omitempty"`
+}
+
+// ResponseHeader is the header of a DNS response returned by a DNS resolver.
+// If the response was truncated and retried over TCP, it is that of the TCP
+// response.
+type ResponseHeader struct {
+	// ID is the message ID of the response.
+	ID int `json:"id"`
+
+	// Rcode is the response code, such as `NOERROR` or `NXDOMAIN`.
+	Rcode string `json:"rcode"`
+
+	// the flags of the response.
+	Authoritative      bool `json:"aa"`
+	Truncated          bool `json:"tc"`
+	RecursionDesired   bool `json:"rd"`
+	RecursionAvailable bool `json:"ra"`
+	AuthenticatedData  bool `json:"ad"`
+	CheckingDisabled   bool `json:"cd"`
+
+	// Size is the size of the response in bytes, as re-encoded by DENNIS.
+	// This may differ slightly from the size sent by the resolver, if it
+	// compressed names differently.
+	Size int `json:"size"`
+}
+
+// Flags returns the flags set in the response in the style of dig, such as
+// `qr rd ra`.
+func (h *ResponseHeader) Flags() string {
+	flags := []string{"qr"}
+
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{h.Authoritative, "aa"},
+		{h.Truncated, "tc"},
+		{h.RecursionDesired, "rd"},
+		{h.RecursionAvailable, "ra"},
+		{h.AuthenticatedData, "ad"},
+		{h.CheckingDisabled, "cd"},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+
+	return strings.Join(flags, " ")
+}
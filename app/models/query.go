@@ -17,9 +17,65 @@ type Query struct {
 	// configured DNS resolver.
 	Type string `json:"type"`
 
+	// Types are each type of record resolved, if more than one, the first
+	// being Type. Each Lookup is then of a single type, see Lookup.Type.
+	Types []string `json:"types,omitempty"`
+
 	// Name is the domain name to resolve against each configured DNS resolver.
+	// Internationalized names are in their ASCII (punycode) form.
 	Name string `json:"name"`
 
+	// UnicodeName is the Unicode form of an internationalized Name, as it
+	// would be displayed to a user. It is not set if Name is entirely ASCII.
+	UnicodeName string `json:"unicodeName,omitempty"`
+
+	// Class is the DNS class Name is resolved in, if not IN.
+	Class string `json:"class,omitempty"`
+
+	// Server is the address of the authoritative DNS server the zone Name
+	// was transferred from, if Type is AXFR or IXFR.
+	Server string `json:"server,omitempty"`
+
+	// Serial is the serial of the zone the changes transferred by an IXFR
+	// were made since, set only if Type is IXFR.
+	Serial *uint32 `json:"serial,omitempty"`
+
+	// Groups are the groups of resolvers the Query was resolved against, if
+	// not every resolver. See Resolver.Groups.
+	Groups []string `json:"groups,omitempty"`
+
+	// Samples is the number of times each resolver is queried, if more than
+	// once. See Lookup.Samples.
+	Samples int `json:"samples,omitempty"`
+
+	// DNSSEC is set if the DNSSEC OK bit was set on every Lookup, see
+	// Lookup.DNSSEC.
+	DNSSEC bool `json:"dnssec,omitempty"`
+
+	// DNS64 is set if the A records of Name were resolved alongside Lookups
+	// with AAAA records synthesized by DNS64, see Lookup.DNS64.
+	DNS64 bool `json:"dns64,omitempty"`
+
+	// NoRecursion is set if the Recursion Desired bit was cleared on every
+	// Lookup.
+	NoRecursion bool `json:"noRecursion,omitempty"`
+
+	// CheckingDisabled is set if the Checking Disabled bit was set on every
+	// Lookup.
+	CheckingDisabled bool `json:"checkingDisabled,omitempty"`
+
+	// Capture is set if the raw messages of every Lookup were captured, see
+	// Lookup.Wire.
+	Capture bool `json:"capture,omitempty"`
+
+	// EDNS0 optionally configures the EDNS0 OPT record sent with every
+	// Lookup, in addition to that configured for all Queries.
+	EDNS0 *EDNS0 `json:"edns0,omitempty"`
+
+	// RunAt is the UTC timestamp the Query is scheduled to be resolved at, if
+	// it was scheduled for the future rather than resolved immediately.
+	RunAt *time.Time `json:"runAt,omitempty"`
+
 	// Lookups are the queries and records returned by the configured DNS
 	// resolvers.
 	Lookups []*Lookup `json:"lookups"`
@@ -32,4 +88,57 @@ type Query struct {
 	// resolving against each configured DNS resolver, or nil if the Query is
 	// still running.
 	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	// Timings is a breakdown of the time spent resolving this Query, or nil
+	// if the Query is still running.
+	Timings *Timings `json:"timings,omitempty"`
+
+	// ClientDisconnected is set if the client that created the Query
+	// disconnected before it was told the Query had been created. The Query
+	// is resolved regardless, but this is only recorded once it has finished.
+	ClientDisconnected bool `json:"clientDisconnected,omitempty"`
+
+	// Partial is set if some of the Query's Lookups were resolved but could
+	// not be stored, such as while the database was unavailable, so it's
+	// results are incomplete.
+	Partial bool `json:"partial,omitempty"`
+
+	// Interrupted is set if DENNIS stopped while the Query was being
+	// resolved, and it could not be resumed when DENNIS started again. The
+	// Query is finished with whichever Lookups were stored before it stopped.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// AllTypes returns each type of record resolved for the Query, in the order
+// they were requested.
+func (q *Query) AllTypes() []string {
+	if len(q.Types) > 0 {
+		return q.Types
+	}
+
+	return []string{q.Type}
+}
+
+// IsTransfer returns true if the Query is a zone transfer from Server, AXFR
+// or IXFR, rather than resolved against each resolver.
+func (q *Query) IsTransfer() bool {
+	return q.Type == "AXFR" || q.Type == "IXFR"
+}
+
+// UpdatedAt returns the UTC timestamp indicating when this Query last changed,
+// either when it was created, a Lookup was resolved or it finished.
+func (q *Query) UpdatedAt() time.Time {
+	updatedAt := q.CreatedAt
+
+	for _, lookup := range q.Lookups {
+		if lookup.ResolvedAt.After(updatedAt) {
+			updatedAt = lookup.ResolvedAt
+		}
+	}
+
+	if q.FinishedAt != nil && q.FinishedAt.After(updatedAt) {
+		updatedAt = *q.FinishedAt
+	}
+
+	return updatedAt
 }
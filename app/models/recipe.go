@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Recipe is a named set of Queries that are commonly made together, such as
+// the records checked before a website goes live, that can be run against a
+// domain name at once.
+type Recipe struct {
+	// ID is the unique identifier for this Recipe.
+	ID uuid.UUID `json:"id"`
+
+	// Name is a short human readable name for this Recipe.
+	Name string `json:"name"`
+
+	// Description is an optional longer description of what this Recipe
+	// checks.
+	Description string `json:"description,omitempty"`
+
+	// Steps are the Queries made when this Recipe is run, in order.
+	Steps []*RecipeStep `json:"steps"`
+
+	// BuiltIn is true for Recipes that are included with DENNIS, rather than
+	// defined by a user. Built in Recipes cannot be deleted.
+	BuiltIn bool `json:"builtIn,omitempty"`
+
+	// CreatedAt is the UTC timestamp indicating when this Recipe was created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RecipeStep is a single Query made when a Recipe is run.
+type RecipeStep struct {
+	// Type is the DNS record type to query for.
+	Type string `json:"type"`
+
+	// Prefix is optionally prepended as a label to the domain name the Recipe
+	// is run against, such as `www` or `_dmarc`. If not set, the domain name
+	// itself is queried.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Assertions are optionally the values expected to be returned by every
+	// resolver for this step, evaluated once it has finished resolving.
+	Assertions []*Assertion `json:"assertions,omitempty"`
+}
+
+// The kinds of Assertion that can be made against the Records of a Query.
+const (
+	// AssertionExact matches a Record whose content is exactly the value.
+	AssertionExact = "exact"
+
+	// AssertionRegex matches a Record whose content matches the regular
+	// expression value.
+	AssertionRegex = "regex"
+
+	// AssertionCIDR matches a Record whose content is an IP address within
+	// the CIDR range value.
+	AssertionCIDR = "cidr"
+)
+
+// Assertion is a value expected to be returned for a step of a Recipe.
+type Assertion struct {
+	// Kind is how Value is compared to the content of a Record, one of
+	// `exact`, `regex` or `cidr`.
+	Kind string `json:"kind"`
+
+	// Value is the value expected, interpreted according to Kind.
+	Value string `json:"value"`
+}
+
+// Matcher returns true if any of records satisfies an Assertion.
+type Matcher func(records []*Record) bool
+
+// Compile parses Value according to Kind, returning a Matcher for the
+// Assertion, or an error if Kind is unknown or Value cannot be parsed. The
+// content of records with more than one value, such as TXT records split into
+// multiple strings, is joined before being compared.
+func (a *Assertion) Compile() (Matcher, error) {
+	switch a.Kind {
+	case AssertionExact:
+		value := a.Value
+
+		return matchContent(func(content string) bool { return content == value }), nil
+
+	case AssertionRegex:
+		re, err := regexp.Compile(a.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return matchContent(re.MatchString), nil
+
+	case AssertionCIDR:
+		prefix, err := netip.ParsePrefix(a.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return matchContent(func(content string) bool {
+			addr, err := netip.ParseAddr(content)
+			return err == nil && prefix.Contains(addr.Unmap())
+		}), nil
+	}
+
+	return nil, fmt.Errorf("unknown kind of assertion %q", a.Kind)
+}
+
+// matchContent returns a Matcher that is satisfied by any Record whose joined
+// content satisfies fn.
+func matchContent(fn func(content string) bool) Matcher {
+	return func(records []*Record) bool {
+		for _, record := range records {
+			if fn(strings.Join(record.Content, "")) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// RecipeRun is a Recipe that has been run against a domain name, grouping
+// together the Queries made.
+type RecipeRun struct {
+	// ID is the unique identifier for this RecipeRun.
+	ID uuid.UUID `json:"id"`
+
+	// RecipeID is the ID of the Recipe that was run.
+	RecipeID uuid.UUID `json:"recipeId"`
+
+	// RecipeName is the name of the Recipe when it was run, so it can still be
+	// displayed if the Recipe is later deleted.
+	RecipeName string `json:"recipeName"`
+
+	// Name is the domain name the Recipe was run against, in the canonical
+	// ASCII form each of it's Queries resolved.
+	Name string `json:"name"`
+
+	// QueryIDs are the IDs of the Queries made, in the order of the Recipe's
+	// Steps.
+	QueryIDs []uuid.UUID `json:"queryIds"`
+
+	// Steps are the Steps of the Recipe when it was run, including their
+	// Assertions, so the run can be evaluated if the Recipe later changes.
+	Steps []*RecipeStep `json:"steps,omitempty"`
+
+	// CreatedAt is the UTC timestamp indicating when this RecipeRun was
+	// created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// The status of a RecipeReport.
+const (
+	// RecipeReportPending indicates not all Queries of a RecipeRun have
+	// finished, so it's Assertions cannot be evaluated yet.
+	RecipeReportPending = "pending"
+
+	// RecipeReportPassed indicates every Assertion of a RecipeRun passed.
+	RecipeReportPassed = "passed"
+
+	// RecipeReportFailed indicates at least one Assertion of a RecipeRun
+	// failed.
+	RecipeReportFailed = "failed"
+)
+
+// RecipeReport is the outcome of evaluating the Assertions of a RecipeRun,
+// suitable for use as a deployment gate.
+type RecipeReport struct {
+	// Status is `pending` until every Query has finished, then `passed` if
+	// every Assertion passed, otherwise `failed`.
+	Status string `json:"status"`
+
+	// Assertions are the result of each Assertion, in the order of the Steps
+	// of the RecipeRun. Empty while Status is `pending`.
+	Assertions []*AssertionResult `json:"assertions"`
+}
+
+// AssertionResult is the result of evaluating a single Assertion against the
+// Query made for it's step.
+type AssertionResult struct {
+	// Type and Name are the record type and domain name that were queried.
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	*Assertion
+
+	// Passed is true if every resolver returned a Record matching the
+	// Assertion.
+	Passed bool `json:"passed"`
+
+	// Message describes why the Assertion failed, if it did.
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "testing"
+
+func TestAssertionCompile(t *testing.T) {
+	records := []*Record{
+		{Content: []string{"192.0.2.10"}},
+		{Content: []string{"::ffff:198.51.100.1"}},
+		{Content: []string{"v=spf1 ", "include:example.com -all"}},
+	}
+
+	tests := []struct {
+		name      string
+		assertion *Assertion
+		match     bool
+		err       bool
+	}{
+		{"ExactMatch", &Assertion{Kind: AssertionExact, Value: "192.0.2.10"}, true, false},
+		{"ExactNoMatch", &Assertion{Kind: AssertionExact, Value: "192.0.2.1"}, false, false},
+		{"ExactJoined", &Assertion{Kind: AssertionExact, Value: "v=spf1 include:example.com -all"}, true, false},
+		{"RegexMatch", &Assertion{Kind: AssertionRegex, Value: `^v=spf1 .*-all$`}, true, false},
+		{"RegexNoMatch", &Assertion{Kind: AssertionRegex, Value: `^v=DMARC1`}, false, false},
+		{"RegexInvalid", &Assertion{Kind: AssertionRegex, Value: `(`}, false, true},
+		{"CIDRMatch", &Assertion{Kind: AssertionCIDR, Value: "192.0.2.0/24"}, true, false},
+		{"CIDRMapped", &Assertion{Kind: AssertionCIDR, Value: "198.51.100.0/24"}, true, false},
+		{"CIDRNoMatch", &Assertion{Kind: AssertionCIDR, Value: "203.0.113.0/24"}, false, false},
+		{"CIDRInvalid", &Assertion{Kind: AssertionCIDR, Value: "192.0.2.0"}, false, true},
+		{"UnknownKind", &Assertion{Kind: "glob", Value: "*"}, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, err := test.assertion.Compile()
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if got := match(records); got != test.match {
+				t.Errorf("expected match %t, got %t", test.match, got)
+			}
+
+			if match(nil) {
+				t.Error("expected no records to never match")
+			}
+		})
+	}
+}
@@ -1,12 +1,23 @@
 package models
 
 import (
+	"slices"
+	"strings"
+
 	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/svcb"
 )
 
 // Record is a DNS record that has been queried from a DNS resolver as part of
 // a Lookup request.
 type Record struct {
+	// Name and Type are the owner name and type of the DNS record. They are
+	// only set for records in the authority and additional sections of a
+	// response, which may differ from those of the Query, such as the SOA of
+	// the zone or the glue of a delegation.
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+
 	// TTL is the maximum time, in seconds, resolvers are expected to cache a
 	// DNS record for.
 	TTL int `json:"ttl"`
@@ -26,6 +37,46 @@ type Record struct {
 	// Content is the configuration of a DNS record, such as an IP Address for
 	// an A/AAAA record or another name for a CNAME record.
 	Content []string `json:"content"`
+
+	// Params are the service parameters of a SVCB or HTTPS record, keyed by
+	// their name such as `alpn`, `port`, `ipv4hint` or `ech`, in their
+	// presentation format. Parameters without a value, such as
+	// `no-default-alpn`, have an empty value.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// ParamString formats the service parameters of a record in their
+// presentation format, ordered by name, such as `alpn=h2,h3 port=443`.
+func (r *Record) ParamString() string {
+	keys := make([]string, 0, len(r.Params))
+	for key := range r.Params {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for i, key := range keys {
+		if value := r.Params[key]; value != "" {
+			keys[i] = key + "=" + value
+		}
+	}
+
+	return strings.Join(keys, " ")
+}
+
+// svcbParams converts the service parameters of a SVCB or HTTPS record into
+// Record.Params, or nil if there are none.
+func svcbParams(pairs []svcb.Pair) map[string]string {
+	if len(pairs) < 1 {
+		return nil
+	}
+
+	params := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		params[svcb.KeyToString(svcb.PairToKey(pair))] = pair.String()
+	}
+
+	return params
 }
 
 // RecordFromRR converts a records returned by miekg/dns into a Record model.
@@ -58,12 +109,34 @@ func RecordFromRR(rr dns.RR) *Record {
 			TTL:     int(rr.Hdr.TTL),
 			Content: []string{rr.DNSKEY.String()},
 		}
+	case *dns.DS:
+		return &Record{
+			TTL:     int(rr.Hdr.TTL),
+			Content: []string{rr.DS.String()},
+		}
+	case *dns.HTTPS:
+		return &Record{
+			TTL:      int(rr.Hdr.TTL),
+			Priority: new(int(rr.SVCB.SVCB.Priority)),
+			Content:  []string{rr.SVCB.SVCB.Target},
+			Params:   svcbParams(rr.SVCB.SVCB.Value),
+		}
+	case *dns.LOC:
+		return &Record{
+			TTL:     int(rr.Hdr.TTL),
+			Content: []string{rr.LOC.String()},
+		}
 	case *dns.MX:
 		return &Record{
 			TTL:      int(rr.Hdr.TTL),
 			Priority: new(int(rr.MX.Preference)),
 			Content:  []string{rr.MX.Mx},
 		}
+	case *dns.NAPTR:
+		return &Record{
+			TTL:     int(rr.Hdr.TTL),
+			Content: []string{rr.NAPTR.String()},
+		}
 	case *dns.NS:
 		return &Record{
 			TTL:     int(rr.Hdr.TTL),
@@ -87,11 +160,22 @@ func RecordFromRR(rr dns.RR) *Record {
 			Port:     new(int(rr.SRV.Port)),
 			Content:  []string{rr.SRV.Target},
 		}
+	case *dns.SSHFP:
+		return &Record{
+			TTL:     int(rr.Hdr.TTL),
+			Content: []string{rr.SSHFP.String()},
+		}
 	case *dns.SVCB:
 		return &Record{
 			TTL:      int(rr.Hdr.TTL),
 			Priority: new(int(rr.SVCB.Priority)),
 			Content:  []string{rr.SVCB.Target},
+			Params:   svcbParams(rr.SVCB.Value),
+		}
+	case *dns.TLSA:
+		return &Record{
+			TTL:     int(rr.Hdr.TTL),
+			Content: []string{rr.TLSA.String()},
 		}
 	case *dns.TXT:
 		return &Record{
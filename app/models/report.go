@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// QueryCount is the number of Queries made for the same record Type and Name.
+type QueryCount struct {
+	// Type is the DNS record type that was queried.
+	Type string `json:"type"`
+
+	// Name is the domain name that was queried.
+	Name string `json:"name"`
+
+	// Count is the number of Queries made for Type and Name.
+	Count int `json:"count"`
+}
+
+// DuplicateReport summarizes how often the same record is queried repeatedly,
+// informing whether caching or coalescing of Queries would be worthwhile.
+type DuplicateReport struct {
+	// Since is the UTC timestamp of the start of the period covered by this
+	// report, which ends at CreatedAt.
+	Since time.Time `json:"since"`
+
+	// Queries is the total number of Queries created in the period.
+	Queries int `json:"queries"`
+
+	// Duplicates is the number of Queries for a record Type and Name that
+	// had already been queried in the period.
+	Duplicates int `json:"duplicates"`
+
+	// Names are the most queried record Types and Names in the period, most
+	// queried first.
+	Names []*QueryCount `json:"names"`
+
+	// CreatedAt is the UTC timestamp indicating when this report was
+	// generated.
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// Resolver is one of the upstream DNS resolvers configured in DENNIS that
+// each Query is resolved against.
+type Resolver struct {
+	// Name is the name of the Resolver, as configured by `name` in
+	// Config.Resolvers. Lookups refer to their Resolver by Name.
+	Name string `json:"name"`
+
+	// Description is an optional longer description of the Resolver.
+	Description string `json:"description,omitempty"`
+
+	// Icon is an optional URL of an image displayed alongside the Resolver.
+	Icon string `json:"icon,omitempty"`
+
+	// Groups are the groups the Resolver is tagged with, such as `public` or
+	// `eu`, which a Query may select to be resolved against.
+	Groups []string `json:"groups,omitempty"`
+
+	// Addr is the `host:port` address of the Resolver. It may be omitted if
+	// the addresses of Resolvers are hidden from the user.
+	Addr string `json:"addr,omitempty"`
+
+	// Capabilities are the capabilities of the Resolver discovered by
+	// probing it, or nil if it has not yet been probed.
+	Capabilities *ResolverCapabilities `json:"capabilities,omitempty"`
+
+	// NXDOMAINRedirect is set if the Resolver answered a name that does not
+	// exist, rather than returning NXDOMAIN, when it was last checked.
+	NXDOMAINRedirect *NXDOMAINRedirect `json:"nxdomainRedirect,omitempty"`
+
+	// Health is the outcome of the recent health checks of the Resolver, or
+	// nil if it has not yet been checked.
+	Health *ResolverHealth `json:"health,omitempty"`
+
+	// Fingerprint identifies the server that answered the most recent
+	// health check of the Resolver, or nil if it has not yet been taken.
+	Fingerprint *ResolverFingerprint `json:"fingerprint,omitempty"`
+}
+
+// ResolverCapabilities describe what a Resolver supports, discovered by
+// probing it when DENNIS starts or the Resolver is added.
+type ResolverCapabilities struct {
+	// Reachable is set if the Resolver answered over UDP. If not, the other
+	// capabilities could not be determined.
+	Reachable bool `json:"reachable"`
+
+	// Recursive is set if the Resolver offers recursion (the RA flag), rather
+	// than only answering for zones it is authoritative for.
+	Recursive bool `json:"recursive"`
+
+	// Validating is set if the Resolver validates DNSSEC, setting the AD flag
+	// on an answer from a signed zone.
+	Validating bool `json:"validating"`
+
+	// EDNS is set if the Resolver answered with an EDNS0 OPT record.
+	EDNS bool `json:"edns"`
+
+	// TCP is set if the Resolver answered over TCP.
+	TCP bool `json:"tcp"`
+
+	// RTT is the time taken in milliseconds for the Resolver to answer the
+	// probe over UDP, if it was reachable.
+	RTT int `json:"rtt"`
+
+	// ProbedAt is the UTC timestamp the Resolver was probed.
+	ProbedAt time.Time `json:"probedAt"`
+}
+
+// NXDOMAINRedirect describes a Resolver answering a name that does not exist,
+// as some ISPs do to redirect users to a search or advertising page. Answers
+// from such a Resolver cannot be trusted to show a name does not exist.
+type NXDOMAINRedirect struct {
+	// Name is the random name the Resolver was queried for.
+	Name string `json:"name"`
+
+	// Answer is the content of each record the Resolver answered with.
+	Answer []string `json:"answer"`
+
+	// DetectedAt is the UTC timestamp the redirection was detected.
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// ResolverHealth summarizes the recent health checks of a Resolver, each of
+// which queries it for a name it is expected to answer.
+type ResolverHealth struct {
+	// Healthy is set if the Resolver answered the most recent check.
+	Healthy bool `json:"healthy"`
+
+	// SuccessRate is the fraction of recent checks the Resolver answered,
+	// between 0 and 1.
+	SuccessRate float64 `json:"successRate"`
+
+	// Latency is the mean time in milliseconds taken by the Resolver to
+	// answer the recent checks it answered.
+	Latency int `json:"latency"`
+
+	// Checks is the number of recent checks summarized.
+	Checks int `json:"checks"`
+
+	// ConsecutiveFailures is the number of checks in a row the Resolver has
+	// failed, zero if it is healthy.
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+
+	// Error describes why the most recent check failed, such as a timeout or
+	// SERVFAIL, if it did.
+	Error string `json:"error,omitempty"`
+
+	// CheckedAt is the UTC timestamp of the most recent check.
+	CheckedAt time.Time `json:"checkedAt"`
+}
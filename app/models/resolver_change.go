@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// the kinds of change to a Resolver.
+const (
+	// ResolverAdded indicates a Resolver was configured that was not
+	// previously.
+	ResolverAdded = "added"
+
+	// ResolverRemoved indicates a Resolver that was previously configured no
+	// longer is.
+	ResolverRemoved = "removed"
+
+	// ResolverModified indicates the configuration of a Resolver changed.
+	ResolverModified = "modified"
+
+	// ResolverDisabled and ResolverEnabled indicate a Resolver was disabled
+	// or enabled again through the admin interface, without changing it's
+	// configuration.
+	ResolverDisabled = "disabled"
+	ResolverEnabled  = "enabled"
+)
+
+// ResolverChange records a change to the set of configured Resolvers, so
+// unexpected differences between the results of Queries can be correlated
+// with changes to the Resolvers they were made against.
+type ResolverChange struct {
+	// ID is the unique identifier of this ResolverChange.
+	ID uuid.UUID `json:"id"`
+
+	// Resolver is the name of the Resolver that changed.
+	Resolver string `json:"resolver"`
+
+	// Change is the kind of change, one of `added`, `removed`, `modified`,
+	// `disabled` or `enabled`.
+	Change string `json:"change"`
+
+	// Before and After are the configuration of the Resolver, as JSON, before
+	// and after the change. Before is not set if the Resolver was added, and
+	// After is not set if it was removed. Neither are set if it was disabled
+	// or enabled.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+
+	// Source is what made the change, such as `config` when DENNIS started
+	// with a changed configuration file.
+	Source string `json:"source"`
+
+	// Actor is who made the change, such as the user and host DENNIS was
+	// started by.
+	Actor string `json:"actor"`
+
+	// CreatedAt is the UTC timestamp indicating when this change was
+	// recorded.
+	CreatedAt time.Time `json:"createdAt"`
+}
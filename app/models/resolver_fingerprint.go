@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ResolverFingerprint identifies the server answering for a Resolver and the
+// transports it supports. A Resolver behind an anycast address is answered by
+// whichever site is nearest, so a change in fingerprint shows upstream
+// routing changed, often the explanation for a sudden change in latency. A
+// ResolverFingerprint is only recorded when it differs from the last.
+type ResolverFingerprint struct {
+	// ID is the unique identifier of this ResolverFingerprint.
+	ID uuid.UUID `json:"id"`
+
+	// Resolver is the name of the Resolver fingerprinted.
+	Resolver string `json:"resolver"`
+
+	// NSID is the name server identifier (RFC 5001) returned by the Resolver,
+	// if it supports the option. It is hex encoded if it is not printable.
+	NSID string `json:"nsid,omitempty"`
+
+	// ServerID is the answer of the Resolver to the `id.server` CHAOS TXT
+	// query (RFC 4892), if it answered, which often names the site.
+	ServerID string `json:"serverId,omitempty"`
+
+	// EDNS is set if the Resolver answered with an EDNS0 OPT record, and
+	// UDPSize is the UDP buffer size it advertised.
+	EDNS    bool `json:"edns"`
+	UDPSize int  `json:"udpSize,omitempty"`
+
+	// Recursive is set if the Resolver offers recursion (the RA flag).
+	Recursive bool `json:"recursive"`
+
+	// TCP is set if the Resolver answered over TCP.
+	TCP bool `json:"tcp"`
+
+	// CreatedAt is the UTC timestamp indicating when this fingerprint was
+	// first observed. It is the fingerprint of the Resolver until the next.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Equal returns true if f and o fingerprint the same server, regardless of
+// which Resolver they were observed from or when.
+func (f *ResolverFingerprint) Equal(o *ResolverFingerprint) bool {
+	if f == nil || o == nil {
+		return f == o
+	}
+
+	return f.NSID == o.NSID && f.ServerID == o.ServerID && f.EDNS == o.EDNS &&
+		f.UDPSize == o.UDPSize && f.Recursive == o.Recursive && f.TCP == o.TCP
+}
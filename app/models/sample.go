@@ -0,0 +1,63 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// Sample is a distinct answer returned by a DNS resolver when it is queried
+// repeatedly, used to analyze the distribution and rotation of answers from
+// round-robin or weighted DNS.
+type Sample struct {
+	// Answers are the content of each record in the answer, in the order they
+	// were returned by the DNS resolver.
+	Answers []string `json:"answers"`
+
+	// Error is set if the resolver failed to answer, to the rcode it returned
+	// or `FAILED` if it could not be queried, rather than Answers.
+	Error string `json:"error,omitempty"`
+
+	// Count is the number of times this answer was returned.
+	Count int `json:"count"`
+}
+
+// AddSample counts an answer made up of records against the distinct answers
+// in samples, returning the updated samples.
+func AddSample(samples []*Sample, records []*Record) []*Sample {
+	answers := make([]string, 0, len(records))
+	for _, rec := range records {
+		answers = append(answers, strings.Join(rec.Content, " "))
+	}
+
+	for _, sample := range samples {
+		if sample.Error == "" && slices.Equal(sample.Answers, answers) {
+			sample.Count++
+			return samples
+		}
+	}
+
+	return append(samples, &Sample{Answers: answers, Count: 1})
+}
+
+// AddFailedSample counts a failure to answer with the error msg against the
+// distinct answers in samples, returning the updated samples.
+func AddFailedSample(samples []*Sample, msg string) []*Sample {
+	for _, sample := range samples {
+		if sample.Error == msg {
+			sample.Count++
+			return samples
+		}
+	}
+
+	return append(samples, &Sample{Answers: []string{}, Error: msg, Count: 1})
+}
+
+// SampleCount returns the number of times the resolver was sampled, the sum
+// of the counts of samples.
+func SampleCount(samples []*Sample) (count int) {
+	for _, sample := range samples {
+		count += sample.Count
+	}
+
+	return count
+}
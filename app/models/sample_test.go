@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestAddSample(t *testing.T) {
+	a := []*Record{{Content: []string{"192.0.2.1"}}}
+	b := []*Record{{Content: []string{"192.0.2.2"}}}
+
+	var samples []*Sample
+	samples = AddSample(samples, a)
+	samples = AddSample(samples, b)
+	samples = AddSample(samples, a)
+	samples = AddFailedSample(samples, "SERVFAIL")
+	samples = AddFailedSample(samples, "FAILED")
+	samples = AddFailedSample(samples, "SERVFAIL")
+
+	// an empty answer is distinct from a failure to answer.
+	samples = AddSample(samples, nil)
+
+	want := []struct {
+		answer string
+		err    string
+		count  int
+	}{
+		{"192.0.2.1", "", 2},
+		{"192.0.2.2", "", 1},
+		{"", "SERVFAIL", 2},
+		{"", "FAILED", 1},
+		{"", "", 1},
+	}
+
+	if len(samples) != len(want) {
+		t.Fatalf("expected %d samples, got %d", len(want), len(samples))
+	}
+
+	for i, w := range want {
+		sample := samples[i]
+
+		var answer string
+		if len(sample.Answers) > 0 {
+			answer = sample.Answers[0]
+		}
+
+		if answer != w.answer || sample.Error != w.err || sample.Count != w.count {
+			t.Errorf("%d: expected %q %q %d, got %q %q %d", i, w.answer, w.err, w.count, answer, sample.Error, sample.Count)
+		}
+	}
+
+	if n := SampleCount(samples); n != 7 {
+		t.Errorf("expected 7 samples in total, got %d", n)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StoredResolver is the state of a Resolver managed at runtime through the
+// admin interface, stored so it is restored when DENNIS restarts.
+type StoredResolver struct {
+	// Name is the name of the Resolver.
+	Name string `json:"name"`
+
+	// Config is the configuration of a Resolver added at runtime, as JSON in
+	// the format of `resolvers` in the configuration file. It is not set for
+	// a Resolver from another source, such as the configuration file, that
+	// has only been disabled.
+	Config json.RawMessage `json:"config,omitempty"`
+
+	// Disabled is set if Queries are not resolved against the Resolver.
+	Disabled bool `json:"disabled"`
+
+	// UpdatedAt is the UTC timestamp the StoredResolver was last stored.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// Timings is a breakdown of the time spent in each stage of resolving a
+// Query, all in milliseconds. The time taken by each Lookup is available from
+// it's own RTT and ResolvedAt.
+type Timings struct {
+	// Queued is the time between the Query being created and DENNIS
+	// beginning to resolve it.
+	Queued int `json:"queued"`
+
+	// FirstLookup is the time between DENNIS beginning to resolve the Query
+	// and the first Lookup completing.
+	FirstLookup int `json:"firstLookup"`
+
+	// Lookups is the time between DENNIS beginning to resolve the Query and
+	// the last Lookup completing.
+	Lookups int `json:"lookups"`
+
+	// DBWrite is the total time spent writing Lookups to the database.
+	DBWrite int `json:"dbWrite"`
+
+	// Total is the time between the Query being created and it finishing.
+	Total int `json:"total"`
+}
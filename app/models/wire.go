@@ -0,0 +1,33 @@
+package models
+
+import (
+	"slices"
+
+	"codeberg.org/miekg/dns"
+)
+
+// Wire is the raw DNS messages of a Lookup, in the wire format they were sent
+// to and received from the resolver. They are only captured if requested by
+// the Query, as they are several times larger than the Records parsed from
+// them.
+type Wire struct {
+	// Request is the DNS message sent to the resolver.
+	Request []byte `json:"request"`
+
+	// Response is the DNS message the resolver answered with. If the response
+	// was truncated and retried over TCP, it is that of the TCP response.
+	Response []byte `json:"response"`
+}
+
+// DigString renders the wire format DNS message msg in the presentation format
+// used by dig, or describes why it could not be parsed.
+func DigString(msg []byte) string {
+	// unpacking may modify the buffer, so a copy is parsed.
+	m := &dns.Msg{Data: slices.Clone(msg)}
+
+	if err := m.Unpack(); err != nil {
+		return ";; could not parse message: " + err.Error()
+	}
+
+	return m.String()
+}
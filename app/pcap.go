@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/pcap"
+)
+
+// firstCapturePort is the source port of the first exchange of a packet
+// capture. The port each exchange was actually sent from is not known to
+// DENNIS, so each is given the next port from here.
+const firstCapturePort = 49152
+
+// PacketCapture is the DNS messages exchanged with resolvers while resolving
+// a single Query, which can be written as a pcap file.
+type PacketCapture struct {
+	mu      sync.Mutex
+	packets []*pcap.Packet
+	port    uint16
+
+	// done is closed once the Query has been resolved.
+	done chan struct{}
+}
+
+func newPacketCapture() *PacketCapture {
+	return &PacketCapture{port: firstCapturePort, done: make(chan struct{})}
+}
+
+type packetCaptureKey struct{}
+
+// withPacketCapture returns a copy of ctx where exchanges are recorded to c.
+func withPacketCapture(ctx context.Context, c *PacketCapture) context.Context {
+	return context.WithValue(ctx, packetCaptureKey{}, c)
+}
+
+// packetCaptureFrom returns the PacketCapture exchanges within ctx are
+// recorded to, if any.
+func packetCaptureFrom(ctx context.Context) *PacketCapture {
+	c, _ := ctx.Value(packetCaptureKey{}).(*PacketCapture)
+	return c
+}
+
+// record records req, sent from src to the resolver at addr over network at
+// sentAt, and it's response res, if one was received rtt later. If src is not
+// valid, the address DENNIS would send from is used. The exchange is not
+// recorded if addr is not the address of a DNS server, such as the URL of a
+// resolver implemented by a plugin.
+func (c *PacketCapture) record(
+	network string, src netip.Addr, addr string, sentAt time.Time, req, res []byte, rtt time.Duration,
+) {
+	dst, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		udp, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return
+		}
+
+		dst = udp.AddrPort()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !src.IsValid() {
+		src = localAddr(dst)
+	}
+
+	srcPort := netip.AddrPortFrom(src, c.port)
+	c.port++
+
+	// DNS messages sent over TCP are prefixed by their length.
+	if network == pcap.TCP {
+		req = append(binary.BigEndian.AppendUint16(nil, uint16(len(req))), req...)
+	}
+
+	c.packets = append(c.packets, &pcap.Packet{
+		Time: sentAt, Network: network, Src: srcPort, Dst: dst, Seq: 1, Ack: 1, Payload: req,
+	})
+
+	if res == nil {
+		return
+	}
+
+	if network == pcap.TCP {
+		res = append(binary.BigEndian.AppendUint16(nil, uint16(len(res))), res...)
+	}
+
+	c.packets = append(c.packets, &pcap.Packet{
+		Time: sentAt.Add(rtt), Network: network, Src: dst, Dst: srcPort, Seq: 1, Ack: 1 + uint32(len(req)), Payload: res,
+	})
+}
+
+// localAddr returns the address DENNIS sends packets to dst from, or the
+// unspecified address if it has no route to dst.
+func localAddr(dst netip.AddrPort) netip.Addr {
+	// connecting a UDP socket sends nothing, but chooses a source address.
+	conn, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(dst))
+	if err != nil {
+		if dst.Addr().Unmap().Is4() {
+			return netip.IPv4Unspecified()
+		}
+
+		return netip.IPv6Unspecified()
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+}
+
+// WritePcap writes the exchanges recorded by c to w as a pcap file, in the
+// order they were sent and received.
+func (c *PacketCapture) WritePcap(w io.Writer) error {
+	c.mu.Lock()
+	packets := slices.Clone(c.packets)
+	c.mu.Unlock()
+
+	slices.SortStableFunc(packets, func(a, b *pcap.Packet) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	pw, err := pcap.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range packets {
+		if err := pw.Write(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CaptureQuery creates a Query as CreateQuery does, recording every DNS
+// message exchanged with resolvers while it is resolved, and waits for it to
+// be resolved. Scheduled Queries and zone transfers cannot be captured.
+func (s *Server) CaptureQuery(
+	ctx context.Context, req *apiv1.CreateQueryRequest,
+) (*models.Query, *PacketCapture, error) {
+	if req.RunAt != nil {
+		return nil, nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".runAt",
+			Message: "A scheduled Query cannot be captured",
+		}
+	}
+
+	if apiv1.IsTransfer(req.Type) {
+		return nil, nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".type",
+			Message: "A zone transfer cannot be captured",
+		}
+	}
+
+	c := newPacketCapture()
+
+	res, err := s.CreateQuery(withPacketCapture(ctx, c), req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		s.captures.Delete(res.Query.ID)
+		return nil, nil, ctx.Err()
+	}
+
+	query, err := s.db.GetQueryByID(ctx, res.Query.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return query, c, nil
+}
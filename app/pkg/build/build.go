@@ -1,10 +1,39 @@
 package build
 
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
 var (
 	version = "0.0.0"
 	commit  = "main"
+	date    = ""
 )
 
+// readBuildInfo fills in commit and date from the version control information
+// embedded by the Go toolchain, if they were not set at link time.
+var readBuildInfo = sync.OnceFunc(func() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if commit == "main" {
+				commit = setting.Value
+			}
+		case "vcs.time":
+			if date == "" {
+				date = setting.Value
+			}
+		}
+	}
+})
+
 // GetVersion returns the semantic release of this build of a service.
 func GetVersion() string {
 	return version
@@ -13,9 +42,26 @@ func GetVersion() string {
 // GetCommit returns the commit reference of this build of a service up to
 // n characters. If the commit is shorter than n, the whole commit is returned.
 func GetCommit(n int) string {
+	readBuildInfo()
+
 	if len(commit) > n {
 		return commit[:n]
 	}
 
 	return commit
 }
+
+// GetDate returns the RFC 3339 timestamp of when this build of a service was
+// compiled, or committed if that is not known. If neither are known, an empty
+// string is returned.
+func GetDate() string {
+	readBuildInfo()
+
+	return date
+}
+
+// GetGoVersion returns the version of the Go toolchain used to compile this
+// build of a service.
+func GetGoVersion() string {
+	return runtime.Version()
+}
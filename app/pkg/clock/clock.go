@@ -0,0 +1,154 @@
+// Package clock abstracts the current time and timers, so the time observed by
+// DENNIS can be controlled, such as by tests of scheduled Queries.
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time and calls functions after a delay.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// Until returns the duration until t.
+	Until(t time.Time) time.Duration
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc waits for the duration to elapse and then calls fn in it's
+	// own goroutine. The returned Timer can be used to cancel the call.
+	AfterFunc(d time.Duration, fn func()) Timer
+}
+
+// Timer is a pending call to a function created by Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the function being called, returning false if it has
+	// already been called or stopped.
+	Stop() bool
+}
+
+// Real is the Clock of the system, backed by the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration        { return time.Until(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) AfterFunc(d time.Duration, fn func()) Timer {
+	return time.AfterFunc(d, fn)
+}
+
+// Fake is a deterministic Clock, whose time only changes when it is advanced.
+// Functions waiting on a Fake are called once it is advanced past their
+// deadline, in the order of their deadlines. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiting []*fakeTimer
+}
+
+// NewFake returns a Fake Clock whose current time is now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+func (f *Fake) Until(t time.Time) time.Duration { return t.Sub(f.Now()) }
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	f.add(d, func(now time.Time) { ch <- now })
+
+	return ch
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, func(time.Time) { go fn() })
+}
+
+func (f *Fake) add(d time.Duration, fire func(time.Time)) *fakeTimer {
+	f.mu.Lock()
+	t := &fakeTimer{clock: f, deadline: f.now.Add(d), fire: fire}
+	f.waiting = append(f.waiting, t)
+	f.mu.Unlock()
+
+	// a timer that is already due fires immediately, as it would with the
+	// time package.
+	if d <= 0 {
+		f.Advance(0)
+	}
+
+	return t
+}
+
+// Advance moves the time of the Fake forward by d, calling any functions whose
+// deadline has been reached.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	now := f.now
+
+	var due []*fakeTimer
+	f.waiting = slices.DeleteFunc(f.waiting, func(t *fakeTimer) bool {
+		if t.deadline.After(now) {
+			return false
+		}
+
+		due = append(due, t)
+		return true
+	})
+	f.mu.Unlock()
+
+	slices.SortStableFunc(due, func(a, b *fakeTimer) int {
+		return a.deadline.Compare(b.deadline)
+	})
+
+	for _, t := range due {
+		t.fire(now)
+	}
+}
+
+// Waiting returns the number of functions waiting for the Fake to be advanced.
+func (f *Fake) Waiting() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiting)
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	fire     func(time.Time)
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	i := slices.Index(t.clock.waiting, t)
+	if i < 0 {
+		return false
+	}
+
+	t.clock.waiting = slices.Delete(t.clock.waiting, i, i+1)
+	return true
+}
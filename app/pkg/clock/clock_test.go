@@ -0,0 +1,65 @@
+package clock
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	var (
+		mu    sync.Mutex
+		fired []string
+		wg    sync.WaitGroup
+	)
+
+	record := func(name string) func() {
+		wg.Add(1)
+		return func() {
+			defer wg.Done()
+
+			mu.Lock()
+			fired = append(fired, name)
+			mu.Unlock()
+		}
+	}
+
+	f.AfterFunc(2*time.Minute, record("b"))
+	f.AfterFunc(time.Minute, record("a"))
+
+	stopped := f.AfterFunc(time.Minute, func() { t.Error("stopped timer was called") })
+	if !stopped.Stop() {
+		t.Error("expected timer to be stopped")
+	}
+
+	if n := f.Waiting(); n != 2 {
+		t.Fatalf("expected 2 waiting, got %d", n)
+	}
+
+	f.Advance(90 * time.Second)
+	if n := f.Waiting(); n != 1 {
+		t.Fatalf("expected 1 waiting, got %d", n)
+	}
+
+	f.Advance(time.Minute)
+	wg.Wait()
+
+	slices.Sort(fired)
+	if !slices.Equal(fired, []string{"a", "b"}) {
+		t.Errorf("unexpected timers fired: %v", fired)
+	}
+
+	if got := f.Since(start); got != 150*time.Second {
+		t.Errorf("expected 150s since start, got %s", got)
+	}
+
+	select {
+	case <-f.After(0):
+	default:
+		t.Error("expected elapsed After to have fired")
+	}
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Consul is a Source that finds the instances of a service registered in the
+// Consul catalog.
+type Consul struct {
+	// Addr is the base URL of the Consul HTTP API, such as
+	// `http://127.0.0.1:8500`.
+	Addr string
+
+	// Service is the name of the service in the catalog.
+	Service string
+
+	// Datacenter and Tag optionally filter the instances of Service.
+	Datacenter string
+	Tag        string
+
+	// Token is optionally the ACL token sent with each request.
+	Token string
+
+	// Client is the HTTP client used to reach Consul.
+	Client *http.Client
+}
+
+// consulService is an instance of a service in the response of the Consul
+// `/v1/catalog/service` endpoint.
+type consulService struct {
+	Node           string `json:"Node"`
+	Address        string `json:"Address"`
+	ServiceID      string `json:"ServiceID"`
+	ServiceAddress string `json:"ServiceAddress"`
+	ServicePort    int    `json:"ServicePort"`
+}
+
+// Discover returns an Endpoint for each instance of the service, named after
+// the node it is registered on, in order of name.
+func (c *Consul) Discover(ctx context.Context) ([]*Endpoint, error) {
+	q := url.Values{}
+	if c.Datacenter != "" {
+		q.Set("dc", c.Datacenter)
+	}
+	if c.Tag != "" {
+		q.Set("tag", c.Tag)
+	}
+
+	u := strings.TrimSuffix(c.Addr, "/") + "/v1/catalog/service/" + url.PathEscape(c.Service)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	body, err := get(c.Client, req)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+
+	var services []*consulService
+	if err := json.Unmarshal(body, &services); err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+
+	// a node may run more than one instance of the service, in which case
+	// they are also named by their service ID.
+	nodes := make(map[string]int)
+	for _, svc := range services {
+		nodes[svc.Node]++
+	}
+
+	endpoints := make([]*Endpoint, 0, len(services))
+
+	for _, svc := range services {
+		ep := &Endpoint{Name: svc.Node, Addr: svc.ServiceAddress, Port: svc.ServicePort}
+
+		// the service address is only set if it differs from the node.
+		if ep.Addr == "" {
+			ep.Addr = svc.Address
+		}
+
+		if nodes[svc.Node] > 1 {
+			ep.Name += "/" + svc.ServiceID
+		}
+
+		endpoints = append(endpoints, ep)
+	}
+
+	slices.SortFunc(endpoints, func(a, b *Endpoint) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return endpoints, nil
+}
@@ -0,0 +1,53 @@
+// Package discovery finds the DNS servers behind a service registered with a
+// service catalog, such as Consul or Kubernetes, so each may be queried by
+// DENNIS individually.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Endpoint is a single DNS server found by a Source.
+type Endpoint struct {
+	// Name identifies the Endpoint within it's Source, such as the name of
+	// the node or pod it is running on.
+	Name string
+
+	// Addr is the IP address of the DNS server.
+	Addr string
+
+	// Port is the port of the DNS server, or zero if the Source does not
+	// know it.
+	Port int
+}
+
+// Source finds the Endpoints of a service.
+type Source interface {
+	// Discover returns every Endpoint currently registered for the service,
+	// in a consistent order so they are displayed consistently.
+	Discover(ctx context.Context) ([]*Endpoint, error)
+}
+
+// get sends req using client, returning the body of the response if it was
+// successful.
+func get(client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return body, nil
+}
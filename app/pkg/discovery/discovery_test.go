@@ -0,0 +1,114 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConsul(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/catalog/service/dns" || r.URL.Query().Get("dc") != "dc1" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Write([]byte(`[
+			{"Node": "node-a", "Address": "10.0.0.1", "ServiceID": "dns", "ServiceAddress": "", "ServicePort": 53},
+			{"Node": "node-b", "Address": "10.0.0.2", "ServiceID": "dns-1", "ServiceAddress": "10.0.1.1", "ServicePort": 5353},
+			{"Node": "node-b", "Address": "10.0.0.2", "ServiceID": "dns-2", "ServiceAddress": "10.0.1.2", "ServicePort": 5353}
+		]`))
+	}))
+	defer srv.Close()
+
+	c := &Consul{Addr: srv.URL, Service: "dns", Datacenter: "dc1", Token: "secret", Client: srv.Client()}
+
+	endpoints, err := c.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Endpoint{
+		{Name: "node-a", Addr: "10.0.0.1", Port: 53},
+		{Name: "node-b/dns-1", Addr: "10.0.1.1", Port: 5353},
+		{Name: "node-b/dns-2", Addr: "10.0.1.2", Port: 5353},
+	}
+
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("unexpected endpoints: %+v", endpoints)
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/namespaces/kube-system/services/kube-dns":
+			w.Write([]byte(`{"spec": {"clusterIP": "10.96.0.10", "ports": [{"name": "dns", "port": 53, "protocol": "UDP"}]}}`))
+
+		case "/api/v1/namespaces/kube-system/endpoints/kube-dns":
+			w.Write([]byte(`{
+				"subsets": [{
+					"addresses": [
+						{"ip": "10.244.0.3"},
+						{"ip": "10.244.0.2", "targetRef": {"kind": "Pod", "name": "coredns-a"}}
+					],
+					"notReadyAddresses": [
+						{"ip": "10.244.0.4", "targetRef": {"kind": "Pod", "name": "coredns-c"}}
+					],
+					"ports": [
+						{"name": "metrics", "port": 9153, "protocol": "TCP"},
+						{"name": "dns-tcp", "port": 53, "protocol": "TCP"},
+						{"name": "dns", "port": 53, "protocol": "UDP"}
+					]
+				}]
+			}`))
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	k := &Kubernetes{
+		Server:    srv.URL,
+		TokenFile: token,
+		Namespace: "kube-system",
+		Service:   "kube-dns",
+		ClusterIP: true,
+		Client:    srv.Client(),
+	}
+
+	endpoints, err := k.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Endpoint{
+		{Name: "kube-dns", Addr: "10.96.0.10", Port: 53},
+		{Name: "10.244.0.3", Addr: "10.244.0.3", Port: 53},
+		{Name: "coredns-a", Addr: "10.244.0.2", Port: 53},
+	}
+
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("unexpected endpoints: %+v", endpoints)
+	}
+}
@@ -0,0 +1,213 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// serviceAccountDir is where Kubernetes mounts the credentials of the service
+// account of a pod.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// Kubernetes is a Source that finds the ready endpoints of a Kubernetes
+// Service, such as each CoreDNS pod behind the cluster DNS service, rather
+// than it's ClusterIP.
+type Kubernetes struct {
+	// Server is the base URL of the Kubernetes API server.
+	Server string
+
+	// TokenFile is optionally the path of a bearer token sent with each
+	// request. It is read for every request, as service account tokens are
+	// rotated.
+	TokenFile string
+
+	// Namespace and Service name the Service whose endpoints are found.
+	Namespace string
+	Service   string
+
+	// ClusterIP includes the ClusterIP of the Service as an Endpoint, named
+	// after the Service, alongside each of it's endpoints. This allows the
+	// answer given through the Service to be compared with that of each
+	// replica behind it.
+	ClusterIP bool
+
+	// Client is the HTTP client used to reach the Kubernetes API server.
+	Client *http.Client
+}
+
+// NewInClusterKubernetes initializes a Kubernetes Source using the
+// credentials of the service account of the pod DENNIS is running in. If
+// namespace is empty, the namespace of the pod is used.
+func NewInClusterKubernetes(namespace, service string) (*Kubernetes, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes: not running in a cluster")
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("kubernetes: no certificates in ca.crt")
+	}
+
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: %w", err)
+		}
+
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	return &Kubernetes{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		TokenFile: serviceAccountDir + "token",
+		Namespace: namespace,
+		Service:   service,
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// kubernetesEndpoints is the subset of a Kubernetes Endpoints object used to
+// find the addresses of a Service.
+type kubernetesEndpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP        string `json:"ip"`
+			Hostname  string `json:"hostname"`
+			TargetRef *struct {
+				Name string `json:"name"`
+			} `json:"targetRef"`
+		} `json:"addresses"`
+
+		Ports []*kubernetesPort `json:"ports"`
+	} `json:"subsets"`
+}
+
+// kubernetesPort is a port of a Kubernetes Service or Endpoints object.
+type kubernetesPort struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// kubernetesService is the subset of a Kubernetes Service object used to
+// find it's ClusterIP.
+type kubernetesService struct {
+	Spec struct {
+		ClusterIP string            `json:"clusterIP"`
+		Ports     []*kubernetesPort `json:"ports"`
+	} `json:"spec"`
+}
+
+// dnsPort returns the port named for DNS from ports, otherwise the first over
+// UDP, or zero if there is none.
+func dnsPort(ports []*kubernetesPort) (port int) {
+	for _, p := range ports {
+		if p.Protocol != "" && p.Protocol != "UDP" {
+			continue
+		}
+
+		if port == 0 || p.Name == "dns" {
+			port = p.Port
+		}
+	}
+
+	return port
+}
+
+// Discover returns an Endpoint for each ready address of the Service, named
+// after the pod it belongs to, in order of name. Addresses that are not ready
+// are excluded, as Kubernetes would not send them traffic. If ClusterIP is
+// set, the Endpoint of the Service comes first.
+func (k *Kubernetes) Discover(ctx context.Context) ([]*Endpoint, error) {
+	var eps kubernetesEndpoints
+
+	err := k.get(ctx, "endpoints", &eps)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: %w", err)
+	}
+
+	var endpoints, service []*Endpoint
+
+	if k.ClusterIP {
+		var svc kubernetesService
+
+		err := k.get(ctx, "services", &svc)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: %w", err)
+		}
+
+		// headless Services have no ClusterIP to query.
+		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != "None" {
+			service = append(service, &Endpoint{Name: k.Service, Addr: svc.Spec.ClusterIP, Port: dnsPort(svc.Spec.Ports)})
+		}
+	}
+
+	for _, subset := range eps.Subsets {
+		port := dnsPort(subset.Ports)
+
+		for _, addr := range subset.Addresses {
+			ep := &Endpoint{Name: addr.IP, Addr: addr.IP, Port: port}
+
+			if addr.TargetRef != nil && addr.TargetRef.Name != "" {
+				ep.Name = addr.TargetRef.Name
+			} else if addr.Hostname != "" {
+				ep.Name = addr.Hostname
+			}
+
+			endpoints = append(endpoints, ep)
+		}
+	}
+
+	slices.SortFunc(endpoints, func(a, b *Endpoint) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return append(service, endpoints...), nil
+}
+
+// get decodes the object of kind resource named after the Service into v.
+func (k *Kubernetes) get(ctx context.Context, resource string, v any) error {
+	u := strings.TrimSuffix(k.Server, "/") + "/api/v1/namespaces/" + url.PathEscape(k.Namespace) +
+		"/" + resource + "/" + url.PathEscape(k.Service)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	if k.TokenFile != "" {
+		token, err := os.ReadFile(k.TokenFile)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	body, err := get(k.Client, req)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,129 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// clfTime is the layout of timestamps in the Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog returns a middleware function that writes a line for every
+// request to w once it has been answered, in the Common Log Format, or the
+// Combined Log Format if combined is set, which appends the `Referer` and
+// `User-Agent` headers. Lines are written whole, so w may be shared.
+func AccessLog(w io.Writer, combined bool) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			cw := &countingWriter{ResponseWriter: rw}
+
+			defer func() {
+				line := accessLogLine(r, start, cw.status, cw.bytes, combined)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				_, _ = io.WriteString(w, line)
+			}()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// accessLogLine formats the access log entry for the request r received at
+// start, answered with status and a body of n bytes.
+func accessLogLine(r *http.Request, start time.Time, status int, n int64, combined bool) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	user := "-"
+	if u := r.URL.User; u != nil && u.Username() != "" {
+		user = u.Username()
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	size := "-"
+	if n > 0 {
+		size = strconv.FormatInt(n, 10)
+	}
+
+	line := fmt.Sprintf(
+		"%s - %s [%s] %s %d %s",
+		clfField(host), clfField(user), start.Format(clfTime),
+		strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto), status, size,
+	)
+
+	if combined {
+		line += " " + clfQuote(r.Referer()) + " " + clfQuote(r.UserAgent())
+	}
+
+	return line + "\n"
+}
+
+// clfField returns s with quotes and control characters escaped, or `-` if it
+// is empty.
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	q := strconv.Quote(s)
+	return q[1 : len(q)-1]
+}
+
+// clfQuote returns s quoted and escaped, or `"-"` if it is empty.
+func clfQuote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+
+	return strconv.Quote(s)
+}
+
+// countingWriter wraps an http.ResponseWriter to record the status and
+// number of bytes of the response written to it.
+type countingWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int64
+}
+
+func (cw *countingWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	n, err := cw.ResponseWriter.Write(b)
+	cw.bytes += int64(n)
+
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// net/http.ResponseController.
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		combined bool
+		headers  map[string]string
+		handler  http.HandlerFunc
+		want     string
+	}{
+		{
+			name: "Common",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			want: `^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+				`"GET /query\?name=example\.com HTTP/1\.1" 200 5\n$`,
+		},
+		{
+			name: "NoBody",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			want: `\] "GET /query\?name=example\.com HTTP/1\.1" 204 -\n$`,
+		},
+		{
+			name:     "Combined",
+			combined: true,
+			headers:  map[string]string{"Referer": "https://example.com/", "User-Agent": `curl "8"`},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			want: `\] "GET /query\?name=example\.com HTTP/1\.1" 404 19 "https://example\.com/" "curl \\"8\\""\n$`,
+		},
+		{
+			name:     "CombinedEmpty",
+			combined: true,
+			handler:  func(w http.ResponseWriter, r *http.Request) {},
+			want:     `" 200 - "-" "-"\n$`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			r := httptest.NewRequest(http.MethodGet, "/query?name=example.com", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+
+			AccessLog(&buf, test.combined)(test.handler).ServeHTTP(httptest.NewRecorder(), r)
+
+			if !regexp.MustCompile(test.want).MatchString(buf.String()) {
+				t.Errorf("expected line matching %q, got %q", test.want, buf.String())
+			}
+		})
+	}
+}
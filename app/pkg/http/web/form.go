@@ -0,0 +1,64 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/url"
+)
+
+var (
+	// ErrFormTooLarge is returned by ParseForm when the request body exceeds
+	// the maximum size allowed.
+	ErrFormTooLarge = errors.New("form too large")
+
+	// ErrFormMediaType is returned by ParseForm when the request body is not
+	// a URL-encoded form, including multipart forms which are never accepted.
+	ErrFormMediaType = errors.New("form must be application/x-www-form-urlencoded")
+
+	// ErrFormMalformed is returned by ParseForm when the request body could
+	// not be parsed as a URL-encoded form.
+	ErrFormMalformed = errors.New("form is malformed")
+)
+
+// ParseForm parses the body of the request as a URL-encoded form, returning
+// it's values. Bodies larger than maxBytes, and multipart forms, are rejected
+// without being read in full. Values in the URL query string are not
+// included.
+func (r *Request) ParseForm(maxBytes int64) (url.Values, error) {
+	return parseForm(r.Header.Get("Content-Type"), r.Body, maxBytes)
+}
+
+// parseForm implements ParseForm independently of an HTTP request.
+func parseForm(contentType string, body io.Reader, maxBytes int64) (url.Values, error) {
+	// browsers always send a `Content-Type` with forms, but tolerate clients
+	// that don't as the body is still parsed strictly.
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/x-www-form-urlencoded" {
+			return nil, ErrFormMediaType
+		}
+	}
+
+	if body == nil {
+		return url.Values{}, nil
+	}
+
+	// read one more byte than allowed to tell the difference between a body
+	// that is exactly maxBytes and one that is too large.
+	buf, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) > maxBytes {
+		return nil, ErrFormTooLarge
+	}
+
+	values, err := url.ParseQuery(string(buf))
+	if err != nil {
+		return nil, ErrFormMalformed
+	}
+
+	return values, nil
+}
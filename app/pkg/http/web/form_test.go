@@ -0,0 +1,37 @@
+package web
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func FuzzParseForm(f *testing.F) {
+	f.Add("application/x-www-form-urlencoded", "type=A&name=example.com", int64(1024))
+	f.Add("application/x-www-form-urlencoded; charset=utf-8", "name=%zz", int64(1024))
+	f.Add("multipart/form-data; boundary=x", "--x\r\n\r\n--x--", int64(1024))
+	f.Add("", "type=A;name=example.com", int64(1024))
+	f.Add("application/x-www-form-urlencoded", strings.Repeat("a", 64), int64(32))
+
+	f.Fuzz(func(t *testing.T, contentType, body string, maxBytes int64) {
+		if maxBytes < 0 || maxBytes > 1<<20 {
+			t.Skip()
+		}
+
+		values, err := parseForm(contentType, strings.NewReader(body), maxBytes)
+		if err != nil {
+			if !errors.Is(err, ErrFormTooLarge) && !errors.Is(err, ErrFormMediaType) && !errors.Is(err, ErrFormMalformed) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+
+		if int64(len(body)) > maxBytes {
+			t.Fatalf("accepted body of %d bytes larger than %d", len(body), maxBytes)
+		}
+
+		if values == nil {
+			t.Fatal("expected non-nil values")
+		}
+	})
+}
@@ -3,7 +3,10 @@ package web
 import (
 	"context"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gofrs/uuid"
@@ -29,6 +32,21 @@ func (r *Request) Log() *slog.Logger {
 	)
 }
 
+// Accepts returns true if the request's `Accept` header explicitly lists the
+// media type mediaType, such as `text/plain`. Wildcards are not considered.
+func (r *Request) Accepts(mediaType string) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for accept := range strings.SplitSeq(value, ",") {
+			accept, _, _ = strings.Cut(accept, ";")
+			if strings.EqualFold(strings.TrimSpace(accept), mediaType) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // URLParam retrieves the value of a URL parameter that has been embedded
 // within the router using curly brackets (i.e. `/foo/{bar}`). If a param has
 // not been set, an empty string will be returned.
@@ -51,12 +69,32 @@ type Router struct {
 	r   chi.Router
 	err ErrorHandler
 	log *slog.Logger
+
+	// sample is the fraction of successful GET and HEAD requests logged.
+	sample float64
 }
 
 // New initializes a new Router, with log being the destination for unhandled
-// errors.
+// errors and handled requests.
 func New(log *slog.Logger) *Router {
-	return &Router{r: chi.NewRouter(), log: log}
+	return &Router{r: chi.NewRouter(), log: log, sample: 1}
+}
+
+// SampleLogs sets the fraction of successful GET and HEAD requests, between 0
+// and 1, that are logged once handled. Failed requests and other methods are
+// always logged. It must be called before any routes are registered.
+func (rt *Router) SampleLogs(fraction float64) {
+	rt.sample = fraction
+}
+
+// shouldLog returns true if a request with method answered with status should
+// be logged, according to the sampling fraction.
+func (rt *Router) shouldLog(method string, status int) bool {
+	if (method != http.MethodGet && method != http.MethodHead) || status >= 400 {
+		return true
+	}
+
+	return rand.Float64() < rt.sample
 }
 
 func (rt *Router) ErrorHandler(hn ErrorHandler) {
@@ -82,14 +120,26 @@ func (rt *Router) Use(mw ...func(http.Handler) http.Handler) {
 	rt.r.Use(mw...)
 }
 
+// SetHeader returns a middleware function that sets the response header key
+// to value on every request, such as to identify the server that answered it.
+func SetHeader(key, value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(key, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Route creates a sub-router of Router, where all requests for a given prefix are
 // answered by that Router instance.
 func (rt *Router) Route(prefix string, fn func(*Router)) {
 	rt.r.Route(prefix, func(r chi.Router) {
 		fn(&Router{
-			r:   r,
-			err: rt.err,
-			log: rt.log,
+			r:      r,
+			err:    rt.err,
+			log:    rt.log,
+			sample: rt.sample,
 		})
 	})
 }
@@ -132,8 +182,20 @@ func (rt *Router) Handle(path string, hn http.Handler) {
 func (rt *Router) handle(hn Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
+		start := time.Now()
 
 		req := &Request{Request: r, log: rt.log}
+
+		status := http.StatusNoContent
+		defer func() {
+			if rt.shouldLog(r.Method, status) {
+				req.Log().Debug("handled request",
+					slog.Int("http_status", status),
+					slog.Int64("http_latency_ms", time.Since(start).Milliseconds()),
+				)
+			}
+		}()
+
 		tpl, err := hn(ctx, req)
 		if err != nil {
 			tpl = rt.err(ctx, req, err)
@@ -160,11 +222,18 @@ func (rt *Router) handle(hn Handler) http.HandlerFunc {
 
 		// use status code from Template if it implements StatusCoder,
 		// otherwise fallback to default.
-		status := http.StatusOK
+		status = http.StatusOK
 		if sc, ok := tpl.(StatusCoder); ok {
 			status = sc.StatusCode()
 		}
 
+		// set any additional headers from Template if it implements Headerer.
+		if hd, ok := tpl.(Headerer); ok {
+			for key, values := range hd.Header() {
+				w.Header()[key] = values
+			}
+		}
+
 		// this is special case to set the `Location` header, should consider
 		// a more generic interface for Templates to set this, like StatusCoder
 		// and ContentTyper do.
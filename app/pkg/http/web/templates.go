@@ -29,6 +29,12 @@ type ContentTyper interface {
 	ContentType() string
 }
 
+// Headerer is optionally implemented by Templates to set additional HTTP
+// response headers, such as `ETag`.
+type Headerer interface {
+	Header() http.Header
+}
+
 // jsonTemplate is a Template wrapper that renders an object as JSON as if it
 // were a Template.
 type jsonTemplate struct {
@@ -92,3 +98,62 @@ func (r *redirect) Render(_ context.Context, w io.Writer) error {
 func Redirect(location string, status int) Template {
 	return &redirect{location: location, status: status}
 }
+
+// statusTemplate is a Template wrapper that overrides the HTTP status code
+// returned by another Template.
+type statusTemplate struct {
+	Template
+
+	status int
+}
+
+func (s *statusTemplate) StatusCode() int {
+	return s.status
+}
+
+// Status is a Template wrapper that will render tpl with the HTTP status code
+// status, such as a HTML page that should not be served with HTTP 200 OK.
+func Status(tpl Template, status int) Template {
+	return &statusTemplate{Template: tpl, status: status}
+}
+
+// headerTemplate is a Template wrapper that sets additional HTTP response
+// headers for another Template.
+type headerTemplate struct {
+	Template
+
+	header http.Header
+}
+
+func (h *headerTemplate) Header() http.Header {
+	return h.header
+}
+
+func (h *headerTemplate) StatusCode() int {
+	if sc, ok := h.Template.(StatusCoder); ok {
+		return sc.StatusCode()
+	}
+
+	return http.StatusOK
+}
+
+func (h *headerTemplate) ContentType() string {
+	if ct, ok := h.Template.(ContentTyper); ok {
+		return ct.ContentType()
+	}
+
+	return "text/html; charset=utf-8"
+}
+
+// WithHeader is a Template wrapper that will render tpl with the additional
+// HTTP response header key set to value.
+func WithHeader(tpl Template, key, value string) Template {
+	header := make(http.Header)
+	if h, ok := tpl.(*headerTemplate); ok {
+		header, tpl = h.header, h.Template
+	}
+
+	header.Set(key, value)
+
+	return &headerTemplate{Template: tpl, header: header}
+}
@@ -0,0 +1,133 @@
+// Package idn implements helpers for internationalized domain names, such as
+// converting between their Unicode and ASCII (punycode) forms and detecting
+// names that may be used to impersonate another.
+package idn
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// profile converts names as they would be looked up, but permits characters
+// that are not valid in hostnames yet are common in DNS, such as underscores.
+var profile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
+
+// ToASCII converts a name containing Unicode characters to it's ASCII form,
+// i.e. `bücher.example` to `xn--bcher-kva.example`. Names that are already
+// ASCII are returned unchanged.
+func ToASCII(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+
+	return profile.ToASCII(name)
+}
+
+// ToUnicode converts a name containing punycode labels to it's Unicode form.
+// If the name cannot be converted, it is returned unchanged.
+func ToUnicode(name string) string {
+	u, err := profile.ToUnicode(name)
+	if err != nil {
+		return name
+	}
+
+	return u
+}
+
+// Suspicious returns true if any label of name, once converted to Unicode,
+// mixes characters from different scripts, or is written entirely in a
+// script other than Latin using only characters that resemble Latin letters.
+// These are common techniques for registering names that look like another,
+// such as `аррӏе.com` written in Cyrillic.
+func Suspicious(name string) bool {
+	for label := range strings.SplitSeq(ToUnicode(name), ".") {
+		if mixedScripts(label) || confusable(label) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scripts are those considered when detecting mixed scripts. Characters in
+// scripts shared between languages, such as digits and hyphens, are ignored.
+var scripts = map[string]*unicode.RangeTable{
+	"Latin":    unicode.Latin,
+	"Cyrillic": unicode.Cyrillic,
+	"Greek":    unicode.Greek,
+	"Armenian": unicode.Armenian,
+	"Hebrew":   unicode.Hebrew,
+	"Arabic":   unicode.Arabic,
+	"Han":      unicode.Han,
+	"Hiragana": unicode.Hiragana,
+	"Katakana": unicode.Katakana,
+	"Hangul":   unicode.Hangul,
+	"Thai":     unicode.Thai,
+}
+
+// mixedScripts returns true if label contains characters from more than one
+// script, other than combinations used together by a single language, such as
+// Han, Hiragana and Katakana in Japanese.
+func mixedScripts(label string) bool {
+	seen := make(map[string]bool)
+
+	for _, r := range label {
+		for name, table := range scripts {
+			if unicode.Is(table, r) {
+				seen[name] = true
+			}
+		}
+	}
+
+	// Japanese and Korean are routinely written using Han alongside their
+	// own scripts.
+	delete(seen, "Han")
+	if seen["Hiragana"] || seen["Katakana"] {
+		delete(seen, "Hiragana")
+		delete(seen, "Katakana")
+		seen["Japanese"] = true
+	}
+
+	return len(seen) > 1
+}
+
+// latinLookalikes are Cyrillic and Greek characters that are commonly
+// confused with Latin letters.
+const latinLookalikes = "аеорсухіјѕԁӏԛԝъвнкмтАВЕКМНОРСТХІЈЅαεικνορτυχΑΒΕΖΗΙΚΜΝΟΡΤΥΧ"
+
+// confusable returns true if every letter of label is a Cyrillic or Greek
+// character that resembles a Latin letter.
+func confusable(label string) bool {
+	letters := 0
+
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		if !strings.ContainsRune(latinLookalikes, r) {
+			return false
+		}
+
+		letters++
+	}
+
+	return letters > 0
+}
+
+// isASCII returns true if s only contains ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+package idn
+
+import "testing"
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		name  string
+		ascii string
+	}{
+		{"example.com", "example.com"},
+		{"_dmarc.example.com", "_dmarc.example.com"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"BÜCHER.example", "xn--bcher-kva.example"},
+		{"日本語.jp", "xn--wgv71a119e.jp"},
+	}
+
+	for _, test := range tests {
+		ascii, err := ToASCII(test.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+		} else if ascii != test.ascii {
+			t.Errorf("%s: expected %q, got %q", test.name, test.ascii, ascii)
+		}
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	tests := []struct {
+		name    string
+		unicode string
+	}{
+		{"example.com", "example.com"},
+		{"xn--bcher-kva.example", "bücher.example"},
+		// invalid punycode is returned unchanged.
+		{"xn--a.example", "xn--a.example"},
+	}
+
+	for _, test := range tests {
+		if unicode := ToUnicode(test.name); unicode != test.unicode {
+			t.Errorf("%s: expected %q, got %q", test.name, test.unicode, unicode)
+		}
+	}
+}
+
+func TestSuspicious(t *testing.T) {
+	tests := []struct {
+		desc       string
+		name       string
+		suspicious bool
+	}{
+		{"ASCII", "apple.com", false},
+		{"Latin", "bücher.example", false},
+		{"Cyrillic", "пример.рф", false},
+		{"Greek", "παράδειγμα.ελ", false},
+		{"Japanese", "日本語ひらがなカタカナ.jp", false},
+		{"Chinese", "例子.中国", false},
+		{"Digits and hyphens", "пример-123.рф", false},
+
+		// mixed scripts.
+		{"Latin and Cyrillic", "pаypal.com", true},
+		{"Latin and Greek", "gοogle.com", true},
+		{"Cyrillic and Greek", "пρимер.example", true},
+		{"Mixed in punycode", mustASCII(t, "pаypal.com"), true},
+
+		// confusable, entirely lookalikes of Latin letters.
+		{"Cyrillic confusable", "аррӏе.com", true},
+		{"Greek confusable", "οκ.example", true},
+		{"Confusable in any label", "www.аррӏе.com", true},
+	}
+
+	for _, test := range tests {
+		if got := Suspicious(test.name); got != test.suspicious {
+			t.Errorf("%s: %s: expected suspicious %t, got %t", test.desc, test.name, test.suspicious, got)
+		}
+	}
+}
+
+func TestMixedScripts(t *testing.T) {
+	tests := []struct {
+		label string
+		mixed bool
+	}{
+		{"example", false},
+		{"", false},
+		{"123-456", false},
+		{"ひらがなカタカナ", false},
+		{"漢字ひらがな", false},
+		{"한국어漢字", false},
+		{"exаmple", true},
+		{"漢字한국어ひらがな", true},
+		{"שלוםhello", true},
+	}
+
+	for _, test := range tests {
+		if got := mixedScripts(test.label); got != test.mixed {
+			t.Errorf("%q: expected mixed %t, got %t", test.label, test.mixed, got)
+		}
+	}
+}
+
+func TestConfusable(t *testing.T) {
+	tests := []struct {
+		label      string
+		confusable bool
+	}{
+		{"аррӏе", true},
+		{"раураӏ", true},
+		{"ерех-1", true},
+		{"пример", false},
+		{"apple", false},
+		{"123", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := confusable(test.label); got != test.confusable {
+			t.Errorf("%q: expected confusable %t, got %t", test.label, test.confusable, got)
+		}
+	}
+}
+
+// mustASCII converts name to it's ASCII form, failing the test if it cannot.
+func mustASCII(t *testing.T, name string) string {
+	t.Helper()
+
+	ascii, err := ToASCII(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ascii
+}
@@ -0,0 +1,216 @@
+// Package pcap writes packets in the pcap file format understood by tcpdump
+// and Wireshark. Packets are given as the payload of a UDP or TCP segment
+// between two addresses, and their IP and transport headers are synthesized.
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+// the networks of a Packet.
+const (
+	UDP = "udp"
+	TCP = "tcp"
+)
+
+const (
+	// magicNanoseconds identifies a pcap file with timestamps in nanoseconds.
+	magicNanoseconds = 0xa1b23c4d
+
+	// snapLen is the largest packet that may be written.
+	snapLen = 262144
+
+	// linkTypeRaw is the link type of packets beginning with an IPv4 or IPv6
+	// header, without a link layer header.
+	linkTypeRaw = 101
+)
+
+// the IP protocol numbers of the transport protocols.
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+)
+
+// the lengths of the headers synthesized for each Packet.
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+	tcpHeaderLen  = 20
+)
+
+// Packet is the payload of a single UDP datagram or TCP segment.
+type Packet struct {
+	// Time is when the Packet was sent or received.
+	Time time.Time
+
+	// Network is the transport protocol of the Packet, either UDP or TCP.
+	Network string
+
+	// Src and Dst are the addresses the Packet was sent from and to. They
+	// must be of the same IP version.
+	Src, Dst netip.AddrPort
+
+	// Seq and Ack are the sequence and acknowledgement numbers of a TCP
+	// segment. They are ignored for UDP.
+	Seq, Ack uint32
+
+	// Payload is the content of the datagram or segment. DNS messages sent
+	// over TCP must be prefixed by their length.
+	Payload []byte
+}
+
+// Writer writes Packets to a pcap file.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the header of a pcap file to w, returning a Writer that
+// appends Packets to it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], magicNanoseconds)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], snapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], linkTypeRaw)
+
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w}, nil
+}
+
+// Write appends p to the pcap file, synthesizing it's IP and transport
+// headers.
+func (w *Writer) Write(p *Packet) error {
+	b, err := encode(p)
+	if err != nil {
+		return err
+	}
+
+	ts := p.Time.UnixNano()
+
+	rec := make([]byte, 16)
+	binary.LittleEndian.PutUint32(rec[0:], uint32(ts/int64(time.Second)))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(ts%int64(time.Second)))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(b)))
+
+	if _, err := w.w.Write(rec); err != nil {
+		return err
+	}
+
+	_, err = w.w.Write(b)
+	return err
+}
+
+// encode returns p as an IPv4 or IPv6 packet.
+func encode(p *Packet) ([]byte, error) {
+	src, dst := p.Src.Addr().Unmap(), p.Dst.Addr().Unmap()
+	if src.Is4() != dst.Is4() {
+		return nil, errors.New("source and destination must be of the same IP version")
+	}
+
+	var transport []byte
+	var protocol byte
+
+	switch p.Network {
+	case UDP:
+		protocol = protocolUDP
+		transport = make([]byte, udpHeaderLen+len(p.Payload))
+		binary.BigEndian.PutUint16(transport[0:], p.Src.Port())
+		binary.BigEndian.PutUint16(transport[2:], p.Dst.Port())
+		binary.BigEndian.PutUint16(transport[4:], uint16(len(transport)))
+		copy(transport[udpHeaderLen:], p.Payload)
+
+	case TCP:
+		protocol = protocolTCP
+		transport = make([]byte, tcpHeaderLen+len(p.Payload))
+		binary.BigEndian.PutUint16(transport[0:], p.Src.Port())
+		binary.BigEndian.PutUint16(transport[2:], p.Dst.Port())
+		binary.BigEndian.PutUint32(transport[4:], p.Seq)
+		binary.BigEndian.PutUint32(transport[8:], p.Ack)
+		transport[12] = (tcpHeaderLen / 4) << 4
+		transport[13] = 0x18 // PSH, ACK
+		binary.BigEndian.PutUint16(transport[14:], 65535)
+		copy(transport[tcpHeaderLen:], p.Payload)
+
+	default:
+		return nil, fmt.Errorf("unsupported network %q", p.Network)
+	}
+
+	// the checksum of the transport header covers a pseudo-header of the
+	// addresses, protocol and length.
+	pseudo := append(src.AsSlice(), dst.AsSlice()...)
+	pseudo = append(pseudo, 0, protocol)
+	pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(transport)))
+
+	sum := checksum(append(pseudo, transport...))
+	if protocol == protocolUDP && sum == 0 {
+		sum = 0xffff
+	}
+
+	if protocol == protocolUDP {
+		binary.BigEndian.PutUint16(transport[6:], sum)
+	} else {
+		binary.BigEndian.PutUint16(transport[16:], sum)
+	}
+
+	if src.Is4() {
+		if ipv4HeaderLen+len(transport) > 65535 {
+			return nil, errors.New("packet is too large for IPv4")
+		}
+
+		ip := make([]byte, ipv4HeaderLen, ipv4HeaderLen+len(transport))
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(ipv4HeaderLen+len(transport)))
+		ip[6] = 0x40 // don't fragment
+		ip[8] = 64
+		ip[9] = protocol
+		copy(ip[12:], src.AsSlice())
+		copy(ip[16:], dst.AsSlice())
+		binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+
+		return append(ip, transport...), nil
+	}
+
+	if len(transport) > 65535 {
+		return nil, errors.New("packet is too large for IPv6")
+	}
+
+	ip := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(transport))
+	ip[0] = 0x60
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(transport)))
+	ip[6] = protocol
+	ip[7] = 64
+	copy(ip[8:], src.AsSlice())
+	copy(ip[24:], dst.AsSlice())
+
+	return append(ip, transport...), nil
+}
+
+// checksum returns the internet checksum (RFC 1071) of b.
+func checksum(b []byte) uint16 {
+	var sum uint32
+
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+
+	return ^uint16(sum)
+}
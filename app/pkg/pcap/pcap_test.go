@@ -0,0 +1,126 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := binary.LittleEndian.Uint32(buf.Bytes()); got != magicNanoseconds {
+		t.Fatalf("expected magic %x, got %x", magicNanoseconds, got)
+	}
+
+	p := &Packet{
+		Time:    time.Unix(1700000000, 123),
+		Network: UDP,
+		Src:     netip.MustParseAddrPort("192.0.2.1:49152"),
+		Dst:     netip.MustParseAddrPort("192.0.2.53:53"),
+		Payload: []byte("hello"),
+	}
+
+	if err := w.Write(p); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := buf.Bytes()[24:]
+	sec, nsec := binary.LittleEndian.Uint32(rec), binary.LittleEndian.Uint32(rec[4:])
+	if sec != 1700000000 || nsec != 123 {
+		t.Errorf("expected timestamp 1700000000.123, got %d.%d", sec, nsec)
+	}
+
+	if n := binary.LittleEndian.Uint32(rec[8:]); n != ipv4HeaderLen+udpHeaderLen+5 {
+		t.Errorf("expected length %d, got %d", ipv4HeaderLen+udpHeaderLen+5, n)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name      string
+		packet    *Packet
+		headerLen int
+	}{
+		{
+			name: "UDP over IPv4",
+			packet: &Packet{
+				Network: UDP,
+				Src:     netip.MustParseAddrPort("192.0.2.1:49152"),
+				Dst:     netip.MustParseAddrPort("192.0.2.53:53"),
+				Payload: []byte("odd"),
+			},
+			headerLen: ipv4HeaderLen,
+		},
+		{
+			name: "UDP over IPv6",
+			packet: &Packet{
+				Network: UDP,
+				Src:     netip.MustParseAddrPort("[2001:db8::1]:49152"),
+				Dst:     netip.MustParseAddrPort("[2001:db8::53]:53"),
+				Payload: []byte("even"),
+			},
+			headerLen: ipv6HeaderLen,
+		},
+		{
+			name: "TCP over IPv4",
+			packet: &Packet{
+				Network: TCP,
+				Src:     netip.MustParseAddrPort("192.0.2.1:49152"),
+				Dst:     netip.MustParseAddrPort("192.0.2.53:53"),
+				Seq:     1,
+				Ack:     1,
+				Payload: []byte{0, 3, 'a', 'b', 'c'},
+			},
+			headerLen: ipv4HeaderLen,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := encode(test.packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ip, transport := b[:test.headerLen], b[test.headerLen:]
+
+			if test.headerLen == ipv4HeaderLen && checksum(ip) != 0 {
+				t.Error("IPv4 header checksum is invalid")
+			}
+
+			src, dst := test.packet.Src.Addr(), test.packet.Dst.Addr()
+
+			pseudo := append(src.AsSlice(), dst.AsSlice()...)
+			pseudo = append(pseudo, 0, protocolUDP)
+			if test.packet.Network == TCP {
+				pseudo[len(pseudo)-1] = protocolTCP
+			}
+			pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(transport)))
+
+			if checksum(append(pseudo, transport...)) != 0 {
+				t.Errorf("%s checksum is invalid", test.packet.Network)
+			}
+
+			if !bytes.HasSuffix(b, test.packet.Payload) {
+				t.Error("expected packet to end with payload")
+			}
+		})
+	}
+
+	_, err := encode(&Packet{
+		Network: UDP,
+		Src:     netip.MustParseAddrPort("192.0.2.1:49152"),
+		Dst:     netip.MustParseAddrPort("[2001:db8::53]:53"),
+	})
+	if err == nil {
+		t.Error("expected error for mixed IP versions")
+	}
+}
@@ -0,0 +1,171 @@
+// Package plugin implements the protocol between DENNIS and plugins, external
+// processes that integrate DENNIS with systems it does not support natively,
+// without needing to fork DENNIS.
+//
+// A plugin is an executable started by DENNIS. It is sent JSON-RPC 1.0
+// requests, one JSON object per request, on it's STDIN, and writes responses
+// to it's STDOUT. Anything written to STDERR is passed through to DENNIS' own
+// STDERR. The plugin should exit when it's STDIN is closed.
+//
+// Currently only resolver transports are supported, see Transport.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// process is a running plugin, and an RPC client connected to it.
+type process struct {
+	cmd    *exec.Cmd
+	client *rpc.Client
+}
+
+// plugin manages the lifecycle of a plugin process. The process is started on
+// first use, and restarted on next use if it exits.
+type plugin struct {
+	command string
+	args    []string
+
+	mu      sync.Mutex
+	proc    *process
+	stopped bool
+}
+
+// errStopped is returned when calling a plugin that has been stopped.
+var errStopped = errors.New("plugin: stopped")
+
+// call invokes method on the plugin process with args, decoding the response
+// into reply. If ctx is canceled before the plugin responds, call returns
+// immediately and the response is discarded.
+func (p *plugin) call(ctx context.Context, method string, args, reply any) error {
+	for attempt := 0; ; attempt++ {
+		proc, err := p.start()
+		if err != nil {
+			return err
+		}
+
+		c := proc.client.Go(method, args, reply, make(chan *rpc.Call, 1))
+
+		select {
+		case <-c.Done:
+			if errors.Is(c.Error, rpc.ErrShutdown) || errors.Is(c.Error, io.ErrUnexpectedEOF) {
+				// the process has exited, it will be restarted on next use.
+				p.reset(proc)
+
+				// the process exited before the call was sent, so it is safe
+				// to retry once with a new process.
+				if errors.Is(c.Error, rpc.ErrShutdown) && attempt == 0 {
+					continue
+				}
+			}
+
+			return c.Error
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// start returns the running plugin process, starting it if necessary.
+func (p *plugin) start() (*process, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return nil, errStopped
+	} else if p.proc != nil {
+		return p.proc, nil
+	}
+
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin: could not start %q: %w", p.command, err)
+	}
+
+	p.proc = &process{
+		cmd:    cmd,
+		client: jsonrpc.NewClient(&pipe{Reader: stdout, WriteCloser: stdin}),
+	}
+
+	return p.proc, nil
+}
+
+// reset closes proc, if it is still the current process, so it will be
+// restarted on next use.
+func (p *plugin) reset(proc *process) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc == proc {
+		p.proc = nil
+		proc.close()
+	}
+}
+
+// Close stops the plugin process, if it is running. The plugin cannot be used
+// once it has been closed.
+func (p *plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+
+	if p.proc != nil {
+		p.proc.close()
+		p.proc = nil
+	}
+
+	return nil
+}
+
+// exitTimeout is how long a plugin process is given to exit once it's STDIN has
+// been closed, before it is killed.
+const exitTimeout = 5 * time.Second
+
+// close closes the STDIN of the process, signalling it to exit, and waits for
+// it to do so. If it does not exit within exitTimeout, it is killed.
+func (proc *process) close() {
+	proc.client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		proc.cmd.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(exitTimeout):
+		proc.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// pipe joins the STDOUT and STDIN of a plugin process into a single
+// connection for an RPC client.
+type pipe struct {
+	io.Reader
+	io.WriteCloser
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codeberg.org/miekg/dns"
+)
+
+// Transport is a resolver transport implemented by a plugin, such as DNS over
+// a proprietary protocol, allowing DENNIS to query resolvers it cannot reach
+// natively.
+//
+// The plugin must implement the JSON-RPC method `Transport.Exchange`, which
+// is given an ExchangeRequest and returns an ExchangeResponse.
+type Transport struct {
+	plugin
+}
+
+// ExchangeRequest is the arguments of the `Transport.Exchange` method.
+type ExchangeRequest struct {
+	// Network is the network DENNIS would use to reach the resolver natively,
+	// `udp` or `tcp`, which the plugin is free to ignore.
+	Network string `json:"network"`
+
+	// Address is the address of the resolver, as configured.
+	Address string `json:"address"`
+
+	// Msg is the DNS message to send to the resolver in wire format, encoded
+	// in base64.
+	Msg []byte `json:"msg"`
+}
+
+// ExchangeResponse is the result of the `Transport.Exchange` method.
+type ExchangeResponse struct {
+	// Msg is the DNS message answered by the resolver in wire format, encoded
+	// in base64.
+	Msg []byte `json:"msg"`
+}
+
+// NewTransport initializes a resolver transport implemented by the plugin
+// executable command, started with args. The plugin is not started until it
+// is first used.
+func NewTransport(command string, args []string) *Transport {
+	return &Transport{plugin{command: command, args: args}}
+}
+
+// Exchange sends msg to the resolver at address using the plugin, returning
+// it's answer and the round-trip time, including the time spent in the
+// plugin.
+func (t *Transport) Exchange(
+	ctx context.Context, msg *dns.Msg, network, address string,
+) (*dns.Msg, time.Duration, error) {
+	if err := msg.Pack(); err != nil {
+		return nil, 0, fmt.Errorf("plugin: could not pack message: %w", err)
+	}
+
+	req := &ExchangeRequest{Network: network, Address: address, Msg: msg.Data}
+	res := new(ExchangeResponse)
+
+	start := time.Now()
+
+	err := t.call(ctx, "Transport.Exchange", req, res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rtt := time.Since(start)
+
+	answer := &dns.Msg{Data: res.Msg}
+	if err := answer.Unpack(); err != nil {
+		return nil, rtt, fmt.Errorf("plugin: could not unpack answer: %w", err)
+	}
+
+	return answer, rtt, nil
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+	"net/netip"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"testing"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/rdata"
+)
+
+// TestMain runs the test binary as an example Transport plugin when started
+// by a test, otherwise runs the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("DENNIS_TEST_PLUGIN") == "1" {
+		servePlugin()
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// exampleTransport answers every question with the address 192.0.2.1.
+type exampleTransport struct{}
+
+func (exampleTransport) Exchange(req *ExchangeRequest, res *ExchangeResponse) error {
+	msg := &dns.Msg{Data: req.Msg}
+	if err := msg.Unpack(); err != nil {
+		return err
+	}
+
+	msg.Response = true
+	msg.Answer = append(msg.Answer, &dns.A{
+		Hdr: dns.Header{Name: msg.Question[0].Header().Name, Class: dns.ClassINET, TTL: 60},
+		A:   rdata.A{Addr: netip.MustParseAddr("192.0.2.1")},
+	})
+
+	if err := msg.Pack(); err != nil {
+		return err
+	}
+
+	res.Msg = msg.Data
+	return nil
+}
+
+func servePlugin() {
+	srv := rpc.NewServer()
+	srv.RegisterName("Transport", exampleTransport{})
+	srv.ServeCodec(jsonrpc.NewServerCodec(&pipe{Reader: os.Stdin, WriteCloser: os.Stdout}))
+}
+
+func TestTransport(t *testing.T) {
+	t.Setenv("DENNIS_TEST_PLUGIN", "1")
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTransport(exe, nil)
+	defer tr.Close()
+
+	for range 2 {
+		res, _, err := tr.Exchange(context.Background(), dns.NewMsg("example.com.", dns.TypeA), "udp", "192.0.2.53:53")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(res.Answer) != 1 {
+			t.Fatalf("expected 1 answer, got %d", len(res.Answer))
+		} else if a, ok := res.Answer[0].(*dns.A); !ok || a.A.Addr.String() != "192.0.2.1" {
+			t.Fatalf("unexpected answer %s", res.Answer[0])
+		}
+
+		// the plugin is restarted on next use if it exits.
+		tr.proc.cmd.Process.Kill()
+		tr.proc.cmd.Wait()
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+)
+
+// probeTimeout is the longest a resolver is given to answer each probe.
+const probeTimeout = 5 * time.Second
+
+// ProbeResolvers probes each resolver that has not yet been probed to discover
+// it's capabilities, which are reported by ListResolvers. Resolvers are probed
+// concurrently, and ProbeResolvers returns once all have been probed.
+func (s *Server) ProbeResolvers(ctx context.Context) {
+	wg := new(sync.WaitGroup)
+
+	for _, rsv := range s.resolvers() {
+		if rsv.capabilities.Load() != nil {
+			continue
+		}
+
+		wg.Go(func() {
+			caps := s.probe(ctx, rsv)
+			rsv.capabilities.Store(caps)
+
+			s.log.Debug("probed resolver", slog.String("resolver", rsv.name), slog.Bool("reachable", caps.Reachable))
+		})
+	}
+
+	wg.Wait()
+}
+
+// probe discovers the capabilities of rsv. The SOA of the root zone is
+// queried, as every recursive resolver can answer it, and it is signed, so a
+// validating resolver marks it authenticated.
+func (s *Server) probe(ctx context.Context, rsv *resolver) *models.ResolverCapabilities {
+	caps := &models.ResolverCapabilities{ProbedAt: s.clock.Now().UTC()}
+
+	req := dns.NewMsg(".", dns.TypeSOA)
+	req.UDPSize = defaultUDPSize
+	req.Security = true
+
+	udpCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	res, rtt, err := rsv.client.Exchange(udpCtx, req, "udp", rsv.addr)
+	if err != nil {
+		return caps
+	}
+
+	caps.Reachable = true
+	caps.RTT = milliseconds(rtt)
+	caps.Recursive = res.RecursionAvailable
+	caps.Validating = res.AuthenticatedData
+	caps.EDNS = res.UDPSize > 0
+
+	tcpCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	_, _, err = rsv.client.Exchange(tcpCtx, req, "tcp", rsv.addr)
+	caps.TCP = err == nil
+
+	return caps
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/jamescun/dennis/app/pkg/proxy"
+
+	"codeberg.org/miekg/dns"
+)
+
+// proxyClient exchanges messages with a resolver through a SOCKS5 or HTTP
+// CONNECT proxy. Neither can reliably carry UDP, so every message is sent
+// over TCP, whichever network is asked for.
+type proxyClient struct {
+	client *dns.Client
+	dialer proxy.Dialer
+}
+
+// newProxyClient returns a client connecting through the proxy at rawURL,
+// which has been validated, and to the proxy itself with forward.
+func newProxyClient(rawURL string, forward *net.Dialer) (*proxyClient, error) {
+	u, err := proxy.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := proxy.New(u, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proxyClient{client: dns.NewClient(), dialer: d}, nil
+}
+
+func (c *proxyClient) Exchange(ctx context.Context, msg *dns.Msg, _, address string) (*dns.Msg, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.client.Dialer.Timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(c.client.WriteTimeout))
+
+	return c.client.ExchangeWithConn(ctx, msg, conn)
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// queryCacheSize is the most finished Queries held by queryCache.
+const queryCacheSize = 1000
+
+// queryCache is a read-through cache of finished Queries, by ID. A finished
+// Query never changes, so it can be served from memory rather than the
+// database, including while the database is unavailable. Once full, the least
+// recently viewed Query is evicted.
+//
+// If maxAge is set, a Query is evicted once it is as old as maxAge, when it
+// is also deleted from the database, so it is not served after it has
+// expired.
+type queryCache struct {
+	mu     sync.Mutex
+	size   int
+	maxAge time.Duration
+	order  *list.List
+	items  map[uuid.UUID]*list.Element
+}
+
+// cachedQuery is a Query held by queryCache, until expires if set.
+type cachedQuery struct {
+	query   *models.Query
+	expires time.Time
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{size: size, order: list.New(), items: make(map[uuid.UUID]*list.Element)}
+}
+
+// setMaxAge sets the age at which Queries are evicted, or never if zero.
+func (c *queryCache) setMaxAge(maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxAge = maxAge
+}
+
+// get returns a copy of the Query of id, if it is cached and has not expired
+// by now.
+func (c *queryCache) get(id uuid.UUID, now time.Time) (*models.Query, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+
+	cached := e.Value.(*cachedQuery)
+	if !cached.expires.IsZero() && !now.Before(cached.expires) {
+		c.order.Remove(e)
+		delete(c.items, id)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return copyQuery(cached.query), true
+}
+
+// add caches a copy of query if it has finished and has not expired by now,
+// evicting the least recently viewed Query if the cache is full.
+func (c *queryCache) add(query *models.Query, now time.Time) {
+	if query.FinishedAt == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.maxAge > 0 {
+		expires = query.CreatedAt.Add(c.maxAge)
+
+		if !now.Before(expires) {
+			return
+		}
+	}
+
+	if e, ok := c.items[query.ID]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.items[query.ID] = c.order.PushFront(&cachedQuery{query: copyQuery(query), expires: expires})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedQuery).query.ID)
+	}
+}
+
+// len returns the number of Queries cached.
+func (c *queryCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// copyQuery returns a copy of query that can be reordered by the caller
+// without modifying query. The Lookups themselves are shared, and must not be
+// modified.
+func copyQuery(query *models.Query) *models.Query {
+	q := *query
+	q.Lookups = slices.Clone(query.Lookups)
+
+	return &q
+}
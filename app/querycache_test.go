@@ -0,0 +1,88 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestQueryCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newQuery := func(age time.Duration) *models.Query {
+		return &models.Query{ID: uuid.Must(uuid.NewV7()), CreatedAt: now.Add(-age), FinishedAt: new(now.Add(-age))}
+	}
+
+	t.Run("Unfinished", func(t *testing.T) {
+		c := newQueryCache(10)
+
+		q := newQuery(0)
+		q.FinishedAt = nil
+		c.add(q, now)
+
+		if _, ok := c.get(q.ID, now); ok {
+			t.Error("expected unfinished query to not be cached")
+		}
+	})
+
+	t.Run("Evicted", func(t *testing.T) {
+		c := newQueryCache(2)
+
+		a, b, d := newQuery(0), newQuery(0), newQuery(0)
+		c.add(a, now)
+		c.add(b, now)
+
+		// a is viewed, so b is least recently viewed.
+		c.get(a.ID, now)
+		c.add(d, now)
+
+		if _, ok := c.get(b.ID, now); ok {
+			t.Error("expected least recently viewed query to be evicted")
+		}
+
+		if _, ok := c.get(a.ID, now); !ok {
+			t.Error("expected recently viewed query to be cached")
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		c := newQueryCache(10)
+		c.setMaxAge(time.Hour)
+
+		q := newQuery(30 * time.Minute)
+		c.add(q, now)
+
+		if _, ok := c.get(q.ID, now); !ok {
+			t.Fatal("expected query to be cached before it expires")
+		}
+
+		if _, ok := c.get(q.ID, now.Add(30*time.Minute)); ok {
+			t.Error("expected query to be evicted once it expires")
+		}
+
+		if n := c.len(); n != 0 {
+			t.Errorf("expected expired query to be removed, %d cached", n)
+		}
+
+		// already expired when viewed, so never cached.
+		c.add(newQuery(2*time.Hour), now)
+
+		if n := c.len(); n != 0 {
+			t.Errorf("expected expired query to not be cached, %d cached", n)
+		}
+	})
+
+	t.Run("NoMaxAge", func(t *testing.T) {
+		c := newQueryCache(10)
+
+		q := newQuery(24 * time.Hour)
+		c.add(q, now)
+
+		if _, ok := c.get(q.ID, now.Add(365*24*time.Hour)); !ok {
+			t.Error("expected query to be cached without a max age")
+		}
+	})
+}
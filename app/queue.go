@@ -0,0 +1,99 @@
+package app
+
+import (
+	"errors"
+	"sync"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+)
+
+// errQueueClosed is returned when attempting to push a Query onto a queue that
+// has been closed as part of a graceful shutdown.
+var errQueueClosed = errors.New("queue closed")
+
+// queue is a priority queue of Queries awaiting resolution. Interactive
+// Queries are always popped before batch Queries, otherwise Queries are popped
+// in the order they were pushed.
+type queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	closed bool
+
+	interactive []*models.Query
+	batch       []*models.Query
+}
+
+func newQueue() *queue {
+	q := new(queue)
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// push appends a Query to the queue of the given priority, waking a worker
+// blocked in pop if there is one.
+func (q *queue) push(priority string, query *models.Query) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errQueueClosed
+	}
+
+	if priority == apiv1.PriorityBatch {
+		q.batch = append(q.batch, query)
+	} else {
+		q.interactive = append(q.interactive, query)
+	}
+
+	q.cond.Signal()
+
+	return nil
+}
+
+// pop blocks until a Query is available, then removes and returns it. If the
+// queue has been closed and there are no Queries remaining, false is
+// returned.
+func (q *queue) pop() (*models.Query, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		switch {
+		case len(q.interactive) > 0:
+			query := q.interactive[0]
+			q.interactive = q.interactive[1:]
+			return query, true
+
+		case len(q.batch) > 0:
+			query := q.batch[0]
+			q.batch = q.batch[1:]
+			return query, true
+
+		case q.closed:
+			return nil, false
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// len returns the number of interactive and batch Queries waiting in the
+// queue.
+func (q *queue) len() (interactive, batch int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.interactive), len(q.batch)
+}
+
+// close stops the queue accepting new Queries, and wakes all workers blocked
+// in pop so they may exit once the queue has been drained.
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/db"
+)
+
+// dbCheckInterval is how often WatchDB checks whether the database can be
+// reached, and how long it waits for it to answer.
+const dbCheckInterval = 5 * time.Second
+
+// WatchDB checks the database can be reached until ctx is canceled, placing
+// the Server in read-only mode while it cannot, and leaving it automatically
+// once it recovers. In read-only mode finished Queries that have been viewed
+// recently are served from memory, but new Queries cannot be created.
+// Databases that do not implement db.Pinger are never checked.
+func (s *Server) WatchDB(ctx context.Context) {
+	pinger, ok := s.db.(db.Pinger)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(dbCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, dbCheckInterval)
+		err := pinger.Ping(pingCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			if !s.readOnly.Swap(true) {
+				s.log.Error("database is unavailable, entering read-only mode", slog.String("error", err.Error()))
+			}
+		} else if s.readOnly.Swap(false) {
+			s.log.Info("database is available again, leaving read-only mode")
+		}
+	}
+}
+
+// readOnlyError returns an error if the Server is in read-only mode, as new
+// Queries cannot be stored.
+func (s *Server) readOnlyError() error {
+	if !s.readOnly.Load() {
+		return nil
+	}
+
+	return &apiv1.Error{
+		Code:    apiv1.ErrorCodeUnavailable,
+		Message: "DENNIS cannot reach it's database, new Queries cannot be created until it has recovered",
+	}
+}
@@ -0,0 +1,321 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/idn"
+
+	"github.com/gofrs/uuid"
+)
+
+// builtInRecipeID generates a stable ID for a built in Recipe from a short
+// name, so runs of built in Recipes can refer to them across restarts.
+func builtInRecipeID(name string) uuid.UUID {
+	return uuid.NewV5(uuid.NamespaceURL, "https://github.com/jamescun/dennis/recipes/"+name)
+}
+
+// builtInRecipes are the Recipes included with DENNIS.
+var builtInRecipes = []*models.Recipe{
+	{
+		ID:          builtInRecipeID("web-go-live"),
+		Name:        "Web go-live check",
+		Description: "Addresses, certificate authorities, HTTPS service binding and www of a website about to go live.",
+		Steps: []*models.RecipeStep{
+			{Type: "A"},
+			{Type: "AAAA"},
+			{Type: "CAA"},
+			{Type: "HTTPS"},
+			{Type: "CNAME", Prefix: "www"},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:          builtInRecipeID("email"),
+		Name:        "Email configuration",
+		Description: "Mail servers, SPF, DMARC and MTA-STS policies of a domain.",
+		Steps: []*models.RecipeStep{
+			{Type: "MX"},
+			{Type: "TXT"},
+			{Type: "TXT", Prefix: "_dmarc"},
+			{Type: "TXT", Prefix: "_mta-sts"},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:          builtInRecipeID("delegation"),
+		Name:        "Delegation",
+		Description: "Name servers and start of authority of a zone.",
+		Steps: []*models.RecipeStep{
+			{Type: "NS"},
+			{Type: "SOA"},
+		},
+		BuiltIn: true,
+	},
+}
+
+// getRecipe retrieves a built in or user defined Recipe by ID.
+func (s *Server) getRecipe(ctx context.Context, id uuid.UUID) (*models.Recipe, error) {
+	for _, recipe := range builtInRecipes {
+		if recipe.ID == id {
+			return recipe, nil
+		}
+	}
+
+	recipe, err := s.db.GetRecipeByID(ctx, id)
+	if errors.Is(err, db.ErrRecipeNotFound) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Recipe not found by ID"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return recipe, nil
+}
+
+func (s *Server) CreateRecipe(
+	ctx context.Context, req *apiv1.CreateRecipeRequest,
+) (*apiv1.CreateRecipeResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	recipe := &models.Recipe{
+		Name:        req.Name,
+		Description: req.Description,
+		Steps:       req.Steps,
+	}
+
+	err := s.db.CreateRecipe(ctx, recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.CreateRecipeResponse{Recipe: recipe}, nil
+}
+
+func (s *Server) ListRecipes(ctx context.Context, req *apiv1.ListRecipesRequest) (*apiv1.ListRecipesResponse, error) {
+	recipes, err := s.db.ListRecipes(ctx)
+	if err != nil && s.readOnly.Load() {
+		return nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeUnavailable,
+			Message: "DENNIS cannot reach it's database, user defined Recipes cannot be listed until it has recovered",
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.ListRecipesResponse{
+		Recipes: append(append([]*models.Recipe{}, builtInRecipes...), recipes...),
+	}, nil
+}
+
+func (s *Server) DeleteRecipe(
+	ctx context.Context, req *apiv1.DeleteRecipeRequest,
+) (*apiv1.DeleteRecipeResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.FromString(req.ID)
+	if err != nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Invalid UUID for Recipe ID"}
+	}
+
+	recipe, err := s.getRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if recipe.BuiltIn {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Built in Recipes cannot be deleted"}
+	}
+
+	err = s.db.DeleteRecipe(ctx, id)
+	if errors.Is(err, db.ErrRecipeNotFound) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Recipe not found by ID"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.DeleteRecipeResponse{}, nil
+}
+
+func (s *Server) RunRecipe(ctx context.Context, req *apiv1.RunRecipeRequest) (*apiv1.RunRecipeResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	// user defined Recipes are read from the database, which would fail
+	// before any of it's Queries are created.
+	if err := s.readOnlyError(); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.FromString(req.ID)
+	if err != nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Invalid UUID for Recipe ID"}
+	}
+
+	recipe, err := s.getRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := idn.ToASCII(apiv1.NormalizeName(req.Name))
+	if err != nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".name", Message: "Name of domain is invalid"}
+	}
+
+	// check the Query of every step can be created before creating any, so
+	// a Recipe is either run in full or not at all, unless the database
+	// fails partway.
+	queries := make([]*apiv1.CreateQueryRequest, 0, len(recipe.Steps))
+	for _, step := range recipe.Steps {
+		query := &apiv1.CreateQueryRequest{Type: step.Type, Name: name, Priority: req.Priority}
+		if step.Prefix != "" {
+			query.Name = step.Prefix + "." + name
+		}
+
+		if err := s.checkCreateQuery(query); err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, query)
+	}
+
+	run := &models.RecipeRun{
+		RecipeID:   recipe.ID,
+		RecipeName: recipe.Name,
+		Name:       name,
+		Steps:      recipe.Steps,
+	}
+
+	for _, query := range queries {
+		res, err := s.CreateQuery(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		run.QueryIDs = append(run.QueryIDs, res.Query.ID)
+	}
+
+	err = s.db.CreateRecipeRun(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.RunRecipeResponse{Run: run}, nil
+}
+
+func (s *Server) GetRecipeRun(
+	ctx context.Context, req *apiv1.GetRecipeRunRequest,
+) (*apiv1.GetRecipeRunResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.FromString(req.ID)
+	if err != nil {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Invalid UUID for Recipe run ID"}
+	}
+
+	run, err := s.db.GetRecipeRunByID(ctx, id)
+	if errors.Is(err, db.ErrRecipeRunNotFound) {
+		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Recipe run not found by ID"}
+	} else if err != nil && s.readOnly.Load() {
+		return nil, &apiv1.Error{
+			Code:    apiv1.ErrorCodeUnavailable,
+			Message: "DENNIS cannot reach it's database, this Recipe run can be viewed once it has recovered",
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	res := &apiv1.GetRecipeRunResponse{
+		Run:     run,
+		Queries: make([]*models.Query, 0, len(run.QueryIDs)),
+	}
+
+	// queries are indexed by step, nil if the Query has expired.
+	queries := make([]*models.Query, len(run.QueryIDs))
+
+	for i, queryID := range run.QueryIDs {
+		q, err := s.GetQuery(ctx, &apiv1.GetQueryRequest{ID: queryID.String()})
+		if err != nil {
+			// Queries may expire before the run that grouped them.
+			if err, ok := err.(*apiv1.Error); ok && err.Code == apiv1.ErrorCodeNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		queries[i] = q.Query
+		res.Queries = append(res.Queries, q.Query)
+	}
+
+	res.Report = evaluateRecipeRun(run, queries)
+
+	return res, nil
+}
+
+// evaluateRecipeRun evaluates the Assertions of each step of run against the
+// Query made for it, given in the same order as the steps. An Assertion passes
+// if every resolver returned a matching Record, it fails if any resolver
+// returned an error or no matching Record, if no resolvers were queried, if
+// the Query has expired, or if the Assertion is invalid. Each Assertion is
+// parsed once, rather than for every Lookup.
+func evaluateRecipeRun(run *models.RecipeRun, queries []*models.Query) *models.RecipeReport {
+	for _, q := range queries {
+		if q != nil && q.FinishedAt == nil {
+			return &models.RecipeReport{Status: models.RecipeReportPending, Assertions: []*models.AssertionResult{}}
+		}
+	}
+
+	report := &models.RecipeReport{Status: models.RecipeReportPassed, Assertions: []*models.AssertionResult{}}
+
+	for i, step := range run.Steps {
+		q := queries[i]
+
+		for _, assertion := range step.Assertions {
+			result := &models.AssertionResult{Type: step.Type, Name: run.Name, Assertion: assertion}
+			if step.Prefix != "" {
+				result.Name = step.Prefix + "." + run.Name
+			}
+
+			var failed []string
+
+			// Assertions were validated when their Recipe was created, so
+			// are only invalid if stored by an older version of DENNIS.
+			match, err := assertion.Compile()
+
+			if q == nil {
+				result.Message = "Query has expired"
+			} else if err != nil {
+				result.Message = "Assertion is invalid: " + err.Error()
+			} else {
+				for _, lookup := range q.Lookups {
+					if lookup.Error != nil || !match(lookup.Records) {
+						failed = append(failed, lookup.Resolver)
+					}
+				}
+
+				if len(failed) > 0 {
+					result.Message = "Not matched by " + strings.Join(failed, ", ")
+				} else if len(q.Lookups) < 1 {
+					result.Message = "No resolvers were queried"
+				}
+			}
+
+			result.Passed = result.Message == ""
+			if !result.Passed {
+				report.Status = models.RecipeReportFailed
+			}
+
+			report.Assertions = append(report.Assertions, result)
+		}
+	}
+
+	return report
+}
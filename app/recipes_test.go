@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+)
+
+func TestRunRecipe(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, _ := newTestServer(t)
+	defer s.Close()
+
+	// a zone transfer needs a server to transfer from, so the final step
+	// cannot be created.
+	recipe := &models.Recipe{
+		Name:  "Transfer",
+		Steps: []*models.RecipeStep{{Type: "A"}, {Type: "NS"}, {Type: "AXFR"}},
+	}
+	must(t, d.CreateRecipe(ctx, recipe))
+
+	_, err := s.RunRecipe(ctx, &apiv1.RunRecipeRequest{ID: recipe.ID.String(), Name: "example.com"})
+
+	var apiErr *apiv1.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apiv1.ErrorCodeBadRequest {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+
+	if n := s.created.Load(); n != 0 {
+		t.Fatalf("expected no queries to be created, got %d", n)
+	}
+
+	res, err := s.RunRecipe(ctx, &apiv1.RunRecipeRequest{
+		ID: builtInRecipeID("web-go-live").String(), Name: "Example.COM.",
+	})
+	must(t, err)
+
+	if n := len(res.Run.QueryIDs); n != 5 {
+		t.Errorf("expected 5 queries to be created, got %d", n)
+	}
+
+	// the run is named as it's Queries were resolved, so assertions are
+	// reported against the names queried.
+	if res.Run.Name != "example.com" {
+		t.Errorf("expected run to be named example.com, got %q", res.Run.Name)
+	}
+
+	s.SetMaintenance(true)
+
+	_, err = s.RunRecipe(ctx, &apiv1.RunRecipeRequest{ID: builtInRecipeID("web-go-live").String(), Name: "example.org"})
+	if !errors.As(err, &apiErr) || apiErr.Code != apiv1.ErrorCodeUnavailable {
+		t.Fatalf("expected unavailable error in maintenance mode, got %v", err)
+	}
+
+	if n := s.created.Load(); n != 5 {
+		t.Errorf("expected no more queries to be created in maintenance mode, got %d", n-5)
+	}
+}
+
+func TestEvaluateRecipeRun(t *testing.T) {
+	finished := new(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	run := &models.RecipeRun{
+		Name: "example.com",
+		Steps: []*models.RecipeStep{
+			{Type: "A", Assertions: []*models.Assertion{{Kind: models.AssertionCIDR, Value: "192.0.2.0/24"}}},
+			{
+				Type: "CNAME", Prefix: "www",
+				Assertions: []*models.Assertion{{Kind: models.AssertionExact, Value: "example.com."}},
+			},
+		},
+	}
+
+	lookup := func(resolver string, content ...string) *models.Lookup {
+		l := &models.Lookup{Resolver: resolver}
+		for _, c := range content {
+			l.Records = append(l.Records, &models.Record{Content: []string{c}})
+		}
+
+		return l
+	}
+
+	query := func(lookups ...*models.Lookup) *models.Query {
+		return &models.Query{FinishedAt: finished, Lookups: lookups}
+	}
+
+	passing := []*models.Query{
+		query(lookup("a", "192.0.2.1"), lookup("b", "192.0.2.2")),
+		query(lookup("a", "example.com.")),
+	}
+
+	tests := []struct {
+		name     string
+		run      *models.RecipeRun
+		queries  []*models.Query
+		status   string
+		messages []string
+	}{
+		{"Passed", run, passing, models.RecipeReportPassed, []string{"", ""}},
+		{
+			"NotMatched", run,
+			[]*models.Query{query(lookup("a", "192.0.2.1"), lookup("b", "203.0.113.1")), passing[1]},
+			models.RecipeReportFailed, []string{"Not matched by b", ""},
+		},
+		{
+			"LookupError", run,
+			[]*models.Query{query(lookup("a", "192.0.2.1"), &models.Lookup{Resolver: "b", Error: new("SERVFAIL")}), passing[1]},
+			models.RecipeReportFailed, []string{"Not matched by b", ""},
+		},
+		{"Pending", run, []*models.Query{passing[0], {Lookups: []*models.Lookup{}}}, models.RecipeReportPending, nil},
+		{"Expired", run, []*models.Query{passing[0], nil}, models.RecipeReportFailed, []string{"", "Query has expired"}},
+		{
+			"NoLookups", run, []*models.Query{passing[0], query()}, models.RecipeReportFailed,
+			[]string{"", "No resolvers were queried"},
+		},
+		{
+			"Invalid",
+			&models.RecipeRun{Name: "example.com", Steps: []*models.RecipeStep{
+				{Type: "A", Assertions: []*models.Assertion{{Kind: models.AssertionRegex, Value: "("}}},
+			}},
+			passing[:1],
+			models.RecipeReportFailed, []string{"Assertion is invalid: error parsing regexp: missing closing ): `(`"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := evaluateRecipeRun(test.run, test.queries)
+
+			if report.Status != test.status {
+				t.Errorf("expected status %q, got %q", test.status, report.Status)
+			}
+
+			if len(report.Assertions) != len(test.messages) {
+				t.Fatalf("expected %d assertion results, got %d", len(test.messages), len(report.Assertions))
+			}
+
+			for i, result := range report.Assertions {
+				if result.Message != test.messages[i] {
+					t.Errorf("%d: expected message %q, got %q", i, test.messages[i], result.Message)
+				}
+
+				if result.Passed != (test.messages[i] == "") {
+					t.Errorf("%d: expected passed %t, got %t", i, test.messages[i] == "", result.Passed)
+				}
+			}
+		})
+	}
+
+	// the name of a step with a prefix is the name it queried.
+	report := evaluateRecipeRun(run, passing)
+	if name := report.Assertions[1].Name; name != "www.example.com" {
+		t.Errorf("expected name www.example.com, got %q", name)
+	}
+}
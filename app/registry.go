@@ -0,0 +1,116 @@
+package app
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/jamescun/dennis/app/config"
+)
+
+// registry is the set of resolvers Queries are resolved against, from each of
+// their sources, which may change while Queries are being resolved. It is
+// safe for concurrent use.
+type registry struct {
+	mu sync.RWMutex
+
+	// all are every resolver in the order they are displayed, and enabled
+	// those that have not been disabled. Each is replaced rather than
+	// modified, so may be read without mu once retrieved.
+	all     []*resolver
+	enabled []*resolver
+
+	// configured are the resolvers from the configuration file, and configs
+	// their configuration, so they are kept if it is reloaded unchanged.
+	// discovered are those found by each discovery source, by name.
+	configured []*resolver
+	configs    []*config.Resolver
+	discovered map[string][]*resolver
+
+	// imported are the resolvers imported from a resolv.conf, and added
+	// those added individually, through the admin interface. imports and
+	// additions are their configuration, recorded as ResolverChanges.
+	imported  []*resolver
+	imports   []*config.Resolver
+	added     []*resolver
+	additions []*config.Resolver
+
+	// disabled are the names of the resolvers disabled through the admin
+	// interface, which are kept but not resolved against. A discovered
+	// resolver remains disabled if it is discovered again.
+	disabled map[string]bool
+}
+
+func newRegistry() *registry {
+	return &registry{
+		discovered: make(map[string][]*resolver),
+		disabled:   make(map[string]bool),
+	}
+}
+
+// resolvers returns the enabled resolvers, in the order they are displayed.
+// The slice returned must not be modified.
+func (r *registry) resolvers() []*resolver {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.enabled
+}
+
+// list returns every resolver, in the order they are displayed, and the
+// names of those disabled. Neither may be modified.
+func (r *registry) list() ([]*resolver, map[string]bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.all, maps.Clone(r.disabled)
+}
+
+// get returns the resolver named name, if there is one.
+func (r *registry) get(name string) *resolver {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := slices.IndexFunc(r.all, func(rsv *resolver) bool {
+		return rsv.name == name
+	})
+	if i < 0 {
+		return nil
+	}
+
+	return r.all[i]
+}
+
+// update calls fn with the registry locked to modify it's resolvers, and
+// then orders them again. Queries already being resolved continue with the
+// resolvers they started with.
+func (r *registry) update(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn()
+
+	all := slices.Concat(r.configured, r.imported, r.added)
+
+	// discovered resolvers follow the others, in order of the name of their
+	// source.
+	for _, name := range slices.Sorted(maps.Keys(r.discovered)) {
+		all = append(all, r.discovered[name]...)
+	}
+
+	// resolvers are kept in the order they should be displayed, those of
+	// equal order remain in the order they were configured.
+	slices.SortStableFunc(all, func(a, b *resolver) int {
+		return cmp.Compare(a.order, b.order)
+	})
+
+	enabled := make([]*resolver, 0, len(all))
+	for _, rsv := range all {
+		if !r.disabled[rsv.name] {
+			enabled = append(enabled, rsv)
+		}
+	}
+
+	r.all, r.enabled = all, enabled
+}
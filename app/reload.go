@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/jamescun/dennis/app/config"
+)
+
+// ReloadResolvers replaces the Resolvers from the configuration file with
+// rsv, such as when it has been changed by updating a Kubernetes ConfigMap,
+// recording the changes made by actor. Resolvers whose configuration has not
+// changed are kept, along with their health and capabilities. Queries already
+// being resolved continue with the resolvers they started with, except those
+// implemented by a plugin that has been removed or changed, as it's process
+// is stopped.
+//
+// Nothing is changed if a Resolver in rsv has the name of one added or
+// imported through the admin interface, as it must be removed first.
+func (s *Server) ReloadResolvers(ctx context.Context, rsv []*config.Resolver, actor string) error {
+	var conflict string
+	var replaced []*resolver
+
+	s.registry.update(func() {
+		for _, r := range rsv {
+			if s.registry.managed(r.Name) {
+				conflict = r.Name
+				return
+			}
+		}
+
+		configured := make([]*resolver, 0, len(rsv))
+
+		for _, r := range rsv {
+			i := slices.IndexFunc(s.registry.configs, func(c *config.Resolver) bool {
+				return reflect.DeepEqual(c, r)
+			})
+			if i >= 0 {
+				configured = append(configured, s.registry.configured[i])
+			} else {
+				configured = append(configured, s.newResolver(r, ResolverChangeSourceConfig))
+			}
+		}
+
+		for _, rsv := range s.registry.configured {
+			if !slices.Contains(configured, rsv) {
+				replaced = append(replaced, rsv)
+			}
+		}
+
+		s.registry.configured, s.registry.configs = configured, rsv
+	})
+
+	if conflict != "" {
+		return fmt.Errorf("resolver %q was added through the admin interface, remove it first", conflict)
+	}
+
+	// plugins of resolvers that are no longer configured would otherwise
+	// keep running until DENNIS exits.
+	for _, rsv := range replaced {
+		if c, ok := rsv.client.(io.Closer); ok {
+			c.Close()
+		}
+	}
+
+	// probing continues after the resolvers have been reloaded, so must not
+	// be canceled with them.
+	go s.ProbeResolvers(context.WithoutCancel(ctx))
+
+	return s.RecordResolverChanges(ctx, rsv, ResolverChangeSourceConfig, actor)
+}
+
+// managed returns true if a resolver added or imported through the admin
+// interface is named name. It must be called with mu held.
+func (r *registry) managed(name string) bool {
+	isNamed := func(rsv *resolver) bool { return rsv.name == name }
+
+	return slices.ContainsFunc(r.added, isNamed) || slices.ContainsFunc(r.imported, isNamed)
+}
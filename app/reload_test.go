@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/models"
+)
+
+func TestReloadResolvers(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, _ := newTestServer(t)
+
+	kept := &config.Resolver{Name: "kept", Addr: "192.0.2.1"}
+	changed := &config.Resolver{Name: "changed", Addr: "192.0.2.2"}
+	removed := &config.Resolver{Name: "removed", Addr: "192.0.2.3"}
+
+	must(t, s.ReloadResolvers(ctx, []*config.Resolver{kept, changed, removed}, "test"))
+
+	before := s.registry.get("kept")
+
+	reloaded := []*config.Resolver{
+		{Name: "kept", Addr: "192.0.2.1"},
+		{Name: "changed", Addr: "192.0.2.4"},
+		{Name: "added", Addr: "192.0.2.5"},
+	}
+
+	must(t, s.ReloadResolvers(ctx, reloaded, "test"))
+
+	var names []string
+	for _, rsv := range s.resolvers() {
+		names = append(names, rsv.name+"="+rsv.addr)
+	}
+
+	want := []string{"kept=192.0.2.1:53", "changed=192.0.2.4:53", "added=192.0.2.5:53"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected resolvers %v, got %v", want, names)
+	}
+
+	if s.registry.get("kept") != before {
+		t.Errorf("expected unchanged resolver to be kept")
+	}
+
+	changes, err := d.ListResolverChanges(ctx)
+	must(t, err)
+
+	var recorded []string
+	for _, change := range changes[3:] {
+		recorded = append(recorded, change.Resolver+" "+change.Change)
+	}
+
+	wantChanges := []string{
+		"changed " + models.ResolverModified,
+		"added " + models.ResolverAdded,
+		"removed " + models.ResolverRemoved,
+	}
+	if !slices.Equal(recorded, wantChanges) {
+		t.Errorf("expected changes %v, got %v", wantChanges, recorded)
+	}
+
+	// a resolver added through the admin interface must be removed before
+	// one of the same name can be configured.
+	must(t, s.AddResolver(ctx, &config.Resolver{Name: "admin", Addr: "192.0.2.6"}, "test"))
+
+	err = s.ReloadResolvers(ctx, append(reloaded, &config.Resolver{Name: "admin", Addr: "192.0.2.7"}), "test")
+	if err == nil {
+		t.Fatal("expected conflicting resolver to be rejected")
+	}
+
+	if rsv := s.registry.get("admin"); rsv == nil || rsv.source != ResolverChangeSourceAdmin {
+		t.Errorf("expected added resolver to be kept, got %v", rsv)
+	}
+}
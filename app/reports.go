@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jamescun/dennis/app/db"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
+)
+
+const (
+	// reportInterval is how often reports are regenerated.
+	reportInterval = time.Hour
+
+	// reportWindow is the period of recent Queries covered by each report.
+	reportWindow = 24 * time.Hour
+
+	// reportNames is the number of most queried names kept in a
+	// DuplicateReport.
+	reportNames = 50
+)
+
+// Reports periodically aggregates recent Queries in the database into reports
+// for operators, such as which names are queried repeatedly. Aggregation can
+// be expensive, so reports are generated in the background and stored in the
+// database rather than on request.
+type Reports struct {
+	db    db.DB
+	log   *slog.Logger
+	clock clock.Clock
+}
+
+// NewReports initializes Reports for the Queries in a database.
+func NewReports(db db.DB, log *slog.Logger) *Reports {
+	return &Reports{db: db, log: log, clock: clock.Real}
+}
+
+// SetClock replaces the Clock used to tell the window of recent Queries each
+// report covers. It must be called before Run.
+func (r *Reports) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Run generates reports immediately, then every reportInterval until ctx is
+// canceled.
+func (r *Reports) Run(ctx context.Context) {
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	for {
+		err := r.generate(ctx)
+		if err != nil {
+			r.log.Error("could not generate reports", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// generate aggregates Queries from the last reportWindow into a
+// DuplicateReport and stores it.
+func (r *Reports) generate(ctx context.Context) error {
+	now := r.clock.Now().UTC()
+	since := now.Add(-reportWindow)
+
+	counts, err := r.db.CountQueries(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	report := &models.DuplicateReport{
+		Since:     since,
+		Names:     counts[:min(len(counts), reportNames)],
+		CreatedAt: now,
+	}
+
+	// every Query for a name beyond the first is a duplicate that could have
+	// been served from a cache.
+	for _, c := range counts {
+		report.Queries += c.Count
+		report.Duplicates += c.Count - 1
+	}
+
+	return r.db.PutDuplicateReport(ctx, report)
+}
+
+// GetDuplicateReport retrieves the most recently generated DuplicateReport.
+func (r *Reports) GetDuplicateReport(ctx context.Context) (*models.DuplicateReport, error) {
+	return r.db.GetDuplicateReport(ctx)
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/models"
+)
+
+// ResolverChangeSourceConfig is the Source of ResolverChanges recorded when
+// DENNIS starts with, or reloads, a changed configuration file.
+const ResolverChangeSourceConfig = "config"
+
+// RecordResolverChanges compares the configured Resolvers rsv against those
+// recorded by previous ResolverChanges from the same source, and records a
+// ResolverChange for each Resolver that has been added, removed or modified
+// since, made by actor from source. Resolvers are identified by name, so a
+// renamed Resolver is recorded as removed and added.
+func (s *Server) RecordResolverChanges(ctx context.Context, rsv []*config.Resolver, source, actor string) error {
+	changes, err := s.db.ListResolverChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	// replay the recorded changes to find the last recorded configuration of
+	// each Resolver, if it has not since been removed. Disabling a Resolver
+	// does not change it's configuration.
+	previous := make(map[string]json.RawMessage)
+	for _, change := range changes {
+		if change.Source != source {
+			continue
+		} else if change.Change == models.ResolverDisabled || change.Change == models.ResolverEnabled {
+			continue
+		} else if change.After == nil {
+			delete(previous, change.Resolver)
+		} else {
+			previous[change.Resolver] = change.After
+		}
+	}
+
+	var pending []*models.ResolverChange
+
+	for _, r := range rsv {
+		after, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("could not encode resolver %q: %w", r.Name, err)
+		}
+
+		before, ok := previous[r.Name]
+		delete(previous, r.Name)
+
+		switch {
+		case !ok:
+			pending = append(pending, &models.ResolverChange{Resolver: r.Name, Change: models.ResolverAdded, After: after})
+
+		case !equalJSON(before, after):
+			pending = append(pending, &models.ResolverChange{
+				Resolver: r.Name,
+				Change:   models.ResolverModified,
+				Before:   before,
+				After:    after,
+			})
+		}
+	}
+
+	// any Resolvers remaining are no longer configured, they are removed in
+	// order of name so the log is stable.
+	removed := make([]string, 0, len(previous))
+	for name := range previous {
+		removed = append(removed, name)
+	}
+
+	slices.Sort(removed)
+
+	for _, name := range removed {
+		pending = append(pending, &models.ResolverChange{
+			Resolver: name,
+			Change:   models.ResolverRemoved,
+			Before:   previous[name],
+		})
+	}
+
+	for _, change := range pending {
+		change.Source = source
+		change.Actor = actor
+
+		err := s.db.CreateResolverChange(ctx, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// equalJSON returns true if a and b encode the same JSON value, regardless of
+// formatting or the order of object keys, as databases may not store JSON as
+// it was given.
+func equalJSON(a, b json.RawMessage) bool {
+	var av, bv any
+
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+// ListResolverChanges returns every recorded ResolverChange, most recent
+// first.
+func (s *Server) ListResolverChanges(ctx context.Context) ([]*models.ResolverChange, error) {
+	changes, err := s.db.ListResolverChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(changes)
+
+	return changes, nil
+}
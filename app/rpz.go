@@ -0,0 +1,52 @@
+package app
+
+import (
+	"slices"
+	"strings"
+
+	"codeberg.org/miekg/dns"
+)
+
+// rpzPolicy inspects a response from a resolver that applies Response Policy
+// Zones (RPZ) for signs that it was rewritten by a policy rather than being
+// the upstream answer, returning a description of the policy or nil if there
+// are none.
+//
+// RPZ resolvers commonly include the SOA of the policy zone in the authority
+// or additional section of a rewritten response, and by convention policy
+// zones are named with an `rpz` label. Answers pointing at unspecified or
+// loopback addresses are also a common sinkhole pattern.
+func rpzPolicy(res *dns.Msg) *string {
+	for _, rr := range slices.Concat(res.Ns, res.Extra) {
+		if soa, ok := rr.(*dns.SOA); ok && isRPZZone(soa.Hdr.Name) {
+			return new("Rewritten by response policy zone " + soa.Hdr.Name)
+		}
+	}
+
+	for _, rr := range res.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			if rr.A.Addr.IsUnspecified() || rr.A.Addr.IsLoopback() {
+				return new("Answer is a sinkhole address")
+			}
+		case *dns.AAAA:
+			if rr.AAAA.Addr.IsUnspecified() || rr.AAAA.Addr.IsLoopback() {
+				return new("Answer is a sinkhole address")
+			}
+		}
+	}
+
+	return nil
+}
+
+// isRPZZone returns true if name contains a label beginning or ending with
+// `rpz`, such as `rpz.example.net.` or `malware-rpz.example.`.
+func isRPZZone(name string) bool {
+	for label := range strings.SplitSeq(strings.ToLower(name), ".") {
+		if strings.HasPrefix(label, "rpz") || strings.HasSuffix(label, "rpz") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/pkg/clock"
+)
+
+// schedule runs functions at a specific time in the future, such as queuing a
+// Query scheduled by the user.
+type schedule struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	stopped bool
+	timers  map[clock.Timer]struct{}
+}
+
+func newSchedule(clk clock.Clock) *schedule {
+	return &schedule{clock: clk, timers: make(map[clock.Timer]struct{})}
+}
+
+// add schedules fn to be called at the time at. If at is in the past, fn is
+// called as soon as possible. If the schedule has been stopped, fn is never
+// called.
+func (s *schedule) add(at time.Time, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	var t clock.Timer
+	t = s.clock.AfterFunc(s.clock.Until(at), func() {
+		s.mu.Lock()
+		delete(s.timers, t)
+		s.mu.Unlock()
+
+		fn()
+	})
+
+	s.timers[t] = struct{}{}
+}
+
+// len returns the number of functions waiting to be called.
+func (s *schedule) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.timers)
+}
+
+// stop cancels all functions that have not yet been called, and prevents any
+// more being added.
+func (s *schedule) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+
+	for t := range s.timers {
+		t.Stop()
+	}
+
+	clear(s.timers)
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jamescun/dennis/app/db/file"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
+
+	"github.com/gofrs/uuid"
+)
+
+// waitFor fails the test if fn does not return true within a few seconds, as
+// functions called by a schedule run in their own goroutine.
+func waitFor(t *testing.T, msg string, fn func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", msg)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduleAdd(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newSchedule(c)
+
+	called := make(chan string, 2)
+
+	s.add(c.Now().Add(time.Hour), func() { called <- "later" })
+	s.add(c.Now().Add(time.Minute), func() { called <- "sooner" })
+
+	if n := s.len(); n != 2 {
+		t.Fatalf("expected 2 functions waiting, got %d", n)
+	}
+
+	c.Advance(59 * time.Second)
+
+	select {
+	case name := <-called:
+		t.Fatalf("expected no function to be called yet, %q was", name)
+	default:
+	}
+
+	c.Advance(time.Second)
+
+	if name := <-called; name != "sooner" {
+		t.Fatalf("expected sooner to be called, got %q", name)
+	}
+
+	waitFor(t, "sooner to be removed", func() bool { return s.len() == 1 })
+
+	c.Advance(time.Hour)
+
+	if name := <-called; name != "later" {
+		t.Fatalf("expected later to be called, got %q", name)
+	}
+}
+
+func TestScheduleAddPast(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newSchedule(c)
+
+	called := make(chan struct{})
+
+	// a time in the past is called without advancing the clock.
+	s.add(c.Now().Add(-time.Hour), func() { close(called) })
+
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected function in the past to be called")
+	}
+}
+
+func TestScheduleStop(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newSchedule(c)
+
+	called := make(chan struct{}, 2)
+
+	s.add(c.Now().Add(time.Minute), func() { called <- struct{}{} })
+	s.stop()
+
+	// functions added once stopped are never called.
+	s.add(c.Now().Add(time.Minute), func() { called <- struct{}{} })
+
+	if n := s.len(); n != 0 {
+		t.Errorf("expected no functions waiting once stopped, got %d", n)
+	}
+
+	if n := c.Waiting(); n != 0 {
+		t.Errorf("expected timers to be stopped, %d are waiting", n)
+	}
+
+	c.Advance(time.Hour)
+
+	select {
+	case <-called:
+		t.Fatal("expected no function to be called once stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// finished returns a function reporting whether the Query id has finished.
+func finished(t *testing.T, d *file.DB, id uuid.UUID) func() bool {
+	return func() bool {
+		q, err := d.GetQueryByID(context.Background(), id)
+		must(t, err)
+
+		return q.FinishedAt != nil
+	}
+}
+
+func TestScheduleQueryPast(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, c := newTestServer(t)
+	defer s.Close()
+
+	q := &models.Query{Type: "A", Name: "example.com", RunAt: new(c.Now().Add(-time.Minute))}
+	must(t, d.CreateQuery(ctx, q))
+
+	// a Query that became due while DENNIS was stopped is resolved
+	// immediately.
+	s.scheduleQuery(q)
+
+	waitFor(t, "query to be resolved", finished(t, d, q.ID))
+}
+
+func TestLoadScheduled(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, c := newTestServer(t)
+	defer s.Close()
+
+	due := &models.Query{Type: "A", Name: "example.com", RunAt: new(c.Now().Add(-time.Minute))}
+	must(t, d.CreateQuery(ctx, due))
+
+	later := &models.Query{Type: "A", Name: "example.org", RunAt: new(c.Now().Add(time.Hour))}
+	must(t, d.CreateQuery(ctx, later))
+
+	// queued by this instance before it stopped, so left to RecoverJobs.
+	queued := &models.Query{Type: "A", Name: "example.net", RunAt: new(c.Now().Add(-time.Minute))}
+	must(t, d.CreateQuery(ctx, queued))
+	must(t, d.PutJob(ctx, &models.Job{QueryID: queued.ID, CreatedAt: c.Now()}))
+
+	must(t, s.LoadScheduled(ctx))
+
+	waitFor(t, "due query to be resolved", finished(t, d, due.ID))
+
+	if finished(t, d, later.ID)() {
+		t.Fatal("expected query to not be resolved before it is due")
+	}
+
+	if n := s.schedule.len(); n != 1 {
+		t.Fatalf("expected 1 query to be scheduled, got %d", n)
+	}
+
+	c.Advance(time.Hour)
+
+	waitFor(t, "later query to be resolved", finished(t, d, later.ID))
+
+	if finished(t, d, queued.ID)() {
+		t.Error("expected query with a job to be left to RecoverJobs")
+	}
+}
@@ -1,18 +1,31 @@
 package app
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net"
+	"net/netip"
+	"slices"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	apiv1 "github.com/jamescun/dennis/api/v1"
 	"github.com/jamescun/dennis/app/config"
 	"github.com/jamescun/dennis/app/db"
 	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/build"
+	"github.com/jamescun/dennis/app/pkg/clock"
+	"github.com/jamescun/dennis/app/pkg/idn"
+	"github.com/jamescun/dennis/app/pkg/plugin"
 
 	"codeberg.org/miekg/dns"
 	"github.com/gofrs/uuid"
@@ -21,58 +34,527 @@ import (
 // Server is an implementation of api/v1/apiv1.API backed by the database. It is
 // consumed by both the API and Web interfaces.
 type Server struct {
-	db  db.DB
-	rsv []*resolver
-	wg  *sync.WaitGroup
-	log *slog.Logger
+	db       db.DB
+	features apiv1.Features
+	queue    *queue
+	schedule *schedule
+	wg       *sync.WaitGroup
+	log      *slog.Logger
+
+	// registry are the resolvers Queries are resolved against, from each of
+	// their sources.
+	registry *registry
+
+	// client is the DNS client shared by resolvers not implemented by a
+	// plugin, nor bound to a source address or proxied.
+	client *dns.Client
+
+	// proxy is the URL of the proxy lookups to resolvers are sent through,
+	// unless a resolver configures it's own, empty if they are sent directly.
+	proxy string
+
+	// workers is the number of Queries resolved concurrently.
+	workers int
+
+	// maxSamples is the maximum number of times a Query may ask for each
+	// resolver to be queried.
+	maxSamples int
+
+	// maxRecords is the maximum number of Records stored for each Lookup.
+	maxRecords int
+
+	// transferTimeout is the longest a zone transfer may take.
+	transferTimeout time.Duration
+
+	// maxTransferRecords is the maximum number of Records stored for a zone
+	// transfer.
+	maxTransferRecords int
+
+	// allowInternalNames permits Queries for single-label names.
+	allowInternalNames bool
+
+	// edns0 is the EDNS0 OPT record sent with every lookup, extended by the
+	// EDNS0 of each Query.
+	edns0 models.EDNS0
+
+	// created counts the Queries created since the Server started.
+	created atomic.Int64
+
+	// resolving counts the Queries currently being resolved by workers.
+	resolving atomic.Int64
+
+	// maintenance is set when the Server is in maintenance mode, and will
+	// not create new Queries.
+	maintenance atomic.Bool
+
+	// readOnly is set by WatchDB while the database cannot be reached, when
+	// new Queries cannot be created.
+	readOnly atomic.Bool
+
+	// cache holds recently viewed finished Queries, which can still be
+	// served while the database cannot be reached.
+	cache *queryCache
+
+	// queryMaxAge is how long Queries are kept before they expire, or
+	// forever if zero.
+	queryMaxAge time.Duration
+
+	// captures are the PacketCaptures of Queries waiting to be resolved, by
+	// the ID of the Query.
+	captures sync.Map
+
+	// jobs are the Jobs of Queries waiting to be resolved, or being
+	// resolved, by the ID of the Query.
+	jobs sync.Map
+
+	// dispatcher distributes Queries between every instance sharing the
+	// database, if set, until stopDispatch is called. consumer identifies
+	// this Server to the dispatcher.
+	dispatcher   db.Dispatcher
+	consumer     string
+	stopDispatch context.CancelFunc
+
+	// clock tells the time Queries are created, scheduled and resolved.
+	clock clock.Clock
+
+	// instanceID identifies this Server among replicas on it's Lookups.
+	instanceID string
+
+	// location is where this Server resolves from, stored on it's Lookups.
+	location string
+
+	// agents are the remote agents that also resolve each Query, if any may
+	// register.
+	agents *Agents
+}
+
+// Stats are counters describing the activity of a Server since it started.
+type Stats struct {
+	// Queries is the number of Queries created.
+	Queries int64 `json:"queries"`
 }
 
 type resolver struct {
-	name   string
-	addr   string
-	client interface {
+	// source is where the resolver came from, one of the sources of
+	// ResolverChanges, such as `config`.
+	source string
+
+	name        string
+	description string
+	icon        string
+	order       int
+	groups      []string
+	rpz         bool
+	dnssec      bool
+	addr        string
+	sourceAddr  netip.Addr
+	tcpOnly     bool
+	retries     int
+	interval    time.Duration
+	client      interface {
 		Exchange(ctx context.Context, msg *dns.Msg, network, address string) (*dns.Msg, time.Duration, error)
 	}
+
+	// capabilities are set once the resolver has been probed.
+	capabilities atomic.Pointer[models.ResolverCapabilities]
+
+	// nxdomain is set while the resolver is redirecting NXDOMAIN.
+	nxdomain atomic.Pointer[models.NXDOMAINRedirect]
+
+	// health is set once the resolver has been health checked, summarizing
+	// the most recent checks.
+	health atomic.Pointer[models.ResolverHealth]
+	checks []healthCheck
+
+	// fingerprint is the most recent fingerprint of the resolver, once it
+	// has been taken or loaded from the database.
+	fingerprint atomic.Pointer[models.ResolverFingerprint]
+}
+
+// newResolver initializes a resolver from it's configuration, from source.
+func (s *Server) newResolver(r *config.Resolver, source string) *resolver {
+	port := "53"
+	if r.Port > 0 {
+		port = strconv.Itoa(r.Port)
+	}
+
+	rsv := &resolver{
+		source:      source,
+		name:        r.Name,
+		description: r.Description,
+		icon:        r.Icon,
+		order:       r.Order,
+		groups:      r.Groups,
+		rpz:         r.RPZ,
+		dnssec:      r.DNSSEC,
+		addr:        net.JoinHostPort(r.Addr, port),
+		retries:     r.Retries,
+		interval:    defaultRetryInterval,
+		client:      s.client,
+	}
+
+	if r.RetryInterval > 0 {
+		rsv.interval = time.Duration(r.RetryInterval) * time.Millisecond
+	}
+
+	// resolvers sending from a specific address or interface have their own
+	// client, as the dialer of the shared client is shared by every resolver.
+	forward := new(net.Dialer)
+	if r.SourceAddr != "" || r.Interface != "" {
+		rsv.sourceAddr, _ = netip.ParseAddr(r.SourceAddr)
+
+		c := newBoundClient(rsv.sourceAddr, r.Interface)
+		rsv.client, forward = c, c.tcp.Dialer
+	}
+
+	proxyURL := cmp.Or(r.Proxy, s.proxy)
+	if proxyURL != "" && r.Plugin == nil {
+		c, err := newProxyClient(proxyURL, forward)
+		if err != nil {
+			s.log.Error(
+				"could not configure proxy of resolver",
+				slog.String("resolver", r.Name), slog.String("error", err.Error()),
+			)
+		} else {
+			rsv.client, rsv.tcpOnly = c, true
+		}
+	}
+
+	if r.Plugin != nil {
+		rsv.addr = r.Addr
+		rsv.client = plugin.NewTransport(r.Plugin.Command, r.Plugin.Args)
+	}
+
+	return rsv
 }
 
+// adHocResolver returns a resolver for addr, a server given by a Query rather
+// than configured, named after it's address prefixed by `@` as with dig.
+func (s *Server) adHocResolver(addr string) *resolver {
+	return &resolver{
+		name:     "@" + addr,
+		addr:     addr,
+		interval: defaultRetryInterval,
+		client:   s.client,
+	}
+}
+
+// inGroups returns true if the resolver is tagged with any of groups, or if
+// no groups are given.
+func (r *resolver) inGroups(groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+
+	for _, group := range groups {
+		if slices.Contains(r.groups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvers returns the enabled resolvers, in the order they are displayed.
+// The slice returned must not be modified.
+func (s *Server) resolvers() []*resolver {
+	return s.registry.resolvers()
+}
+
+// defaultWorkers is the number of Queries resolved concurrently if not
+// configured by config.Resolution.
+const defaultWorkers = 16
+
+// defaultUDPSize is the EDNS0 UDP buffer size advertised to resolvers if not
+// configured by config.Resolution. 1232 bytes avoids IP fragmentation on most
+// networks, see https://www.dnsflagday.net/2020/.
+const defaultUDPSize = 1232
+
+// defaultRetryInterval is how long to wait between retries of a lookup if not
+// configured by config.Resolver.
+const defaultRetryInterval = 100 * time.Millisecond
+
+// defaultMaxSamples is the maximum number of times a Query may ask for each
+// resolver to be queried if not configured by config.Resolution.
+const defaultMaxSamples = 10
+
+// defaultMaxRecords is the maximum number of Records stored for each Lookup if
+// not configured by config.Resolution.
+const defaultMaxRecords = 1000
+
 // NewServer initializes a new Server implementation of api/v1/apiv1.API backed
-// by the given database. log is the destination for error messages generated
-// by the asynchronous resolution process.
-func NewServer(db db.DB, rsv []*config.Resolver, log *slog.Logger) *Server {
+// by the given database. features are the optional features enabled in this
+// deployment. log is the destination for error messages generated by the
+// asynchronous resolution process.
+func NewServer(
+	db db.DB, rsv []*config.Resolver, res config.Resolution, features apiv1.Features, log *slog.Logger,
+) *Server {
 	s := &Server{
-		db:  db,
-		wg:  new(sync.WaitGroup),
-		log: log,
+		db:       db,
+		features: features,
+		queue:    newQueue(),
+		schedule: newSchedule(clock.Real),
+		cache:    newQueryCache(queryCacheSize),
+		wg:       new(sync.WaitGroup),
+		log:      log,
+		clock:    clock.Real,
+
+		registry: newRegistry(),
+		client:   new(dns.Client),
+		proxy:    res.Proxy,
+	}
+
+	s.registry.update(func() {
+		for _, r := range rsv {
+			s.registry.configured = append(s.registry.configured, s.newResolver(r, ResolverChangeSourceConfig))
+		}
+
+		s.registry.configs = rsv
+	})
+
+	s.workers = defaultWorkers
+	if res.Workers > 0 {
+		s.workers = res.Workers
 	}
 
-	client := new(dns.Client)
+	s.maxSamples = defaultMaxSamples
+	if res.MaxSamples > 0 {
+		s.maxSamples = res.MaxSamples
+	}
+
+	s.maxRecords = defaultMaxRecords
+	if res.MaxRecords > 0 {
+		s.maxRecords = res.MaxRecords
+	}
 
-	for _, r := range rsv {
-		port := "53"
-		if r.Port > 0 {
-			port = strconv.Itoa(r.Port)
+	s.allowInternalNames = res.AllowInternalNames
+
+	s.transferTimeout = defaultTransferTimeout
+	if res.TransferTimeout > 0 {
+		s.transferTimeout = time.Duration(res.TransferTimeout) * time.Second
+	}
+
+	s.maxTransferRecords = defaultMaxTransferRecords
+	if res.MaxTransferRecords > 0 {
+		s.maxTransferRecords = res.MaxTransferRecords
+	}
+
+	s.edns0.UDPSize = defaultUDPSize
+	if res.EDNS0 != nil {
+		if res.EDNS0.UDPSize > 0 {
+			s.edns0.UDPSize = res.EDNS0.UDPSize
 		}
 
-		s.rsv = append(s.rsv, &resolver{
-			name:   r.Name,
-			addr:   net.JoinHostPort(r.Addr, port),
-			client: client,
-		})
+		for _, option := range res.EDNS0.Options {
+			s.edns0.Options = append(s.edns0.Options, &models.EDNS0Option{Code: option.Code, Data: option.Data})
+		}
+	}
+
+	for range s.workers {
+		s.wg.Add(1)
+		go s.worker()
 	}
 
 	return s
 }
 
-// Close waits until all resolutions have completed before returning, as part
-// of a graceful shutdown.
+// Stats returns counters describing the activity of the Server since it
+// started.
+func (s *Server) Stats() *Stats {
+	return &Stats{
+		Queries: s.created.Load(),
+	}
+}
+
+// Diagnostics describe the internal state of a Server, to diagnose stuck
+// resolutions.
+type Diagnostics struct {
+	// Interactive and Batch are the number of Queries waiting in the queue
+	// of each priority.
+	Interactive int `json:"interactive"`
+	Batch       int `json:"batch"`
+
+	// Scheduled is the number of Queries scheduled to run in the future.
+	Scheduled int `json:"scheduled"`
+
+	// Resolving is the number of Queries currently being resolved.
+	Resolving int64 `json:"resolving"`
+
+	// DB are the statistics of the database, if it reports any, such as the
+	// writes to Redis held while it was unreachable.
+	DB map[string]int64 `json:"db,omitempty"`
+}
+
+// Diagnostics returns the current internal state of the Server.
+func (s *Server) Diagnostics() *Diagnostics {
+	interactive, batch := s.queue.len()
+
+	d := &Diagnostics{
+		Interactive: interactive,
+		Batch:       batch,
+		Scheduled:   s.schedule.len(),
+		Resolving:   s.resolving.Load(),
+	}
+
+	if st, ok := s.db.(db.Stater); ok {
+		d.DB = st.Stats()
+	}
+
+	return d
+}
+
+// SetMaintenance enables or disables maintenance mode. While in maintenance
+// mode, existing Queries may be retrieved but new Queries cannot be created.
+func (s *Server) SetMaintenance(enabled bool) {
+	s.maintenance.Store(enabled)
+}
+
+// SetInstanceID sets the identifier of this Server stored on every Lookup it
+// resolves. It must be called before the Server is used.
+func (s *Server) SetInstanceID(id string) {
+	s.instanceID = id
+}
+
+// SetQueryMaxAge sets how long Queries are kept in the database before they
+// expire, so they are no longer served from memory once they have. It must
+// be called before the Server is used.
+func (s *Server) SetQueryMaxAge(maxAge time.Duration) {
+	s.queryMaxAge = maxAge
+	s.cache.setMaxAge(maxAge)
+}
+
+// SetLocation sets where this Server resolves from, stored on every Lookup it
+// resolves. It must be called before the Server is used.
+func (s *Server) SetLocation(location string) {
+	s.location = location
+}
+
+// SetAgents allows remote agents to register with agents and resolve each
+// Query alongside the Server's own resolvers, telling the time by the Server's
+// Clock. It must be called before the Server is used.
+func (s *Server) SetAgents(agents *Agents) {
+	s.agents = agents
+	s.agents.clock = s.clock
+}
+
+// SetClock replaces the Clock used to tell the time, such as with a
+// clock.Fake to control when scheduled Queries run. It must be called before
+// the Server is used.
+func (s *Server) SetClock(c clock.Clock) {
+	s.clock = c
+	s.schedule.clock = c
+
+	if s.agents != nil {
+		s.agents.clock = c
+	}
+}
+
+// LoadScheduled schedules the Queries in the database that were scheduled
+// with RunAt but have not finished, such as those pending when DENNIS was last
+// stopped. Queries that became due while DENNIS was stopped are resolved
+// immediately, those that had already been queued are left to RecoverJobs.
+func (s *Server) LoadScheduled(ctx context.Context) error {
+	// Jobs are listed first, as a Query may finish and remove it's Job once
+	// it has been recovered.
+	queued, err := s.jobQueryIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	queries, err := s.db.ListScheduledQueries(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range queries {
+		if _, ok := queued[query.ID]; ok {
+			continue
+		}
+
+		s.scheduleQuery(query)
+	}
+
+	return nil
+}
+
+// scheduleQuery pushes query onto the queue once it's RunAt has been reached.
+// Scheduled Queries are always interactive, as they are expected to be
+// resolved at the time requested.
+func (s *Server) scheduleQuery(query *models.Query) {
+	s.schedule.add(*query.RunAt, func() {
+		err := s.enqueueScheduled(context.Background(), query)
+		if err != nil {
+			// the queue is only closed during shutdown, the Query will be
+			// recovered when DENNIS next starts.
+			s.log.Debug(
+				"could not queue scheduled query",
+				slog.String("query_id", query.ID.String()), slog.String("error", err.Error()),
+			)
+		}
+	})
+}
+
+// ListScheduled returns the Queries that have been scheduled with RunAt but
+// have not finished, in the order they will run. It is not part of the public
+// API, as it would reveal the names queried by other users.
+func (s *Server) ListScheduled(ctx context.Context) ([]*models.Query, error) {
+	queries, err := s.db.ListScheduledQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(queries, func(a, b *models.Query) int {
+		return a.RunAt.Compare(*b.RunAt)
+	})
+
+	return queries, nil
+}
+
+// Close stops accepting new Queries and waits until all queued resolutions
+// have completed before returning, as part of a graceful shutdown. Scheduled
+// Queries that are not yet due remain in the database until DENNIS next
+// starts.
 func (s *Server) Close() error {
+	s.schedule.stop()
+	s.queue.close()
+
+	if s.stopDispatch != nil {
+		s.stopDispatch()
+	}
+
 	s.wg.Wait()
+
+	// resolvers implemented by plugins are stopped once no more lookups will
+	// be made.
+	for _, rsv := range s.resolvers() {
+		if c, ok := rsv.client.(io.Closer); ok {
+			c.Close()
+		}
+	}
+
 	return nil
 }
 
-func (s *Server) resolveAll(query *models.Query) {
+// worker resolves Queries from the queue until it is closed and drained.
+func (s *Server) worker() {
 	defer s.wg.Done()
 
+	for {
+		query, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+
+		s.resolving.Add(1)
+		s.resolveAll(query, s.db.CreateLookup)
+		s.resolving.Add(-1)
+	}
+}
+
+// resolveAll resolves query, passing each of it's Lookups to create to be
+// stored, then finishes it. An error is returned if the Query could not be
+// finished.
+func (s *Server) resolveAll(query *models.Query, create lookupStore) error {
 	wg := new(sync.WaitGroup)
 	log := s.log.With(
 		slog.String("query_id", query.ID.String()),
@@ -80,44 +562,161 @@ func (s *Server) resolveAll(query *models.Query) {
 		slog.String("query_name", query.Name),
 	)
 
+	// zone transfers may take far longer than resolving, so have their own
+	// timeout on top of that used to store their outcome.
+	timeout := 30 * time.Second
+	if query.IsTransfer() {
+		timeout += s.transferTimeout
+	}
+
 	// this context must be detached from the request context, as it needs to
 	// continue after the end of the requests lifecycle.
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	for _, rsv := range s.rsv {
+	if c, ok := s.captures.LoadAndDelete(query.ID); ok {
+		ctx = withPacketCapture(ctx, c.(*PacketCapture))
+		defer close(c.(*PacketCapture).done)
+	}
+
+	s.startJob(ctx, log, query.ID)
+
+	// a scheduled Query is queued from when it became due, rather than when
+	// it was created.
+	queuedAt := query.CreatedAt
+	if query.RunAt != nil && query.RunAt.After(queuedAt) {
+		queuedAt = *query.RunAt
+	}
+
+	sw := startStopwatch(queuedAt, s.clock.Now())
+
+	// a Lookup that could not be stored, such as while the database was
+	// unavailable, leaves the Query with partial results.
+	var partial atomic.Bool
+	store := func(ctx context.Context, queryID uuid.UUID, l *models.Lookup) error {
+		err := create(ctx, queryID, l)
+		if err != nil {
+			partial.Store(true)
+		}
+
+		return err
+	}
+
+	if query.IsTransfer() {
 		wg.Add(1)
-		go s.resolve(ctx, wg, log, rsv, query)
+		go s.transfer(ctx, wg, log, sw, query, store)
+	} else {
+		s.resolveLookups(ctx, wg, log, sw, query, store)
+
+		// a Query given a server is only resolved against that server, which
+		// agents may not be able to reach.
+		if s.agents != nil && query.Server == "" {
+			wg.Add(1)
+			go s.resolveRemote(ctx, wg, log, sw, query, store)
+		}
 	}
 
 	wg.Wait()
 
-	now := time.Now().UTC()
+	now := s.clock.Now().UTC()
 	query.FinishedAt = &now
+	query.Timings = sw.stop(now)
+	query.Partial = partial.Load()
 
 	err := s.db.UpdateQuery(ctx, query)
 	if err != nil {
+		// the Job is kept, so the Query is finished by RecoverJobs when
+		// DENNIS next starts.
 		log.Error("could not update query", slog.String("error", err.Error()))
+		return err
+	}
+
+	s.finishJob(ctx, log, query.ID)
+
+	return nil
+}
+
+// lookupStore stores a Lookup of the Query with the ID queryID once it has
+// been resolved, such as db.DB.CreateLookup.
+type lookupStore func(ctx context.Context, queryID uuid.UUID, l *models.Lookup) error
+
+// resolveLookups starts resolving query against each resolver in it's groups,
+// adding each to wg, and passes their Lookups to store.
+func (s *Server) resolveLookups(
+	ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, sw *stopwatch, query *models.Query, store lookupStore,
+) {
+	resolvers := s.resolvers()
+
+	// a Query given a server is resolved against only that server.
+	if query.Server != "" {
+		resolvers = []*resolver{s.adHocResolver(query.Server)}
+	}
+
+	for _, t := range query.AllTypes() {
+		// each type is resolved as if it were a Query of it's own, with the
+		// same ID so it's Lookups are stored together.
+		q := query
+		if t != query.Type {
+			q = new(*query)
+			q.Type = t
+		}
+
+		for _, rsv := range resolvers {
+			if !rsv.inGroups(query.Groups) {
+				continue
+			}
+
+			wg.Add(1)
+			go s.resolve(ctx, wg, log, sw, rsv, q, store)
+		}
 	}
 }
 
-func (s *Server) resolve(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, rsv *resolver, query *models.Query) {
+func (s *Server) resolve(
+	ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, sw *stopwatch, rsv *resolver, query *models.Query,
+	store lookupStore,
+) {
 	defer wg.Done()
 
 	log.Debug("starting resolution...", slog.String("resolver", rsv.name))
 	defer log.Debug("resolution complete", slog.String("resolver", rsv.name))
 
-	req := dns.NewMsg(query.Name, dns.StringToType[query.Type])
-	res, rtt, err := rsv.client.Exchange(ctx, req, "udp", rsv.addr)
+	req := s.newMsg(rsv, query)
+
+	// the request is packed before it is sent so it can be captured, as the
+	// client may reuse it's buffer for the response.
+	var wire *models.Wire
+	if query.Capture && req.Pack() == nil {
+		wire = &models.Wire{Request: bytes.Clone(req.Data)}
+	}
+
+	// rtt is measured by the client using the monotonic clock, so it is not
+	// affected by any adjustment to the wall clock during the exchange.
+	res, rtt, attempts, err := s.retryExchange(ctx, rsv, req)
 	if err != nil {
 		log.Error("could not resolve query", slog.String("resolver", rsv.name), slog.String("error", err.Error()))
+
+		// a response that could not be parsed is kept when captured, as it is
+		// exactly what capturing is for.
+		if wire != nil && res != nil && len(res.Data) > 0 {
+			wire.Response = bytes.Clone(res.Data)
+			s.createMalformedLookup(ctx, log, sw, rsv, query, wire, rtt, attempts, store)
+		}
+
 		return
 	}
 
 	l := &models.Lookup{
-		Resolver:   rsv.name,
-		RTT:        int(rtt / time.Millisecond),
-		ResolvedAt: time.Now().UTC(),
+		Resolver:       rsv.name,
+		InstanceID:     s.instanceID,
+		Location:       s.location,
+		Type:           lookupType(query),
+		ResolverHealth: rsv.unhealthy(),
+		Header:         responseHeader(res),
+		Attempts:       attempts,
+		Records:        []*models.Record{},
+		RTT:            milliseconds(rtt),
+		ResolvedAt:     s.clock.Now().UTC(),
 	}
 
 	if res.Rcode != dns.RcodeSuccess {
@@ -125,50 +724,509 @@ func (s *Server) resolve(ctx context.Context, wg *sync.WaitGroup, log *slog.Logg
 	} else if errors.Is(err, context.Canceled) {
 		l.Error = new("CANCELED")
 	} else {
-		for _, answer := range res.Answer {
-			rr := models.RecordFromRR(answer)
-			if rr != nil {
-				l.Records = append(l.Records, rr)
+		var total int
+		l.Records, total = s.recordsFromAnswer(res.Answer)
+
+		if total > len(l.Records) {
+			l.TruncatedByDennis = true
+			l.TotalRecords = total
+		}
+	}
+
+	l.Authority = s.recordsFromSection(res.Ns)
+	l.Additional = s.recordsFromSection(res.Extra)
+
+	if wire != nil {
+		wire.Response = bytes.Clone(res.Data)
+		l.Wire = wire
+	}
+
+	if rsv.rpz {
+		l.Policy = rpzPolicy(res)
+	}
+
+	if req.Security {
+		l.DNSSEC = dnssecStatus(ctx, rsv, req, res)
+	}
+
+	if query.Type == "AAAA" && l.Error == nil {
+		l.DNS64 = detectDNS64(l.Records)
+		if l.DNS64 != nil && query.DNS64 {
+			l.DNS64.A = s.resolveA(ctx, rsv, query)
+		}
+	}
+
+	if query.EDNS0 != nil && (query.EDNS0.Disabled || query.EDNS0.UDPSize > 0) {
+		l.EDNS0Differs = s.edns0Differs(ctx, rsv, query, res)
+	}
+
+	// when sampling, query the resolver repeatedly to observe how it rotates
+	// between answers. the first sample is the response above. failures are
+	// counted as samples of their own, so every sample is accounted for.
+	if query.Samples > 1 && l.Error == nil {
+		l.Samples = models.AddSample(nil, l.Records)
+
+		for range query.Samples - 1 {
+			if ctx.Err() != nil {
+				break
 			}
+
+			res, _, _, err := s.retryExchange(ctx, rsv, req)
+			if err != nil {
+				log.Error("could not sample query", slog.String("resolver", rsv.name), slog.String("error", err.Error()))
+				l.Samples = models.AddFailedSample(l.Samples, "FAILED")
+				continue
+			} else if res.Rcode != dns.RcodeSuccess {
+				l.Samples = models.AddFailedSample(l.Samples, dns.RcodeToString[res.Rcode])
+				continue
+			}
+
+			records, _ := s.recordsFromAnswer(res.Answer)
+			l.Samples = models.AddSample(l.Samples, records)
 		}
 	}
 
-	err = s.db.CreateLookup(ctx, query.ID, l)
+	start := s.clock.Now()
+	err = store(ctx, query.ID, l)
+	sw.lookup(l.ResolvedAt, s.clock.Since(start))
 	if err != nil {
 		log.Error("could not create lookup", slog.String("resolver", rsv.name), slog.String("error", err.Error()))
 		return
 	}
 }
 
+// createMalformedLookup passes store a Lookup of query for a response from rsv
+// that could not be parsed, with it's raw messages captured in wire.
+func (s *Server) createMalformedLookup(
+	ctx context.Context, log *slog.Logger, sw *stopwatch, rsv *resolver, query *models.Query, wire *models.Wire,
+	rtt time.Duration, attempts int, store lookupStore,
+) {
+	l := &models.Lookup{
+		Resolver:       rsv.name,
+		InstanceID:     s.instanceID,
+		Location:       s.location,
+		Type:           lookupType(query),
+		ResolverHealth: rsv.unhealthy(),
+		Error:          new(models.LookupMalformed),
+		Attempts:       attempts,
+		Records:        []*models.Record{},
+		RTT:            milliseconds(rtt),
+		Wire:           wire,
+		ResolvedAt:     s.clock.Now().UTC(),
+	}
+
+	start := s.clock.Now()
+	err := store(ctx, query.ID, l)
+	sw.lookup(l.ResolvedAt, s.clock.Since(start))
+	if err != nil {
+		log.Error("could not create lookup", slog.String("resolver", rsv.name), slog.String("error", err.Error()))
+	}
+}
+
+// lookupType returns the type of record resolved by a Lookup of query, if the
+// Query resolves more than one type, otherwise an empty string.
+func lookupType(query *models.Query) string {
+	if len(query.Types) > 1 {
+		return query.Type
+	}
+
+	return ""
+}
+
+// checkInternalName returns an error if req is for a single-label name and
+// internal names have not been allowed by config.Resolution. req must have
+// been validated.
+func (s *Server) checkInternalName(req *apiv1.CreateQueryRequest) error {
+	if s.allowInternalNames {
+		return nil
+	}
+
+	if name, _ := req.QueryName(); apiv1.SingleLabel(name) {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".name",
+			Message: "Single-label names are not permitted on this server",
+		}
+	}
+
+	return nil
+}
+
+// newMsg creates the DNS message sent to rsv to resolve query, including an
+// EDNS0 OPT record combining the server's configuration with the Query's.
+func (s *Server) newMsg(rsv *resolver, query *models.Query) *dns.Msg {
+	req := dns.NewMsg(query.Name, dns.StringToType[query.Type])
+	if query.Class == apiv1.ClassCH {
+		req.Question[0].Header().Class = dns.ClassCHAOS
+	}
+
+	req.RecursionDesired = !query.NoRecursion
+	req.CheckingDisabled = query.CheckingDisabled
+
+	// without EDNS0 there is no OPT record to carry the DNSSEC OK bit, the
+	// UDP size or any options.
+	if query.EDNS0 != nil && query.EDNS0.Disabled {
+		return req
+	}
+
+	req.UDPSize = uint16(s.edns0.UDPSize)
+	req.Security = query.DNSSEC || rsv.dnssec
+
+	options := s.edns0.Options
+	if query.EDNS0 != nil {
+		if query.EDNS0.UDPSize > 0 {
+			req.UDPSize = uint16(query.EDNS0.UDPSize)
+		}
+
+		options = append(slices.Clip(options), query.EDNS0.Options...)
+	}
+
+	// options are validated as hex when configured or requested.
+	for _, option := range options {
+		req.Pseudo = append(req.Pseudo, &dns.ERFC3597{EDNS0Code: uint16(option.Code), Code: option.Data})
+	}
+
+	return req
+}
+
+// edns0Differs returns true if rsv gives a different answer to query with the
+// default EDNS0 configuration than res, the answer given with the EDNS0 of the
+// Query. The order of records is ignored, as resolvers may rotate them.
+func (s *Server) edns0Differs(ctx context.Context, rsv *resolver, query *models.Query, res *dns.Msg) bool {
+	def := *query
+	def.EDNS0 = &models.EDNS0{Options: query.EDNS0.Options}
+
+	defRes, _, _, err := s.retryExchange(ctx, rsv, s.newMsg(rsv, &def))
+	if err != nil {
+		return false
+	}
+
+	return res.Rcode != defRes.Rcode || !slices.Equal(answerContent(res.Answer), answerContent(defRes.Answer))
+}
+
+// answerContent returns the presentation format of each record in answer,
+// sorted so answers can be compared regardless of order. TTLs are ignored, as
+// they count down between lookups of a caching resolver.
+func answerContent(answer []dns.RR) []string {
+	content := make([]string, 0, len(answer))
+	for _, rr := range answer {
+		rr = rr.Clone()
+		rr.Header().TTL = 0
+
+		content = append(content, rr.String())
+	}
+
+	slices.Sort(content)
+	return content
+}
+
+// exchange sends req to rsv over UDP, retrying over TCP if the answer was
+// truncated because it was larger than the advertised UDP buffer size. The
+// rtt returned includes both attempts. Resolvers reached through a proxy are
+// only sent req over TCP.
+func exchange(ctx context.Context, rsv *resolver, req *dns.Msg) (*dns.Msg, time.Duration, error) {
+	if rsv.tcpOnly {
+		return capturedExchange(ctx, rsv, req, "tcp")
+	}
+
+	res, rtt, err := capturedExchange(ctx, rsv, req, "udp")
+	if err != nil || !res.Truncated {
+		return res, rtt, err
+	}
+
+	res, tcpRTT, err := capturedExchange(ctx, rsv, req, "tcp")
+	return res, rtt + tcpRTT, err
+}
+
+// capturedExchange exchanges req with rsv over network, recording both
+// messages if a PacketCapture is within ctx.
+func capturedExchange(
+	ctx context.Context, rsv *resolver, req *dns.Msg, network string,
+) (*dns.Msg, time.Duration, error) {
+	c := packetCaptureFrom(ctx)
+	if c == nil {
+		return rsv.client.Exchange(ctx, req, network, rsv.addr)
+	}
+
+	// the request is packed and copied before it is sent, as the client may
+	// reuse it's buffer for the response.
+	if err := req.Pack(); err != nil {
+		return nil, 0, err
+	}
+
+	sent, sentAt := bytes.Clone(req.Data), time.Now()
+
+	res, rtt, err := rsv.client.Exchange(ctx, req, network, rsv.addr)
+
+	var received []byte
+	if res != nil && len(res.Data) > 0 {
+		received = res.Data
+	}
+
+	c.record(network, rsv.sourceAddr, rsv.addr, sentAt, sent, received, rtt)
+
+	return res, rtt, err
+}
+
+// retryExchange calls exchange, retrying up to the number of times configured
+// for rsv if the resolver does not respond, such as when a UDP packet is
+// dropped. attempts is the number of exchanges made, and rtt is that of the
+// last.
+func (s *Server) retryExchange(
+	ctx context.Context, rsv *resolver, req *dns.Msg,
+) (res *dns.Msg, rtt time.Duration, attempts int, err error) {
+	for {
+		attempts++
+
+		res, rtt, err = exchange(ctx, rsv, req)
+		if err == nil || attempts > rsv.retries || ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(rsv.interval):
+		}
+	}
+}
+
+// recordsFromAnswer converts the supported records in the answer section of a
+// DNS response into Record models, keeping at most the configured maximum.
+// total is the number of supported records in the answer, including any that
+// were discarded.
+func (s *Server) recordsFromAnswer(answer []dns.RR) (records []*models.Record, total int) {
+	records = []*models.Record{}
+
+	for _, rr := range answer {
+		if rec := models.RecordFromRR(rr); rec != nil {
+			if total < s.maxRecords {
+				records = append(records, rec)
+			}
+
+			total++
+		}
+	}
+
+	return records, total
+}
+
+// responseHeader converts the header of a DNS response into a ResponseHeader
+// model.
+func responseHeader(res *dns.Msg) *models.ResponseHeader {
+	h := &models.ResponseHeader{
+		ID:                 int(res.ID),
+		Rcode:              dns.RcodeToString[res.Rcode],
+		Authoritative:      res.Authoritative,
+		Truncated:          res.Truncated,
+		RecursionDesired:   res.RecursionDesired,
+		RecursionAvailable: res.RecursionAvailable,
+		AuthenticatedData:  res.AuthenticatedData,
+		CheckingDisabled:   res.CheckingDisabled,
+	}
+
+	// the buffer a response is read into is larger than the response, so it's
+	// size is measured by packing a copy.
+	c := res.Copy()
+	c.Data = nil
+	if c.Pack() == nil {
+		h.Size = len(c.Data)
+	}
+
+	return h
+}
+
+// recordsFromSection converts the supported records in the authority or
+// additional section of a DNS response into Record models, including their
+// name and type, keeping at most the configured maximum. nil is returned if
+// there are none.
+func (s *Server) recordsFromSection(section []dns.RR) []*models.Record {
+	var records []*models.Record
+
+	for _, rr := range section {
+		if len(records) >= s.maxRecords {
+			break
+		}
+
+		if rec := models.RecordFromRR(rr); rec != nil {
+			rec.Name = rr.Header().Name
+			rec.Type = dns.TypeToString[dns.RRToType(rr)]
+			records = append(records, rec)
+		}
+	}
+
+	return records
+}
+
 func (s *Server) CreateQuery(ctx context.Context, req *apiv1.CreateQueryRequest) (*apiv1.CreateQueryResponse, error) {
 	s.wg.Add(1)
 	defer s.wg.Done()
 
-	if err := req.Validate(); err != nil {
+	if err := s.checkCreateQuery(req); err != nil {
 		return nil, err
 	}
 
+	// the name has already been validated, so it can be converted.
+	name, _ := req.QueryName()
+
+	types := req.QueryTypes()
+
 	query := &models.Query{
-		Type: req.Type,
-		Name: req.Name,
+		Type:    types[0],
+		Name:    name,
+		Groups:  req.Groups,
+		Samples: req.Samples,
+		DNSSEC:  req.DNSSEC,
+		DNS64:   req.DNS64,
+		EDNS0:   req.EDNS0,
+		Serial:  req.Serial,
+
+		NoRecursion:      req.NoRecursion,
+		CheckingDisabled: req.CheckingDisabled,
+		Capture:          req.Capture,
 
 		// NOTE(jc): cannot be null, Redis will not append to a null value.
 		Lookups: []*models.Lookup{},
 	}
 
-	err := s.db.CreateQuery(ctx, query)
+	if len(types) > 1 {
+		query.Types = types
+	}
+
+	// the server has already been validated, so it can be converted. it is
+	// either the server a zone is transferred from, or an ad-hoc resolver.
+	if req.Server != "" {
+		query.Server, _ = req.ServerAddr()
+	}
+
+	if unicode := idn.ToUnicode(name); unicode != name {
+		query.UnicodeName = unicode
+	}
+
+	// IN is assumed when a Query has no class, so is not stored.
+	if req.Class != apiv1.ClassIN {
+		query.Class = req.Class
+	}
+
+	// a RunAt in the past is resolved immediately, as if it were not set.
+	if req.RunAt != nil && req.RunAt.After(s.clock.Now()) {
+		query.RunAt = new(req.RunAt.UTC())
+	}
+
+	// the Query is stored even if the client disconnects while it is, so a
+	// canceled request is not mistaken for a failure of the database.
+	err := s.db.CreateQuery(context.WithoutCancel(ctx), query)
 	if err != nil {
 		return nil, err
 	}
 
-	s.wg.Add(1)
-	go s.resolveAll(query)
+	// the client is no longer waiting for the Query, but it is resolved as
+	// normal. this is recorded before it is queued, as it is then shared with
+	// the worker resolving it.
+	if errors.Is(ctx.Err(), context.Canceled) {
+		query.ClientDisconnected = true
+
+		s.log.Info("client disconnected before query was created", slog.String("query_id", query.ID.String()))
+	}
+
+	// the capture is registered before the Query is queued, so it is found
+	// by the worker that resolves it.
+	if c := packetCaptureFrom(ctx); c != nil {
+		s.captures.Store(query.ID, c)
+	}
+
+	if query.RunAt != nil {
+		s.scheduleQuery(query)
+	} else {
+		err = s.enqueue(context.WithoutCancel(ctx), s.newJob(query, req.Priority), query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.created.Add(1)
 
 	return &apiv1.CreateQueryResponse{
 		Query: query,
 	}, nil
 }
 
+// checkCreateQuery returns an error if the Query of req cannot be created,
+// before anything is stored, so callers creating several Queries can check
+// them all before creating any.
+func (s *Server) checkCreateQuery(req *apiv1.CreateQueryRequest) error {
+	if err := req.ValidateAt(s.clock.Now()); err != nil {
+		return err
+	}
+
+	if s.maintenance.Load() {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeUnavailable,
+			Message: "DENNIS is down for maintenance, new Queries cannot be created until it has finished",
+		}
+	}
+
+	if err := s.readOnlyError(); err != nil {
+		return err
+	}
+
+	if apiv1.IsTransfer(req.Type) && !s.features.Enabled(apiv1.FeatureZoneTransfer) {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeFeatureDisabled,
+			Field:   ".type",
+			Message: "Zone transfers are not enabled on this server",
+		}
+	}
+
+	if req.Server != "" && !apiv1.IsTransfer(req.Type) && !s.features.Enabled(apiv1.FeatureAdHocResolver) {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeFeatureDisabled,
+			Field:   ".server",
+			Message: "Ad-hoc resolvers are not enabled on this server",
+		}
+	}
+
+	if req.Samples > s.maxSamples {
+		return &apiv1.Error{
+			Code:    apiv1.ErrorCodeBadRequest,
+			Field:   ".samples",
+			Message: "Samples must not be greater than " + strconv.Itoa(s.maxSamples),
+		}
+	}
+
+	if err := s.checkGroups(req.Groups); err != nil {
+		return err
+	}
+
+	if err := s.checkInternalName(req); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkGroups returns an error if any of groups has no resolvers, so a Query
+// is not silently resolved against fewer resolvers than expected.
+func (s *Server) checkGroups(groups []string) error {
+	resolvers := s.resolvers()
+
+	for i, group := range groups {
+		found := slices.ContainsFunc(resolvers, func(rsv *resolver) bool {
+			return slices.Contains(rsv.groups, group)
+		})
+
+		if !found {
+			return &apiv1.Error{
+				Code:    apiv1.ErrorCodeBadRequest,
+				Field:   ".groups[" + strconv.Itoa(i) + "]",
+				Message: "There are no resolvers in group " + group,
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *Server) GetQuery(ctx context.Context, req *apiv1.GetQueryRequest) (*apiv1.GetQueryResponse, error) {
 	s.wg.Add(1)
 	defer s.wg.Done()
@@ -182,14 +1240,176 @@ func (s *Server) GetQuery(ctx context.Context, req *apiv1.GetQueryRequest) (*api
 		return nil, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Field: ".id", Message: "Invalid UUID for Query ID"}
 	}
 
-	query, err := s.db.GetQueryByID(ctx, id)
-	if errors.Is(err, db.ErrQueryNotFound) {
-		return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Query not found by ID"}
-	} else if err != nil {
+	query, cached := s.cache.get(id, s.clock.Now())
+	if !cached {
+		query, err = s.db.GetQueryByID(ctx, id)
+		if errors.Is(err, db.ErrQueryNotFound) {
+			return nil, &apiv1.Error{Code: apiv1.ErrorCodeNotFound, Message: "Query not found by ID"}
+		} else if err != nil && s.readOnly.Load() {
+			return nil, &apiv1.Error{
+				Code:    apiv1.ErrorCodeUnavailable,
+				Message: "DENNIS cannot reach it's database, this Query can be viewed once it has recovered",
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// zone transfers are stored in batches as they are received.
+	if query.IsTransfer() {
+		query.Lookups = mergeTransfer(query.Lookups)
+	}
+
+	// Lookups are stored in the order they completed, display them in the
+	// order of their types and resolvers instead.
+	types := query.AllTypes()
+	slices.SortStableFunc(query.Lookups, func(a, b *models.Lookup) int {
+		if c := cmp.Compare(slices.Index(types, a.Type), slices.Index(types, b.Type)); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(s.indexOf(a.Resolver), s.indexOf(b.Resolver)); c != 0 {
+			return c
+		}
+
+		// Lookups of the same resolver from different locations, such as
+		// those of remote agents, are ordered by location.
+		return cmp.Compare(a.Location, b.Location)
+	})
+
+	s.cache.add(query, s.clock.Now())
+
+	etag, err := queryETag(query)
+	if err != nil {
 		return nil, err
 	}
 
-	return &apiv1.GetQueryResponse{
-		Query: query,
+	res := &apiv1.GetQueryResponse{
+		Query:     query,
+		Age:       milliseconds(s.clock.Since(query.CreatedAt)),
+		UpdatedAt: query.UpdatedAt(),
+		ETag:      etag,
+	}
+
+	if s.queryMaxAge > 0 {
+		res.ExpiresIn = new(max(milliseconds(s.queryMaxAge-s.clock.Since(query.CreatedAt)), 0))
+	}
+
+	return res, nil
+}
+
+// queryETag returns an opaque identifier for the content of a Query, derived
+// from a hash of it's JSON representation.
+func queryETag(query *models.Query) (string, error) {
+	b, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+func (s *Server) ListResolvers(
+	ctx context.Context, req *apiv1.ListResolversRequest,
+) (*apiv1.ListResolversResponse, error) {
+	resolvers := s.resolvers()
+
+	res := &apiv1.ListResolversResponse{
+		Resolvers: make([]*models.Resolver, 0, len(resolvers)),
+	}
+
+	for _, rsv := range resolvers {
+		res.Resolvers = append(res.Resolvers, &models.Resolver{
+			Name:        rsv.name,
+			Description: rsv.description,
+			Icon:        rsv.icon,
+			Groups:      rsv.groups,
+			Addr:        rsv.addr,
+
+			Capabilities:     rsv.capabilities.Load(),
+			NXDOMAINRedirect: rsv.nxdomain.Load(),
+			Health:           rsv.health.Load(),
+			Fingerprint:      rsv.fingerprint.Load(),
+		})
+	}
+
+	return res, nil
+}
+
+// indexOf returns the position of the resolver named name in the order they
+// are displayed. If there is no resolver by that name, such as when it has
+// since been removed from the configuration, it is placed last.
+func (s *Server) indexOf(name string) int {
+	resolvers := s.resolvers()
+
+	for i, rsv := range resolvers {
+		if rsv.name == name {
+			return i
+		}
+	}
+
+	return len(resolvers)
+}
+
+func (s *Server) GetStatus(ctx context.Context, req *apiv1.GetStatusRequest) (*apiv1.GetStatusResponse, error) {
+	// interactive Queries only wait if every worker is busy, a backlog of at
+	// least as many as there are workers means a new Query waits for every
+	// worker to finish at least once.
+	interactive, batch := s.queue.len()
+
+	return &apiv1.GetStatusResponse{
+		Maintenance: s.maintenance.Load(),
+		ReadOnly:    s.readOnly.Load(),
+		Queued:      interactive + batch,
+		Busy:        interactive >= s.workers,
+	}, nil
+}
+
+// backends are the database backends compiled into DENNIS, as reported by
+// GetVersion.
+var backends = []string{"file", "postgres", "redis"}
+
+// transports returns the network transports the Server uses to query it's
+// resolvers, as reported by GetVersion. UDP and TCP are always used, as
+// truncated answers are retried over TCP, as are proxies and plugins if any
+// resolver is configured to use them.
+func (s *Server) transports() []string {
+	transports := []string{"udp", "tcp"}
+
+	for _, rsv := range s.resolvers() {
+		var transport string
+
+		switch rsv.client.(type) {
+		case *proxyClient:
+			transport = "proxy"
+		case *plugin.Transport:
+			transport = "plugin"
+		}
+
+		if transport != "" && !slices.Contains(transports, transport) {
+			transports = append(transports, transport)
+		}
+	}
+
+	return transports
+}
+
+func (s *Server) GetVersion(ctx context.Context, req *apiv1.GetVersionRequest) (*apiv1.GetVersionResponse, error) {
+	return &apiv1.GetVersionResponse{
+		Version:    build.GetVersion(),
+		Commit:     build.GetCommit(40),
+		Date:       build.GetDate(),
+		GoVersion:  build.GetGoVersion(),
+		Backends:   backends,
+		Transports: s.transports(),
+		Features:   s.features,
+	}, nil
+}
+
+func (s *Server) GetTime(ctx context.Context, req *apiv1.GetTimeRequest) (*apiv1.GetTimeResponse, error) {
+	return &apiv1.GetTimeResponse{
+		Time: s.clock.Now().UTC(),
 	}, nil
 }
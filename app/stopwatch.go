@@ -0,0 +1,58 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+)
+
+// stopwatch records the time spent in each stage of resolving a Query. It is
+// shared between the goroutines resolving each Lookup, so is safe for
+// concurrent use.
+type stopwatch struct {
+	mu sync.Mutex
+
+	created     time.Time
+	start       time.Time
+	firstLookup time.Duration
+	dbWrite     time.Duration
+}
+
+// startStopwatch begins timing the resolution of a Query created at created,
+// whose resolution started at start.
+func startStopwatch(created, start time.Time) *stopwatch {
+	return &stopwatch{created: created, start: start}
+}
+
+// lookup records the completion of a Lookup, and the time taken to write it
+// to the database.
+func (sw *stopwatch) lookup(resolved time.Time, write time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.firstLookup == 0 {
+		sw.firstLookup = resolved.Sub(sw.start)
+	}
+
+	sw.dbWrite += write
+}
+
+// stop returns the Timings of a Query that has finished at finished.
+func (sw *stopwatch) stop(finished time.Time) *models.Timings {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	return &models.Timings{
+		Queued:      milliseconds(sw.start.Sub(sw.created)),
+		FirstLookup: milliseconds(sw.firstLookup),
+		Lookups:     milliseconds(finished.Sub(sw.start)),
+		DBWrite:     milliseconds(sw.dbWrite),
+		Total:       milliseconds(finished.Sub(sw.created)),
+	}
+}
+
+// milliseconds converts d into a whole number of milliseconds.
+func milliseconds(d time.Duration) int {
+	return int(d / time.Millisecond)
+}
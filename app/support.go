@@ -0,0 +1,164 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/config"
+)
+
+// Support produces support bundles, describing a running instance of DENNIS
+// for bug reports against DENNIS itself.
+type Support struct {
+	server  *Server
+	cfg     *config.Config
+	logs    *RecentLogs
+	errors  *Errors
+	started time.Time
+}
+
+// NewSupport initializes Support for a Server started with the configuration
+// cfg, including the recent logs retained by logs, if not nil, and the
+// unexpected errors retained by errs.
+func NewSupport(server *Server, cfg *config.Config, logs *RecentLogs, errs *Errors) *Support {
+	return &Support{
+		server:  server,
+		cfg:     cfg,
+		logs:    logs,
+		errors:  errs,
+		started: server.clock.Now().UTC(),
+	}
+}
+
+// SupportRuntime describes the process running DENNIS, included in a support
+// bundle.
+type SupportRuntime struct {
+	// OS and Arch are the operating system and architecture DENNIS was built
+	// for.
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+
+	// CPUs is the number of logical CPUs usable by DENNIS, and Goroutines the
+	// number running when the bundle was produced.
+	CPUs       int `json:"cpus"`
+	Goroutines int `json:"goroutines"`
+
+	// HeapBytes is the memory allocated to live objects on the heap.
+	HeapBytes uint64 `json:"heapBytes"`
+
+	// InstanceID identifies the instance among replicas, if set.
+	InstanceID string `json:"instanceId,omitempty"`
+
+	// StartedAt is the UTC timestamp DENNIS started at.
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// WriteBundle writes a support bundle to w as a gzipped tarball, with a
+// directory named name holding the version, configuration, resolvers and
+// their health, internal state, recent unexpected errors and recent logs of
+// DENNIS. The secrets of the configuration are redacted, and removed from
+// anything else they appear in, but logs may include the names queried by
+// users.
+func (s *Support) WriteBundle(ctx context.Context, w io.Writer, name string) error {
+	ver, err := s.server.GetVersion(ctx, &apiv1.GetVersionRequest{})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := s.cfg.Redacted()
+	if err != nil {
+		return err
+	}
+
+	resolvers, err := s.server.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return err
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	rt := &SupportRuntime{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  mem.HeapAlloc,
+		InstanceID: s.server.instanceID,
+		StartedAt:  s.started,
+	}
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"version.json", ver},
+		{"runtime.json", rt},
+		{"config.json", cfg},
+		{"resolvers.json", resolvers},
+		{"registry.json", s.server.ManagedResolvers()},
+		{"diagnostics.json", s.server.Diagnostics()},
+		{"errors.json", s.errors.list()},
+	}
+
+	// secrets are removed from every file, as they may appear in errors and
+	// logs, such as a connection string that could not be parsed.
+	var pairs []string
+	for _, secret := range s.cfg.Secrets() {
+		pairs = append(pairs, secret, "REDACTED")
+	}
+
+	scrub := strings.NewReplacer(pairs...)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	modTime := s.server.clock.Now().UTC()
+
+	add := func(file string, content []byte) error {
+		content = []byte(scrub.Replace(string(content)))
+
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name + "/" + file,
+			Mode:    0o644,
+			Size:    int64(len(content)),
+			ModTime: modTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write(content)
+		return err
+	}
+
+	for _, file := range files {
+		b, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := add(file.name, append(b, '\n')); err != nil {
+			return err
+		}
+	}
+
+	if s.logs != nil {
+		if err := add("logs.txt", s.logs.content()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
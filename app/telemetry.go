@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/pkg/build"
+)
+
+// defaultTelemetryInterval is how often usage statistics are reported if not
+// configured by config.Telemetry.
+const defaultTelemetryInterval = 24 * time.Hour
+
+// TelemetryReport is the anonymous usage statistics reported by Telemetry.
+// It intentionally contains only aggregate counters, never the names queried
+// or the resolvers queried against.
+type TelemetryReport struct {
+	// Version is the semantic release of this build of DENNIS.
+	Version string `json:"version"`
+
+	// OS and Arch are the platform DENNIS is running on.
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+
+	// Backend is the type of database DENNIS has been configured with.
+	Backend string `json:"backend"`
+
+	// Resolvers is the number of upstream DNS resolvers configured.
+	Resolvers int `json:"resolvers"`
+
+	// Queries is the number of Queries created since DENNIS started.
+	Queries int64 `json:"queries"`
+}
+
+// Telemetry reports anonymous usage statistics about a Server to help the
+// maintainers of DENNIS prioritize their work. It is disabled unless the
+// operator opts in.
+type Telemetry struct {
+	cfg       config.Telemetry
+	backend   string
+	resolvers int
+	server    *Server
+	client    *http.Client
+	log       *slog.Logger
+}
+
+// NewTelemetry initializes Telemetry for a Server, configured with the type of
+// database backend and number of resolvers it was configured with.
+func NewTelemetry(cfg config.Telemetry, backend string, resolvers int, server *Server, log *slog.Logger) *Telemetry {
+	return &Telemetry{
+		cfg:       cfg,
+		backend:   backend,
+		resolvers: resolvers,
+		server:    server,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		log:       log,
+	}
+}
+
+// Report returns the usage statistics that will be reported, regardless of
+// whether reporting is enabled, so operators may inspect them.
+func (t *Telemetry) Report() *TelemetryReport {
+	return &TelemetryReport{
+		Version:   build.GetVersion(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Backend:   t.backend,
+		Resolvers: t.resolvers,
+		Queries:   t.server.Stats().Queries,
+	}
+}
+
+// Run periodically sends reports until ctx is canceled. If reporting has not
+// been enabled, Run returns immediately.
+func (t *Telemetry) Run(ctx context.Context) {
+	if !t.cfg.Enabled {
+		return
+	}
+
+	interval := defaultTelemetryInterval
+	if t.cfg.Interval > 0 {
+		interval = time.Duration(t.cfg.Interval) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := t.send(ctx)
+			if err != nil {
+				t.log.Debug("could not send telemetry", slog.String("error", err.Error()))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Telemetry) send(ctx context.Context) error {
+	body, err := json.Marshal(t.Report())
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/rdata"
+)
+
+const (
+	// defaultMaxTransferRecords is the maximum number of Records stored for a
+	// zone transfer if not configured by config.Resolution.
+	defaultMaxTransferRecords = 100000
+
+	// defaultTransferTimeout is the longest a zone transfer may take if not
+	// configured by config.Resolution.
+	defaultTransferTimeout = 5 * time.Minute
+
+	// transferBatchSize is the number of Records of a zone transfer stored
+	// together, so the records of a large zone are not all held in memory
+	// until the transfer completes.
+	transferBatchSize = 1000
+)
+
+// transfer performs a zone transfer of the zone named by query from it's
+// server, passing the records of the zone to store in batches as they are
+// received, each as a Lookup named after the server. The final Lookup holds
+// no records, only the outcome of the transfer. GetQuery merges them into a
+// single Lookup.
+func (s *Server) transfer(
+	ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, sw *stopwatch, query *models.Query, store lookupStore,
+) {
+	defer wg.Done()
+
+	log.Debug("starting transfer...", slog.String("server", query.Server))
+	defer log.Debug("transfer complete", slog.String("server", query.Server))
+
+	start := s.clock.Now()
+
+	newLookup := func(records []*models.Record) *models.Lookup {
+		return &models.Lookup{
+			Resolver:   query.Server,
+			InstanceID: s.instanceID,
+			Attempts:   1,
+			Records:    records,
+			RTT:        milliseconds(s.clock.Since(start)),
+			ResolvedAt: s.clock.Now().UTC(),
+		}
+	}
+
+	save := func(l *models.Lookup) {
+		start := s.clock.Now()
+		err := store(ctx, query.ID, l)
+		sw.lookup(l.ResolvedAt, s.clock.Since(start))
+		if err != nil {
+			log.Error("could not create lookup", slog.String("server", query.Server), slog.String("error", err.Error()))
+		}
+	}
+
+	// the transfer itself is limited to the transfer timeout, leaving ctx to
+	// store it's outcome.
+	tctx, cancel := context.WithTimeout(ctx, s.transferTimeout)
+	defer cancel()
+
+	total, err := s.transferRecords(tctx, query, func(records []*models.Record) {
+		save(newLookup(records))
+	})
+
+	l := newLookup([]*models.Record{})
+	if err != nil {
+		log.Error("could not transfer zone", slog.String("server", query.Server), slog.String("error", err.Error()))
+		l.Error = new(transferError(err))
+	}
+
+	if total > s.maxTransferRecords {
+		l.TruncatedByDennis = true
+		l.TotalRecords = total
+	}
+
+	save(l)
+}
+
+// transferRecords receives the records of the zone named by query from it's
+// server, passing them to batch every transferBatchSize records, and once the
+// transfer has ended, keeping at most the configured maximum. total is the
+// number of records received, including any that were discarded.
+//
+// The records of an IXFR are kept as they are received: the current SOA,
+// followed by each change as the SOA of the version it was made to, the
+// records deleted, then the SOA of the version it made and the records added,
+// ending with the current SOA again (RFC 1995). A server may instead answer
+// with every record of the zone, as with AXFR, or only the current SOA if the
+// zone has not changed since query.Serial.
+func (s *Server) transferRecords(
+	ctx context.Context, query *models.Query, batch func(records []*models.Record),
+) (total int, err error) {
+	req := dns.NewMsg(query.Name, dns.TypeAXFR)
+	if query.Serial != nil {
+		req = dns.NewMsg(query.Name, dns.TypeIXFR)
+
+		// the serial the changes are transferred since is given by the SOA of
+		// the version of the zone already held, in the authority section.
+		req.Ns = []dns.RR{&dns.SOA{
+			Hdr: dns.Header{Name: req.Question[0].Header().Name, Class: dns.ClassINET},
+			SOA: rdata.SOA{Ns: ".", Mbox: ".", Serial: *query.Serial},
+		}}
+	}
+
+	// transfers use their own client, rather than that shared by resolvers,
+	// as they connect to a server given by the user.
+	env, err := dns.NewClient().TransferIn(ctx, req, "tcp", query.Server)
+	if err != nil {
+		return 0, err
+	}
+
+	var records []*models.Record
+	defer func() {
+		if len(records) > 0 {
+			batch(records)
+		}
+	}()
+
+	for e := range env {
+		for _, rr := range e.Answer {
+			if total < s.maxTransferRecords {
+				records = append(records, transferRecord(rr))
+			}
+
+			total++
+		}
+
+		if len(records) >= transferBatchSize {
+			batch(records)
+			records = nil
+		}
+
+		if e.Error != nil {
+			// the channel must be drained for the transfer to stop.
+			for range env {
+			}
+
+			return total, e.Error
+		}
+	}
+
+	return total, nil
+}
+
+// mergeTransfer merges the Lookups of a zone transfer, stored in batches by
+// transfer, into a single Lookup with every record, in the order they were
+// received, and the outcome of the transfer from the final Lookup. The
+// Lookups themselves are not modified.
+func mergeTransfer(lookups []*models.Lookup) []*models.Lookup {
+	if len(lookups) <= 1 {
+		return lookups
+	}
+
+	merged := new(*lookups[len(lookups)-1])
+	merged.Records = []*models.Record{}
+
+	for _, l := range lookups {
+		merged.Records = append(merged.Records, l.Records...)
+	}
+
+	return []*models.Lookup{merged}
+}
+
+// transferRecord converts a record of a zone into a Record model, including
+// it's name and type. Records of types DENNIS does not otherwise support are
+// kept in their presentation format, as a zone may contain any type.
+func transferRecord(rr dns.RR) *models.Record {
+	rec := models.RecordFromRR(rr)
+	if rec == nil {
+		rec = &models.Record{TTL: int(rr.Header().TTL)}
+		if data := rr.Data(); data != nil {
+			rec.Content = []string{data.String()}
+		}
+	}
+
+	rec.Name = rr.Header().Name
+	rec.Type = dns.TypeToString[dns.RRToType(rr)]
+
+	return rec
+}
+
+// transferError returns the error recorded for a failed zone transfer, the
+// rcode if the server refused it, otherwise `FAILED`.
+func transferError(err error) string {
+	if errors.Is(err, dns.ErrRcode) {
+		if i := strings.LastIndex(err.Error(), ": "); i >= 0 {
+			return err.Error()[i+2:]
+		}
+	}
+
+	return "FAILED"
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnstest"
+)
+
+// transferServer starts a DNS server over TCP transferring a zone of count A
+// records between it's SOA records, 100 records to each message.
+func transferServer(t *testing.T, count int) string {
+	t.Helper()
+
+	soa := dnstest.New("example.com. IN SOA ns.example.com. admin.example.com. 1 3600 600 86400 60")
+
+	handler := dns.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+		r.Unpack()
+		w.Hijack()
+
+		env := make(chan *dns.Envelope)
+		go func() {
+			defer close(env)
+
+			env <- &dns.Envelope{Answer: []dns.RR{soa}}
+
+			var rrs []dns.RR
+			for i := range count {
+				rrs = append(rrs, dnstest.New(fmt.Sprintf("host%d.example.com. IN A 192.0.2.1", i)))
+
+				if len(rrs) == 100 {
+					env <- &dns.Envelope{Answer: rrs}
+					rrs = nil
+				}
+			}
+
+			env <- &dns.Envelope{Answer: append(rrs, soa)}
+		}()
+
+		dns.NewClient().TransferOut(w, r, env)
+		w.Close()
+	})
+
+	cancel, addr, err := dnstest.TCPServer("127.0.0.1:0", func(s *dns.Server) { s.Handler = handler })
+	must(t, err)
+	t.Cleanup(cancel)
+
+	return addr
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, _ := newTestServer(t)
+	defer s.Close()
+
+	s.maxTransferRecords = 2000
+
+	q := &models.Query{Type: apiv1.TypeAXFR, Name: "example.com", Server: transferServer(t, 2500)}
+	must(t, d.CreateQuery(ctx, q))
+
+	must(t, s.resolveAll(q, d.CreateLookup))
+
+	stored, err := d.GetQueryByID(ctx, q.ID)
+	must(t, err)
+
+	// two batches of records, then the outcome of the transfer.
+	if n := len(stored.Lookups); n != 3 {
+		t.Fatalf("expected 3 lookups to be stored, got %d", n)
+	}
+
+	res, err := s.GetQuery(ctx, &apiv1.GetQueryRequest{ID: q.ID.String()})
+	must(t, err)
+
+	if n := len(res.Query.Lookups); n != 1 {
+		t.Fatalf("expected lookups to be merged into 1, got %d", n)
+	}
+
+	l := res.Query.Lookups[0]
+
+	if l.Error != nil {
+		t.Errorf("expected no error, got %q", *l.Error)
+	}
+
+	if n := len(l.Records); n != 2000 {
+		t.Errorf("expected 2000 records, got %d", n)
+	}
+
+	if !l.TruncatedByDennis || l.TotalRecords != 2502 {
+		t.Errorf("expected lookup to be truncated from 2502 records, got %t and %d", l.TruncatedByDennis, l.TotalRecords)
+	}
+
+	if first := l.Records[0]; first.Type != "SOA" {
+		t.Errorf("expected records in the order they were received, first is %s", first.Type)
+	}
+}
+
+func TestTransferIncremental(t *testing.T) {
+	ctx := context.Background()
+
+	s, d, _ := newTestServer(t)
+	defer s.Close()
+
+	soa := func(serial int) dns.RR {
+		return dnstest.New(fmt.Sprintf("example.com. IN SOA ns.example.com. admin.example.com. %d 3600 600 86400 60", serial))
+	}
+
+	// the changes from serial 1 to 2 are transferred across messages, one
+	// record deleted and another added.
+	handler := dns.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+		r.Unpack()
+		w.Hijack()
+
+		if _, ok := r.Question[0].(*dns.IXFR); !ok {
+			t.Errorf("expected IXFR question, got %s", r.Question[0])
+		} else if soa, ok := r.Ns[0].(*dns.SOA); !ok || soa.Serial != 1 {
+			t.Errorf("expected SOA with serial 1 in authority, got %v", r.Ns)
+		}
+
+		env := make(chan *dns.Envelope)
+		go func() {
+			defer close(env)
+
+			env <- &dns.Envelope{Answer: []dns.RR{soa(2), soa(1), dnstest.New("old.example.com. IN A 192.0.2.1")}}
+			env <- &dns.Envelope{Answer: []dns.RR{soa(2), dnstest.New("new.example.com. IN A 192.0.2.2")}}
+			env <- &dns.Envelope{Answer: []dns.RR{soa(2)}}
+		}()
+
+		dns.NewClient().TransferOut(w, r, env)
+		w.Close()
+	})
+
+	cancel, addr, err := dnstest.TCPServer("127.0.0.1:0", func(s *dns.Server) { s.Handler = handler })
+	must(t, err)
+	t.Cleanup(cancel)
+
+	q := &models.Query{Type: apiv1.TypeIXFR, Name: "example.com", Server: addr, Serial: new(uint32(1))}
+	must(t, d.CreateQuery(ctx, q))
+
+	must(t, s.resolveAll(q, d.CreateLookup))
+
+	res, err := s.GetQuery(ctx, &apiv1.GetQueryRequest{ID: q.ID.String()})
+	must(t, err)
+
+	l := res.Query.Lookups[0]
+	if l.Error != nil {
+		t.Fatalf("expected no error, got %q", *l.Error)
+	}
+
+	var got []string
+	for _, rec := range l.Records {
+		got = append(got, rec.Type+" "+rec.Name)
+	}
+
+	want := []string{
+		"SOA example.com.", "SOA example.com.", "A old.example.com.", "SOA example.com.", "A new.example.com.",
+		"SOA example.com.",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected records %v, got %v", want, got)
+	}
+}
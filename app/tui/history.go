@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// maxHistory is the number of Entries kept in memory by History.
+const maxHistory = 500
+
+// Entry is a Query made from the TUI, remembered so it can be viewed again.
+type Entry struct {
+	// Target is the DENNIS the Query was made against, either the URL of a
+	// server or the configuration file when running locally.
+	Target string `json:"target"`
+
+	// ID is the unique ID of the Query.
+	ID string `json:"id"`
+
+	// Line is the query as typed into the query bar.
+	Line string `json:"line"`
+
+	// CreatedAt is the UTC timestamp the Query was made.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// History is the Queries previously made from the TUI, most recent first. If
+// it has a path, each Entry is also appended to that file as a line of JSON.
+type History struct {
+	path    string
+	entries []*Entry
+}
+
+// LoadHistory reads the History persisted at path, which need not exist yet.
+// If path is empty, History is only kept in memory.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not open history: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := new(Entry)
+
+		// a line that cannot be read, such as one cut short by a crash, is
+		// skipped rather than losing the rest of the History.
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+
+		h.entries = append(h.entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read history: %w", err)
+	}
+
+	slices.Reverse(h.entries)
+
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[:maxHistory]
+	}
+
+	return h, nil
+}
+
+// Add remembers e as the most recent Entry, appending it to the file of
+// History if it has one.
+func (h *History) Add(e *Entry) error {
+	h.entries = slices.Insert(h.entries, 0, e)
+
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[:maxHistory]
+	}
+
+	if h.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return fmt.Errorf("could not create history: %w", err)
+	}
+
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open history: %w", err)
+	}
+	defer f.Close()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(b, '\n'))
+	if err != nil {
+		return fmt.Errorf("could not write history: %w", err)
+	}
+
+	return nil
+}
+
+// Entries returns the Entries made against target, most recent first.
+func (h *History) Entries(target string) []*Entry {
+	var entries []*Entry
+
+	for _, e := range h.entries {
+		if e.Target == target {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
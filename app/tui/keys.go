@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// the names of the special keys understood by the TUI.
+const (
+	keyEnter     = "enter"
+	keyBackspace = "backspace"
+	keyTab       = "tab"
+	keyEsc       = "esc"
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdown"
+	keyClear     = "ctrl+u"
+	keyQuit      = "ctrl+c"
+)
+
+// key is a single key pressed by the user, either a printable character or
+// one of the special keys above.
+type key struct {
+	// r is the character typed, if the key is printable.
+	r rune
+
+	// name is the name of a special key, if it is not printable.
+	name string
+}
+
+// escapes are the ANSI escape sequences sent by terminals for special keys,
+// following `ESC [` or `ESC O`.
+var escapes = map[string]string{
+	"A":  keyUp,
+	"B":  keyDown,
+	"C":  keyRight,
+	"D":  keyLeft,
+	"H":  keyHome,
+	"F":  keyEnd,
+	"1~": keyHome,
+	"4~": keyEnd,
+	"5~": keyPageUp,
+	"6~": keyPageDown,
+}
+
+// decodeKeys decodes the keys in b, as read from a terminal in raw mode.
+// Control characters and escape sequences that are not understood are
+// ignored.
+func decodeKeys(b []byte) []key {
+	var keys []key
+
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) > 2 && (b[1] == '[' || b[1] == 'O') {
+				// the sequence ends at the first letter or tilde.
+				end := 2
+				for end < len(b) && end < 6 && !isFinal(b[end]) {
+					end++
+				}
+
+				if end < len(b) {
+					if name, ok := escapes[string(b[2:end+1])]; ok {
+						keys = append(keys, key{name: name})
+					}
+
+					b = b[end+1:]
+					continue
+				}
+			}
+
+			keys = append(keys, key{name: keyEsc})
+			b = b[1:]
+
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{name: keyEnter})
+			b = b[1:]
+
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{name: keyBackspace})
+			b = b[1:]
+
+		case c == '\t':
+			keys = append(keys, key{name: keyTab})
+			b = b[1:]
+
+		case c == 0x03 || c == 0x04:
+			keys = append(keys, key{name: keyQuit})
+			b = b[1:]
+
+		case c == 0x15:
+			keys = append(keys, key{name: keyClear})
+			b = b[1:]
+
+		case c == 0x01:
+			keys = append(keys, key{name: keyHome})
+			b = b[1:]
+
+		case c == 0x05:
+			keys = append(keys, key{name: keyEnd})
+			b = b[1:]
+
+		case c < 0x20:
+			b = b[1:]
+
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, key{r: r})
+			}
+
+			b = b[size:]
+		}
+	}
+
+	return keys
+}
+
+// isFinal returns true if c ends an escape sequence.
+func isFinal(c byte) bool {
+	return c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// readKeys sends the keys read from r to keys until r returns an error, at
+// which point keys is closed.
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+
+	buf := make([]byte, 256)
+
+	for {
+		n, err := r.Read(buf)
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+)
+
+// ParseQuery parses a line typed into the query bar in the style of dig, such
+// as `AAAA MX example.com @1.1.1.1`, into a request to create a Query. The
+// word containing a dot or colon is the name, a word beginning with `@` is the
+// server, and any other words are record types. If no type is given, A is
+// assumed. The request is otherwise validated by the API.
+func ParseQuery(line string) (*apiv1.CreateQueryRequest, error) {
+	req := &apiv1.CreateQueryRequest{Priority: apiv1.PriorityInteractive}
+
+	var types []string
+
+	for _, word := range strings.Fields(line) {
+		switch {
+		case strings.HasPrefix(word, "@"):
+			if req.Server != "" {
+				return nil, errors.New("only one server may be given")
+			}
+
+			req.Server = word
+
+		case strings.ContainsAny(word, ".:"):
+			if req.Name != "" {
+				return nil, errors.New("only one name may be given")
+			}
+
+			req.Name = word
+
+		default:
+			types = append(types, strings.ToUpper(word))
+		}
+	}
+
+	if req.Name == "" {
+		return nil, errors.New("a name to query is required, such as example.com")
+	}
+
+	switch len(types) {
+	case 0:
+		req.Type = "A"
+	case 1:
+		req.Type = types[0]
+	default:
+		req.Types = types
+	}
+
+	return req, nil
+}
@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"golang.org/x/term"
+)
+
+// the ANSI styles lines are drawn in.
+const (
+	styleNone   = ""
+	styleTitle  = "\x1b[7m"
+	styleHeader = "\x1b[1;36m"
+	styleDim    = "\x1b[2m"
+	styleError  = "\x1b[31m"
+	styleSelect = "\x1b[1m"
+	styleReset  = "\x1b[0m"
+)
+
+// line is a single line of the screen, drawn in style.
+type line struct {
+	text  string
+	style string
+}
+
+// headerLines is the number of lines above the Query or History.
+const headerLines = 4
+
+// draw redraws the whole screen of out.
+func (t *TUI) draw(out *os.File) {
+	width, height, err := term.GetSize(int(out.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	lines := []line{
+		{text: "DENNIS · " + t.target + " · Enter to query, Tab for history, Ctrl-C to quit", style: styleTitle},
+		{text: "> " + string(t.input)},
+		t.statusLine(),
+		{text: strings.Repeat("─", width), style: styleDim},
+	}
+
+	var body []line
+	if t.browsing {
+		body = t.historyLines()
+	} else if t.query != nil {
+		body = queryLines(t.query, t.resolvers)
+	} else {
+		body = []line{
+			{text: "Type a name to query, such as `AAAA MX example.com` or `example.com @1.1.1.1`.", style: styleDim},
+		}
+	}
+
+	// the Query is scrolled, but never past it's last page. History is
+	// scrolled to keep the selected Entry visible.
+	visible := max(height-headerLines, 1)
+
+	if t.browsing {
+		body = body[max(t.selected-visible+1, 0):]
+	} else {
+		t.scroll = min(t.scroll, max(len(body)-visible, 0))
+		body = body[t.scroll:]
+	}
+
+	lines = append(lines, body[:min(len(body), visible)]...)
+
+	b := new(strings.Builder)
+	b.WriteString("\x1b[H")
+
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+
+		b.WriteString(l.style)
+		b.WriteString(truncate(l.text, width))
+		if l.style != styleNone {
+			b.WriteString(styleReset)
+		}
+		b.WriteString("\x1b[K")
+	}
+
+	b.WriteString("\x1b[J")
+
+	// the cursor is shown in the query bar, unless History is displayed.
+	if t.browsing {
+		b.WriteString("\x1b[?25l")
+	} else {
+		b.WriteString("\x1b[2;" + strconv.Itoa(min(t.cursor+3, width)) + "H\x1b[?25h")
+	}
+
+	io.WriteString(out, b.String())
+}
+
+// truncate shortens s to at most width characters.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+
+	return string(r[:width])
+}
+
+// statusLine describes the Query displayed, or the last error encountered.
+func (t *TUI) statusLine() line {
+	switch {
+	case t.failed:
+		return line{text: t.status, style: styleError}
+	case t.status != "":
+		return line{text: t.status}
+	case t.browsing:
+		return line{text: "History: Up and Down to select, Enter to open, Esc to return", style: styleDim}
+	case t.query == nil:
+		return line{}
+	}
+
+	q := t.query
+
+	text := strings.Join(q.AllTypes(), ", ") + ": " + q.Name
+	if q.FinishedAt == nil {
+		text += " · resolving..."
+	} else {
+		text += " · finished in " + q.FinishedAt.Sub(q.CreatedAt).Round(time.Millisecond).String()
+	}
+
+	return line{text: text + " · " + q.ID.String(), style: styleDim}
+}
+
+// historyLines lists the Entries of History, highlighting the selected Entry.
+func (t *TUI) historyLines() []line {
+	entries := t.history.Entries(t.target)
+	if len(entries) == 0 {
+		return []line{{text: "No queries have been made yet.", style: styleDim}}
+	}
+
+	lines := make([]line, 0, len(entries))
+
+	for i, e := range entries {
+		l := line{text: "  " + e.CreatedAt.Local().Format("2006-01-02 15:04:05") + "  " + e.Line}
+		if i == t.selected {
+			l.text, l.style = ">"+l.text[1:], styleSelect
+		}
+
+		lines = append(lines, l)
+	}
+
+	return lines
+}
+
+// queryLines draws a pane for each resolver of q, in the order resolvers are
+// displayed, containing it's Lookups. Resolvers that have not answered yet
+// are shown as resolving, and Lookups of other resolvers, such as an ad-hoc
+// resolver, follow.
+func queryLines(q *models.Query, resolvers []*models.Resolver) []line {
+	var names []string
+
+	// zone transfers and ad-hoc resolvers do not use the configured resolvers.
+	if q.Server == "" {
+		for _, rsv := range resolvers {
+			if inGroups(rsv, q.Groups) {
+				names = append(names, rsv.Name)
+			}
+		}
+	}
+
+	lookups := make(map[string][]*models.Lookup)
+	for _, l := range q.Lookups {
+		if !slices.Contains(names, l.Resolver) {
+			names = append(names, l.Resolver)
+		}
+
+		lookups[l.Resolver] = append(lookups[l.Resolver], l)
+	}
+
+	var lines []line
+
+	for _, name := range names {
+		lines = append(lines, line{text: "┌─ " + name, style: styleHeader})
+
+		if len(lookups[name]) == 0 {
+			if q.FinishedAt == nil {
+				lines = append(lines, line{text: "│ resolving...", style: styleDim})
+			} else {
+				lines = append(lines, line{text: "│ no answer", style: styleError})
+			}
+		}
+
+		for _, l := range lookups[name] {
+			lines = append(lines, lookupLines(l)...)
+		}
+
+		lines = append(lines, line{})
+	}
+
+	return lines
+}
+
+// lookupLines draws a summary of l followed by it's records.
+func lookupLines(l *models.Lookup) []line {
+	summary := []string{}
+	if l.Type != "" {
+		summary = append(summary, l.Type)
+	}
+
+	// the Lookups of a resolver from each location are shown together.
+	if l.Location != "" {
+		summary = append(summary, "from "+l.Location)
+	}
+
+	switch {
+	case l.Error != nil:
+		summary = append(summary, *l.Error)
+	case l.Header != nil:
+		summary = append(summary, l.Header.Rcode)
+	}
+
+	summary = append(summary, strconv.Itoa(l.RTT)+"ms")
+
+	if l.Header != nil {
+		summary = append(summary, "flags: "+l.Header.Flags())
+	}
+
+	if l.DNSSEC != nil {
+		summary = append(summary, "dnssec: "+*l.DNSSEC)
+	}
+
+	if l.ResolverHealth != nil {
+		summary = append(summary, "failing health checks")
+	}
+
+	style := styleDim
+	if l.Error != nil {
+		style = styleError
+	}
+
+	lines := []line{{text: "│ " + strings.Join(summary, " · "), style: style}}
+
+	for _, record := range l.Records {
+		for _, content := range record.Content {
+			// records of a zone transfer are of any name and type.
+			if record.Name != "" {
+				content = record.Name + " " + record.Type + " " + content
+			}
+
+			lines = append(lines, line{text: fmt.Sprintf("│ %-7d %s", record.TTL, content)})
+		}
+
+		if len(record.Params) > 0 {
+			lines = append(lines, line{text: fmt.Sprintf("│ %-7d %s", record.TTL, record.ParamString())})
+		}
+	}
+
+	if l.TruncatedByDennis {
+		more := strconv.Itoa(l.TotalRecords - len(l.Records))
+		lines = append(lines, line{text: "│ (" + more + " more records not stored)", style: styleDim})
+	}
+
+	return lines
+}
+
+// inGroups returns true if rsv is in any of groups, or if no groups are
+// given.
+func inGroups(rsv *models.Resolver, groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+
+	for _, group := range groups {
+		if slices.Contains(rsv.Groups, group) {
+			return true
+		}
+	}
+
+	return false
+}
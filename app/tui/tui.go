@@ -0,0 +1,295 @@
+// Package tui implements an interactive terminal interface to DENNIS, for
+// users who never leave the terminal but want to compare resolvers.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+
+	"golang.org/x/term"
+)
+
+// refreshInterval is how often a Query is retrieved while it is resolving.
+const refreshInterval = 250 * time.Millisecond
+
+// requestTimeout is the longest the TUI waits for the API to answer.
+const requestTimeout = 10 * time.Second
+
+// TUI is an interactive terminal interface to an implementation of API, such
+// as a Client of a remote DENNIS or a local Server. Names are queried from a
+// query bar, the Lookups of each resolver are displayed in their own pane as
+// they resolve, and previous Queries can be browsed from History.
+type TUI struct {
+	api     apiv1.API
+	target  string
+	history *History
+
+	resolvers []*models.Resolver
+
+	// query is the Query displayed, as last retrieved. It is retrieved again
+	// while resolving is set.
+	query     *models.Query
+	resolving bool
+
+	// input is the content of the query bar, and cursor the position within
+	// it that characters are typed.
+	input  []rune
+	cursor int
+
+	// status is a message displayed below the query bar, such as an error.
+	status string
+	failed bool
+
+	// browsing is set while History is displayed rather than the Query,
+	// with selected being the Entry highlighted.
+	browsing bool
+	selected int
+
+	// scroll is the number of lines of the Query scrolled past.
+	scroll int
+}
+
+// New initializes a TUI for api. target describes the DENNIS api is connected
+// to, and distinguishes it's Entries in history.
+func New(api apiv1.API, target string, history *History) *TUI {
+	return &TUI{api: api, target: target, history: history}
+}
+
+// Run takes over the terminal of in and out, which must be a terminal, until
+// the user quits or ctx is canceled. The terminal is restored before Run
+// returns.
+func (t *TUI) Run(ctx context.Context, in, out *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("input is not a terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("could not configure terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	// draw on the alternate screen, so the terminal's scrollback is left as
+	// it was once the TUI exits.
+	fmt.Fprint(out, "\x1b[?1049h")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	if err := t.loadResolvers(ctx); err != nil {
+		t.setError(err)
+	}
+
+	keys := make(chan key, 16)
+	go readKeys(in, keys)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		t.draw(out)
+
+		select {
+		case k, ok := <-keys:
+			if !ok || t.handle(ctx, k) {
+				return nil
+			}
+
+		case <-ticker.C:
+			t.refresh(ctx)
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// loadResolvers retrieves the resolvers, to display a pane for each before
+// they have resolved.
+func (t *TUI) loadResolvers(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	res, err := t.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return err
+	}
+
+	t.resolvers = res.Resolvers
+
+	return nil
+}
+
+// handle updates the TUI for a key pressed by the user. It returns true if the
+// user asked to quit.
+func (t *TUI) handle(ctx context.Context, k key) bool {
+	switch k.name {
+	case keyQuit:
+		return true
+
+	case keyTab:
+		t.browsing = !t.browsing
+		t.selected = 0
+		return false
+	}
+
+	if t.browsing {
+		t.handleHistory(ctx, k)
+	} else {
+		t.handleQuery(ctx, k)
+	}
+
+	return false
+}
+
+// handleHistory handles a key pressed while browsing History.
+func (t *TUI) handleHistory(ctx context.Context, k key) {
+	entries := t.history.Entries(t.target)
+
+	switch k.name {
+	case keyUp:
+		t.selected = max(t.selected-1, 0)
+
+	case keyDown:
+		t.selected = min(t.selected+1, max(len(entries)-1, 0))
+
+	case keyEsc:
+		t.browsing = false
+
+	case keyEnter:
+		if t.selected < len(entries) {
+			t.browsing = false
+			t.open(ctx, entries[t.selected])
+		}
+	}
+}
+
+// handleQuery handles a key pressed while the query bar is focused.
+func (t *TUI) handleQuery(ctx context.Context, k key) {
+	switch k.name {
+	case "":
+		t.input = slices.Insert(t.input, t.cursor, k.r)
+		t.cursor++
+
+	case keyBackspace:
+		if t.cursor > 0 {
+			t.input = slices.Delete(t.input, t.cursor-1, t.cursor)
+			t.cursor--
+		}
+
+	case keyLeft:
+		t.cursor = max(t.cursor-1, 0)
+
+	case keyRight:
+		t.cursor = min(t.cursor+1, len(t.input))
+
+	case keyHome:
+		t.cursor = 0
+
+	case keyEnd:
+		t.cursor = len(t.input)
+
+	case keyClear, keyEsc:
+		t.input, t.cursor = nil, 0
+
+	case keyUp:
+		t.scroll = max(t.scroll-1, 0)
+
+	case keyDown:
+		t.scroll++
+
+	case keyPageUp:
+		t.scroll = max(t.scroll-10, 0)
+
+	case keyPageDown:
+		t.scroll += 10
+
+	case keyEnter:
+		t.submit(ctx)
+	}
+}
+
+// submit creates a Query from the query bar, remembering it in History.
+func (t *TUI) submit(ctx context.Context) {
+	line := string(t.input)
+
+	req, err := ParseQuery(line)
+	if err != nil {
+		t.setError(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	res, err := t.api.CreateQuery(ctx, req)
+	if err != nil {
+		t.setError(err)
+		return
+	}
+
+	// the Query returned may be shared with a local Server as it resolves,
+	// so only Queries retrieved by refresh are displayed.
+	t.query, t.resolving, t.scroll = &models.Query{ID: res.Query.ID}, true, 0
+	t.setStatus("")
+	t.refresh(ctx)
+
+	err = t.history.Add(&Entry{
+		Target:    t.target,
+		ID:        res.Query.ID.String(),
+		Line:      line,
+		CreatedAt: res.Query.CreatedAt,
+	})
+	if err != nil {
+		t.setError(err)
+	}
+}
+
+// open displays the Query of an Entry from History.
+func (t *TUI) open(ctx context.Context, e *Entry) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	res, err := t.api.GetQuery(ctx, &apiv1.GetQueryRequest{ID: e.ID})
+	if err != nil {
+		t.setError(err)
+		return
+	}
+
+	t.query, t.resolving, t.scroll = res.Query, res.Query.FinishedAt == nil, 0
+	t.input, t.cursor = []rune(e.Line), len([]rune(e.Line))
+	t.setStatus("")
+}
+
+// refresh retrieves the Query again if it is still resolving, so it's panes
+// update as each resolver answers.
+func (t *TUI) refresh(ctx context.Context) {
+	if t.query == nil || !t.resolving {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	res, err := t.api.GetQuery(ctx, &apiv1.GetQueryRequest{ID: t.query.ID.String()})
+	if err != nil {
+		t.setError(err)
+		return
+	}
+
+	t.query, t.resolving = res.Query, res.Query.FinishedAt == nil
+}
+
+func (t *TUI) setStatus(msg string) {
+	t.status, t.failed = msg, false
+}
+
+func (t *TUI) setError(err error) {
+	t.status, t.failed = err.Error(), true
+}
@@ -2,12 +2,20 @@ package app
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
 	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/clock"
 	"github.com/jamescun/dennis/app/pkg/http/web"
 	"github.com/jamescun/dennis/app/views/templates"
+	"github.com/jamescun/dennis/app/views/text"
 )
 
 // UI implements the HTML-based graphical user interface of DENNIS for users to
@@ -15,44 +23,201 @@ import (
 type UI struct {
 	api apiv1.API
 	log *slog.Logger
+
+	// assets optionally overrides the embedded assets for templates.
+	assets fs.FS
+
+	// support is optionally a URL where users can report errors.
+	support string
+	errors  *Errors
+
+	// clock is the time timestamps are rendered relative to.
+	clock clock.Clock
 }
 
 // NewUI initializes a new user interface for a given logic backup implementing
-// API, and a logger for error messages.
-func NewUI(backend apiv1.API, log *slog.Logger) *UI {
+// API, and a logger for error messages. If assets is set, it's files are
+// served in place of the embedded assets, such as `css/style.css`. Unexpected
+// errors are retained in errs, and users are directed to report them to
+// support if set.
+func NewUI(backend apiv1.API, assets fs.FS, support string, errs *Errors, log *slog.Logger) *UI {
 	return &UI{
-		api: backend,
-		log: log,
+		api:     backend,
+		log:     log,
+		assets:  assets,
+		support: support,
+		errors:  errs,
+		clock:   clock.Real,
 	}
 }
 
+// SetClock replaces the Clock timestamps are rendered relative to, such as
+// `3m ago`. It must be called before the UI is served.
+func (ui *UI) SetClock(c clock.Clock) {
+	ui.clock = c
+}
+
 // Routes applies the path-based routes of UI to an HTTP router.
 func (ui *UI) Routes(r *web.Router) {
+	r.Use(ui.banners)
 	r.NotFound(ui.NotFound)
 	r.ErrorHandler(ui.ErrorHandler)
 
 	r.Get("/", ui.Index)
 	r.Post("/query", ui.Query)
 	r.Get("/query/{id}", ui.GetQuery)
+	r.Post("/recipe", ui.RunRecipe)
+	r.Get("/run/{id}", ui.GetRecipeRun)
+	r.Get("/status", ui.Status)
+
+	// mount the embedded assets for templates, and any overrides.
+	r.Handle("/assets/*", templates.Assets("/assets", ui.assets))
+}
 
-	// mount the embedded assets for templates.
-	r.Handle("/assets/*", templates.Assets("/assets"))
+// banners is an HTTP middleware that checks the status of DENNIS before each
+// request, adding any banners that should be displayed to users, such as
+// maintenance mode, to the request context.
+func (ui *UI) banners(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		status, err := ui.api.GetStatus(ctx, &apiv1.GetStatusRequest{})
+		if err != nil {
+			ui.log.Error("could not get status", slog.String("error", err.Error()))
+		} else if status.Maintenance {
+			ctx = templates.WithBanners(ctx, "DENNIS is down for maintenance. "+
+				"Results of existing Queries can still be viewed, but new Queries cannot be created.")
+		} else if status.ReadOnly {
+			ctx = templates.WithBanners(ctx, "DENNIS cannot reach it's database. "+
+				"Recently viewed results can still be viewed, but new Queries cannot be created until it recovers.")
+		} else if status.Busy {
+			ctx = templates.WithBanners(ctx, "DENNIS is busy, results may be slower than usual.")
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
 func (ui *UI) Index(ctx context.Context, r *web.Request) (web.Template, error) {
-	return templates.Index(nil), nil
+	return ui.index(ctx, nil)
 }
 
+// index renders the Index page, optionally displaying a validation error from
+// a previous attempt at creating a Query.
+func (ui *UI) index(ctx context.Context, queryErr *apiv1.Error) (web.Template, error) {
+	ver, err := ui.api.GetVersion(ctx, &apiv1.GetVersionRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	recipes, err := ui.api.ListRecipes(ctx, &apiv1.ListRecipesRequest{})
+	if isAPIError(err, apiv1.ErrorCodeUnavailable) {
+		// user defined Recipes cannot be read while DENNIS is read-only, but
+		// the page is still useful without them.
+		recipes = &apiv1.ListRecipesResponse{Recipes: builtInRecipes}
+	} else if err != nil {
+		return nil, err
+	}
+
+	rsv, err := ui.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return templates.Index(ver.Features, recipes.Recipes, resolverGroups(rsv.Resolvers), queryErr), nil
+}
+
+// resolverGroups returns the distinct groups of resolvers, sorted by name.
+func resolverGroups(resolvers []*models.Resolver) []string {
+	var groups []string
+
+	for _, rsv := range resolvers {
+		groups = append(groups, rsv.Groups...)
+	}
+
+	slices.Sort(groups)
+
+	return slices.Compact(groups)
+}
+
+// maxFormSize is the largest form body, in bytes, that will be accepted when
+// creating a Query. The form only contains a type and name, so any legitimate
+// request is well below this.
+const maxFormSize = 4096
+
 func (ui *UI) Query(ctx context.Context, r *web.Request) (web.Template, error) {
-	res, err := ui.api.CreateQuery(ctx, &apiv1.CreateQueryRequest{
-		Type: r.FormValue("type"),
-		Name: r.FormValue("name"),
-	})
+	form, err := r.ParseForm(maxFormSize)
+	if err != nil {
+		return ui.formError(ctx, err)
+	}
+
+	// samples is optional, older forms and scripts do not send it.
+	var samples int
+	if s := form.Get("samples"); s != "" {
+		samples, err = strconv.Atoi(s)
+		if err != nil {
+			return ui.index(ctx, &apiv1.Error{
+				Code:    apiv1.ErrorCodeBadRequest,
+				Field:   ".samples",
+				Message: "Samples must be a number",
+			})
+		}
+	}
+
+	// edns0 and udpSize are advanced options, only sent if changed.
+	var edns0 *models.EDNS0
+	if form.Get("edns0") == "off" {
+		edns0 = &models.EDNS0{Disabled: true}
+	} else if s := form.Get("udpSize"); s != "" {
+		udpSize, err := strconv.Atoi(s)
+		if err != nil {
+			return ui.index(ctx, &apiv1.Error{
+				Code:    apiv1.ErrorCodeBadRequest,
+				Field:   ".edns0.udpSize",
+				Message: "UDP size must be a number",
+			})
+		}
+
+		edns0 = &models.EDNS0{UDPSize: udpSize}
+	}
+
+	req := &apiv1.CreateQueryRequest{
+		Name:     form.Get("name"),
+		Class:    form.Get("class"),
+		Server:   form.Get("server"),
+		Groups:   form["group"],
+		Priority: apiv1.PriorityInteractive,
+		Samples:  samples,
+		DNSSEC:   form.Get("dnssec") != "",
+		EDNS0:    edns0,
+
+		// DNS64 only applies to AAAA Queries, it is ignored for other types
+		// rather than rejected as the form always offers it.
+		DNS64: form.Get("dns64") != "" && slices.Contains(form["type"], "AAAA"),
+
+		NoRecursion:      form.Get("norecursion") != "",
+		CheckingDisabled: form.Get("cd") != "",
+		Capture:          form.Get("capture") != "",
+	}
+
+	// several types may be selected, a single type is sent as Type so it is
+	// validated as it always has been.
+	if types := form["type"]; len(types) > 1 {
+		req.Types = types
+	} else {
+		req.Type = form.Get("type")
+	}
+
+	res, err := ui.api.CreateQuery(ctx, req)
 	if err != nil {
 		if err, ok := err.(*apiv1.Error); ok {
+			if err.Code == apiv1.ErrorCodeUnavailable {
+				return web.Status(templates.Unavailable(err.Message), http.StatusServiceUnavailable), nil
+			}
+
 			// a validation error was discovered at the logic layer, display it to
 			// the user to try again.
-			return templates.Index(err), nil
+			return ui.index(ctx, err)
 		}
 		return nil, err
 	}
@@ -60,15 +225,129 @@ func (ui *UI) Query(ctx context.Context, r *web.Request) (web.Template, error) {
 	return web.Redirect("/query/"+res.Query.ID.String(), http.StatusSeeOther), nil
 }
 
+// formError renders the Index page with a friendly explanation of why a
+// submitted form could not be parsed, and an appropriate HTTP status code.
+func (ui *UI) formError(ctx context.Context, err error) (web.Template, error) {
+	var (
+		status  int
+		message string
+	)
+
+	switch {
+	case errors.Is(err, web.ErrFormTooLarge):
+		status, message = http.StatusRequestEntityTooLarge, "Form submission is too large"
+	case errors.Is(err, web.ErrFormMediaType):
+		status = http.StatusUnsupportedMediaType
+		message = "Form submission must be URL-encoded, file uploads are not supported"
+	case errors.Is(err, web.ErrFormMalformed):
+		status, message = http.StatusBadRequest, "Form submission is malformed"
+	default:
+		return nil, err
+	}
+
+	tpl, err := ui.index(ctx, &apiv1.Error{Code: apiv1.ErrorCodeBadRequest, Message: message})
+	if err != nil {
+		return nil, err
+	}
+
+	return web.Status(tpl, status), nil
+}
+
 func (ui *UI) GetQuery(ctx context.Context, r *web.Request) (web.Template, error) {
 	res, err := ui.api.GetQuery(ctx, &apiv1.GetQueryRequest{
 		ID: web.URLParam(ctx, "id"),
 	})
+	if apiErr, ok := err.(*apiv1.Error); ok && apiErr.Code == apiv1.ErrorCodeUnavailable {
+		return web.Status(templates.Unavailable(apiErr.Message), http.StatusServiceUnavailable), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if r.Accepts("text/plain") {
+		return withQueryCaching(r, text.Query(res.Query), res, textETag(res)), nil
+	}
+
+	rsv, err := ui.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
 	if err != nil {
 		return nil, err
 	}
 
-	return templates.GetQuery(res.Query), nil
+	return withQueryCaching(r, templates.GetQuery(res.Query, rsv.Resolvers, location(r), ui.clock.Now()), res, ""), nil
+}
+
+// location returns the location timestamps should be rendered in. This is UTC
+// unless the user has requested their own timezone, i.e. `?tz=Europe/London`.
+func location(r *web.Request) *time.Location {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			return l
+		}
+	}
+
+	return time.UTC
+}
+
+func (ui *UI) RunRecipe(ctx context.Context, r *web.Request) (web.Template, error) {
+	form, err := r.ParseForm(maxFormSize)
+	if err != nil {
+		return ui.formError(ctx, err)
+	}
+
+	res, err := ui.api.RunRecipe(ctx, &apiv1.RunRecipeRequest{
+		ID:       form.Get("recipe"),
+		Name:     form.Get("name"),
+		Priority: apiv1.PriorityInteractive,
+	})
+	if err != nil {
+		if err, ok := err.(*apiv1.Error); ok {
+			if err.Code == apiv1.ErrorCodeUnavailable {
+				return web.Status(templates.Unavailable(err.Message), http.StatusServiceUnavailable), nil
+			}
+
+			// a validation error was discovered at the logic layer, display it to
+			// the user to try again.
+			return ui.index(ctx, err)
+		}
+		return nil, err
+	}
+
+	return web.Redirect("/run/"+res.Run.ID.String(), http.StatusSeeOther), nil
+}
+
+func (ui *UI) GetRecipeRun(ctx context.Context, r *web.Request) (web.Template, error) {
+	res, err := ui.api.GetRecipeRun(ctx, &apiv1.GetRecipeRunRequest{
+		ID: web.URLParam(ctx, "id"),
+	})
+	if apiErr, ok := err.(*apiv1.Error); ok && apiErr.Code == apiv1.ErrorCodeUnavailable {
+		return web.Status(templates.Unavailable(apiErr.Message), http.StatusServiceUnavailable), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rsv, err := ui.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return templates.GetRecipeRun(res.Run, res.Queries, res.Report, rsv.Resolvers, location(r), ui.clock.Now()), nil
+}
+
+// Status renders the current health and latency of each resolver, if the
+// status page has been enabled, otherwise it does not exist.
+func (ui *UI) Status(ctx context.Context, r *web.Request) (web.Template, error) {
+	ver, err := ui.api.GetVersion(ctx, &apiv1.GetVersionRequest{})
+	if err != nil {
+		return nil, err
+	} else if !ver.Features.Enabled(apiv1.FeatureStatusPage) {
+		return web.Status(templates.NotFound(), http.StatusNotFound), nil
+	}
+
+	rsv, err := ui.api.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return templates.Status(rsv.Resolvers, location(r), ui.clock.Now()), nil
 }
 
 func (ui *UI) NotFound(ctx context.Context, r *web.Request) (web.Template, error) {
@@ -78,5 +357,7 @@ func (ui *UI) NotFound(ctx context.Context, r *web.Request) (web.Template, error
 func (ui *UI) ErrorHandler(ctx context.Context, r *web.Request, err error) web.Template {
 	r.Log().Error("an unexpected error occurred", slog.String("error", err.Error()))
 
-	return templates.Error()
+	logged := ui.errors.add(ctx, r, err)
+
+	return templates.Error(logged.RequestID, logged.OccurredAt, ui.support)
 }
@@ -0,0 +1,21 @@
+package templates
+
+import (
+	"context"
+)
+
+// bannersKey is the context key used to store banners for page().
+type bannersKey struct{}
+
+// WithBanners returns a copy of parent containing messages to be displayed as
+// a banner at the top of every page rendered with it, such as a notice that
+// DENNIS is down for maintenance.
+func WithBanners(parent context.Context, banners ...string) context.Context {
+	return context.WithValue(parent, bannersKey{}, banners)
+}
+
+// getBanners returns any messages set on ctx by WithBanners.
+func getBanners(ctx context.Context) []string {
+	banners, _ := ctx.Value(bannersKey{}).([]string)
+	return banners
+}
@@ -0,0 +1,135 @@
+package templates
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/a-h/templ"
+)
+
+// waterfall returns the CSS to position a bar within a waterfall chart of the
+// total time taken by Query q, beginning offset milliseconds after the Query
+// was created and lasting for duration milliseconds.
+func waterfall(q *models.Query, offset, duration int) string {
+	total := float64(max(q.Timings.Total, 1))
+
+	left := min(max(float64(offset)/total*100, 0), 100)
+	width := min(max(float64(duration)/total*100, 1), 100-left)
+
+	return fmt.Sprintf("margin-left: %.1f%%; width: %.1f%%;", left, width)
+}
+
+// lookupOffset returns the number of milliseconds after Query q was created
+// that Lookup l was sent to it's resolver.
+func lookupOffset(q *models.Query, l *models.Lookup) int {
+	return int(l.ResolvedAt.Sub(q.CreatedAt)/time.Millisecond) - l.RTT
+}
+
+// queryType returns the record types of Query q, preceded by it's class if it
+// is not IN, such as `CH TXT` or `A, AAAA`.
+func queryType(q *models.Query) string {
+	types := strings.Join(q.AllTypes(), ", ")
+
+	if q.Class != "" {
+		return q.Class + " " + types
+	}
+
+	return types
+}
+
+// timestampFormat is the layout of absolute timestamps rendered by templates.
+const timestampFormat = "2006-01-02 15:04:05 MST"
+
+// timestamp formats t as an absolute timestamp in the location loc, such as
+// `2026-10-18 00:36:08 UTC`.
+func timestamp(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(timestampFormat)
+}
+
+// dataURL returns a URL embedding msg, so it can be downloaded without
+// another request.
+func dataURL(msg []byte) templ.SafeURL {
+	return templ.SafeURL("data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(msg))
+}
+
+// site returns the identity of the server that answered for a resolver when
+// it was fingerprinted, preferring it's NSID, or `-` if it gave neither.
+func site(fp *models.ResolverFingerprint) string {
+	switch {
+	case fp.NSID != "":
+		return fp.NSID
+	case fp.ServerID != "":
+		return fp.ServerID
+	default:
+		return "-"
+	}
+}
+
+// relative formats t relative to now in the largest whole unit, such as
+// `12s ago` or `3h ago`.
+func relative(t, now time.Time) string {
+	d := now.Sub(t)
+
+	suffix := " ago"
+	if d < 0 {
+		d, suffix = -d, " from now"
+	}
+
+	switch {
+	case d < time.Second:
+		return "just now"
+	case d < time.Minute:
+		return strconv.Itoa(int(d/time.Second)) + "s" + suffix
+	case d < time.Hour:
+		return strconv.Itoa(int(d/time.Minute)) + "m" + suffix
+	case d < 24*time.Hour:
+		return strconv.Itoa(int(d/time.Hour)) + "h" + suffix
+	default:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d" + suffix
+	}
+}
+
+// within formats how long from now until t as an estimate, such as
+// `within ~23m`, or `by now` if t has passed.
+func within(t, now time.Time) string {
+	d := t.Sub(now)
+
+	switch {
+	case d <= 0:
+		return "by now"
+	case d < time.Minute:
+		return "within ~" + strconv.Itoa(int(d/time.Second)) + "s"
+	case d < time.Hour:
+		return "within ~" + strconv.Itoa(int(d/time.Minute)) + "m"
+	default:
+		return "within ~" + strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+}
+
+// resolverByName returns the Resolver from rsv named name, or nil if there is
+// no Resolver by that name.
+func resolverByName(rsv []*models.Resolver, name string) *models.Resolver {
+	for _, r := range rsv {
+		if r.Name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// finished returns true if every Query in queries has finished resolving.
+func finished(queries []*models.Query) bool {
+	for _, q := range queries {
+		if q.FinishedAt == nil {
+			return false
+		}
+	}
+
+	return true
+}
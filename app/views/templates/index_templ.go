@@ -10,11 +10,14 @@ import templruntime "github.com/a-h/templ/runtime"
 
 import (
 	"github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app/models"
 )
 
 // Index is the root page served by DENNIS, containing a description of
-// DENNIS and the form allowing a user to initiate a new query.
-func Index(err *apiv1.Error) templ.Component {
+// DENNIS and the forms allowing a user to initiate a new query or run one of
+// recipes. The forms only offer the options of features that are enabled, and
+// groups of resolvers if any are configured.
+func Index(features apiv1.Features, recipes []*models.Recipe, groups []string, err *apiv1.Error) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -51,29 +54,150 @@ func Index(err *apiv1.Error) templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
+			if features.Enabled(apiv1.FeatureAPI) {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<p>Queries can also be made programmatically using the JSON API at <code>/api/v1</code>.</p>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, " ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
 			if err != nil {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<p>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<p>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 				var templ_7745c5c3_Var3 string
 				templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(err.Error())
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 14, Col: 19}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 21, Col: 19}
 				}
 				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</p>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</p>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " <form method=\"POST\" action=\"/query\"><label for=\"type\">Type:</label> <select name=\"type\" multiple size=\"4\" title=\"Hold Ctrl or Cmd to select several types\"><option value=\"A\" selected>A</option> <option value=\"AAAA\">AAAA</option> <option value=\"CAA\">CAA</option> <option value=\"CNAME\">CNAME</option> <option value=\"DNSKEY\">DNSKEY</option> <option value=\"DS\">DS</option> <option value=\"HTTPS\">HTTPS</option> <option value=\"LOC\">LOC</option> <option value=\"MX\">MX</option> <option value=\"NAPTR\">NAPTR</option> <option value=\"NS\">NS</option> <option value=\"PTR\">PTR</option> <option value=\"SOA\">SOA</option> <option value=\"SRV\">SRV</option> <option value=\"SSHFP\">SSHFP</option> <option value=\"SVCB\">SVCB</option> <option value=\"TLSA\">TLSA</option> <option value=\"TXT\">TXT</option></select> <label for=\"class\">Class:</label> <select name=\"class\"><option value=\"IN\">IN</option> <option value=\"CH\">CH</option></select> <label for=\"name\">Name:</label> <input type=\"text\" name=\"name\" placeholder=\"name to query\"> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if len(groups) > 0 {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<fieldset title=\"Only query the resolvers in these groups, or every resolver if none are selected\"><legend>Groups:</legend> ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				for _, group := range groups {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<label><input type=\"checkbox\" name=\"group\" value=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var4 string
+					templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(group)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 60, Col: 62}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\"> ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var5 string
+					templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(group)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 60, Col: 75}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</label>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</fieldset>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<label for=\"samples\">Samples:</label> <input type=\"number\" name=\"samples\" min=\"1\" value=\"1\"> <label for=\"dnssec\">DNSSEC:</label> <input type=\"checkbox\" name=\"dnssec\"> <details><summary>Advanced</summary> <label for=\"edns0\">EDNS0:</label> <select name=\"edns0\"><option value=\"on\">Enabled</option> <option value=\"off\">Disabled</option></select> <label for=\"udpSize\">UDP size:</label> <input type=\"number\" name=\"udpSize\" min=\"512\" max=\"4096\" placeholder=\"default\"> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if features.Enabled(apiv1.FeatureAdHocResolver) {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<label for=\"server\" title=\"Query only this server rather than the configured resolvers, like the @server of dig\">Server:</label> <input type=\"text\" name=\"server\" placeholder=\"@ns1.example.com\"> ")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " <form method=\"POST\" action=\"/query\"><label for=\"type\">Type:</label> <select name=\"type\"><option value=\"A\">A</option> <option value=\"AAAA\">AAAA</option> <option value=\"CAA\">CAA</option> <option value=\"CNAME\">CNAME</option> <option value=\"DNSKEY\">DNSKEY</option> <option value=\"MX\">MX</option> <option value=\"NS\">NS</option> <option value=\"PTR\">PTR</option> <option value=\"SOA\">SOA</option> <option value=\"SRV\">SRV</option> <option value=\"SVCB\">SVCB</option> <option value=\"TXT\">TXT</option></select> <label for=\"name\">Name:</label> <input type=\"text\" name=\"name\" placeholder=\"name to query\"> <button type=\"submit\">Query</button></form>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<label for=\"norecursion\" title=\"Clear the Recursion Desired (RD) bit, to query an authoritative server\">No recursion:</label> <input type=\"checkbox\" name=\"norecursion\"> <label for=\"cd\" title=\"Ask validating resolvers to return answers that fail DNSSEC validation\">Checking disabled:</label> <input type=\"checkbox\" name=\"cd\"> <label for=\"capture\" title=\"Store the raw request and response of every lookup\">Capture:</label> <input type=\"checkbox\" name=\"capture\"> <label for=\"dns64\" title=\"Resolve A records alongside AAAA records synthesized by DNS64\">DNS64:</label> <input type=\"checkbox\" name=\"dns64\"></details> <button type=\"submit\">Query</button></form>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
+			if len(recipes) > 0 {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<h3>Recipes</h3><p>Run a set of queries commonly made together against a domain.</p><form method=\"POST\" action=\"/recipe\"><label for=\"recipe\">Recipe:</label> <select name=\"recipe\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				for _, recipe := range recipes {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "<option value=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var6 string
+					templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(recipe.ID.String())
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 122, Col: 40}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "\" title=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var7 string
+					templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(recipe.Description)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 122, Col: 69}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var8 string
+					templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(recipe.Name)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/views/templates/index.templ`, Line: 122, Col: 85}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "</option>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "</select> <label for=\"name\">Name:</label> <input type=\"text\" name=\"name\" placeholder=\"domain to check\"> <button type=\"submit\">Run</button></form>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
 			return nil
 		})
 		templ_7745c5c3_Err = page(NoPageTitle).Render(templ.WithChildren(ctx, templ_7745c5c3_Var2), templ_7745c5c3_Buffer)
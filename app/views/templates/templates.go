@@ -2,6 +2,8 @@ package templates
 
 import (
 	"embed"
+	"errors"
+	"io/fs"
 	"net/http"
 )
 
@@ -10,7 +12,38 @@ var assets embed.FS
 
 // Assets returns an HTTP Handler that serves static CSS and Images to support
 // the applications templates. prefix will automatically be stripped from the
-// request path before serving.
-func Assets(prefix string) http.Handler {
-	return http.StripPrefix(prefix, http.FileServerFS(assets))
+// request path before serving. If override is set, files within it are served
+// in place of the embedded assets of the same path, such as `css/style.css`,
+// falling back to the embedded assets otherwise.
+func Assets(prefix string, override fs.FS) http.Handler {
+	if override == nil {
+		return http.StripPrefix(prefix, http.FileServerFS(assets))
+	}
+
+	h := http.StripPrefix(prefix, http.FileServerFS(&overlayFS{override: override, base: assets}))
+
+	// overridden assets may be changed while DENNIS is running, so browsers
+	// must revalidate them on every request. unchanged assets are answered
+	// with 304 Not Modified from their modification time.
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// overlayFS is a file system that opens files from override if they exist,
+// otherwise from base. Files are opened from override on every call, so
+// changes are visible immediately.
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.override.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.base.Open(name)
+	}
+
+	return f, err
 }
@@ -0,0 +1,94 @@
+package text
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+// calendar is a Template that renders scheduled Queries as an iCalendar feed.
+type calendar struct {
+	queries []*models.Query
+	now     time.Time
+}
+
+// Calendar renders Queries scheduled with RunAt as an iCalendar (RFC 5545)
+// feed, with an event for each Query at the time it will run, so planned DNS
+// verification can be subscribed to from a calendar. Events are stamped as
+// generated at now.
+func Calendar(queries []*models.Query, now time.Time) web.Template {
+	return &calendar{queries: queries, now: now}
+}
+
+func (t *calendar) ContentType() string {
+	return "text/calendar; charset=utf-8"
+}
+
+// icsTime is the layout of a UTC timestamp in iCalendar.
+const icsTime = "20060102T150405Z"
+
+func (t *calendar) Render(_ context.Context, w io.Writer) error {
+	now := t.now.UTC().Format(icsTime)
+
+	icsLine(w, "BEGIN:VCALENDAR")
+	icsLine(w, "VERSION:2.0")
+	icsLine(w, "PRODID:-//jamescun//DENNIS//EN")
+	icsLine(w, "X-WR-CALNAME:DENNIS scheduled queries")
+
+	for _, q := range t.queries {
+		if q.RunAt == nil {
+			continue
+		}
+
+		types := strings.Join(q.AllTypes(), ", ")
+		description := "Query " + q.ID.String() + " is scheduled to resolve " + types + " records of " + q.Name + "."
+
+		icsLine(w, "BEGIN:VEVENT")
+		icsLine(w, "UID:"+q.ID.String()+"@dennis")
+		icsLine(w, "DTSTAMP:"+now)
+		icsLine(w, "DTSTART:"+q.RunAt.UTC().Format(icsTime))
+		icsLine(w, "SUMMARY:"+icsEscape("DNS query: "+types+" "+q.Name))
+		icsLine(w, "DESCRIPTION:"+icsEscape(description))
+		icsLine(w, "END:VEVENT")
+	}
+
+	return icsLine(w, "END:VCALENDAR")
+}
+
+// icsLineLength is the longest a line of iCalendar may be, in octets,
+// excluding it's line break.
+const icsLineLength = 75
+
+// icsLine writes line to w terminated by CRLF, as iCalendar requires. Lines
+// longer than icsLineLength are folded onto continuation lines beginning with
+// a space, without splitting a UTF-8 character (RFC 5545, section 3.1).
+func icsLine(w io.Writer, line string) error {
+	var b strings.Builder
+
+	for limit := icsLineLength; len(line) > limit; limit = icsLineLength - 1 {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+
+		b.WriteString(line[:i])
+		b.WriteString("\r\n ")
+
+		line = line[i:]
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// icsEscape escapes the characters with special meaning in an iCalendar text
+// value.
+var icsEscape = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace
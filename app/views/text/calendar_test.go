@@ -0,0 +1,93 @@
+package text
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestCalendar(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	runAt := now.Add(time.Hour)
+
+	queries := []*models.Query{
+		{ID: uuid.Must(uuid.FromString("0195a1b2-0000-7000-8000-000000000000")), Type: "A", Name: "example.com"},
+		{
+			ID:    uuid.Must(uuid.FromString("0195a1b2-0000-7000-8000-000000000001")),
+			Type:  "A",
+			Types: []string{"A", "AAAA", "MX", "TXT"},
+			Name:  "a-rather-long-name.of-a-zone.example.com",
+			RunAt: &runAt,
+		},
+	}
+
+	got := render(t, Calendar(queries, now))
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//jamescun//DENNIS//EN",
+		"X-WR-CALNAME:DENNIS scheduled queries",
+		"BEGIN:VEVENT",
+		"UID:0195a1b2-0000-7000-8000-000000000001@dennis",
+		"DTSTAMP:20260101T000000Z",
+		"DTSTART:20260101T010000Z",
+		`SUMMARY:DNS query: A\, AAAA\, MX\, TXT a-rather-long-name.of-a-zone.example`,
+		" .com",
+		"DESCRIPTION:Query 0195a1b2-0000-7000-8000-000000000001 is scheduled to reso",
+		` lve A\, AAAA\, MX\, TXT records of a-rather-long-name.of-a-zone.example.co`,
+		" m.",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	if got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestICSLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"Short", "SUMMARY:short", []string{"SUMMARY:short"}},
+		{"Exact", strings.Repeat("a", 75), []string{strings.Repeat("a", 75)}},
+		{
+			"Folded", strings.Repeat("a", 75+74+1),
+			[]string{strings.Repeat("a", 75), " " + strings.Repeat("a", 74), " a"},
+		},
+		{
+			// the 2 octets of é would straddle the fold, so are moved to the
+			// continuation line.
+			"Multibyte", strings.Repeat("a", 74) + "é",
+			[]string{strings.Repeat("a", 74), " é"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := new(strings.Builder)
+			if err := icsLine(b, test.line); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+			if strings.Join(lines, "|") != strings.Join(test.want, "|") {
+				t.Errorf("expected %q, got %q", test.want, lines)
+			}
+
+			for _, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("expected lines of at most 75 octets, got %d", len(l))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package text implements plain text renderings of DENNIS' models, suitable
+// for terminals, emails and anything else that can't render HTML.
+package text
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+// query is a Template that renders a Query as plain text.
+type query struct {
+	q *models.Query
+}
+
+// Query renders the result of querying the configured DNS resolvers as plain
+// text, with the records of each Lookup aligned in columns.
+func Query(q *models.Query) web.Template {
+	return &query{q: q}
+}
+
+func (t *query) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (t *query) Render(_ context.Context, w io.Writer) error {
+	q := t.q
+
+	types := strings.Join(q.AllTypes(), ", ")
+
+	if q.Class != "" {
+		fmt.Fprintf(w, "%s %s: %s\n", q.Class, types, q.Name)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", types, q.Name)
+	}
+	fmt.Fprintf(w, "Created At:  %s\n", q.CreatedAt.Format(time.RFC3339))
+	if q.RunAt != nil {
+		fmt.Fprintf(w, "Run At:      %s\n", q.RunAt.Format(time.RFC3339))
+	}
+	if q.Server != "" && !q.IsTransfer() {
+		fmt.Fprintf(w, "Server:      @%s\n", q.Server)
+	}
+	if q.Serial != nil {
+		fmt.Fprintf(w, "Serial:      %d\n", *q.Serial)
+	}
+	if len(q.Groups) > 0 {
+		fmt.Fprintf(w, "Groups:      %s\n", strings.Join(q.Groups, ", "))
+	}
+	if q.EDNS0 != nil && q.EDNS0.Disabled {
+		fmt.Fprintf(w, "EDNS0:       disabled\n")
+	} else if q.EDNS0 != nil && q.EDNS0.UDPSize > 0 {
+		fmt.Fprintf(w, "EDNS0:       %d bytes\n", q.EDNS0.UDPSize)
+	}
+	if q.NoRecursion && q.CheckingDisabled {
+		fmt.Fprintf(w, "Flags:       -rd +cd\n")
+	} else if q.NoRecursion {
+		fmt.Fprintf(w, "Flags:       -rd\n")
+	} else if q.CheckingDisabled {
+		fmt.Fprintf(w, "Flags:       +cd\n")
+	}
+	if q.FinishedAt == nil {
+		fmt.Fprintf(w, "Finished At: still resolving...\n\n")
+	} else if q.Interrupted {
+		fmt.Fprintf(w, "Finished At: %s (interrupted, DENNIS stopped while resolving)\n\n", q.FinishedAt.Format(time.RFC3339))
+	} else if q.Partial {
+		fmt.Fprintf(w, "Finished At: %s (partial, some lookups could not be saved)\n\n", q.FinishedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(w, "Finished At: %s\n\n", q.FinishedAt.Format(time.RFC3339))
+	}
+
+	estimates := q.EstimateCache()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOLVER\tRTT\tTTL\tCONTENT")
+
+	for _, lookup := range q.Lookups {
+		resolver, rtt := lookup.Label(), strconv.Itoa(lookup.RTT)+"ms"
+		if lookup.Type != "" {
+			resolver = lookup.Type + " " + resolver
+		}
+		if lookup.Attempts > 1 {
+			rtt += " (" + strconv.Itoa(lookup.Attempts) + " attempts)"
+		}
+
+		if lookup.DNSSEC != nil {
+			resolver += " (dnssec: " + *lookup.DNSSEC + ")"
+		}
+
+		if lookup.DNS64 != nil {
+			resolver += " (dns64)"
+		}
+
+		if lookup.EDNS0Differs {
+			resolver += " (edns0 differs)"
+		}
+
+		if lookup.ResolverHealth != nil {
+			resolver += " (failing health checks)"
+		}
+
+		if e, ok := estimates[lookup]; ok && e.Stale && !q.IsTransfer() {
+			resolver += " (stale until " + e.ExpiresAt.Format(time.RFC3339) + ")"
+		} else if ok && e.Cached && !q.IsTransfer() {
+			resolver += " (cached until " + e.ExpiresAt.Format(time.RFC3339) + ")"
+		}
+
+		if lookup.Header != nil {
+			resolver += " (" + lookup.Header.Rcode + "; " + lookup.Header.Flags() + ")"
+		}
+
+		if lookup.Error != nil {
+			fmt.Fprintf(tw, "%s\t%s\t-\t%s\n", resolver, rtt, *lookup.Error)
+			resolver, rtt = "", ""
+		}
+
+		for _, record := range lookup.Records {
+			for _, content := range record.Content {
+				// records of a zone transfer are of any name and type.
+				if record.Name != "" {
+					content = record.Name + " " + record.Type + " " + content
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", resolver, rtt, record.TTL, content)
+
+				// only print the resolver on the first line of it's records.
+				resolver, rtt = "", ""
+			}
+
+			if len(record.Params) > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", resolver, rtt, record.TTL, record.ParamString())
+				resolver, rtt = "", ""
+			}
+		}
+
+		if lookup.DNS64 != nil {
+			synthesized := "(synthesized from " + strings.Join(lookup.DNS64.Embedded, ", ")
+			if len(lookup.DNS64.A) > 0 {
+				synthesized += "; A records: " + strings.Join(lookup.DNS64.A, ", ")
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t-\t%s)\n", resolver, rtt, synthesized)
+			resolver, rtt = "", ""
+		}
+
+		if lookup.TruncatedByDennis {
+			fmt.Fprintf(tw, "%s\t%s\t-\t(%d of %d records shown)\n", resolver, rtt, len(lookup.Records), lookup.TotalRecords)
+			resolver, rtt = "", ""
+		}
+
+		// records of the authority and additional sections are prefixed with
+		// their section, name and type, as they may differ from the Query.
+		for _, section := range []struct {
+			name    string
+			records []*models.Record
+		}{{"authority", lookup.Authority}, {"additional", lookup.Additional}} {
+			for _, record := range section.records {
+				for _, content := range record.Content {
+					fmt.Fprintf(
+						tw, "%s\t%s\t%d\t[%s] %s %s %s\n",
+						resolver, rtt, record.TTL, section.name, record.Name, record.Type, content,
+					)
+					resolver, rtt = "", ""
+				}
+			}
+		}
+
+		// a Lookup that printed nothing else, such as one answered with
+		// NOERROR but no records, still has a row of it's own.
+		if rtt != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\n", resolver, rtt)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,140 @@
+package text
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamescun/dennis/app/models"
+	"github.com/jamescun/dennis/app/pkg/http/web"
+)
+
+// render returns tpl rendered as a string.
+func render(t *testing.T, tpl web.Template) string {
+	t.Helper()
+
+	b := new(strings.Builder)
+	if err := tpl.Render(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestQuery(t *testing.T) {
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finishedAt := createdAt.Add(time.Second)
+
+	tests := []struct {
+		name    string
+		query   *models.Query
+		lookups string
+	}{
+		{
+			"Error",
+			&models.Query{Type: "A", Lookups: []*models.Lookup{
+				{Resolver: "a", RTT: 12, Error: new("TIMEOUT"), Records: []*models.Record{}},
+				{Resolver: "b", RTT: 3, Attempts: 2, Error: new("SERVFAIL"), Records: []*models.Record{}},
+			}},
+			`
+RESOLVER  RTT               TTL  CONTENT
+a         12ms              -    TIMEOUT
+b         3ms (2 attempts)  -    SERVFAIL
+`,
+		},
+		{
+			"NoRecords",
+			&models.Query{Type: "A", Lookups: []*models.Lookup{
+				{
+					Resolver: "a", RTT: 5, Records: []*models.Record{},
+					Header: &models.ResponseHeader{Rcode: "NOERROR", RecursionDesired: true, RecursionAvailable: true},
+				},
+			}},
+			`
+RESOLVER               RTT  TTL  CONTENT
+a (NOERROR; qr rd ra)  5ms  -    -
+`,
+		},
+		{
+			"Records",
+			&models.Query{Type: "A", Lookups: []*models.Lookup{
+				{Resolver: "a", RTT: 5, Records: []*models.Record{
+					{TTL: 60, Content: []string{"192.0.2.1"}},
+					{TTL: 60, Content: []string{"192.0.2.2"}},
+				}},
+			}},
+			`
+RESOLVER  RTT  TTL  CONTENT
+a         5ms  60   192.0.2.1
+               60   192.0.2.2
+`,
+		},
+		{
+			"SVCBParams",
+			&models.Query{Type: "HTTPS", Lookups: []*models.Lookup{
+				{Resolver: "a", RTT: 5, Records: []*models.Record{
+					{
+						TTL: 300, Content: []string{"1 ."},
+						Params: map[string]string{"port": "443", "alpn": "h2,h3", "no-default-alpn": ""},
+					},
+				}},
+			}},
+			`
+RESOLVER  RTT  TTL  CONTENT
+a         5ms  300  1 .
+               300  alpn=h2,h3 no-default-alpn port=443
+`,
+		},
+		{
+			"Sections",
+			&models.Query{Type: "A", Lookups: []*models.Lookup{
+				{
+					Resolver: "a", RTT: 5, Records: []*models.Record{},
+					Authority: []*models.Record{
+						{Name: "example.com.", Type: "NS", TTL: 3600, Content: []string{"ns1.example.com."}},
+					},
+					Additional: []*models.Record{
+						{Name: "ns1.example.com.", Type: "A", TTL: 3600, Content: []string{"192.0.2.53"}},
+					},
+				},
+			}},
+			`
+RESOLVER  RTT  TTL   CONTENT
+a         5ms  3600  [authority] example.com. NS ns1.example.com.
+               3600  [additional] ns1.example.com. A 192.0.2.53
+`,
+		},
+		{
+			"Transfer",
+			&models.Query{Type: "AXFR", Server: "192.0.2.53:53", Lookups: []*models.Lookup{
+				{
+					Resolver: "192.0.2.53:53", RTT: 40, TotalRecords: 3, TruncatedByDennis: true,
+					Records: []*models.Record{
+						{Name: "example.com.", Type: "SOA", TTL: 60, Content: []string{"ns.example.com. admin.example.com. 1"}},
+						{Name: "www.example.com.", Type: "A", TTL: 60, Content: []string{"192.0.2.1"}},
+					},
+				},
+			}},
+			`
+RESOLVER       RTT   TTL  CONTENT
+192.0.2.53:53  40ms  60   example.com. SOA ns.example.com. admin.example.com. 1
+                     60   www.example.com. A 192.0.2.1
+                     -    (2 of 3 records shown)
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := test.query
+			q.Name, q.CreatedAt, q.FinishedAt = "example.com", createdAt, &finishedAt
+
+			header := q.Type + ": example.com\nCreated At:  2026-01-01T00:00:00Z\nFinished At: 2026-01-01T00:00:01Z\n"
+
+			if got, want := render(t, Query(q)), header+test.lookups; got != want {
+				t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/pkg/build"
+)
+
+// doctorTimeout is the longest doctor waits for the database to answer.
+const doctorTimeout = 10 * time.Second
+
+// the statuses of a check made by doctor.
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+// doctorColors are the ANSI escape codes each status is printed in, when
+// writing to a terminal.
+var doctorColors = map[string]string{
+	doctorPass: "\x1b[32m",
+	doctorWarn: "\x1b[33m",
+	doctorFail: "\x1b[31m",
+}
+
+// doctorReport prints the outcome of each check made by doctor, one per line.
+type doctorReport struct {
+	w      io.Writer
+	color  bool
+	failed bool
+}
+
+// check prints the outcome of a single check, recording if it failed.
+func (r *doctorReport) check(status, name, format string, args ...any) {
+	if status == doctorFail {
+		r.failed = true
+	}
+
+	label := status
+	if r.color {
+		label = doctorColors[status] + status + "\x1b[0m"
+	}
+
+	fmt.Fprintf(r.w, "%s  %-10s %s\n", label, name, fmt.Sprintf(format, args...))
+}
+
+// isTerminal returns true if f is a terminal, rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// doctor runs a series of local checks against the configuration in
+// configFile, printing a pass or fail report to STDOUT, to diagnose why
+// DENNIS does not start or work as expected. It returns 1 if any check failed.
+func doctor(ctx context.Context, configFile string) int {
+	r := &doctorReport{
+		w:     os.Stdout,
+		color: isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+
+	cfg, err := config.Read(configFile)
+	if err != nil {
+		r.check(doctorFail, "config", "%s: %s", configFile, err)
+
+		// every other check depends on the configuration.
+		return 1
+	}
+
+	r.check(doctorPass, "config", "%s is valid", configFile)
+
+	doctorDB(ctx, r, cfg)
+	doctorResolvers(ctx, r, cfg)
+
+	doctorListen(r, "listen", cfg.Listen.Addr)
+	if cfg.Admin != nil {
+		doctorListen(r, "admin", cfg.Admin.Addr)
+	}
+
+	doctorClock(r, time.Now())
+
+	if r.failed {
+		return 1
+	}
+
+	return 0
+}
+
+// doctorDB checks the database can be connected to and read from. A file
+// database that does not exist yet is not created, as DENNIS creates it when
+// it starts, only it's directory is checked.
+func doctorDB(ctx context.Context, r *doctorReport, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	if f := cfg.DB.File; f != nil {
+		if _, err := os.Stat(f.Path); errors.Is(err, fs.ErrNotExist) {
+			if info, err := os.Stat(filepath.Dir(f.Path)); err != nil || !info.IsDir() {
+				r.check(doctorFail, "database", "%s does not exist, nor does it's directory", f.Path)
+			} else {
+				r.check(doctorWarn, "database", "%s does not exist yet, it will be created when DENNIS starts", f.Path)
+			}
+
+			return
+		}
+	}
+
+	conn, err := getDB(ctx, slog.New(slog.DiscardHandler), cfg.DB, 0, getCompressor(cfg.DB))
+	if err != nil {
+		r.check(doctorFail, "database", "could not connect to %s: %s", cfg.DB.Backend(), err)
+		return
+	}
+
+	if c, ok := conn.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	_, err = conn.ListScheduledQueries(ctx)
+	if err != nil {
+		r.check(doctorFail, "database", "could not read from %s: %s", cfg.DB.Backend(), err)
+		return
+	}
+
+	r.check(doctorPass, "database", "%s is reachable", cfg.DB.Backend())
+}
+
+// doctorResolvers probes each configured resolver as DENNIS does when it
+// starts. Resolvers found by discovery are not checked.
+func doctorResolvers(ctx context.Context, r *doctorReport, cfg *config.Config) {
+	// the Server is only used to probe resolvers, so is given no database
+	// and it's logs are discarded.
+	srv := app.NewServer(
+		nil, cfg.Resolvers, cfg.Resolution, apiv1.NewFeatures(cfg.Features), slog.New(slog.DiscardHandler),
+	)
+	defer srv.Close()
+
+	srv.ProbeResolvers(ctx)
+
+	res, err := srv.ListResolvers(ctx, &apiv1.ListResolversRequest{})
+	if err != nil {
+		r.check(doctorFail, "resolver", "could not list resolvers: %s", err)
+		return
+	}
+
+	for _, rsv := range res.Resolvers {
+		name := rsv.Name + " (" + rsv.Addr + ")"
+
+		switch caps := rsv.Capabilities; {
+		case caps == nil || !caps.Reachable:
+			r.check(doctorFail, "resolver", "%s did not answer over UDP", name)
+		case !caps.TCP:
+			r.check(doctorWarn, "resolver", "%s did not answer over TCP, truncated answers cannot be retried", name)
+		case !caps.Recursive:
+			r.check(doctorWarn, "resolver", "%s does not offer recursion", name)
+		default:
+			r.check(doctorPass, "resolver", "%s answered in %dms", name, caps.RTT)
+		}
+	}
+}
+
+// doctorListen checks that addr can be listened on, such as that another
+// instance of DENNIS is not already running.
+func doctorListen(r *doctorReport, name, addr string) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		r.check(doctorFail, name, "cannot listen on %s: %s", addr, err)
+		return
+	}
+
+	l.Close()
+
+	r.check(doctorPass, name, "%s is available", addr)
+}
+
+// doctorClock checks that the system clock is not behind the date DENNIS was
+// built, as a clock far in the past breaks TLS and misdates Queries.
+func doctorClock(r *doctorReport, now time.Time) {
+	built, err := time.Parse(time.RFC3339, build.GetDate())
+	if err != nil {
+		r.check(doctorWarn, "clock", "could not be checked, the build date of DENNIS is unknown")
+		return
+	}
+
+	if now.Before(built) {
+		r.check(
+			doctorFail, "clock", "%s is before DENNIS was built on %s",
+			now.UTC().Format(time.RFC3339), built.UTC().Format(time.RFC3339),
+		)
+		return
+	}
+
+	r.check(doctorPass, "clock", "%s", now.UTC().Format(time.RFC3339))
+}
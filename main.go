@@ -1,16 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"os/user"
+	"reflect"
+	"runtime/pprof"
+	"strings"
+	"syscall"
 	"time"
 
+	apiv1 "github.com/jamescun/dennis/api/v1"
 	"github.com/jamescun/dennis/app"
 	"github.com/jamescun/dennis/app/config"
 	"github.com/jamescun/dennis/app/db"
@@ -35,14 +44,18 @@ func run(ctx context.Context, configFile string) int {
 		return exitError(2, "config: %s", err)
 	}
 
-	log := cfg.Logging.GetLogger()
+	// recent logs are retained for support bundles.
+	logs := app.NewRecentLogs()
+	log := cfg.Logging.NewLogger(io.MultiWriter(os.Stdout, logs))
 
 	queryMaxAge := time.Duration(0)
 	if cfg.QueryMaxAge > 0 {
 		queryMaxAge = time.Duration(cfg.QueryMaxAge) * time.Second
 	}
 
-	conn, err := getDB(ctx, cfg.DB, queryMaxAge)
+	compressor := getCompressor(cfg.DB)
+
+	conn, err := getDB(ctx, log, cfg.DB, queryMaxAge, compressor)
 	if err != nil {
 		return exitError(1, "db: %s", err)
 	}
@@ -53,17 +66,153 @@ func run(ctx context.Context, configFile string) int {
 		go expireOldQueries(ctx, log, conn, queryMaxAge)
 	}
 
-	api := app.NewServer(conn, cfg.Resolvers, log)
-	ui := app.NewUI(api, log)
+	features := apiv1.NewFeatures(cfg.Features)
+
+	api := app.NewServer(conn, cfg.Resolvers, cfg.Resolution, features, log)
+	api.SetMaintenance(cfg.Maintenance)
+	api.SetQueryMaxAge(queryMaxAge)
+
+	instanceID := cfg.GetInstanceID()
+	api.SetInstanceID(instanceID)
+	api.SetLocation(cfg.Location)
+
+	// resolvers added or disabled from the admin server are loaded before
+	// any Query is recovered or scheduled, as they may be resolved at once.
+	err = api.LoadResolvers(ctx)
+	if err != nil {
+		return exitError(1, "db: could not load resolvers: %s", err)
+	}
+
+	var agents *app.Agents
+	if cfg.Agents != nil {
+		agents = app.NewAgents(cfg.Agents.Token, time.Duration(cfg.Agents.Wait)*time.Second, log)
+		api.SetAgents(agents)
+	}
+
+	err = api.RecoverJobs(ctx)
+	if err != nil {
+		return exitError(1, "db: could not recover jobs: %s", err)
+	}
+
+	err = api.LoadScheduled(ctx)
+	if err != nil {
+		return exitError(1, "db: could not load scheduled queries: %s", err)
+	}
+
+	// dispatched Queries are only received once every resolver has been
+	// loaded, as they may be resolved against any of them.
+	if d, ok := conn.(db.Dispatcher); ok && cfg.DB.Redis != nil && cfg.DB.Redis.Dispatch {
+		log.Info("dispatching queries between instances")
+
+		api.SetDispatcher(d)
+	}
+
+	err = api.RecordResolverChanges(ctx, cfg.Resolvers, app.ResolverChangeSourceConfig, actor(configFile))
+	if err != nil {
+		return exitError(1, "db: could not record resolver changes: %s", err)
+	}
+
+	go api.RecoverAbandonedJobs(ctx)
+	go api.ProbeResolvers(ctx)
+	go api.DetectNXDOMAINRedirection(ctx)
+
+	go api.CheckResolverHealth(ctx, cfg.HealthCheck)
+	go api.WatchDB(ctx)
+
+	for i, d := range cfg.Discovery {
+		discovery, err := app.NewDiscovery(d, api, log)
+		if err != nil {
+			return exitError(2, "config: discovery[%d]: %s", i, err)
+		}
+
+		go discovery.Run(ctx)
+	}
+
+	var assets fs.FS
+	if cfg.UI.Assets != "" {
+		if info, err := os.Stat(cfg.UI.Assets); err != nil || !info.IsDir() {
+			return exitError(2, "config: ui.assets: must be a directory")
+		}
+
+		assets = os.DirFS(cfg.UI.Assets)
+	}
+
+	errs := app.NewErrors()
+
+	ui := app.NewUI(api, assets, cfg.UI.Support, errs, log)
+	httpAPI := app.NewAPI(api, cfg.HideResolverAddrs, errs, log)
 
 	r := web.New(log)
+	if cfg.Logging.SampleRate > 0 {
+		r.SampleLogs(cfg.Logging.SampleRate)
+	}
+
+	if al := cfg.Logging.AccessLog; al != nil {
+		w, err := al.Open()
+		if err != nil {
+			return exitError(2, "config: logging.accessLog: could not open: %s", err)
+		}
+		defer w.Close()
+
+		r.Use(web.AccessLog(w, al.Combined()))
+	}
+
+	if instanceID != "" {
+		r.Use(web.SetHeader("Served-By", instanceID))
+	}
+
 	r.Route("/", ui.Routes)
 
+	if features.Enabled(apiv1.FeatureAPI) {
+		r.Route("/api/v1", httpAPI.Routes)
+	}
+
+	if agents != nil {
+		r.Route("/agent/v1", agents.Routes)
+	}
+
 	s := &http.Server{
 		Addr:    cfg.Listen.Addr,
 		Handler: r,
 	}
 
+	if agents != nil {
+		s.RegisterOnShutdown(agents.Close)
+	}
+
+	telemetry := app.NewTelemetry(cfg.Telemetry, cfg.DB.Backend(), len(cfg.Resolvers), api, log)
+	go telemetry.Run(ctx)
+
+	reports := app.NewReports(conn, log)
+
+	var adminServer *http.Server
+	if cfg.Admin != nil {
+		go reports.Run(ctx)
+
+		support := app.NewSupport(api, cfg, logs, errs)
+		admin := app.NewAdmin(api, telemetry, reports, compressor, errs, support, cfg.Admin.Pprof, log)
+
+		ar := web.New(log)
+		ar.Route("/", admin.Routes)
+
+		adminServer = &http.Server{
+			Addr:    cfg.Admin.Addr,
+			Handler: ar,
+		}
+
+		go func() {
+			log.Info("starting DENNIS admin...", slog.String("addr", cfg.Admin.Addr))
+
+			err := adminServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("DENNIS admin server error", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	go dumpDiagnosticsOnSignal(ctx, log, api)
+	go reloadOnChange(ctx, log, api, configFile, cfg)
+
 	// launch goroutine to initiate a graceful shutdown when an interrupt is
 	// received.
 	go func() {
@@ -80,6 +229,13 @@ func run(ctx context.Context, configFile string) int {
 		if err != nil {
 			log.Error("could not shutdown gracefully", slog.String("error", err.Error()))
 		}
+
+		if adminServer != nil {
+			err := adminServer.Shutdown(ctx)
+			if err != nil {
+				log.Error("could not shutdown admin gracefully", slog.String("error", err.Error()))
+			}
+		}
 	}()
 
 	log.Info(
@@ -93,14 +249,45 @@ func run(ctx context.Context, configFile string) int {
 		log.Error("DENNIS server error", slog.String("error", err.Error()))
 	}
 
+	// wait for any queued resolutions to complete before exiting.
+	err = api.Close()
+	if err != nil {
+		log.Error("could not close DENNIS server", slog.String("error", err.Error()))
+	}
+
+	// then wait for any writes held by the database to be made.
+	if c, ok := conn.(io.Closer); ok {
+		err = c.Close()
+		if err != nil {
+			log.Error("could not close database", slog.String("error", err.Error()))
+		}
+	}
+
 	return 0
 }
 
+// getCompressor configures the compression of stored Queries, or returns nil
+// if it is not enabled.
+func getCompressor(cfg config.DB) *db.Compressor {
+	if cfg.Compression == nil {
+		return nil
+	}
+
+	threshold := db.DefaultCompressionThreshold
+	if cfg.Compression.Threshold > 0 {
+		threshold = cfg.Compression.Threshold
+	}
+
+	return db.NewCompressor(threshold)
+}
+
 // getDB configures a database backend from the configuration file.
-func getDB(ctx context.Context, cfg config.DB, maxAge time.Duration) (db.DB, error) {
+func getDB(
+	ctx context.Context, log *slog.Logger, cfg config.DB, maxAge time.Duration, compressor *db.Compressor,
+) (db.DB, error) {
 	switch {
 	case cfg.File != nil:
-		conn, err := file.FromConfig(ctx, cfg.File)
+		conn, err := file.FromConfig(ctx, cfg.File, compressor)
 		if err != nil {
 			return nil, fmt.Errorf("file: %w", err)
 		}
@@ -113,10 +300,12 @@ func getDB(ctx context.Context, cfg config.DB, maxAge time.Duration) (db.DB, err
 			return nil, fmt.Errorf("postgres: %w", err)
 		}
 
+		conn.SetLogger(log)
+
 		return conn, nil
 
 	case cfg.Redis != nil:
-		conn, err := redis.FromConfig(ctx, cfg.Redis, maxAge)
+		conn, err := redis.FromConfig(ctx, cfg.Redis, maxAge, compressor)
 		if err != nil {
 			return nil, fmt.Errorf("redis: %w", err)
 		}
@@ -147,16 +336,139 @@ func expireOldQueries(ctx context.Context, log *slog.Logger, conn db.DB, maxAge
 	}
 }
 
+// actor describes who started DENNIS with configFile, as the user and host it
+// is running as, to attribute changes made by configuration.
+func actor(configFile string) string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return username + "@" + hostname + " (" + configFile + ")"
+}
+
+// configPollInterval is how often the configuration file is checked for
+// changes, as a Kubernetes ConfigMap mounted as a volume is updated in place
+// without DENNIS being signalled.
+const configPollInterval = 10 * time.Second
+
+// reloadOnChange reloads the resolvers configured by configFile whenever
+// SIGHUP is received or it's content changes. Every other setting of cfg only
+// takes effect when DENNIS is restarted, which is logged if they change.
+func reloadOnChange(ctx context.Context, log *slog.Logger, api *app.Server, configFile string, cfg *config.Config) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	last, _ := os.ReadFile(configFile)
+
+	for {
+		select {
+		case <-hup:
+			last, _ = os.ReadFile(configFile)
+
+		case <-ticker.C:
+			b, err := os.ReadFile(configFile)
+			if err != nil || bytes.Equal(b, last) {
+				continue
+			}
+
+			last = b
+
+		case <-ctx.Done():
+			return
+		}
+
+		next, err := config.Read(configFile)
+		if err != nil {
+			log.Error("could not reload configuration", slog.String("error", err.Error()))
+			continue
+		}
+
+		err = api.ReloadResolvers(ctx, next.Resolvers, actor(configFile))
+		if err != nil {
+			log.Error("could not reload resolvers", slog.String("error", err.Error()))
+			continue
+		}
+
+		log.Info("reloaded resolvers", slog.Int("resolvers", len(next.Resolvers)))
+
+		// only the resolvers are compared against the configuration DENNIS
+		// started with, as they are the only settings reloaded.
+		before, after := *cfg, *next
+		before.Resolvers, after.Resolvers = nil, nil
+
+		if !reflect.DeepEqual(before, after) {
+			log.Warn("configuration has changed besides resolvers, restart DENNIS to apply it")
+		}
+	}
+}
+
+// dumpDiagnosticsOnSignal writes the stacks of all goroutines, the state of
+// the resolution queue and database statistics to the log whenever SIGQUIT
+// is received, to diagnose stuck resolutions in production. This replaces the
+// Go runtime's default behaviour of exiting on SIGQUIT.
+func dumpDiagnosticsOnSignal(ctx context.Context, log *slog.Logger, api *app.Server) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGQUIT)
+	defer signal.Stop(quit)
+
+	for {
+		select {
+		case <-quit:
+			stacks := new(strings.Builder)
+			pprof.Lookup("goroutine").WriteTo(stacks, 2)
+
+			// the statistics of the database are included in those of the
+			// server.
+			log.Info(
+				"diagnostic dump",
+				slog.Any("server", api.Diagnostics()),
+				slog.String("goroutines", stacks.String()),
+			)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func main() {
 	flag.Parse()
 
 	// if requested, print version information then exit.
 	if *showVersion {
-		fmt.Printf("Version: %s\nCommit:  %s\n", build.GetVersion(), build.GetCommit(7))
+		fmt.Printf(
+			"Version: %s\nCommit:  %s\nDate:    %s\nGo:      %s\n",
+			build.GetVersion(), build.GetCommit(7), build.GetDate(), build.GetGoVersion(),
+		)
 		return
 	}
 
-	os.Exit(run(context.Background(), *configFile))
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+		os.Exit(run(context.Background(), *configFile))
+
+	case "doctor":
+		os.Exit(doctor(context.Background(), *configFile))
+
+	case "tui":
+		os.Exit(runTUI(context.Background(), *configFile, flag.Args()[1:]))
+
+	case "agent":
+		os.Exit(runAgent(context.Background(), *configFile, flag.Args()[1:]))
+
+	default:
+		os.Exit(exitError(2, "unknown command %q", cmd))
+	}
 }
 
 // exitError prints an formattable error message to STDERR and returns the
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	apiv1 "github.com/jamescun/dennis/api/v1"
+	"github.com/jamescun/dennis/app"
+	"github.com/jamescun/dennis/app/config"
+	"github.com/jamescun/dennis/app/tui"
+)
+
+// runTUI starts the interactive terminal interface, connected to the DENNIS
+// server given by the `-server` flag in args, or otherwise resolving Queries
+// locally using the configuration in configFile.
+func runTUI(ctx context.Context, configFile string, args []string) int {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	server := flags.String(
+		"server", "", "URL of a DENNIS server, such as http://localhost:8080, rather than running locally",
+	)
+	history := flags.String("history", historyPath(), "path to the history of queries, or empty to not keep it")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	h, err := tui.LoadHistory(*history)
+	if err != nil {
+		return exitError(1, "tui: %s", err)
+	}
+
+	if *server != "" {
+		client := apiv1.NewClient(*server, &http.Client{Timeout: 30 * time.Second})
+
+		err = tui.New(client, *server, h).Run(ctx, os.Stdin, os.Stdout)
+		if err != nil {
+			return exitError(1, "tui: %s", err)
+		}
+
+		return 0
+	}
+
+	cfg, err := config.Read(configFile)
+	if err != nil {
+		return exitError(2, "config: %s", err)
+	}
+
+	conn, err := getDB(ctx, slog.New(slog.DiscardHandler), cfg.DB, 0, getCompressor(cfg.DB))
+	if err != nil {
+		return exitError(1, "db: %s", err)
+	}
+
+	// logs would be drawn over the terminal interface, so are discarded.
+	api := app.NewServer(
+		conn, cfg.Resolvers, cfg.Resolution, apiv1.NewFeatures(cfg.Features), slog.New(slog.DiscardHandler),
+	)
+	api.SetInstanceID(cfg.GetInstanceID())
+	defer api.Close()
+
+	target, _ := filepath.Abs(configFile)
+
+	err = tui.New(api, target, h).Run(ctx, os.Stdin, os.Stdout)
+	if err != nil {
+		return exitError(1, "tui: %s", err)
+	}
+
+	return 0
+}
+
+// historyPath returns the default path of the history of the TUI, within the
+// user's configuration directory, or empty if there is none.
+func historyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "dennis", "history.jsonl")
+}